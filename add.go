@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type AddCommand struct {
+	*flag.FlagSet
+	paths []string
+}
+
+func NewAddCommand(args []string) *AddCommand {
+	c := &AddCommand{}
+	c.FlagSet = flag.NewFlagSet("add", flag.ExitOnError)
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go add PATH...\n")
+		fmt.Fprint(o, "\tAdd file contents to the index\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) == 0 {
+		fmt.Println("nothing specified, nothing added")
+		os.Exit(1)
+	}
+	c.paths = c.Args()
+
+	return c
+}
+
+func (c *AddCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(c.paths))
+	for _, p := range c.paths {
+		rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+	}
+	return repo.Add(paths)
+}
@@ -0,0 +1,362 @@
+// Package attr は.gitattributesによるパスごとの属性を扱う
+package attr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/ignore"
+	"github.com/showa-93/wyag-go/wildmatch"
+)
+
+// State は属性の状態
+type State int
+
+const (
+	Unspecified State = iota
+	// Set は "text" のように名前のみで指定された状態
+	Set
+	// Unset は "-text" のように打ち消された状態
+	Unset
+	// Value は "eol=lf" のように値が指定された状態
+	Value
+)
+
+type Attribute struct {
+	Name  string
+	State State
+	Value string
+}
+
+// String はcheck-attrと同じ形式で状態を返す
+func (a Attribute) String() string {
+	switch a.State {
+	case Set:
+		return "set"
+	case Unset:
+		return "unset"
+	case Value:
+		return a.Value
+	}
+	return "unspecified"
+}
+
+// Rule は.gitattributesの1行
+type Rule struct {
+	Source string
+	Line   int
+	// Macro は "[attr]name" で定義されたマクロの名前 マクロでない場合は空文字
+	Macro   string
+	Pattern *ignore.Pattern
+	Attrs   []Attribute
+}
+
+// builtinMacros は定義済みのマクロ
+var builtinMacros = map[string][]Attribute{
+	"binary": {
+		{Name: "diff", State: Unset},
+		{Name: "merge", State: Unset},
+		{Name: "text", State: Unset},
+	},
+}
+
+// ParseRules はファイルの内容を読み込む
+// 否定パターンは使えないため読み飛ばす
+func ParseRules(data []byte, base, source string) []*Rule {
+	var rules []*Rule
+	text := strings.TrimPrefix(string(data), "\xef\xbb\xbf")
+	for i, line := range strings.Split(text, "\n") {
+		r := parseRule(strings.TrimSpace(line), base)
+		if r == nil {
+			continue
+		}
+		r.Source = source
+		r.Line = i + 1
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ReadRules はファイルから読み込む ファイルが存在しない場合は空を返す
+func ReadRules(path, base, source string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ParseRules(data, base, source), nil
+}
+
+func parseRule(line, base string) *Rule {
+	if line == "" || line[0] == '#' {
+		return nil
+	}
+
+	var pattern, rest string
+	if line[0] == '"' {
+		p, n, ok := unquote(line)
+		if !ok {
+			return nil
+		}
+		pattern, rest = p, line[n:]
+	} else {
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			i = len(line)
+		}
+		pattern, rest = line[:i], line[i:]
+	}
+
+	r := &Rule{}
+	if strings.HasPrefix(pattern, "[attr]") {
+		r.Macro = strings.TrimPrefix(pattern, "[attr]")
+		if !validName(r.Macro) {
+			return nil
+		}
+	} else {
+		r.Pattern = ignore.ParsePattern(pattern, base)
+		if r.Pattern == nil || r.Pattern.Negate {
+			return nil
+		}
+	}
+
+	for _, f := range strings.Fields(rest) {
+		a := Attribute{Name: f, State: Set}
+		switch {
+		case f[0] == '-':
+			a = Attribute{Name: f[1:], State: Unset}
+		case f[0] == '!':
+			a = Attribute{Name: f[1:], State: Unspecified}
+		case strings.Contains(f, "="):
+			name, value, _ := strings.Cut(f, "=")
+			a = Attribute{Name: name, State: Value, Value: value}
+		}
+		if !validName(a.Name) {
+			continue
+		}
+		r.Attrs = append(r.Attrs, a)
+	}
+	return r
+}
+
+func validName(name string) bool {
+	if name == "" || name[0] == '-' {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '-' || c == '.' || c == '_' ||
+			(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			return false
+		}
+	}
+	return true
+}
+
+// unquote はCの文字列のように引用符で囲まれたパターンを読み込み、読み込んだバイト数を返す
+func unquote(s string) (string, int, bool) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return sb.String(), i + 1, true
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, false
+			}
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// Matcher はワークツリーの.gitattributesと追加のルールを組み合わせて属性を求める
+// 優先度はinfo、深いディレクトリの.gitattributes、浅いディレクトリの.gitattributes、globalの順に高く、
+// 同じファイル中では後に書かれたものが優先される
+type Matcher struct {
+	worktree string
+	caseFold bool
+	global   []*Rule
+	info     []*Rule
+	dirs     map[string][]*Rule
+}
+
+// NewMatcher はMatcherを作る
+// globalはcore.attributesFile、infoは.git/info/attributesのルール
+// worktreeが空の場合は.gitattributesを読み込まない
+func NewMatcher(worktree string, caseFold bool, global, info []*Rule) *Matcher {
+	return &Matcher{
+		worktree: worktree,
+		caseFold: caseFold,
+		global:   global,
+		info:     info,
+		dirs:     make(map[string][]*Rule),
+	}
+}
+
+// AddRules はdirの.gitattributesの代わりにrulesを使う
+func (m *Matcher) AddRules(dir string, rules []*Rule) {
+	m.dirs[dir] = rules
+}
+
+func (m *Matcher) gitattributes(dir string) ([]*Rule, error) {
+	if rules, ok := m.dirs[dir]; ok {
+		return rules, nil
+	}
+	var rules []*Rule
+	if m.worktree != "" {
+		source := ".gitattributes"
+		if dir != "" {
+			source = dir + "/.gitattributes"
+		}
+		r, err := ReadRules(filepath.Join(m.worktree, filepath.FromSlash(source)), dir, source)
+		if err != nil {
+			return nil, err
+		}
+		rules = r
+	}
+	m.dirs[dir] = rules
+	return rules, nil
+}
+
+// Attributes はワークツリーからの相対パスpathに指定された属性を返す
+// "!" で未指定に戻された属性は含まない
+func (m *Matcher) Attributes(path string) ([]Attribute, error) {
+	path = strings.Trim(path, "/")
+	elems := strings.Split(path, "/")
+
+	dirs := make([][]*Rule, 0, len(elems))
+	for i := 0; i < len(elems); i++ {
+		r, err := m.gitattributes(strings.Join(elems[:i], "/"))
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, r)
+	}
+
+	// 優先度の低い順に並べる
+	rules := append([]*Rule{}, m.global...)
+	for _, r := range dirs {
+		rules = append(rules, r...)
+	}
+	rules = append(rules, m.info...)
+
+	// マクロはglobal、ルートの.gitattributes、infoでのみ定義できる
+	macros := make(map[string][]Attribute)
+	for name, attrs := range builtinMacros {
+		macros[name] = attrs
+	}
+	for _, list := range [][]*Rule{m.global, dirs[0], m.info} {
+		for _, r := range list {
+			if r.Macro != "" {
+				macros[r.Macro] = r.Attrs
+			}
+		}
+	}
+
+	// gitと同じく属性が初めて現れた順に並べる
+	order := newRegistry()
+	order.register("binary")
+	for _, a := range builtinMacros["binary"] {
+		order.register(a.Name)
+	}
+	for _, list := range append([][]*Rule{m.global, dirs[0], m.info}, dirs[1:]...) {
+		for _, r := range list {
+			if r.Macro != "" {
+				order.register(r.Macro)
+			}
+			for _, a := range r.Attrs {
+				order.register(a.Name)
+			}
+		}
+	}
+
+	s := &state{values: make(map[string]Attribute), macros: macros}
+	var flags wildmatch.Flag
+	if m.caseFold {
+		flags = wildmatch.CaseFold
+	}
+	for _, r := range rules {
+		if r.Pattern == nil || !r.Pattern.Match(path, false, flags) {
+			continue
+		}
+		for _, a := range r.Attrs {
+			s.assign(a, 0)
+		}
+	}
+
+	var attrs []Attribute
+	for _, name := range order.names {
+		if a, ok := s.values[name]; ok && a.State != Unspecified {
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs, nil
+}
+
+type registry struct {
+	names []string
+	seen  map[string]struct{}
+}
+
+func newRegistry() *registry {
+	return &registry{seen: make(map[string]struct{})}
+}
+
+func (r *registry) register(name string) {
+	if _, ok := r.seen[name]; ok {
+		return
+	}
+	r.seen[name] = struct{}{}
+	r.names = append(r.names, name)
+}
+
+// Get はpathのnamesの属性を返す 指定がなければUnspecifiedとなる
+func (m *Matcher) Get(path string, names ...string) ([]Attribute, error) {
+	all, err := m.Attributes(path)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([]Attribute, 0, len(names))
+	for _, name := range names {
+		a := Attribute{Name: name}
+		for _, v := range all {
+			if v.Name == name {
+				a = v
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+// マクロの展開の入れ子の上限
+const maxMacroDepth = 10
+
+type state struct {
+	values map[string]Attribute
+	macros map[string][]Attribute
+}
+
+func (s *state) assign(a Attribute, depth int) {
+	s.values[a.Name] = a
+	// マクロは設定された場合のみ展開する
+	if attrs, ok := s.macros[a.Name]; ok && a.State == Set && depth < maxMacroDepth {
+		for _, m := range attrs {
+			s.assign(m, depth+1)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package attr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	worktree := t.TempDir()
+	files := map[string]string{
+		".gitattributes": "[attr]mybin -text -diff\n* text=auto\n*.bin mybin\n*.c diff=cpp eol=lf\n" +
+			"\"quoted name.txt\" quoted\nsub/*.md -text\n/root-only anchor\n",
+		// サブディレクトリではマクロを定義できない
+		"sub/.gitattributes": "*.c eol=crlf !diff\n*.png binary\n[attr]ignored x\ndeep/** deepattr\n*.txt ignoredmacro\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(worktree, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	global := ParseRules([]byte("*.sh eol=lf\n"), "", "global")
+	info := ParseRules([]byte("*.log info=yes\n*.c text\n"), "", ".git/info/attributes")
+	m := NewMatcher(worktree, false, global, info)
+
+	// 期待する結果は同じワークツリーでの git check-attr --all の出力
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "a.txt", want: []string{"text: auto"}},
+		{path: "x.bin", want: []string{"diff: unset", "text: unset", "mybin: set"}},
+		{path: "a.c", want: []string{"diff: cpp", "text: set", "eol: lf"}},
+		{path: "sub/a.c", want: []string{"text: set", "eol: crlf"}},
+		{path: "quoted name.txt", want: []string{"text: auto", "quoted: set"}},
+		{path: "sub/README.md", want: []string{"text: unset"}},
+		{path: "README.md", want: []string{"text: auto"}},
+		{path: "sub/x/README.md", want: []string{"text: auto"}},
+		{path: "root-only", want: []string{"text: auto", "anchor: set"}},
+		{path: "sub/root-only", want: []string{"text: auto"}},
+		{path: "sub/i.png", want: []string{"binary: set", "diff: unset", "merge: unset", "text: unset"}},
+		{path: "sub/deep/x", want: []string{"text: auto", "deepattr: set"}},
+		{path: "sub/deep/y/z", want: []string{"text: auto", "deepattr: set"}},
+		{path: "a.log", want: []string{"text: auto", "info: yes"}},
+		{path: "a.sh", want: []string{"text: auto", "eol: lf"}},
+		{path: "sub/a.txt", want: []string{"text: auto", "ignoredmacro: set"}},
+	}
+	for _, tt := range tests {
+		attrs, err := m.Attributes(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, 0, len(attrs))
+		for _, a := range attrs {
+			got = append(got, a.Name+": "+a.String())
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("Attributes(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	attrs, err := m.Get("sub/a.c", "eol", "diff", "crlf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range attrs {
+		got = append(got, a.Name+": "+a.String())
+	}
+	if want := "eol: crlf, diff: unspecified, crlf: unspecified"; strings.Join(got, ", ") != want {
+		t.Errorf("Get(sub/a.c) = %v, want %s", got, want)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules := ParseRules([]byte("# comment\n\n!negated text\n*.c\ttext -diff !eol x=y=z\n[attr]m a\n"), "", "f")
+	if len(rules) != 2 {
+		t.Fatalf("ParseRules() returned %d rules, want 2", len(rules))
+	}
+	r := rules[0]
+	if r.Line != 4 || r.Pattern == nil || r.Pattern.Text != "*.c" {
+		t.Errorf("rule = line %d pattern %v, want line 4 *.c", r.Line, r.Pattern)
+	}
+	want := []Attribute{
+		{Name: "text", State: Set},
+		{Name: "diff", State: Unset},
+		{Name: "eol", State: Unspecified},
+		{Name: "x", State: Value, Value: "y=z"},
+	}
+	if len(r.Attrs) != len(want) {
+		t.Fatalf("Attrs = %v, want %v", r.Attrs, want)
+	}
+	for i := range want {
+		if r.Attrs[i] != want[i] {
+			t.Errorf("Attrs[%d] = %v, want %v", i, r.Attrs[i], want[i])
+		}
+	}
+	if rules[1].Macro != "m" || rules[1].Pattern != nil {
+		t.Errorf("macro rule = %+v, want macro m", rules[1])
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// lineRangeFlag は "start,end"、"start,+count"、"start,"、",end" の形式の行の範囲のフラグ
+type lineRangeFlag struct {
+	start, end int
+}
+
+func (l *lineRangeFlag) String() string {
+	return ""
+}
+
+func (l *lineRangeFlag) Set(s string) error {
+	first, second, ok := strings.Cut(s, ",")
+	if !ok {
+		return fmt.Errorf("invalid line range %s", s)
+	}
+	var err error
+	start, end := 1, 0
+	if first != "" {
+		if start, err = strconv.Atoi(first); err != nil || start < 1 {
+			return fmt.Errorf("invalid line range %s", s)
+		}
+	}
+	if second != "" {
+		if strings.HasPrefix(second, "+") {
+			n, err := strconv.Atoi(second[1:])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid line range %s", s)
+			}
+			end = start + n - 1
+		} else if end, err = strconv.Atoi(second); err != nil || end < 1 {
+			return fmt.Errorf("invalid line range %s", s)
+		}
+	}
+	// gitと同じく逆順に指定した範囲は入れ替える
+	if end != 0 && end < start {
+		start, end = end, start
+	}
+	l.start, l.end = start, end
+	return nil
+}
+
+type BlameCommand struct {
+	*flag.FlagSet
+	lines            lineRangeFlag
+	porcelain        bool
+	ignoreWhitespace bool
+	file             string
+	rev              string
+}
+
+func NewBlameCommand(args []string) *BlameCommand {
+	c := &BlameCommand{}
+	c.FlagSet = flag.NewFlagSet("blame", flag.ExitOnError)
+	c.FlagSet.Var(&c.lines, "L", "Annotate only the line range given by start,end")
+	c.FlagSet.BoolVar(&c.porcelain, "porcelain", false, "Show in a format designed for machine consumption")
+	c.FlagSet.BoolVar(&c.ignoreWhitespace, "w", false, "Ignore whitespace when comparing the parent's version and the child's")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go blame [-L start,end] [--porcelain] [-w] FILE [REV]\n")
+		fmt.Fprint(o, "\tShow what revision and author last modified each line of a file\n")
+	}
+
+	// ファイルとリビジョンの後にもフラグを指定できるようにする
+	var rest []string
+	for c.Parse(args); len(c.Args()) > 0; c.Parse(args) {
+		rest = append(rest, c.Args()[0])
+		args = c.Args()[1:]
+	}
+	if len(rest) == 0 || len(rest) > 2 {
+		c.Usage()
+		os.Exit(1)
+	}
+	c.file, c.rev = rest[0], "HEAD"
+	if len(rest) == 2 {
+		c.rev = rest[1]
+	}
+	return c
+}
+
+func (c *BlameCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	p, err := repo.RelativePath(filepath.Join(BasePath, c.file))
+	if err != nil {
+		return err
+	}
+	lines, err := repo.Blame(p, c.rev, repository.BlameOptions{
+		Start:            c.lines.start,
+		End:              c.lines.end,
+		IgnoreWhitespace: c.ignoreWhitespace,
+	})
+	if err != nil {
+		return err
+	}
+
+	commits := make(map[string]*object.CommitObject)
+	for _, l := range lines {
+		if _, ok := commits[l.Commit]; ok {
+			continue
+		}
+		o, err := repo.ReadObject(l.Commit)
+		if err != nil {
+			return err
+		}
+		commits[l.Commit] = o.(*object.CommitObject)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if c.porcelain {
+		return writePorcelainBlame(out, lines, commits)
+	}
+	return writeBlame(out, repo, p, lines, commits)
+}
+
+// writeBlame は "コミット (作者 日時 行番号) 内容" の形式で表示する
+// 親のないコミットは "^" をつけ、別のパスから引き継いだ行がある場合はパスも表示する
+func writeBlame(w io.Writer, repo *repository.Repository, p string, lines []*repository.BlameLine, commits map[string]*object.CommitObject) error {
+	authors := make(map[string]*object.Signature)
+	showPath := false
+	nameWidth, pathWidth, maxLine := 0, 0, 0
+	for _, l := range lines {
+		if _, ok := authors[l.Commit]; !ok {
+			author, err := commits[l.Commit].Author()
+			if err != nil {
+				return err
+			}
+			authors[l.Commit] = author
+		}
+		if n := utf8.RuneCountInString(authors[l.Commit].Name); n > nameWidth {
+			nameWidth = n
+		}
+		if n := utf8.RuneCountInString(quotePath(l.Path)); n > pathWidth {
+			pathWidth = n
+		}
+		showPath = showPath || l.Path != p
+		if l.FinalLine > maxLine {
+			maxLine = l.FinalLine
+		}
+	}
+	lineWidth := len(strconv.Itoa(maxLine))
+
+	for _, l := range lines {
+		abbrev, err := repo.Abbrev(l.Commit, repository.DefaultAbbrev+1)
+		if err != nil {
+			return err
+		}
+		if len(commits[l.Commit].Parents()) == 0 {
+			abbrev = "^" + abbrev[:len(abbrev)-1]
+		}
+		var path string
+		if showPath {
+			path = pad(quotePath(l.Path), pathWidth) + " "
+		}
+		author := authors[l.Commit]
+		date := author.When.Format("2006-01-02 15:04:05 ") + author.Timezone()
+		content := strings.TrimSuffix(l.Content, "\n")
+		if _, err := fmt.Fprintf(w, "%s %s(%s %s %*d) %s\n", abbrev, path, pad(author.Name, nameWidth), date, lineWidth, l.FinalLine, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// writePorcelainBlame はgitの--porcelainと同じ形式で表示する
+// コミットの情報は最初に現れた行にのみ表示する
+func writePorcelainBlame(w io.Writer, lines []*repository.BlameLine, commits map[string]*object.CommitObject) error {
+	paths := make(map[string]map[string]bool)
+	for _, l := range lines {
+		if paths[l.Commit] == nil {
+			paths[l.Commit] = make(map[string]bool)
+		}
+		paths[l.Commit][l.Path] = true
+	}
+
+	shown := make(map[string]bool)
+	for i, l := range lines {
+		// 同じコミットの連続した行をまとめ、まとまりの先頭に行数を表示する
+		if i == 0 || !sameBlameGroup(lines[i-1], l) {
+			n := 1
+			for n < len(lines)-i && sameBlameGroup(lines[i+n-1], lines[i+n]) {
+				n++
+			}
+			fmt.Fprintf(w, "%s %d %d %d\n", l.Commit, l.OrigLine, l.FinalLine, n)
+		} else {
+			fmt.Fprintf(w, "%s %d %d\n", l.Commit, l.OrigLine, l.FinalLine)
+		}
+
+		if !shown[l.Commit] {
+			shown[l.Commit] = true
+			if err := writeBlameCommit(w, l, commits[l.Commit]); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "filename %s\n", quotePath(l.Path))
+		} else if len(paths[l.Commit]) > 1 {
+			fmt.Fprintf(w, "filename %s\n", quotePath(l.Path))
+		}
+
+		content := l.Content
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if _, err := fmt.Fprintf(w, "\t%s", content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameBlameGroup(a, b *repository.BlameLine) bool {
+	return a.Commit == b.Commit && a.Path == b.Path && a.OrigLine+1 == b.OrigLine && a.FinalLine+1 == b.FinalLine
+}
+
+func writeBlameCommit(w io.Writer, l *repository.BlameLine, commit *object.CommitObject) error {
+	author, err := commit.Author()
+	if err != nil {
+		return err
+	}
+	committer, err := commit.Committer()
+	if err != nil {
+		return err
+	}
+	for _, s := range []struct {
+		role string
+		sig  *object.Signature
+	}{{"author", author}, {"committer", committer}} {
+		fmt.Fprintf(w, "%s %s\n%s-mail <%s>\n", s.role, s.sig.Name, s.role, s.sig.Email)
+		fmt.Fprintf(w, "%s-time %d\n%s-tz %s\n", s.role, s.sig.When.Unix(), s.role, s.sig.Timezone())
+	}
+	fmt.Fprintf(w, "summary %s\n", commit.Subject())
+	switch {
+	case l.Previous != "":
+		fmt.Fprintf(w, "previous %s %s\n", l.Previous, quotePath(l.PreviousPath))
+	case len(commit.Parents()) == 0:
+		fmt.Fprintln(w, "boundary")
+	}
+	return nil
+}
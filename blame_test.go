@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlame(t *testing.T) {
+	repo, first := newTestRepository(t, map[string]string{"f.txt": "a\nb\nc\nd\n"})
+	if err := os.Remove(filepath.Join(repo.Worktree(), "f.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add([]string{"f.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	rename := commitTestFiles(t, repo, "rename", map[string]string{"g.txt": "a\nb\nC\nd\n"})
+	third := commitTestFiles(t, repo, "third", map[string]string{"g.txt": "a\nB\nC\n  d\ne\n"})
+
+	header := func(sha, summary, previous string) string {
+		return sha + "\n" +
+			"author T\n" +
+			"author-mail <t@example.com>\n" +
+			"author-time 1700000000\n" +
+			"author-tz +0900\n" +
+			"committer T\n" +
+			"committer-mail <t@example.com>\n" +
+			"committer-time 1700000000\n" +
+			"committer-tz +0900\n" +
+			"summary " + summary + "\n" +
+			previous + "\n"
+	}
+	// 期待する結果は git blame ARGS g.txt の出力
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: nil,
+			want: "^" + first[:7] + " f.txt (T 2023-11-15 07:13:20 +0900 1) a\n" +
+				third[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 2) B\n" +
+				rename[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 3) C\n" +
+				third[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 4)   d\n" +
+				third[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 5) e\n",
+		},
+		{
+			// 範囲に別のパスから引き継いだ行がなければパスは表示しない
+			args: []string{"-L", "2,4"},
+			want: third[:8] + " (T 2023-11-15 07:13:20 +0900 2) B\n" +
+				rename[:8] + " (T 2023-11-15 07:13:20 +0900 3) C\n" +
+				third[:8] + " (T 2023-11-15 07:13:20 +0900 4)   d\n",
+		},
+		{
+			args: []string{"-L", "3,+2"},
+			want: rename[:8] + " (T 2023-11-15 07:13:20 +0900 3) C\n" +
+				third[:8] + " (T 2023-11-15 07:13:20 +0900 4)   d\n",
+		},
+		{
+			args: []string{"-w"},
+			want: "^" + first[:7] + " f.txt (T 2023-11-15 07:13:20 +0900 1) a\n" +
+				third[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 2) B\n" +
+				rename[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 3) C\n" +
+				"^" + first[:7] + " f.txt (T 2023-11-15 07:13:20 +0900 4)   d\n" +
+				third[:8] + " g.txt (T 2023-11-15 07:13:20 +0900 5) e\n",
+		},
+		{
+			args: []string{"--porcelain"},
+			want: header(first+" 1 1 1", "first", "boundary") +
+				"filename f.txt\n\ta\n" +
+				header(third+" 2 2 1", "third", "previous "+rename+" g.txt") +
+				"filename g.txt\n\tB\n" +
+				header(rename+" 3 3 1", "rename", "previous "+first+" f.txt") +
+				"filename g.txt\n\tC\n" +
+				third + " 4 4 2\n\t  d\n" +
+				third + " 5 5\n\te\n",
+		},
+		{
+			args: []string{"--porcelain", "-L", "3,5"},
+			want: header(rename+" 3 3 1", "rename", "previous "+first+" f.txt") +
+				"filename g.txt\n\tC\n" +
+				header(third+" 4 4 2", "third", "previous "+rename+" g.txt") +
+				"filename g.txt\n\t  d\n" +
+				third + " 5 5\n\te\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			stdout, _, err := runCommand(t, repo, "", NewBlameCommand(append(tt.args, "g.txt")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout != tt.want {
+				t.Errorf("blame =\n%s\nwant\n%s", stdout, tt.want)
+			}
+		})
+	}
+}
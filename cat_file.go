@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+	"github.com/showa-93/wyag-go/storage"
+)
+
+const defaultBatchFormat = "%(objectname) %(objecttype) %(objectsize)"
+
+// batchFlag は "--batch" と "--batch=FORMAT" の両方を受け付けるフラグ
+type batchFlag struct {
+	enabled bool
+	format  string
+}
+
+func (b *batchFlag) String() string {
+	return b.format
+}
+
+func (b *batchFlag) Set(s string) error {
+	b.enabled = true
+	b.format = defaultBatchFormat
+	if s != "true" {
+		b.format = s
+	}
+	return nil
+}
+
+func (b *batchFlag) IsBoolFlag() bool {
+	return true
+}
+
+type CatFile struct {
+	*flag.FlagSet
+	Type       object.ObjectType
+	Object     string
+	showType   bool
+	showSize   bool
+	pretty     bool
+	exists     bool
+	batch      batchFlag
+	batchCheck batchFlag
+	buffer     bool
+}
+
+func NewCatFile(args []string) *CatFile {
+	cf := &CatFile{}
+	cf.FlagSet = flag.NewFlagSet("cat-file", flag.ExitOnError)
+	cf.FlagSet.BoolVar(&cf.showType, "t", false, "Show the object type")
+	cf.FlagSet.BoolVar(&cf.showSize, "s", false, "Show the object size")
+	cf.FlagSet.BoolVar(&cf.pretty, "p", false, "Pretty-print the contents of OBJECT based on its type")
+	cf.FlagSet.BoolVar(&cf.exists, "e", false, "Exit with zero status if OBJECT exists and is a valid object")
+	cf.FlagSet.Var(&cf.batch, "batch", "Print object information and contents for each object provided on stdin")
+	cf.FlagSet.Var(&cf.batchCheck, "batch-check", "Print object information for each object provided on stdin")
+	cf.FlagSet.BoolVar(&cf.buffer, "buffer", false, "Do not flush output after each object in batch mode")
+	cf.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go cat-file (-t | -s | -p | -e) OBJECT\n")
+		fmt.Fprint(o, "   or: wyag-go cat-file TYPE OBJECT\n")
+		fmt.Fprint(o, "   or: wyag-go cat-file (--batch | --batch-check)[=FORMAT] [--buffer]\n")
+		fmt.Fprint(o, "\tProvide content of repository objects.\n")
+	}
+
+	cf.Parse(args)
+
+	modes := 0
+	for _, m := range []bool{cf.showType, cf.showSize, cf.pretty, cf.exists, cf.batch.enabled, cf.batchCheck.enabled} {
+		if m {
+			modes++
+		}
+	}
+	switch {
+	case modes > 1:
+		fmt.Println("only one of -t, -s, -p, -e, --batch and --batch-check can be specified")
+		os.Exit(1)
+	case cf.batch.enabled || cf.batchCheck.enabled:
+		if len(cf.Args()) != 0 {
+			fmt.Printf("expected 0 arguments count=%d\n", len(cf.Args()))
+			os.Exit(1)
+		}
+	case modes == 1:
+		if len(cf.Args()) != 1 {
+			fmt.Printf("expected 1 arguments count=%d\n", len(cf.Args()))
+			os.Exit(1)
+		}
+		cf.Object = cf.Args()[0]
+	default:
+		if len(cf.Args()) != 2 {
+			fmt.Printf("expected 2 arguments count=%d\n", len(cf.Args()))
+			os.Exit(1)
+		}
+		t, ok := object.ConvertObjectType(cf.Args()[0])
+		if !ok {
+			fmt.Printf("unknown object type %s\n", cf.Args()[0])
+			os.Exit(1)
+		}
+		cf.Type = t
+		cf.Object = cf.Args()[1]
+	}
+
+	return cf
+}
+
+func (cf *CatFile) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cf.batch.enabled:
+		return cf.runBatch(repo, cf.batch.format, true)
+	case cf.batchCheck.enabled:
+		return cf.runBatch(repo, cf.batchCheck.format, false)
+	}
+
+	// gitと同じく名前を解決できない場合は-eでもメッセージを出力して終了コード128で終わる
+	if shas, err := repo.ResolveObject(cf.Object); err != nil || len(shas) == 0 {
+		fmt.Fprintf(os.Stderr, "fatal: Not a valid object name %s\n", cf.Object)
+		return ExitCode(128)
+	}
+	// TYPEが指定された場合はタグやコミットをたどってその型のオブジェクトを表示する
+	sha, err := repo.FindObject(cf.Object, string(cf.Type), true)
+	if err != nil {
+		if cf.exists && errors.Is(err, storage.ErrObjectNotFound) {
+			return ExitCode(1)
+		}
+		return err
+	}
+	if sha == "" {
+		return fmt.Errorf("%s: bad file", cf.Object)
+	}
+	// 中身はすべて読み込まずに出力する
+	h, rc, err := repo.OpenObject(sha)
+	if err != nil {
+		if cf.exists && errors.Is(err, storage.ErrObjectNotFound) {
+			return ExitCode(1)
+		}
+		return err
+	}
+	defer rc.Close()
+	if cf.exists {
+		return nil
+	}
+
+	switch {
+	case cf.showType:
+		fmt.Fprintln(os.Stdout, h.Type)
+	case cf.showSize:
+		fmt.Fprintln(os.Stdout, h.Size)
+	case cf.pretty && h.Type == object.Tree:
+		o, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		for _, item := range o.(*object.TreeObject).Items() {
+			mode := strings.Repeat("0", 6-len(item.Mode())) + item.Mode()
+			fmt.Fprintf(os.Stdout, "%s %s %s\t%s\n", mode, item.Type(), item.Sha(), quotePath(item.Path()))
+		}
+	default:
+		_, err = io.Copy(os.Stdout, rc)
+	}
+	return err
+}
+
+// runBatch は標準入力から1行ずつオブジェクト名を読み込み、formatに従って情報を出力する
+// contentsが真の場合は続けてオブジェクトの中身を出力する
+func (cf *CatFile) runBatch(repo *repository.Repository, format string, contents bool) error {
+	in := bufio.NewReader(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == "" && err == io.EOF {
+			return nil
+		}
+		name := strings.TrimSuffix(line, "\n")
+		// 書式に%(rest)がある場合は最初の空白以降を出力に含める
+		rest := ""
+		if strings.Contains(format, "%(rest)") {
+			if i := strings.IndexAny(name, " \t"); i >= 0 {
+				name, rest = name[:i], strings.TrimLeft(name[i:], " \t")
+			}
+		}
+
+		if err := cf.batchOne(out, repo, name, rest, format, contents); err != nil {
+			return err
+		}
+		if !cf.buffer {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (cf *CatFile) batchOne(out *bufio.Writer, repo *repository.Repository, name, rest, format string, contents bool) error {
+	// gitと同じく解決できない名前は存在しないオブジェクトとして扱い、続きの入力を読み続ける
+	shas, err := repo.ResolveObject(name)
+	switch {
+	case err != nil:
+		fmt.Fprintf(out, "%s missing\n", name)
+		return nil
+	case len(shas) > 1:
+		fmt.Fprintf(out, "%s ambiguous\n", name)
+		return nil
+	case len(shas) == 0:
+		fmt.Fprintf(out, "%s missing\n", name)
+		return nil
+	}
+
+	h, rc, err := repo.OpenObject(shas[0])
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			fmt.Fprintf(out, "%s missing\n", name)
+			return nil
+		}
+		return err
+	}
+	defer rc.Close()
+
+	info := expandBatchFormat(format, shas[0], h.Type, h.Size, rest)
+	out.WriteString(info)
+	out.WriteByte('\n')
+	if contents {
+		if _, err := io.Copy(out, rc); err != nil {
+			return err
+		}
+		out.WriteByte('\n')
+	}
+	return nil
+}
+
+// expandBatchFormat は書式中の%(objectname)などを置き換える
+func expandBatchFormat(format, sha string, t object.ObjectType, size int64, rest string) string {
+	r := strings.NewReplacer(
+		"%(objectname)", sha,
+		"%(objecttype)", string(t),
+		"%(objectsize)", strconv.FormatInt(size, 10),
+		"%(rest)", rest,
+	)
+	return r.Replace(format)
+}
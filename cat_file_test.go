@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatFile(t *testing.T) {
+	repo, _ := newTestRepository(t, map[string]string{"a.txt": "a\n", "d/b.txt": "b\n"})
+
+	// 期待する結果は git cat-file ARGS の出力と終了コード
+	tests := []struct {
+		args       []string
+		want       string
+		wantStderr string
+		wantCode   int
+	}{
+		{args: []string{"-t", "HEAD"}, want: "commit\n"},
+		{args: []string{"-t", "HEAD:d"}, want: "tree\n"},
+		{args: []string{"-s", "HEAD:a.txt"}, want: "2\n"},
+		{args: []string{"-p", "HEAD:a.txt"}, want: "a\n"},
+		{args: []string{"-p", "HEAD^{tree}"}, want: "100644 blob 78981922613b2afb6025042ff6bd878ac1994e85\ta.txt\n" +
+			"040000 tree f8f7aefc2900a3d737cea9eee45729fd55761e1a\td\n"},
+		{args: []string{"blob", "HEAD:d/b.txt"}, want: "b\n"},
+		{args: []string{"-e", "HEAD"}},
+		{args: []string{"-e", "0123456789012345678901234567890123456789"}, wantCode: 1},
+		{args: []string{"-e", "nope"}, wantStderr: "fatal: Not a valid object name nope\n", wantCode: 128},
+		{args: []string{"-t", "nope"}, wantStderr: "fatal: Not a valid object name nope\n", wantCode: 128},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := runCommand(t, repo, "", NewCatFile(tt.args))
+		code := 0
+		var ec ExitCode
+		if errors.As(err, &ec) {
+			code = int(ec)
+		} else if err != nil {
+			t.Errorf("cat-file %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want || stderr != tt.wantStderr || code != tt.wantCode {
+			t.Errorf("cat-file %v = %q, %q, %d, want %q, %q, %d", tt.args, stdout, stderr, code, tt.want, tt.wantStderr, tt.wantCode)
+		}
+	}
+}
+
+func TestCatFileBatch(t *testing.T) {
+	repo, _ := newTestRepository(t, map[string]string{"a.txt": "a\n"})
+	// 解決できない名前や存在しないオブジェクトは "NAME missing" を出力して続きを読む
+	stdin := "HEAD:a.txt\nnope\n0123456789012345678901234567890123456789\nHEAD:a.txt extra\n"
+
+	// 期待する結果は git cat-file ARGS の出力
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"--batch-check"},
+			want: "78981922613b2afb6025042ff6bd878ac1994e85 blob 2\n" +
+				"nope missing\n" +
+				"0123456789012345678901234567890123456789 missing\n" +
+				"HEAD:a.txt extra missing\n",
+		},
+		{
+			args: []string{"--batch"},
+			want: "78981922613b2afb6025042ff6bd878ac1994e85 blob 2\na\n\n" +
+				"nope missing\n" +
+				"0123456789012345678901234567890123456789 missing\n" +
+				"HEAD:a.txt extra missing\n",
+		},
+		{
+			args: []string{"--batch-check=%(objecttype) %(rest)"},
+			want: "blob \n" +
+				"nope missing\n" +
+				"0123456789012345678901234567890123456789 missing\n" +
+				"blob extra\n",
+		},
+	}
+	for _, tt := range tests {
+		stdout, _, err := runCommand(t, repo, stdin, NewCatFile(tt.args))
+		if err != nil {
+			t.Errorf("cat-file %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want {
+			t.Errorf("cat-file %v = %q, want %q", tt.args, stdout, tt.want)
+		}
+	}
+}
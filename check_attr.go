@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/showa-93/wyag-go/attr"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type CheckAttrCommand struct {
+	*flag.FlagSet
+	all   bool
+	attrs []string
+	paths []string
+}
+
+func NewCheckAttrCommand(args []string) *CheckAttrCommand {
+	c := &CheckAttrCommand{}
+	c.FlagSet = flag.NewFlagSet("check-attr", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.all, "a", false, "List all attributes that are associated with the specified paths")
+	c.FlagSet.BoolVar(&c.all, "all", false, "List all attributes that are associated with the specified paths")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go check-attr (-a | ATTR...) [--] PATH...\n")
+		fmt.Fprint(o, "\tDisplay gitattributes information\n")
+	}
+
+	c.Parse(args)
+	rest := c.Args()
+	sep := -1
+	for i, a := range rest {
+		if a == "--" {
+			sep = i
+			break
+		}
+	}
+	switch {
+	case sep >= 0:
+		c.attrs, c.paths = rest[:sep], rest[sep+1:]
+	case c.all:
+		c.paths = rest
+	case len(rest) > 0:
+		// "--" がなければ最初の引数を属性とする
+		c.attrs, c.paths = rest[:1], rest[1:]
+	}
+	if c.all && len(c.attrs) > 0 {
+		fmt.Println("attributes and -a both specified")
+		os.Exit(1)
+	}
+	if !c.all && len(c.attrs) == 0 {
+		fmt.Println("no attribute specified")
+		os.Exit(1)
+	}
+	if len(c.paths) == 0 {
+		fmt.Println("no path specified")
+		os.Exit(1)
+	}
+
+	return c
+}
+
+func (c *CheckAttrCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	m, err := repo.Attributes()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range c.paths {
+		rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+		if err != nil {
+			return err
+		}
+		var attrs []attr.Attribute
+		if c.all {
+			attrs, err = m.Attributes(rel)
+		} else {
+			attrs, err = m.Get(rel, c.attrs...)
+		}
+		if err != nil {
+			return err
+		}
+		for _, a := range attrs {
+			fmt.Fprintf(os.Stdout, "%s: %s: %s\n", p, a.Name, a)
+		}
+	}
+	return nil
+}
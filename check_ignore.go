@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type CheckIgnoreCommand struct {
+	*flag.FlagSet
+	verbose     bool
+	nonMatching bool
+	noIndex     bool
+	paths       []string
+}
+
+func NewCheckIgnoreCommand(args []string) *CheckIgnoreCommand {
+	c := &CheckIgnoreCommand{}
+	c.FlagSet = flag.NewFlagSet("check-ignore", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.verbose, "v", false, "Output details about the matching pattern")
+	c.FlagSet.BoolVar(&c.nonMatching, "n", false, "Show given paths which don't match any pattern (with -v)")
+	c.FlagSet.BoolVar(&c.noIndex, "no-index", false, "Don't look in the index when undertaking the checks")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go check-ignore [-v] [-n] [--no-index] PATH...\n")
+		fmt.Fprint(o, "\tDebug gitignore / exclude files\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) == 0 {
+		fmt.Println("no path specified")
+		os.Exit(1)
+	}
+	if c.nonMatching && !c.verbose {
+		fmt.Println("-n is only valid with -v")
+		os.Exit(1)
+	}
+	c.paths = c.Args()
+
+	return c
+}
+
+func (c *CheckIgnoreCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	m, err := repo.Ignore()
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	ignored := 0
+	for _, p := range c.paths {
+		full := filepath.Join(BasePath, p)
+		rel, err := repo.RelativePath(full)
+		if err != nil {
+			return err
+		}
+		if rel == "" {
+			return fmt.Errorf("pathspec '%s' did not match any file(s) known to wyag", p)
+		}
+
+		isDir := strings.HasSuffix(p, "/")
+		if fi, err := os.Lstat(full); err == nil && fi.IsDir() {
+			isDir = true
+		}
+
+		pattern, err := m.Match(rel, isDir)
+		if err != nil {
+			return err
+		}
+		// 追跡中のファイルは除外の対象にならない
+		if !c.noIndex {
+			if _, ok := idx.Entry(rel); ok {
+				pattern = nil
+			}
+		}
+		if !c.verbose && pattern != nil && pattern.Negate {
+			pattern = nil
+		}
+
+		switch {
+		case pattern != nil && c.verbose:
+			fmt.Fprintf(os.Stdout, "%s:%d:%s\t%s\n", pattern.Source, pattern.Line, pattern.Text, p)
+		case pattern != nil:
+			fmt.Fprintln(os.Stdout, p)
+		case c.nonMatching:
+			fmt.Fprintf(os.Stdout, "::\t%s\n", p)
+		}
+		if pattern != nil {
+			ignored++
+		}
+	}
+
+	if ignored == 0 {
+		return ExitCode(1)
+	}
+	return nil
+}
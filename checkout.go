@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type CheckoutCommand struct {
+	*flag.FlagSet
+	sha  string
+	path string
+}
+
+func NewCheckoutCommand(args []string) *CheckoutCommand {
+	c := &CheckoutCommand{}
+	c.FlagSet = flag.NewFlagSet("cat-file", flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: checkout [OBJECT] [PATH]\n")
+		fmt.Fprint(o, "\tCheckout a commit inside of a directory.\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) != 2 {
+		fmt.Printf("expected 1 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+	c.sha = c.Args()[0]
+	c.path = c.Args()[1]
+
+	return c
+}
+
+func (cc *CheckoutCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	name, err := repo.FindObject(cc.sha, "", false)
+	if err != nil {
+		return err
+	}
+	o, err := repo.ReadObject(name)
+	if err != nil {
+		return err
+	}
+
+	if o.TypeHeader() == object.Commit {
+		tree := o.(*object.CommitObject).Tree()
+		if tree == "" {
+			return errors.New("invalid commit")
+		}
+		if o, err = repo.ReadObject(tree); err != nil {
+			return err
+		}
+	}
+
+	fi, err := os.Stat(cc.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(cc.path, os.FileMode(0755)); err != nil {
+				return err
+			}
+		} else {
+			return err
+		}
+	} else {
+
+		if !fi.IsDir() {
+			return fmt.Errorf("not a directiry %s", cc.path)
+		}
+		entries, err := os.ReadDir(cc.path)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			return fmt.Errorf("not a empty %s", cc.path)
+		}
+	}
+
+	return repo.CheckoutTree(o.(*object.TreeObject), cc.path)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// SequenceCommand はcherry-pickとrevertに共通するコマンド
+type SequenceCommand struct {
+	*flag.FlagSet
+	action       repository.SequenceAction
+	recordOrigin bool
+	cont         bool
+	abort        bool
+	skip         bool
+	revs         []string
+}
+
+func NewCherryPickCommand(args []string) *SequenceCommand {
+	c := &SequenceCommand{action: repository.ActionPick}
+	c.FlagSet = flag.NewFlagSet("cherry-pick", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.recordOrigin, "x", false, "Append a line that says \"(cherry picked from commit ...)\"")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go cherry-pick [-x] REV...\n")
+		fmt.Fprint(o, "       wyag-go cherry-pick (--continue | --skip | --abort)\n")
+		fmt.Fprint(o, "\tApply the changes introduced by some existing commits\n")
+	}
+	return c.parse(args)
+}
+
+func (c *SequenceCommand) parse(args []string) *SequenceCommand {
+	c.FlagSet.BoolVar(&c.cont, "continue", false, "Continue the operation in progress")
+	c.FlagSet.BoolVar(&c.abort, "abort", false, "Cancel the operation and return to the pre-sequence state")
+	c.FlagSet.BoolVar(&c.skip, "skip", false, "Skip the current commit and continue with the rest of the sequence")
+
+	c.Parse(args)
+	c.revs = c.Args()
+	n := 0
+	for _, b := range []bool{c.cont, c.abort, c.skip} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 || n == 1 && len(c.revs) > 0 || n == 0 && len(c.revs) == 0 {
+		c.Usage()
+		os.Exit(1)
+	}
+	return c
+}
+
+func (c *SequenceCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	var results []*repository.PickResult
+	switch {
+	case c.abort:
+		return repo.AbortSequence()
+	case c.cont:
+		results, err = repo.ContinueSequence()
+	case c.skip:
+		results, err = repo.SkipSequence()
+	default:
+		commits, err := c.commits(repo)
+		if err != nil {
+			return err
+		}
+		results, err = repo.StartSequence(c.action, commits, repository.SequenceOptions{RecordOrigin: c.recordOrigin})
+		if err != nil && len(results) == 0 {
+			return err
+		}
+		return c.report(repo, results, err)
+	}
+	return c.report(repo, results, err)
+}
+
+// commits は適用するコミットを順に返す
+// 範囲を指定した場合、cherry-pickは古い順、revertは新しい順に並べる
+func (c *SequenceCommand) commits(repo *repository.Repository) ([]string, error) {
+	walked := false
+	for _, rev := range c.revs {
+		if strings.Contains(rev, "..") || strings.HasPrefix(rev, "^") {
+			walked = true
+		}
+	}
+	if !walked {
+		commits := make([]string, 0, len(c.revs))
+		for _, rev := range c.revs {
+			sha, err := repo.FindObject(rev, string(object.Commit), true)
+			if err != nil {
+				return nil, err
+			}
+			if sha == "" {
+				return nil, fmt.Errorf("%s is not a commit", rev)
+			}
+			commits = append(commits, sha)
+		}
+		return commits, nil
+	}
+
+	walk := repo.NewRevWalk(repository.RevWalkOptions{MaxCount: -1, Reverse: c.action == repository.ActionPick})
+	if err := addRevisions(repo, walk, c.revs); err != nil {
+		return nil, err
+	}
+	var commits []string
+	for {
+		sha, err := walk.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, sha)
+	}
+	if len(commits) == 0 {
+		return nil, errors.New("empty commit set passed")
+	}
+	return commits, nil
+}
+
+// report は作成したコミットを表示し、止まった場合はgitと同じく再開の方法を表示する
+func (c *SequenceCommand) report(repo *repository.Repository, results []*repository.PickResult, err error) error {
+	ref, _, rerr := repo.Head()
+	if rerr != nil {
+		return rerr
+	}
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	if ref == "HEAD" {
+		branch = "detached HEAD"
+	}
+
+	for _, res := range results {
+		if res.Merge != nil {
+			for _, msg := range res.Merge.Messages {
+				fmt.Fprintln(os.Stdout, msg)
+			}
+		}
+		if res.Created != "" {
+			o, err := repo.ReadObject(res.Created)
+			if err != nil {
+				return err
+			}
+			abbrev, err := repo.Abbrev(res.Created, repository.DefaultAbbrev)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "[%s %s] %s\n", branch, abbrev, o.(*object.CommitObject).Subject())
+			continue
+		}
+
+		o, err := repo.ReadObject(res.Commit)
+		if err != nil {
+			return err
+		}
+		abbrev, err := repo.Abbrev(res.Commit, repository.DefaultAbbrev)
+		if err != nil {
+			return err
+		}
+		verb := "apply"
+		if res.Action == repository.ActionRevert {
+			verb = "revert"
+		}
+		fmt.Fprintf(os.Stderr, "error: could not %s %s... %s\n", verb, abbrev, o.(*object.CommitObject).Subject())
+		break
+	}
+
+	if err != nil {
+		if !errors.Is(err, repository.ErrEmptyPick) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		fmt.Fprintf(os.Stderr, "hint: use \"wyag-go commit --allow-empty\" to keep it, or \"wyag-go %s --skip\" to skip it\n", c.FlagSet.Name())
+		return ExitCode(1)
+	}
+	if len(results) > 0 && results[len(results)-1].Created == "" {
+		name := c.FlagSet.Name()
+		fmt.Fprintf(os.Stderr, "hint: After resolving the conflicts, mark them with\n")
+		fmt.Fprintf(os.Stderr, "hint: \"wyag-go add <pathspec>\", then run\n")
+		fmt.Fprintf(os.Stderr, "hint: \"wyag-go %s --continue\".\n", name)
+		fmt.Fprintf(os.Stderr, "hint: You can instead skip this commit with \"wyag-go %s --skip\".\n", name)
+		fmt.Fprintf(os.Stderr, "hint: To abort and get back to the state before \"wyag-go %s\",\n", name)
+		fmt.Fprintf(os.Stderr, "hint: run \"wyag-go %s --abort\".\n", name)
+		return ExitCode(1)
+	}
+	return nil
+}
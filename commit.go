@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/gpg"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// messagesFlag は複数回指定できるメッセージのフラグ 指定した順に段落としてつなげる
+type messagesFlag []string
+
+func (m *messagesFlag) String() string {
+	return ""
+}
+
+func (m *messagesFlag) Set(s string) error {
+	*m = append(*m, s)
+	return nil
+}
+
+// signFlag は "-S" で署名し、"-S=KEYID" で署名に使う鍵を指定するフラグ
+// 指定されなかった場合はsetが偽になり、設定に従う
+type signFlag struct {
+	set     bool
+	enabled bool
+	key     string
+}
+
+func (f *signFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (f *signFlag) String() string {
+	return ""
+}
+
+func (f *signFlag) Set(s string) error {
+	f.set = true
+	switch s {
+	case "true":
+		f.enabled = true
+	case "false":
+		f.enabled, f.key = false, ""
+	default:
+		f.enabled, f.key = true, s
+	}
+	return nil
+}
+
+// signer は署名する場合にrepoの設定に従ったSignerを返す
+// フラグが指定されなかった場合はconfigKeyの設定で署名するか決める
+func (f *signFlag) signer(repo *repository.Repository, configKey string) (gpg.Signer, error) {
+	enabled := f.enabled
+	if !f.set {
+		var err error
+		if enabled, err = repo.Config().GetBool(configKey, false); err != nil {
+			return nil, err
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return repo.Signer(f.key)
+}
+
+// readMessage は-mで指定したメッセージか-Fで指定したファイルの内容を整えて返す
+func readMessage(messages []string, file string) (string, error) {
+	message := strings.Join(messages, "\n\n")
+	if file != "" {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return "", err
+		}
+		message = string(data)
+	}
+	return object.CleanupMessage(message), nil
+}
+
+type CommitCommand struct {
+	*flag.FlagSet
+	messages   messagesFlag
+	file       string
+	sign       signFlag
+	allowEmpty bool
+}
+
+func NewCommitCommand(args []string) *CommitCommand {
+	c := &CommitCommand{}
+	c.FlagSet = flag.NewFlagSet("commit", flag.ExitOnError)
+	c.FlagSet.Var(&c.messages, "m", "Use the given message as the commit message")
+	c.FlagSet.StringVar(&c.file, "F", "", "Take the commit message from the given file")
+	c.FlagSet.Var(&c.sign, "S", "GPG-sign commits (-S=KEYID to choose the key)")
+	c.FlagSet.BoolVar(&c.allowEmpty, "allow-empty", false, "Allow recording a commit that has the exact same tree as its sole parent")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go commit [-S[=KEYID]] [--allow-empty] (-m MESSAGE... | -F FILE)\n")
+		fmt.Fprint(o, "\tRecord changes to the repository\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) != 0 {
+		fmt.Printf("expected 0 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+	if len(c.messages) == 0 && c.file == "" {
+		fmt.Println("no commit message given (use -m or -F)")
+		os.Exit(1)
+	}
+
+	return c
+}
+
+func (c *CommitCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	message, err := readMessage(c.messages, c.file)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		return errors.New("Aborting commit due to empty commit message.")
+	}
+	signer, err := c.sign.signer(repo, "commit.gpgsign")
+	if err != nil {
+		return err
+	}
+
+	ref, head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	sha, err := repo.Commit(repository.CommitOptions{Message: message, AllowEmpty: c.allowEmpty, Signer: signer})
+	if err != nil {
+		return err
+	}
+
+	// gitと同じく "[ブランチ 短縮sha] 件名" を表示する
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	if ref == "HEAD" {
+		branch = "detached HEAD"
+	}
+	if head == "" {
+		branch += " (root-commit)"
+	}
+	abbrev, err := repo.Abbrev(sha, repository.DefaultAbbrev)
+	if err != nil {
+		return err
+	}
+	subject, _, _ := strings.Cut(message, "\n")
+	fmt.Fprintf(os.Stdout, "[%s %s] %s\n", branch, abbrev, subject)
+	return nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/showa-93/wyag-go/config"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ConfigCommand struct {
+	*flag.FlagSet
+	get        string
+	getAll     string
+	set        string
+	add        string
+	unset      string
+	unsetAll   string
+	list       bool
+	showOrigin bool
+	valueType  string
+	file       string
+	scope      config.Scope
+	value      string
+}
+
+func NewConfigCommand(args []string) *ConfigCommand {
+	c := &ConfigCommand{}
+	c.FlagSet = flag.NewFlagSet("config", flag.ExitOnError)
+	c.FlagSet.StringVar(&c.get, "get", "", "Get the value for a given key")
+	c.FlagSet.StringVar(&c.getAll, "get-all", "", "Get all values for a multi-valued key")
+	c.FlagSet.StringVar(&c.set, "set", "", "Set the value for a given key")
+	c.FlagSet.StringVar(&c.add, "add", "", "Add a new value to a multi-valued key")
+	c.FlagSet.StringVar(&c.unset, "unset", "", "Remove the value for a given key")
+	c.FlagSet.StringVar(&c.unsetAll, "unset-all", "", "Remove all values for a given key")
+	c.FlagSet.BoolVar(&c.list, "list", false, "List all variables set in config file")
+	c.FlagSet.BoolVar(&c.list, "l", false, "List all variables set in config file")
+	c.FlagSet.BoolVar(&c.showOrigin, "show-origin", false, "Show the origin file of each value")
+	c.FlagSet.StringVar(&c.valueType, "type", "", "Interpret the value as bool, int or path")
+	c.FlagSet.StringVar(&c.file, "file", "", "Use the given config file")
+	system := c.FlagSet.Bool("system", false, "Use the system-wide config file")
+	global := c.FlagSet.Bool("global", false, "Use the global config file")
+	local := c.FlagSet.Bool("local", false, "Use the repository config file")
+	worktree := c.FlagSet.Bool("worktree", false, "Use the per-worktree config file")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go config [--system|--global|--local|--worktree|--file FILE] [--type TYPE]\n")
+		fmt.Fprint(o, "\t(--get KEY | --get-all KEY | --set KEY VALUE | --add KEY VALUE | --unset KEY | --unset-all KEY | --list [--show-origin])\n")
+		fmt.Fprint(o, "\tGet and set repository or global options.\n")
+	}
+
+	c.Parse(args)
+
+	actions := 0
+	for _, a := range []string{c.get, c.getAll, c.set, c.add, c.unset, c.unsetAll} {
+		if a != "" {
+			actions++
+		}
+	}
+	if c.list {
+		actions++
+	}
+	if actions != 1 {
+		fmt.Println("expected exactly one action")
+		os.Exit(1)
+	}
+
+	if c.set != "" || c.add != "" {
+		if len(c.Args()) != 1 {
+			fmt.Printf("expected 1 arguments count=%d\n", len(c.Args()))
+			os.Exit(1)
+		}
+		c.value = c.Args()[0]
+	} else if len(c.Args()) != 0 {
+		fmt.Printf("expected 0 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+
+	switch {
+	case *system:
+		c.scope = config.ScopeSystem
+	case *global:
+		c.scope = config.ScopeGlobal
+	case *local:
+		c.scope = config.ScopeLocal
+	case *worktree:
+		c.scope = config.ScopeWorktree
+	}
+
+	return c
+}
+
+func (c *ConfigCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, false)
+	if err != nil {
+		return err
+	}
+	gitdir := ""
+	if repo != nil {
+		gitdir = repo.GitDir()
+	}
+
+	switch {
+	case c.set != "":
+		return c.write(gitdir, func(f *config.File) error { return f.Set(c.set, c.value) })
+	case c.add != "":
+		return c.write(gitdir, func(f *config.File) error { return f.Add(c.add, c.value) })
+	case c.unset != "":
+		return c.write(gitdir, func(f *config.File) error { return f.Unset(c.unset) })
+	case c.unsetAll != "":
+		return c.write(gitdir, func(f *config.File) error { return f.UnsetAll(c.unsetAll) })
+	}
+
+	conf, err := c.read(gitdir)
+	if err != nil {
+		return err
+	}
+
+	if c.list {
+		for _, v := range conf.List() {
+			if c.showOrigin {
+				fmt.Fprintf(os.Stdout, "file:%s\t", v.Origin)
+			}
+			if v.NoValue {
+				fmt.Fprintln(os.Stdout, v.Key())
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "%s=%s\n", v.Key(), v.Value)
+		}
+		return nil
+	}
+
+	key := c.get
+	if key == "" {
+		key = c.getAll
+	}
+	if _, _, _, err := config.ParseKey(key); err != nil {
+		return err
+	}
+	values := conf.Variables(key)
+	if len(values) == 0 {
+		// gitと同様に値が存在しない場合は何も出力せず終了コード1で終わる
+		return ExitCode(1)
+	}
+	if c.get != "" {
+		values = values[len(values)-1:]
+	}
+	for _, v := range values {
+		s, err := c.format(key, v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, s)
+	}
+	return nil
+}
+
+// read は指定されたスコープまたはファイルの設定を読み込む
+func (c *ConfigCommand) read(gitdir string) (*config.Config, error) {
+	if c.file != "" {
+		return config.LoadFile(c.file, config.ScopeLocal, gitdir)
+	}
+	if c.scope == 0 {
+		return config.Load(gitdir)
+	}
+
+	conf := config.New()
+	for _, p := range config.ScopePaths(c.scope, gitdir) {
+		sc, err := config.LoadFile(p, c.scope, gitdir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		conf.Merge(sc)
+	}
+	return conf, nil
+}
+
+// write は指定されたスコープの設定ファイルを書き換える 指定がなければリポジトリの設定を書き換える
+func (c *ConfigCommand) write(gitdir string, fn func(f *config.File) error) error {
+	path := c.file
+	scope := c.scope
+	if path == "" {
+		if scope == 0 {
+			scope = config.ScopeLocal
+		}
+		if (scope == config.ScopeLocal || scope == config.ScopeWorktree) && gitdir == "" {
+			return fmt.Errorf("%w path=%s", repository.ErrNotRepositry, BasePath)
+		}
+		p, err := config.WritePath(scope, gitdir)
+		if err != nil {
+			return err
+		}
+		path = p
+	}
+
+	f, err := config.ReadFile(path, scope)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		if errors.Is(err, config.ErrNotFound) {
+			return ExitCode(5)
+		}
+		return err
+	}
+	return f.Save()
+}
+
+// format は--typeに従って値を正規化する
+// "[section] name" のように値を持たない変数は、gitと同じく真偽値としてはtrueとして扱う
+func (c *ConfigCommand) format(key string, v *config.Variable) (string, error) {
+	switch c.valueType {
+	case "":
+		return v.Value, nil
+	case "bool":
+		if v.NoValue {
+			return "true", nil
+		}
+		b, err := config.ParseBool(v.Value)
+		if err != nil {
+			return "", fmt.Errorf("%w key=%s", err, key)
+		}
+		return strconv.FormatBool(b), nil
+	case "int":
+		if v.NoValue {
+			return "", fmt.Errorf("%w: missing value key=%s", config.ErrInvalidValue, key)
+		}
+		i, err := config.ParseInt(v.Value)
+		if err != nil {
+			return "", fmt.Errorf("%w key=%s", err, key)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case "path":
+		if v.NoValue {
+			return "", fmt.Errorf("%w: missing value key=%s", config.ErrInvalidValue, key)
+		}
+		return config.ExpandPath(v.Value)
+	}
+	return "", fmt.Errorf("unknown type %s", c.valueType)
+}
@@ -0,0 +1,296 @@
+// Package config はgitの設定ファイルを扱う
+// system, global, リポジトリ, ワークツリーの各スコープを読み込み、後に読み込んだものほど優先する
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+var (
+	ErrNotFound      = errors.New("key not found")
+	ErrMultipleValue = errors.New("key has multiple values")
+	ErrInvalidValue  = errors.New("invalid config value")
+)
+
+// Scope は設定ファイルの種類
+// 値が大きいほど優先度が高い
+type Scope int
+
+const (
+	ScopeSystem Scope = iota + 1
+	ScopeGlobal
+	ScopeLocal
+	ScopeWorktree
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeSystem:
+		return "system"
+	case ScopeGlobal:
+		return "global"
+	case ScopeLocal:
+		return "local"
+	case ScopeWorktree:
+		return "worktree"
+	}
+	return "unknown"
+}
+
+// Config は読み込んだ設定値を読み込んだ順に保持する
+type Config struct {
+	vars []*Variable
+}
+
+func New() *Config {
+	return &Config{}
+}
+
+// Merge はoのすべての値をcの後ろに追加する oの値が優先される
+func (c *Config) Merge(o *Config) {
+	c.vars = append(c.vars, o.vars...)
+}
+
+// List はすべての値を読み込んだ順に返す
+func (c *Config) List() []*Variable {
+	return c.vars
+}
+
+// Set はメモリ上の値を上書きする ファイルには書き込まない
+func (c *Config) Set(key, value string) error {
+	sec, sub, name, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	c.vars = append(c.vars, &Variable{Section: sec, Subsection: sub, Name: name, Value: value})
+	return nil
+}
+
+func (c *Config) lookup(key string) ([]*Variable, error) {
+	sec, sub, name, err := ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var vars []*Variable
+	for _, v := range c.vars {
+		if v.Section == sec && v.Subsection == sub && v.Name == name {
+			vars = append(vars, v)
+		}
+	}
+	return vars, nil
+}
+
+// Get はkeyの最後の値を返す
+func (c *Config) Get(key string) (string, bool) {
+	vars, err := c.lookup(key)
+	if err != nil || len(vars) == 0 {
+		return "", false
+	}
+	return vars[len(vars)-1].Value, true
+}
+
+// GetAll はkeyのすべての値を読み込んだ順に返す
+func (c *Config) GetAll(key string) []string {
+	vars, _ := c.lookup(key)
+	values := make([]string, 0, len(vars))
+	for _, v := range vars {
+		values = append(values, v.Value)
+	}
+	return values
+}
+
+// Variables はkeyのすべての変数を読み込んだ順に返す
+// 値の有無を区別する必要がある場合に使う
+func (c *Config) Variables(key string) []*Variable {
+	vars, _ := c.lookup(key)
+	return vars
+}
+
+// GetBool はkeyを真偽値として返す 値がない場合はdefを返す
+// true/yes/on/1 と false/no/off/0/空文字、および整数を受け付ける
+func (c *Config) GetBool(key string, def bool) (bool, error) {
+	vars, err := c.lookup(key)
+	if err != nil || len(vars) == 0 {
+		return def, err
+	}
+	v := vars[len(vars)-1]
+	if v.NoValue {
+		return true, nil
+	}
+	b, err := ParseBool(v.Value)
+	if err != nil {
+		return def, fmt.Errorf("%w key=%s", err, key)
+	}
+	return b, nil
+}
+
+// GetInt はkeyを整数として返す 値がない場合はdefを返す
+// k, m, gの接尾辞はそれぞれ1024, 1024^2, 1024^3倍を表す
+func (c *Config) GetInt(key string, def int64) (int64, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return def, nil
+	}
+	i, err := ParseInt(v)
+	if err != nil {
+		return def, fmt.Errorf("%w key=%s", err, key)
+	}
+	return i, nil
+}
+
+// GetPath はkeyをパスとして返す 先頭の "~/" と "~user/" はホームディレクトリに展開する
+func (c *Config) GetPath(key string) (string, bool, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return "", false, nil
+	}
+	p, err := ExpandPath(v)
+	if err != nil {
+		return "", false, fmt.Errorf("%w key=%s", err, key)
+	}
+	return p, true, nil
+}
+
+// Subsections はsectionに含まれるサブセクションの名前を出現順で返す
+func (c *Config) Subsections(section string) []string {
+	section = strings.ToLower(section)
+	exist := make(map[string]struct{})
+	var subs []string
+	for _, v := range c.vars {
+		if v.Section != section || v.Subsection == "" {
+			continue
+		}
+		if _, ok := exist[v.Subsection]; ok {
+			continue
+		}
+		exist[v.Subsection] = struct{}{}
+		subs = append(subs, v.Subsection)
+	}
+	return subs
+}
+
+// RepositoryFormatVersion はcore.repositoryformatversionを返す
+func (c *Config) RepositoryFormatVersion() (int, error) {
+	v, err := c.GetInt("core.repositoryformatversion", 0)
+	return int(v), err
+}
+
+// Extensions はextensionsセクションのキーと値を返す
+func (c *Config) Extensions() map[string]string {
+	ext := make(map[string]string)
+	for _, v := range c.vars {
+		if v.Section == "extensions" && v.Subsection == "" {
+			ext[v.Name] = v.Value
+		}
+	}
+	return ext
+}
+
+// ObjectFormat はextensions.objectformatで指定されたオブジェクトフォーマットを返す
+// 指定がない場合はsha1となる
+func (c *Config) ObjectFormat() (oid.Format, error) {
+	version, err := c.RepositoryFormatVersion()
+	if err != nil {
+		return "", err
+	}
+	name, ok := c.Get("extensions.objectformat")
+	if !ok || version == 0 {
+		return oid.SHA1, nil
+	}
+	f, ok := oid.ParseFormat(name)
+	if !ok {
+		return "", fmt.Errorf("unknown object format %s", name)
+	}
+	return f, nil
+}
+
+func ParseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off", "":
+		return false, nil
+	}
+	i, err := ParseInt(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: bad boolean %q", ErrInvalidValue, value)
+	}
+	return i != 0, nil
+}
+
+func ParseInt(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	unit := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		}
+		if unit != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	i, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad numeric %q", ErrInvalidValue, value)
+	}
+	return i * unit, nil
+}
+
+// ExpandPath は先頭の "~/" と "~user/" をホームディレクトリに展開する
+func ExpandPath(p string) (string, error) {
+	if !strings.HasPrefix(p, "~") {
+		return p, nil
+	}
+	name, rest, _ := strings.Cut(p[1:], "/")
+	var home string
+	if name == "" {
+		h, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		home = h
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// DefaultConfigure は初期化時の設定ファイルを書き込む
+// sha1以外のオブジェクトフォーマットではrepositoryformatversionを1にしてextensionsに記録する
+func DefaultConfigure(w io.Writer, format oid.Format) error {
+	version := 0
+	if format != oid.SHA1 {
+		version = 1
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[core]\n")
+	fmt.Fprintf(&sb, "\trepositoryformatversion = %d\n", version)
+	sb.WriteString("\tfilemode = false\n")
+	sb.WriteString("\tbare = false\n")
+	if format != oid.SHA1 {
+		sb.WriteString("[extensions]\n")
+		fmt.Fprintf(&sb, "\tobjectformat = %s\n", format)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File は書き換えのために読み込んだ1つの設定ファイル
+// 値の追加や削除は該当する行だけを書き換え、コメントや他の行はそのまま残す
+type File struct {
+	path  string
+	scope Scope
+	data  []byte
+
+	vars     []*Variable
+	sections []section
+}
+
+// ReadFile は設定ファイルを読み込む ファイルが存在しない場合は空のファイルとして扱う
+func ReadFile(path string, scope Scope) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f := &File{path: path, scope: scope}
+	return f, f.reset(data)
+}
+
+func (f *File) reset(data []byte) error {
+	vars, sections, err := parse(f.path, data)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		v.Scope = f.scope
+	}
+	f.data = data
+	f.vars = vars
+	f.sections = sections
+	return nil
+}
+
+// Path は設定ファイルのパスを返す
+func (f *File) Path() string {
+	return f.path
+}
+
+// Config はファイルに書かれた値を返す includeは展開しない
+func (f *File) Config() *Config {
+	return &Config{vars: f.vars}
+}
+
+func (f *File) lookup(sec, sub, name string) []*Variable {
+	var vars []*Variable
+	for _, v := range f.vars {
+		if v.Section == sec && v.Subsection == sub && v.Name == name {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+func (f *File) lines() []string {
+	lines := strings.SplitAfter(string(f.data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splice はstartからend(含まない)までの行をinsertで置き換えて読み込み直す
+func (f *File) splice(start, end int, insert ...string) error {
+	lines := f.lines()
+	if start > 0 && start <= len(lines) && !strings.HasSuffix(lines[start-1], "\n") {
+		lines[start-1] += "\n"
+	}
+
+	var sb strings.Builder
+	for _, l := range lines[:start] {
+		sb.WriteString(l)
+	}
+	for _, l := range insert {
+		sb.WriteString(l)
+	}
+	for _, l := range lines[end:] {
+		sb.WriteString(l)
+	}
+	return f.reset([]byte(sb.String()))
+}
+
+// replace はvの行をinsertで置き換える
+// "[section] name = value" のように同じ行の前にセクションヘッダーがある場合は、ヘッダーを残して別の行にする
+func (f *File) replace(v *Variable, insert ...string) error {
+	if head := strings.TrimRight(f.lines()[v.line][:v.column], " \t"); head != "" {
+		insert = append([]string{head + "\n"}, insert...)
+	}
+	return f.splice(v.line, v.endLine+1, insert...)
+}
+
+func variableLine(key, value string) string {
+	// 名前は指定された大文字小文字のまま書き込む
+	name := key[strings.LastIndex(key, ".")+1:]
+	return fmt.Sprintf("\t%s = %s\n", name, formatValue(value))
+}
+
+// Set はkeyの値をvalueに置き換える 存在しなければ追加する
+// 複数の値が存在する場合はErrMultipleValueを返す
+func (f *File) Set(key, value string) error {
+	sec, sub, name, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	vars := f.lookup(sec, sub, name)
+	switch len(vars) {
+	case 0:
+		return f.Add(key, value)
+	case 1:
+		return f.replace(vars[0], variableLine(key, value))
+	}
+	return fmt.Errorf("%w key=%s", ErrMultipleValue, key)
+}
+
+// Add はkeyに新しい値を追加する 既存の値は残す
+func (f *File) Add(key, value string) error {
+	sec, sub, _, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+
+	// 最後に現れた該当セクションの末尾に追加する
+	for i := len(f.sections) - 1; i >= 0; i-- {
+		s := f.sections[i]
+		if s.name != sec || s.subsection != sub {
+			continue
+		}
+		next := len(f.lines())
+		if i+1 < len(f.sections) {
+			next = f.sections[i+1].line
+		}
+		pos := s.line + 1
+		for _, v := range f.vars {
+			if v.line >= s.line && v.line < next && v.endLine+1 > pos {
+				pos = v.endLine + 1
+			}
+		}
+		return f.splice(pos, pos, variableLine(key, value))
+	}
+
+	n := len(f.lines())
+	return f.splice(n, n, formatSection(sec, sub)+"\n", variableLine(key, value))
+}
+
+// Unset はkeyの値を削除する
+// 存在しない場合はErrNotFound、複数の値が存在する場合はErrMultipleValueを返す
+func (f *File) Unset(key string) error {
+	sec, sub, name, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	vars := f.lookup(sec, sub, name)
+	switch len(vars) {
+	case 0:
+		return fmt.Errorf("%w key=%s", ErrNotFound, key)
+	case 1:
+		return f.replace(vars[0])
+	}
+	return fmt.Errorf("%w key=%s", ErrMultipleValue, key)
+}
+
+// UnsetAll はkeyのすべての値を削除する
+func (f *File) UnsetAll(key string) error {
+	sec, sub, name, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	vars := f.lookup(sec, sub, name)
+	if len(vars) == 0 {
+		return fmt.Errorf("%w key=%s", ErrNotFound, key)
+	}
+	// 後ろから削除すれば前の行番号は変わらない
+	for i := len(vars) - 1; i >= 0; i-- {
+		v := f.lookup(sec, sub, name)[i]
+		if err := f.replace(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save はファイルに書き込む
+func (f *File) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), os.FileMode(0755)); err != nil {
+		return err
+	}
+	lock := f.path + ".lock"
+	if err := os.WriteFile(lock, f.data, os.FileMode(0644)); err != nil {
+		return err
+	}
+	return os.Rename(lock, f.path)
+}
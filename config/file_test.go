@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWrite(t *testing.T) {
+	// 期待する結果は同じ内容のファイルに git config -f FILE を実行した結果
+	tests := []struct {
+		name  string
+		data  string
+		apply func(f *File) error
+		want  string
+	}{
+		{
+			name:  "set a variable on the section header line",
+			data:  "[core] bare = false ; comment\n\tfilemode = true\n",
+			apply: func(f *File) error { return f.Set("core.bare", "true") },
+			want:  "[core]\n\tbare = true\n\tfilemode = true\n",
+		},
+		{
+			name:  "unset a variable on the section header line",
+			data:  "[user] name = a\n\temail = b\n",
+			apply: func(f *File) error { return f.Unset("user.name") },
+			want:  "[user]\n\temail = b\n",
+		},
+		{
+			name:  "unset all with a variable on the section header line",
+			data:  "[m] v = 1\n\tv = 2\n\tw = 0\n[n]\n\tv = 3\n",
+			apply: func(f *File) error { return f.UnsetAll("m.v") },
+			want:  "[m]\n\tw = 0\n[n]\n\tv = 3\n",
+		},
+		{
+			name:  "add after a variable on the section header line",
+			data:  "[core] bare = false\n",
+			apply: func(f *File) error { return f.Add("core.x", "y") },
+			want:  "[core] bare = false\n\tx = y\n",
+		},
+		{
+			name:  "set a variable with continuation lines",
+			data:  "[a] x = 1 \\\n  2\n\ty = 3\n",
+			apply: func(f *File) error { return f.Set("a.x", "3") },
+			want:  "[a]\n\tx = 3\n\ty = 3\n",
+		},
+		{
+			name:  "set a value that needs quoting",
+			data:  "[core]\n\tbare = false\n",
+			apply: func(f *File) error { return f.Set("core.x", " lead#x\"q\\") },
+			want:  "[core]\n\tbare = false\n\tx = \" lead#x\\\"q\\\\\"\n",
+		},
+		{
+			name:  "set in a subsection",
+			data:  "[b \"Sub\"] k = v\n",
+			apply: func(f *File) error { return f.Set("b.Sub.k", "w") },
+			want:  "[b \"Sub\"]\n\tk = w\n",
+		},
+		{
+			name:  "add a new section",
+			data:  "[core]\n\tbare = false",
+			apply: func(f *File) error { return f.Set("remote.origin.url", "x") },
+			want:  "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := ReadFile(path, ScopeLocal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.apply(f); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Save(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/wildmatch"
+)
+
+// includeの入れ子の上限
+const maxIncludeDepth = 10
+
+// ScopePaths はscopeで読み込む設定ファイルのパスを読み込む順に返す
+// globalは$XDG_CONFIG_HOME/git/configと~/.gitconfigの両方を読み、後者が優先される
+func ScopePaths(scope Scope, gitdir string) []string {
+	switch scope {
+	case ScopeSystem:
+		if b, _ := ParseBool(os.Getenv("GIT_CONFIG_NOSYSTEM")); b {
+			return nil
+		}
+		if p := os.Getenv("GIT_CONFIG_SYSTEM"); p != "" {
+			return []string{p}
+		}
+		return []string{"/etc/gitconfig"}
+	case ScopeGlobal:
+		if p := os.Getenv("GIT_CONFIG_GLOBAL"); p != "" {
+			return []string{p}
+		}
+		var paths []string
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			paths = append(paths, filepath.Join(xdg, "git", "config"))
+		} else if home, err := os.UserHomeDir(); err == nil {
+			paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			paths = append(paths, filepath.Join(home, ".gitconfig"))
+		}
+		return paths
+	case ScopeLocal:
+		if gitdir == "" {
+			return nil
+		}
+		return []string{filepath.Join(gitdir, "config")}
+	case ScopeWorktree:
+		if gitdir == "" {
+			return nil
+		}
+		return []string{filepath.Join(gitdir, "config.worktree")}
+	}
+	return nil
+}
+
+// WritePath はscopeの値を書き込む設定ファイルのパスを返す
+// globalは~/.gitconfigが存在せずXDGの設定ファイルのみが存在する場合はそちらに書き込む
+func WritePath(scope Scope, gitdir string) (string, error) {
+	paths := ScopePaths(scope, gitdir)
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no config file for %s scope", scope)
+	}
+	p := paths[len(paths)-1]
+	if scope == ScopeGlobal && len(paths) == 2 {
+		if _, err := os.Stat(paths[1]); os.IsNotExist(err) {
+			if _, err := os.Stat(paths[0]); err == nil {
+				p = paths[0]
+			}
+		}
+	}
+	return p, nil
+}
+
+// Load はsystem, global, リポジトリ, ワークツリーの順に設定ファイルを読み込む
+// 存在しないファイルは読み飛ばす gitdirが空の場合はリポジトリ外として扱う
+// ワークツリーの設定はextensions.worktreeconfigが有効な場合のみ読み込む
+func Load(gitdir string) (*Config, error) {
+	l := &loader{gitdir: gitdir}
+	c := New()
+	for _, scope := range []Scope{ScopeSystem, ScopeGlobal, ScopeLocal, ScopeWorktree} {
+		if scope == ScopeWorktree {
+			enabled, err := c.Scoped(ScopeLocal).GetBool("extensions.worktreeconfig", false)
+			if err != nil {
+				return nil, err
+			}
+			if !enabled {
+				continue
+			}
+		}
+		for _, p := range ScopePaths(scope, gitdir) {
+			sc, err := l.load(p, scope, 0)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			c.Merge(sc)
+		}
+	}
+	return c, nil
+}
+
+// LoadFile は1つの設定ファイルをincludeを展開して読み込む
+// gitdirはincludeIfの条件の判定に使う
+func LoadFile(path string, scope Scope, gitdir string) (*Config, error) {
+	l := &loader{gitdir: gitdir}
+	return l.load(path, scope, 0)
+}
+
+// Scoped は指定したスコープの値だけを含む設定を返す
+func (c *Config) Scoped(scopes ...Scope) *Config {
+	sc := New()
+	for _, v := range c.vars {
+		for _, s := range scopes {
+			if v.Scope == s {
+				sc.vars = append(sc.vars, v)
+				break
+			}
+		}
+	}
+	return sc
+}
+
+type loader struct {
+	gitdir string
+}
+
+func (l *loader) load(path string, scope Scope, depth int) (*Config, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("%w: exceeded maximum include depth (%d) while including %s", ErrInvalidConfig, maxIncludeDepth, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars, _, err := parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := New()
+	for _, v := range vars {
+		v.Scope = scope
+		c.vars = append(c.vars, v)
+
+		if v.Name != "path" || v.NoValue {
+			continue
+		}
+		include := v.Section == "include" && v.Subsection == ""
+		if v.Section == "includeif" {
+			ok, err := l.condition(v.Subsection, path)
+			if err != nil {
+				return nil, err
+			}
+			include = ok
+		}
+		if !include {
+			continue
+		}
+
+		// includeした位置に読み込んだ値を挿入する
+		p, err := ExpandPath(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(path), p)
+		}
+		ic, err := l.load(p, scope, depth+1)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		c.Merge(ic)
+	}
+	return c, nil
+}
+
+// condition はincludeIfの条件を判定する
+// "gitdir:", "gitdir/i:", "onbranch:" に対応する
+func (l *loader) condition(cond, path string) (bool, error) {
+	switch {
+	case strings.HasPrefix(cond, "gitdir:"):
+		return l.matchGitDir(strings.TrimPrefix(cond, "gitdir:"), path, 0)
+	case strings.HasPrefix(cond, "gitdir/i:"):
+		return l.matchGitDir(strings.TrimPrefix(cond, "gitdir/i:"), path, wildmatch.CaseFold)
+	case strings.HasPrefix(cond, "onbranch:"):
+		return l.matchBranch(strings.TrimPrefix(cond, "onbranch:")), nil
+	}
+	return false, nil
+}
+
+func (l *loader) matchGitDir(pattern, path string, flags wildmatch.Flag) (bool, error) {
+	if l.gitdir == "" {
+		return false, nil
+	}
+
+	p, err := ExpandPath(pattern)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case strings.HasPrefix(p, "./"):
+		p = filepath.Join(filepath.Dir(path), p[2:])
+	case !filepath.IsAbs(p):
+		p = "**/" + p
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p = strings.TrimSuffix(p, "/") + "/**"
+	}
+
+	gitdir, err := filepath.Abs(l.gitdir)
+	if err != nil {
+		return false, err
+	}
+	candidates := []string{gitdir}
+	if real, err := filepath.EvalSymlinks(gitdir); err == nil && real != gitdir {
+		candidates = append(candidates, real)
+	}
+	for _, c := range candidates {
+		if wildmatch.Match(filepath.ToSlash(p), filepath.ToSlash(c), flags|wildmatch.PathName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (l *loader) matchBranch(pattern string) bool {
+	if l.gitdir == "" {
+		return false
+	}
+	head, err := os.ReadFile(filepath.Join(l.gitdir, "HEAD"))
+	if err != nil {
+		return false
+	}
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref: refs/heads/") {
+		return false
+	}
+	branch := strings.TrimPrefix(ref, "ref: refs/heads/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return wildmatch.Match(pattern, branch, wildmatch.PathName)
+}
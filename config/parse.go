@@ -0,0 +1,388 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidConfig = errors.New("bad config")
+	ErrInvalidKey    = errors.New("invalid key")
+)
+
+// Variable は設定ファイル中の1つの値
+type Variable struct {
+	// Section とName は小文字で保持し、Subsectionは大文字小文字を区別する
+	Section    string
+	Subsection string
+	Name       string
+	Value      string
+	// NoValue は "[section] name" のように "=" を伴わない場合に真となる 真偽値としてはtrueとして扱う
+	NoValue bool
+
+	Scope  Scope
+	Origin string
+
+	// 設定ファイル中の行番号(0始まり)と、行の先頭から名前までのバイト数 書き換えに使う
+	line    int
+	endLine int
+	column  int
+}
+
+// Key は "section.subsection.name" の形式のキーを返す
+func (v *Variable) Key() string {
+	if v.Subsection == "" {
+		return v.Section + "." + v.Name
+	}
+	return v.Section + "." + v.Subsection + "." + v.Name
+}
+
+// section は設定ファイル中のセクションヘッダーの位置
+type section struct {
+	name       string
+	subsection string
+	line       int
+}
+
+// ParseKey は "section.subsection.name" を分解する
+// セクションと名前は小文字にし、サブセクションはそのまま返す
+func ParseKey(key string) (string, string, string, error) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("%w %s", ErrInvalidKey, key)
+	}
+
+	sec := strings.ToLower(key[:first])
+	name := strings.ToLower(key[last+1:])
+	sub := ""
+	if first != last {
+		sub = key[first+1 : last]
+	}
+
+	for _, c := range sec {
+		if !isKeyChar(c) && c != '.' {
+			return "", "", "", fmt.Errorf("%w %s", ErrInvalidKey, key)
+		}
+	}
+	if !isAlpha(rune(name[0])) {
+		return "", "", "", fmt.Errorf("%w %s", ErrInvalidKey, key)
+	}
+	for _, c := range name {
+		if !isKeyChar(c) {
+			return "", "", "", fmt.Errorf("%w %s", ErrInvalidKey, key)
+		}
+	}
+	return sec, sub, name, nil
+}
+
+func isAlpha(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isKeyChar(c rune) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9') || c == '-'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+// parser はgitの設定ファイルの書式を読み込む
+type parser struct {
+	data []byte
+	pos  int
+	line int
+	// lineStart は現在の行の先頭の位置
+	lineStart int
+	name      string
+
+	sections []section
+	vars     []*Variable
+}
+
+func parse(name string, data []byte) ([]*Variable, []section, error) {
+	p := &parser{data: data, name: name}
+	if err := p.run(); err != nil {
+		return nil, nil, err
+	}
+	return p.vars, p.sections, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w line %d in %s: %s", ErrInvalidConfig, p.line+1, p.name, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) next() byte {
+	c := p.peek()
+	if p.pos < len(p.data) {
+		p.pos++
+		if c == '\n' {
+			p.line++
+			p.lineStart = p.pos
+		}
+	}
+	return c
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+func (p *parser) run() error {
+	var cur *section
+
+	// UTF-8のBOMは読み飛ばす
+	if strings.HasPrefix(string(p.data), "\xef\xbb\xbf") {
+		p.pos = 3
+	}
+
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '\n' || isSpace(c):
+			p.next()
+		case c == '#' || c == ';':
+			p.skipComment()
+		case c == '[':
+			s, err := p.parseSection()
+			if err != nil {
+				return err
+			}
+			p.sections = append(p.sections, s)
+			cur = &p.sections[len(p.sections)-1]
+		case isAlpha(rune(c)):
+			if cur == nil {
+				return p.errorf("variable outside of section")
+			}
+			v, err := p.parseVariable(cur)
+			if err != nil {
+				return err
+			}
+			p.vars = append(p.vars, v)
+		default:
+			return p.errorf("unexpected character %q", c)
+		}
+	}
+	return nil
+}
+
+// parseSection は "[section]", "[section "subsection"]", "[section.subsection]" を読み込む
+func (p *parser) parseSection() (section, error) {
+	s := section{line: p.line}
+	p.next() // '['
+
+	var name strings.Builder
+	for {
+		c := p.next()
+		switch {
+		case c == 0 || c == '\n':
+			return s, p.errorf("unterminated section header")
+		case c == ']':
+			s.name = strings.ToLower(name.String())
+			// 古い形式の "[section.subsection]" はサブセクションも小文字になる
+			if i := strings.Index(s.name, "."); i >= 0 {
+				s.name, s.subsection = s.name[:i], s.name[i+1:]
+			}
+			return s, nil
+		case isSpace(c):
+			for isSpace(p.peek()) {
+				p.next()
+			}
+			if p.next() != '"' {
+				return s, p.errorf("bad section header")
+			}
+			sub, err := p.parseSubsection()
+			if err != nil {
+				return s, err
+			}
+			if p.next() != ']' {
+				return s, p.errorf("bad section header")
+			}
+			s.name = strings.ToLower(name.String())
+			s.subsection = sub
+			return s, nil
+		case isKeyChar(rune(c)) || c == '.':
+			name.WriteByte(c)
+		default:
+			return s, p.errorf("invalid character %q in section header", c)
+		}
+	}
+}
+
+func (p *parser) parseSubsection() (string, error) {
+	var sub strings.Builder
+	for {
+		c := p.next()
+		switch c {
+		case 0, '\n':
+			return "", p.errorf("unterminated subsection")
+		case '"':
+			return sub.String(), nil
+		case '\\':
+			c = p.next()
+			if c == 0 || c == '\n' {
+				return "", p.errorf("unterminated subsection")
+			}
+			sub.WriteByte(c)
+		default:
+			sub.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) parseVariable(s *section) (*Variable, error) {
+	v := &Variable{
+		Section:    s.name,
+		Subsection: s.subsection,
+		Origin:     p.name,
+		line:       p.line,
+		column:     p.pos - p.lineStart,
+	}
+
+	var name strings.Builder
+	for isKeyChar(rune(p.peek())) {
+		name.WriteByte(p.next())
+	}
+	v.Name = strings.ToLower(name.String())
+
+	for isSpace(p.peek()) {
+		p.next()
+	}
+
+	switch c := p.peek(); {
+	case c == 0 || c == '\n' || c == '#' || c == ';':
+		v.NoValue = true
+		v.endLine = p.line
+		p.skipComment()
+		return v, nil
+	case c == '=':
+		p.next()
+	default:
+		return nil, p.errorf("invalid variable name")
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	v.Value = value
+	v.endLine = p.line
+	return v, nil
+}
+
+// parseValue は値を読み込む
+// 引用符の外の前後の空白は取り除き、"#"と";"以降はコメントとして扱う
+// 行末の "\" は次の行に続くことを表す
+func (p *parser) parseValue() (string, error) {
+	for isSpace(p.peek()) {
+		p.next()
+	}
+
+	var (
+		value  strings.Builder
+		quoted bool
+		space  int
+	)
+	for {
+		c := p.peek()
+		if c == 0 || c == '\n' {
+			if quoted {
+				return "", p.errorf("unterminated quoted value")
+			}
+			return value.String(), nil
+		}
+		p.next()
+
+		if !quoted {
+			if isSpace(c) {
+				space++
+				continue
+			}
+			if c == '#' || c == ';' {
+				p.skipComment()
+				return value.String(), nil
+			}
+		}
+		for ; space > 0; space-- {
+			value.WriteByte(' ')
+		}
+
+		switch c {
+		case '\\':
+			e := p.next()
+			switch e {
+			case '\n':
+				continue
+			case 't':
+				value.WriteByte('\t')
+			case 'b':
+				value.WriteByte('\b')
+			case 'n':
+				value.WriteByte('\n')
+			case '\\', '"':
+				value.WriteByte(e)
+			default:
+				return "", p.errorf("invalid escape sequence")
+			}
+		case '"':
+			quoted = !quoted
+		default:
+			value.WriteByte(c)
+		}
+	}
+}
+
+// formatValue は値を設定ファイルに書き込める形式にする
+func formatValue(value string) string {
+	needQuote := value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;")
+
+	var sb strings.Builder
+	if needQuote {
+		sb.WriteByte('"')
+	}
+	for _, c := range []byte(value) {
+		switch c {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if needQuote {
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+// formatSection はセクションヘッダーを書き込める形式にする
+func formatSection(name, subsection string) string {
+	if subsection == "" {
+		return "[" + name + "]"
+	}
+	sub := strings.ReplaceAll(subsection, `\`, `\\`)
+	sub = strings.ReplaceAll(sub, `"`, `\"`)
+	return fmt.Sprintf("[%s \"%s\"]", name, sub)
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	// 期待する結果は git config -f FILE --list の出力
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "variable on the section header line",
+			data: "[core] bare = false ; comment\n\tfilemode = true\n",
+			want: []string{"core.bare=false", "core.filemode=true"},
+		},
+		{
+			name: "continuation line",
+			data: "[a] x = 1 \\\n  2\n",
+			want: []string{"a.x=1   2"},
+		},
+		{
+			name: "quoting and escapes",
+			data: "[a]\n" +
+				"\ty = \" spaced  \"  # comment\n" +
+				"\tz = a\\\"b\\\\c\\td ; comment\n" +
+				"\tw = \"semi;colon\" tail\n",
+			want: []string{"a.y= spaced  ", "a.z=a\"b\\c\td", "a.w=semi;colon tail"},
+		},
+		{
+			name: "no value",
+			data: "[a]\n\tflag\n\tother # comment\n",
+			want: []string{"a.flag", "a.other"},
+		},
+		{
+			name: "subsections",
+			data: "[b \"Sub \\\"q\\\"\"] k = v\n[C.Old] k2 = v2\n",
+			want: []string{"b.Sub \"q\".k=v", "c.old.k2=v2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, _, err := parse("test", []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range vars {
+				if v.NoValue {
+					got = append(got, v.Key())
+				} else {
+					got = append(got, v.Key()+"="+v.Value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parse() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parse()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "variable outside of section", data: "bare = false\n"},
+		{name: "unterminated quote", data: "[a]\n\tx = \"abc\n"},
+		{name: "invalid escape", data: "[a]\n\tx = \\q\n"},
+		{name: "unterminated section", data: "[a\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parse("test", []byte(tt.data)); err == nil {
+				t.Errorf("parse() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/config"
+)
+
+func TestConfigFormatNoValue(t *testing.T) {
+	// 期待する結果は git config -f FILE --type TYPE --get KEY の出力
+	p := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(p, []byte("[a] flag\n\toff = no\n\tempty =\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.LoadFile(p, config.ScopeLocal, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		valueType string
+		key       string
+		want      string
+		wantErr   bool
+	}{
+		{valueType: "bool", key: "a.flag", want: "true"},
+		{valueType: "bool", key: "a.off", want: "false"},
+		{valueType: "bool", key: "a.empty", want: "false"},
+		{valueType: "", key: "a.flag", want: ""},
+		{valueType: "int", key: "a.flag", wantErr: true},
+		{valueType: "path", key: "a.flag", wantErr: true},
+	}
+	for _, tt := range tests {
+		vars := conf.Variables(tt.key)
+		if len(vars) != 1 {
+			t.Fatalf("Variables(%s) = %v, want 1 variable", tt.key, vars)
+		}
+		c := &ConfigCommand{valueType: tt.valueType}
+		got, err := c.format(tt.key, vars[0])
+		if (err != nil) != tt.wantErr {
+			t.Errorf("format(%s) type=%s error = %v, wantErr %v", tt.key, tt.valueType, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("format(%s) type=%s = %q, want %q", tt.key, tt.valueType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package diff
+
+// インデントによる位置の調整に使う重み gitのxdiffと同じ値
+const (
+	maxIndent  = 200
+	maxBlanks  = 20
+	maxSliding = 100
+
+	startOfFilePenalty              = 1
+	endOfFilePenalty                = 21
+	totalBlankWeight                = -30
+	postBlankWeight                 = 6
+	relativeIndentPenalty           = -4
+	relativeIndentWithBlankPenalty  = 10
+	relativeOutdentPenalty          = 24
+	relativeOutdentWithBlankPenalty = 17
+	relativeDedentPenalty           = 23
+	relativeDedentWithBlankPenalty  = 17
+	indentWeight                    = 60
+)
+
+// file は変更のまとまりをずらすための片方のファイル
+type file struct {
+	lines   []string
+	ids     []int
+	changed []bool
+}
+
+// group は連続する変更された行 start == end の場合は空のまとまり
+type group struct {
+	start, end int
+}
+
+func (f *file) isChanged(i int) bool {
+	return i >= 0 && i < len(f.changed) && f.changed[i]
+}
+
+func (f *file) firstGroup() group {
+	g := group{}
+	for f.isChanged(g.end) {
+		g.end++
+	}
+	return g
+}
+
+func (f *file) nextGroup(g *group) bool {
+	if g.end == len(f.changed) {
+		return false
+	}
+	g.start = g.end + 1
+	g.end = g.start
+	for f.isChanged(g.end) {
+		g.end++
+	}
+	return true
+}
+
+func (f *file) previousGroup(g *group) bool {
+	if g.start == 0 {
+		return false
+	}
+	g.end = g.start - 1
+	g.start = g.end
+	for f.isChanged(g.start - 1) {
+		g.start--
+	}
+	return true
+}
+
+// slideDown はまとまりを1行下にずらす 前後のまとまりとつながった場合は併合する
+func (f *file) slideDown(g *group) bool {
+	if g.end < len(f.changed) && f.ids[g.start] == f.ids[g.end] {
+		f.changed[g.start] = false
+		g.start++
+		f.changed[g.end] = true
+		g.end++
+		for f.isChanged(g.end) {
+			g.end++
+		}
+		return true
+	}
+	return false
+}
+
+func (f *file) slideUp(g *group) bool {
+	if g.start > 0 && f.ids[g.start-1] == f.ids[g.end-1] {
+		g.start--
+		f.changed[g.start] = true
+		g.end--
+		f.changed[g.end] = false
+		for f.isChanged(g.start - 1) {
+			g.start--
+		}
+		return true
+	}
+	return false
+}
+
+// compact はfの変更のまとまりを、もう一方のファイルoの変更と揃う位置か
+// インデントから読みやすいと判断される位置にずらす
+func compact(lines []string, ids []int, changed []bool, oids []int, ochanged []bool) {
+	f := &file{lines: lines, ids: ids, changed: changed}
+	o := &file{ids: oids, changed: ochanged}
+
+	g := f.firstGroup()
+	og := o.firstGroup()
+	for {
+		if g.end != g.start {
+			var groupsize, earliestEnd int
+			endMatchingOther := -1
+			for {
+				groupsize = g.end - g.start
+				endMatchingOther = -1
+
+				for f.slideUp(&g) {
+					o.previousGroup(&og)
+				}
+				earliestEnd = g.end
+				if og.end > og.start {
+					endMatchingOther = g.end
+				}
+
+				for f.slideDown(&g) {
+					o.nextGroup(&og)
+					if og.end > og.start {
+						endMatchingOther = g.end
+					}
+				}
+				if groupsize == g.end-g.start {
+					break
+				}
+			}
+
+			switch {
+			case g.end == earliestEnd:
+				// ずらせない
+			case endMatchingOther != -1:
+				// もう一方のファイルの変更と揃う位置まで戻す
+				for og.end == og.start {
+					f.slideUp(&g)
+					o.previousGroup(&og)
+				}
+			default:
+				best := f.bestShift(g, groupsize, earliestEnd)
+				for g.end > best {
+					f.slideUp(&g)
+					o.previousGroup(&og)
+				}
+			}
+		}
+
+		if !f.nextGroup(&g) {
+			break
+		}
+		o.nextGroup(&og)
+	}
+}
+
+// bestShift はまとまりの前後の区切りの位置をインデントから評価し、最もよいまとまりの終端を返す
+func (f *file) bestShift(g group, groupsize, earliestEnd int) int {
+	shift := earliestEnd
+	if g.end-groupsize-1 > shift {
+		shift = g.end - groupsize - 1
+	}
+	if g.end-maxSliding > shift {
+		shift = g.end - maxSliding
+	}
+
+	best := -1
+	var bestScore splitScore
+	for ; shift <= g.end; shift++ {
+		var score splitScore
+		score.add(f.measureSplit(shift))
+		score.add(f.measureSplit(shift - groupsize))
+		if best == -1 || score.compare(bestScore) <= 0 {
+			bestScore = score
+			best = shift
+		}
+	}
+	return best
+}
+
+// indent は行のインデントの幅を返す 空白のみの行は-1を返す
+func indent(line string) int {
+	n := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			n++
+		case '\t':
+			n += 8 - n%8
+		case '\n', '\r', '\v', '\f':
+		default:
+			return n
+		}
+		if n >= maxIndent {
+			return maxIndent
+		}
+	}
+	return -1
+}
+
+type splitMeasurement struct {
+	endOfFile  bool
+	indent     int
+	preBlank   int
+	preIndent  int
+	postBlank  int
+	postIndent int
+}
+
+// measureSplit はsplit行目の直前で区切った場合の前後の行のインデントを測る
+func (f *file) measureSplit(split int) splitMeasurement {
+	var m splitMeasurement
+	if split >= len(f.lines) {
+		m.endOfFile = true
+		m.indent = -1
+	} else {
+		m.indent = indent(f.lines[split])
+	}
+
+	m.preIndent = -1
+	for i := split - 1; i >= 0; i-- {
+		if m.preIndent = indent(f.lines[i]); m.preIndent != -1 {
+			break
+		}
+		m.preBlank++
+		if m.preBlank == maxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+
+	m.postIndent = -1
+	for i := split + 1; i < len(f.lines); i++ {
+		if m.postIndent = indent(f.lines[i]); m.postIndent != -1 {
+			break
+		}
+		m.postBlank++
+		if m.postBlank == maxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+	return m
+}
+
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *splitScore) add(m splitMeasurement) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += endOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	s.penalty += totalBlankWeight * totalBlank
+	s.penalty += postBlankWeight * postBlank
+
+	ind := m.indent
+	if ind == -1 {
+		ind = m.postIndent
+	}
+	anyBlanks := totalBlank != 0
+	s.effectiveIndent += ind
+
+	switch {
+	case ind == -1 || m.preIndent == -1 || ind == m.preIndent:
+	case ind > m.preIndent:
+		if anyBlanks {
+			s.penalty += relativeIndentWithBlankPenalty
+		} else {
+			s.penalty += relativeIndentPenalty
+		}
+	case m.postIndent != -1 && m.postIndent > ind:
+		if anyBlanks {
+			s.penalty += relativeOutdentWithBlankPenalty
+		} else {
+			s.penalty += relativeOutdentPenalty
+		}
+	default:
+		if anyBlanks {
+			s.penalty += relativeDedentWithBlankPenalty
+		} else {
+			s.penalty += relativeDedentPenalty
+		}
+	}
+}
+
+func (s splitScore) compare(o splitScore) int {
+	cmp := 0
+	if s.effectiveIndent > o.effectiveIndent {
+		cmp = 1
+	} else if s.effectiveIndent < o.effectiveIndent {
+		cmp = -1
+	}
+	return indentWeight*cmp + (s.penalty - o.penalty)
+}
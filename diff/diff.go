@@ -0,0 +1,424 @@
+// Package diff は行単位の差分の計算と出力を提供する
+package diff
+
+import "bytes"
+
+// SplitLines はdataを改行を含めた行に分割する
+// 末尾に改行のない最後の行もそのまま1行とする
+func SplitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// Result はaとbの行ごとの差分
+// ChangedA[i]が真ならaのi行目は削除され、ChangedB[j]が真ならbのj行目は追加された
+// 変更されていない行はaとbで同じ順に対応する
+type Result struct {
+	A, B               []string
+	ChangedA, ChangedB []bool
+}
+
+// Lines はaとbの差分をgitのxdiffと同じMyersのアルゴリズムで計算する
+// gitと同じく変更のまとまりをインデントを基準に読みやすい位置にずらす
+func Lines(a, b []string) *Result {
+	// 比較を速くするため行を番号に置き換える
+	ids := make(map[string]int)
+	ia := intern(ids, a)
+	ib := intern(ids, b)
+
+	d := &differ{
+		ca: make([]bool, len(a)),
+		cb: make([]bool, len(b)),
+	}
+	d.prepare(ia, ib, len(ids))
+	d.compare(0, len(d.ha1), 0, len(d.ha2), false)
+
+	compact(a, ia, d.ca, ib, d.cb)
+	compact(b, ib, d.cb, ia, d.ca)
+	return &Result{A: a, B: b, ChangedA: d.ca, ChangedB: d.cb}
+}
+
+func intern(ids map[string]int, lines []string) []int {
+	out := make([]int, len(lines))
+	for i, l := range lines {
+		id, ok := ids[l]
+		if !ok {
+			id = len(ids)
+			ids[l] = id
+		}
+		out[i] = id
+	}
+	return out
+}
+
+// xdiffの探索の打ち切りに使う値
+const (
+	maxEqLimit     = 1024
+	simscanWindow  = 100
+	kpdisRun       = 4
+	maxCostMin     = 256
+	snakeCount     = 20
+	heuristicMin   = 256
+	heuristicKCost = 4
+	lineMax        = int(^uint(0) >> 1)
+)
+
+type differ struct {
+	ca, cb []bool
+	// ha1, ha2 は比較の対象とする行の番号、rindex1, rindex2 はその元の位置
+	ha1, ha2         []int
+	rindex1, rindex2 []int
+	kvdf, kvdb       []int
+	off              int
+	maxCost          int
+}
+
+// prepare は前後の共通の行を除き、もう一方に存在しない行を変更として先に取り除く
+// もう一方に何度も現れる行も、存在しない行に挟まれていれば取り除く
+func (d *differ) prepare(a, b []int, classes int) {
+	count1 := make([]int, classes)
+	count2 := make([]int, classes)
+	for _, id := range a {
+		count1[id]++
+	}
+	for _, id := range b {
+		count2[id]++
+	}
+
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	end := 0
+	for end < len(a)-start && end < len(b)-start && a[len(a)-1-end] == b[len(b)-1-end] {
+		end++
+	}
+	end1, end2 := len(a)-end-1, len(b)-end-1
+
+	dis1 := discards(a, count2, start, end1)
+	dis2 := discards(b, count1, start, end2)
+	d.ha1, d.rindex1 = reduce(a, dis1, d.ca, start, end1)
+	d.ha2, d.rindex2 = reduce(b, dis2, d.cb, start, end2)
+
+	ndiags := len(d.ha1) + len(d.ha2) + 3
+	d.kvdf = make([]int, ndiags)
+	d.kvdb = make([]int, ndiags)
+	d.off = len(d.ha2) + 1
+	d.maxCost = bogoSqrt(ndiags)
+	if d.maxCost < maxCostMin {
+		d.maxCost = maxCostMin
+	}
+}
+
+// discards は行がもう一方のファイルに存在しないなら0、何度も現れるなら2、それ以外は1とする
+func discards(lines, other []int, start, end int) []byte {
+	dis := make([]byte, len(lines)+1)
+	limit := bogoSqrt(len(lines))
+	if limit > maxEqLimit {
+		limit = maxEqLimit
+	}
+	for i := start; i <= end; i++ {
+		switch n := other[lines[i]]; {
+		case n == 0:
+			dis[i] = 0
+		case n >= limit:
+			dis[i] = 2
+		default:
+			dis[i] = 1
+		}
+	}
+	return dis
+}
+
+func reduce(lines []int, dis []byte, changed []bool, start, end int) ([]int, []int) {
+	var ha, rindex []int
+	for i := start; i <= end; i++ {
+		if dis[i] == 1 || (dis[i] == 2 && !cleanMultiMatch(dis, i, start, end)) {
+			ha = append(ha, lines[i])
+			rindex = append(rindex, i)
+		} else {
+			changed[i] = true
+		}
+	}
+	return ha, rindex
+}
+
+// cleanMultiMatch は何度も現れる行iが、存在しない行の連なりの中にあるか判定する
+func cleanMultiMatch(dis []byte, i, s, e int) bool {
+	if i-s > simscanWindow {
+		s = i - simscanWindow
+	}
+	if e-i > simscanWindow {
+		e = i + simscanWindow
+	}
+
+	rdis0, rpdis0 := 0, 1
+	for r := 1; i-r >= s; r++ {
+		if dis[i-r] == 0 {
+			rdis0++
+		} else if dis[i-r] == 2 {
+			rpdis0++
+		} else {
+			break
+		}
+	}
+	if rdis0 == 0 {
+		return false
+	}
+	rdis1, rpdis1 := 0, 1
+	for r := 1; i+r <= e; r++ {
+		if dis[i+r] == 0 {
+			rdis1++
+		} else if dis[i+r] == 2 {
+			rpdis1++
+		} else {
+			break
+		}
+	}
+	if rdis1 == 0 {
+		return false
+	}
+	rdis1 += rdis0
+	rpdis1 += rpdis0
+	return rpdis1*kpdisRun < rpdis1+rdis1
+}
+
+func bogoSqrt(n int) int {
+	i := 1
+	for ; n > 0; n >>= 2 {
+		i <<= 1
+	}
+	return i
+}
+
+// compare はha1[off1:lim1]とha2[off2:lim2]の差分を求め、変更された行に印をつける
+func (d *differ) compare(off1, lim1, off2, lim2 int, needMin bool) {
+	for off1 < lim1 && off2 < lim2 && d.ha1[off1] == d.ha2[off2] {
+		off1++
+		off2++
+	}
+	for off1 < lim1 && off2 < lim2 && d.ha1[lim1-1] == d.ha2[lim2-1] {
+		lim1--
+		lim2--
+	}
+
+	switch {
+	case off1 == lim1:
+		for ; off2 < lim2; off2++ {
+			d.cb[d.rindex2[off2]] = true
+		}
+	case off2 == lim2:
+		for ; off1 < lim1; off1++ {
+			d.ca[d.rindex1[off1]] = true
+		}
+	default:
+		spl := d.split(off1, lim1, off2, lim2, needMin)
+		d.compare(off1, spl.i1, off2, spl.i2, spl.minLo)
+		d.compare(spl.i1, lim1, spl.i2, lim2, spl.minHi)
+	}
+}
+
+type splitPoint struct {
+	i1, i2       int
+	minLo, minHi bool
+}
+
+func (d *differ) kf(k int) int   { return d.kvdf[k+d.off] }
+func (d *differ) setKf(k, v int) { d.kvdf[k+d.off] = v }
+func (d *differ) kb(k int) int   { return d.kvdb[k+d.off] }
+func (d *differ) setKb(k, v int) { d.kvdb[k+d.off] = v }
+
+// split は前後から最短の編集経路を探し、経路が重なる点で分割する
+// 編集の数が多くなりすぎた場合は最短でなくても見込みのある点で分割する
+func (d *differ) split(off1, lim1, off2, lim2 int, needMin bool) splitPoint {
+	ha1, ha2 := d.ha1, d.ha2
+	dmin, dmax := off1-lim2, lim1-off2
+	fmid, bmid := off1-off2, lim1-lim2
+	odd := (fmid-bmid)&1 != 0
+	fmin, fmax := fmid, fmid
+	bmin, bmax := bmid, bmid
+
+	d.setKf(fmid, off1)
+	d.setKb(bmid, lim1)
+
+	for ec := 1; ; ec++ {
+		gotSnake := false
+
+		if fmin > dmin {
+			fmin--
+			d.setKf(fmin-1, -1)
+		} else {
+			fmin++
+		}
+		if fmax < dmax {
+			fmax++
+			d.setKf(fmax+1, -1)
+		} else {
+			fmax--
+		}
+
+		for k := fmax; k >= fmin; k -= 2 {
+			var i1 int
+			if d.kf(k-1) >= d.kf(k+1) {
+				i1 = d.kf(k-1) + 1
+			} else {
+				i1 = d.kf(k + 1)
+			}
+			prev1 := i1
+			i2 := i1 - k
+			for i1 < lim1 && i2 < lim2 && ha1[i1] == ha2[i2] {
+				i1++
+				i2++
+			}
+			if i1-prev1 > snakeCount {
+				gotSnake = true
+			}
+			d.setKf(k, i1)
+			if odd && bmin <= k && k <= bmax && d.kb(k) <= i1 {
+				return splitPoint{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if bmin > dmin {
+			bmin--
+			d.setKb(bmin-1, lineMax)
+		} else {
+			bmin++
+		}
+		if bmax < dmax {
+			bmax++
+			d.setKb(bmax+1, lineMax)
+		} else {
+			bmax--
+		}
+
+		for k := bmax; k >= bmin; k -= 2 {
+			var i1 int
+			if d.kb(k-1) < d.kb(k+1) {
+				i1 = d.kb(k - 1)
+			} else {
+				i1 = d.kb(k+1) - 1
+			}
+			prev1 := i1
+			i2 := i1 - k
+			for i1 > off1 && i2 > off2 && ha1[i1-1] == ha2[i2-1] {
+				i1--
+				i2--
+			}
+			if prev1-i1 > snakeCount {
+				gotSnake = true
+			}
+			d.setKb(k, i1)
+			if !odd && fmin <= k && k <= fmax && i1 <= d.kf(k) {
+				return splitPoint{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if needMin {
+			continue
+		}
+
+		// 十分に長く一致する行の連なりに到達した経路があればそこで分割する
+		if gotSnake && ec > heuristicMin {
+			best := 0
+			var spl splitPoint
+			for k := fmax; k >= fmin; k -= 2 {
+				dd := fmid - k
+				if k > fmid {
+					dd = k - fmid
+				}
+				i1 := d.kf(k)
+				i2 := i1 - k
+				v := (i1 - off1) + (i2 - off2) - dd
+				if v > heuristicKCost*ec && v > best &&
+					off1+snakeCount <= i1 && i1 < lim1 &&
+					off2+snakeCount <= i2 && i2 < lim2 {
+					for n := 1; ha1[i1-n] == ha2[i2-n]; n++ {
+						if n == snakeCount {
+							best = v
+							spl = splitPoint{i1: i1, i2: i2}
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo, spl.minHi = true, false
+				return spl
+			}
+
+			best = 0
+			for k := bmax; k >= bmin; k -= 2 {
+				dd := bmid - k
+				if k > bmid {
+					dd = k - bmid
+				}
+				i1 := d.kb(k)
+				i2 := i1 - k
+				v := (lim1 - i1) + (lim2 - i2) - dd
+				if v > heuristicKCost*ec && v > best &&
+					off1 < i1 && i1 <= lim1-snakeCount &&
+					off2 < i2 && i2 <= lim2-snakeCount {
+					for n := 0; ha1[i1+n] == ha2[i2+n]; n++ {
+						if n == snakeCount-1 {
+							best = v
+							spl = splitPoint{i1: i1, i2: i2}
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo, spl.minHi = false, true
+				return spl
+			}
+		}
+
+		// 探索を打ち切り、最も遠くまで到達した経路で分割する
+		if ec >= d.maxCost {
+			fbest, fbest1 := -1, -1
+			for k := fmax; k >= fmin; k -= 2 {
+				i1 := d.kf(k)
+				if i1 > lim1 {
+					i1 = lim1
+				}
+				i2 := i1 - k
+				if lim2 < i2 {
+					i1, i2 = lim2+k, lim2
+				}
+				if fbest < i1+i2 {
+					fbest, fbest1 = i1+i2, i1
+				}
+			}
+
+			bbest, bbest1 := lineMax, lineMax
+			for k := bmax; k >= bmin; k -= 2 {
+				i1 := d.kb(k)
+				if i1 < off1 {
+					i1 = off1
+				}
+				i2 := i1 - k
+				if i2 < off2 {
+					i1, i2 = off2+k, off2
+				}
+				if i1+i2 < bbest {
+					bbest, bbest1 = i1+i2, i1
+				}
+			}
+
+			if (lim1+lim2)-bbest < fbest-(off1+off2) {
+				return splitPoint{i1: fbest1, i2: fbest - fbest1, minLo: true}
+			}
+			return splitPoint{i1: bbest1, i2: bbest - bbest1, minHi: true}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// editScript は差分を先頭に ' ', '-', '+' をつけた行にする 変更の中では削除した行を先に並べる
+func editScript(r *Result) string {
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(r.A) || j < len(r.B) {
+		switch {
+		case i < len(r.A) && r.ChangedA[i]:
+			sb.WriteString("-" + r.A[i])
+			i++
+		case j < len(r.B) && r.ChangedB[j]:
+			sb.WriteString("+" + r.B[j])
+			j++
+		default:
+			sb.WriteString(" " + r.A[i])
+			i++
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// 期待する結果は git diff --no-index -U100 の出力
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "replace",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: " a\n-b\n+x\n c\n",
+		},
+		{
+			name: "insert at the beginning",
+			a:    "a\nb\nc\n",
+			b:    "x\na\nb\nc\n",
+			want: "+x\n a\n b\n c\n",
+		},
+		{
+			name: "delete at the end",
+			a:    "a\nb\nc\n",
+			b:    "a\nb\n",
+			want: " a\n b\n-c\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\nb\n",
+			want: "+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			a:    "a\nb\n",
+			b:    "",
+			want: "-a\n-b\n",
+		},
+		{
+			name: "repeated block",
+			a:    "a\nb\nc\na\nb\nc\n",
+			b:    "a\nb\nc\n",
+			want: " a\n b\n c\n-a\n-b\n-c\n",
+		},
+		{
+			name: "swap",
+			a:    "x\ny\nz\n",
+			b:    "y\nx\nz\n",
+			want: "-x\n y\n+x\n z\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := editScript(Lines(SplitLines([]byte(tt.a)), SplitLines([]byte(tt.b))))
+			if got != tt.want {
+				t.Errorf("Lines() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 変更の位置が一意に決まらず、gitと同じ位置にずらす必要がある場合の結果
+// 期待する結果は git diff --no-index -U100 の出力
+func TestLinesCompaction(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "blank line and line slide up",
+			a:    "\n\na\n",
+			b:    "\n\na\n\na\n",
+			want: " \n \n+a\n+\n a\n",
+		},
+		{
+			name: "closing brace before indented line",
+			a:    "\n}\n\tb\n",
+			b:    "\n}\n}\n\tb\n",
+			want: " \n+}\n }\n \tb\n",
+		},
+		{
+			name: "block ending with a closing brace",
+			a:    "a\n}\nc {\n",
+			b:    "a\n}\n\tb\n}\nc {\n",
+			want: " a\n+}\n+\tb\n }\n c {\n",
+		},
+		{
+			name: "insertion at the beginning",
+			a:    "a\n\tb\na\n",
+			b:    "a\na\n\tb\na\n",
+			want: "+a\n a\n \tb\n a\n",
+		},
+		{
+			name: "function inserted between functions",
+			a:    "func a() {\n\tx\n}\n\nfunc c() {\n\tz\n}\n",
+			b:    "func a() {\n\tx\n}\n\nfunc b() {\n\ty\n}\n\nfunc c() {\n\tz\n}\n",
+			want: " func a() {\n \tx\n }\n \n+func b() {\n+\ty\n+}\n+\n func c() {\n \tz\n }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := editScript(Lines(SplitLines([]byte(tt.a)), SplitLines([]byte(tt.b))))
+			if got != tt.want {
+				t.Errorf("Lines() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// 衝突の目印の長さ
+const markerSize = 7
+
+// Merge3 はbaseからoursとtheirsへの変更を行単位で3方向マージする
+// 両方で同じ範囲が異なる内容に変更された場合は、gitのmergeスタイルと同じ目印で囲んで両方の行を残す
+// gitと同じく衝突の中で両方に共通する行は衝突から除き、間が3行以下の衝突はひとつにまとめる
+// 戻り値の数は衝突の数
+func Merge3(base, ours, theirs []byte, ourLabel, theirLabel string) ([]byte, int) {
+	b, o, t := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	oc := Lines(b, o).changes()
+	tc := Lines(b, t).changes()
+
+	var segs []segment
+	pos := 0
+	for len(oc) > 0 || len(tc) > 0 {
+		// 先に始まる変更から、重なるか隣接する変更をひとつの範囲にまとめる
+		var ocs, tcs []change
+		if len(tc) == 0 || len(oc) > 0 && oc[0].a <= tc[0].a {
+			ocs, oc = append(ocs, oc[0]), oc[1:]
+		} else {
+			tcs, tc = append(tcs, tc[0]), tc[1:]
+		}
+		first := append(ocs, tcs...)[0]
+		start, end := first.a, first.a+first.delLen
+	group:
+		for {
+			var c change
+			switch {
+			case len(oc) > 0 && oc[0].a <= end:
+				c = oc[0]
+				ocs, oc = append(ocs, c), oc[1:]
+			case len(tc) > 0 && tc[0].a <= end:
+				c = tc[0]
+				tcs, tc = append(tcs, c), tc[1:]
+			default:
+				break group
+			}
+			if c.a+c.delLen > end {
+				end = c.a + c.delLen
+			}
+		}
+
+		segs = append(segs, segment{lines: b[pos:start]})
+		pos = end
+		ol := side(b, o, ocs, start, end)
+		tl := side(b, t, tcs, start, end)
+		switch {
+		case len(tcs) == 0:
+			segs = append(segs, segment{lines: ol})
+		case len(ocs) == 0:
+			segs = append(segs, segment{lines: tl})
+		default:
+			segs = append(segs, refine(ol, tl)...)
+		}
+	}
+	segs = append(segs, segment{lines: b[pos:]})
+
+	var out bytes.Buffer
+	conflicts := 0
+	for _, seg := range simplify(segs) {
+		if !seg.conflict {
+			writeLines(&out, seg.lines)
+			continue
+		}
+		conflicts++
+		writeMarker(&out, '<', ourLabel)
+		writeSide(&out, seg.lines)
+		writeMarker(&out, '=', "")
+		writeSide(&out, seg.theirs)
+		writeMarker(&out, '>', theirLabel)
+	}
+	return out.Bytes(), conflicts
+}
+
+// segment はマージ結果の行のまとまり 衝突の場合はlinesがours側の行になる
+type segment struct {
+	lines    []string
+	conflict bool
+	theirs   []string
+}
+
+// refine は両方で変更された範囲の差分をとり、異なる部分だけを衝突とする
+func refine(ours, theirs []string) []segment {
+	var segs []segment
+	i := 0
+	for _, c := range Lines(ours, theirs).changes() {
+		segs = append(segs, segment{lines: ours[i:c.a]})
+		segs = append(segs, segment{lines: ours[c.a : c.a+c.delLen], conflict: true, theirs: theirs[c.b : c.b+c.insLen]})
+		i = c.a + c.delLen
+	}
+	return append(segs, segment{lines: ours[i:]})
+}
+
+// simplify は間の行が3行以下の衝突を、間の行を両側に含めてひとつの衝突にまとめる
+func simplify(segs []segment) []segment {
+	var out []segment
+	for _, seg := range segs {
+		if len(seg.lines) == 0 && !seg.conflict {
+			continue
+		}
+		n := len(out)
+		switch {
+		case seg.conflict && n >= 2 && out[n-2].conflict && !out[n-1].conflict && len(out[n-1].lines) <= 3:
+			gap := out[n-1].lines
+			c := &out[n-2]
+			c.lines = concat(c.lines, gap, seg.lines)
+			c.theirs = concat(c.theirs, gap, seg.theirs)
+			out = out[:n-1]
+		case seg.conflict && n >= 1 && out[n-1].conflict:
+			c := &out[n-1]
+			c.lines = concat(c.lines, seg.lines)
+			c.theirs = concat(c.theirs, seg.theirs)
+		case !seg.conflict && n >= 1 && !out[n-1].conflict:
+			out[n-1].lines = concat(out[n-1].lines, seg.lines)
+		default:
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+func concat(parts ...[]string) []string {
+	var lines []string
+	for _, p := range parts {
+		lines = append(lines, p...)
+	}
+	return lines
+}
+
+// side はbaseの[start, end)の範囲に対応する変更後の行を返す
+// 範囲内の変更されていない行は変更の前後で同じ位置だけずれている
+func side(base, lines []string, cs []change, start, end int) []string {
+	if len(cs) == 0 {
+		return base[start:end]
+	}
+	first, last := cs[0], cs[len(cs)-1]
+	from := first.b - (first.a - start)
+	to := last.b + last.insLen + (end - (last.a + last.delLen))
+	return lines[from:to]
+}
+
+func writeLines(out *bytes.Buffer, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+func writeMarker(out *bytes.Buffer, c byte, label string) {
+	out.WriteString(strings.Repeat(string(c), markerSize))
+	if label != "" {
+		out.WriteString(" " + label)
+	}
+	out.WriteByte('\n')
+}
+
+// writeSide は衝突の片側を書き込む 末尾に改行がなければ目印の前に補う
+func writeSide(out *bytes.Buffer, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteByte('\n')
+	}
+}
@@ -0,0 +1,87 @@
+package diff
+
+import "testing"
+
+// 期待する結果は git merge-file -p -L ours -L base -L theirs の出力
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+	}{
+		{
+			name:   "separate changes",
+			base:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			ours:   "1\n2x\n3\n4\n5\n6\n7\n8\n9\n",
+			theirs: "1\n2\n3\n4\n5\n6\n7\n8x\n9\n",
+			want:   "1\n2x\n3\n4\n5\n6\n7\n8x\n9\n",
+		},
+		{
+			name:   "same change",
+			base:   "1\n2\n3\n",
+			ours:   "1\nx\n3\n",
+			theirs: "1\nx\n3\n",
+			want:   "1\nx\n3\n",
+		},
+		{
+			name:      "conflict",
+			base:      "1\nA\nB\nC\n2\n",
+			ours:      "1\nA\nX\nC\n2\n",
+			theirs:    "1\nA\nY\nC\n2\n",
+			want:      "1\nA\n<<<<<<< ours\nX\n=======\nY\n>>>>>>> theirs\nC\n2\n",
+			conflicts: 1,
+		},
+		{
+			name:      "adjacent changes conflict",
+			base:      "1\n2\n3\n4\n5\n",
+			ours:      "1\n2o\n3\n4\n5\n",
+			theirs:    "1\n2\n3t\n4\n5\n",
+			want:      "1\n<<<<<<< ours\n2o\n3\n=======\n2\n3t\n>>>>>>> theirs\n4\n5\n",
+			conflicts: 1,
+		},
+		{
+			name:      "common lines are removed from conflict",
+			base:      "",
+			ours:      "a\nb\nc\n",
+			theirs:    "a\nB\nc\n",
+			want:      "a\n<<<<<<< ours\nb\n=======\nB\n>>>>>>> theirs\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "close conflicts are joined",
+			base:      "1\n2\n3\n4\n5\n6\n7\n",
+			ours:      "1\nx\n3\n4\n5\ny\n7\n",
+			theirs:    "1\nz\n3\n4\n5\nw\n7\n",
+			want:      "1\n<<<<<<< ours\nx\n3\n4\n5\ny\n=======\nz\n3\n4\n5\nw\n>>>>>>> theirs\n7\n",
+			conflicts: 1,
+		},
+		{
+			name:      "distant conflicts are separated",
+			base:      "1\n2\n3\n4\n5\n6\n7\n8\n",
+			ours:      "1\nx\n3\n4\n5\n6\ny\n8\n",
+			theirs:    "1\nz\n3\n4\n5\n6\nw\n8\n",
+			want:      "1\n<<<<<<< ours\nx\n=======\nz\n>>>>>>> theirs\n3\n4\n5\n6\n<<<<<<< ours\ny\n=======\nw\n>>>>>>> theirs\n8\n",
+			conflicts: 2,
+		},
+		{
+			name:      "missing newline at end of file",
+			base:      "a\nb\n",
+			ours:      "a\nb\nc",
+			theirs:    "a\nb\nd",
+			want:      "a\nb\n<<<<<<< ours\nc\n=======\nd\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge3([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs), "ours", "theirs")
+			if string(got) != tt.want {
+				t.Errorf("Merge3() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if conflicts != tt.conflicts {
+				t.Errorf("conflicts = %d, want %d", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package diff
+
+// MaxScore は内容が完全に一致する場合の類似度
+const MaxScore = 60000
+
+// DefaultRenameScore は名前の変更とみなす類似度の下限(50%)
+const DefaultRenameScore = MaxScore / 2
+
+const spanHashBase = 107927
+
+// Similarity はsrcの内容がdstにどれだけ含まれているかをgitと同じ方法で0〜MaxScoreで返す
+// 内容を改行か64バイトごとの塊に分け、同じ塊が含まれるバイト数を数える
+// 大きさがminScoreを満たせないほど異なる場合は計算せずに0を返す
+func Similarity(src, dst []byte, minScore int) int {
+	maxSize, baseSize := len(src), len(dst)
+	if maxSize < baseSize {
+		maxSize, baseSize = baseSize, maxSize
+	}
+	if maxSize == 0 {
+		return 0
+	}
+	deltaSize := maxSize - baseSize
+	if int64(maxSize)*int64(MaxScore-minScore) < int64(deltaSize)*MaxScore {
+		return 0
+	}
+
+	srcSpans := spanHashes(src, !IsBinary(src))
+	dstSpans := spanHashes(dst, !IsBinary(dst))
+	copied := 0
+	for h, n := range srcSpans {
+		if m := dstSpans[h]; m < n {
+			copied += m
+		} else {
+			copied += n
+		}
+	}
+	return int(int64(copied) * MaxScore / int64(maxSize))
+}
+
+func spanHashes(data []byte, text bool) map[uint32]int {
+	spans := make(map[uint32]int)
+	var accum1, accum2 uint32
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := uint32(data[i])
+		// テキストではCRLFのCRを無視する
+		if text && c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		old := accum1
+		accum1 = (accum1 << 7) ^ (accum2 >> 25)
+		accum2 = (accum2 << 7) ^ (old >> 25)
+		accum1 += c
+		n++
+		if n < 64 && c != '\n' {
+			continue
+		}
+		spans[(accum1+accum2*0x61)%spanHashBase] += n
+		n = 0
+		accum1, accum2 = 0, 0
+	}
+	if n > 0 {
+		spans[(accum1+accum2*0x61)%spanHashBase] += n
+	}
+	return spans
+}
+
+// IsBinary はgitと同じく先頭8000バイトにNULを含む内容をバイナリとみなす
+func IsBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package diff
+
+import "testing"
+
+func TestSimilarity(t *testing.T) {
+	// 期待する類似度は git diff -M1% --name-status でsrcをdstに名前を変えた場合の "R" に続く値
+	tests := []struct {
+		name     string
+		src, dst string
+		minScore int
+		percent  int
+	}{
+		{
+			name:    "identical",
+			src:     "line 1\nline 2\n",
+			dst:     "line 1\nline 2\n",
+			percent: 100,
+		},
+		{
+			name:    "one line changed",
+			src:     "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\n",
+			dst:     "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline ten\n",
+			percent: 87,
+		},
+		{
+			name:    "half changed",
+			src:     "line 1\nline 2\nline 3\nline 4\n",
+			dst:     "line 1\nline 2\nchanged\nchanged\n",
+			percent: 46,
+		},
+		{
+			name:    "CR of CRLF is ignored",
+			src:     "line 1\r\nline 2\r\n",
+			dst:     "line 1\nline 2\n",
+			percent: 87,
+		},
+		{
+			name:    "appended",
+			src:     "aaaa\nbbbb\n",
+			dst:     "aaaa\nbbbb\ncccc\ndddd\neeee\nffff\n",
+			percent: 33,
+		},
+		{
+			// 大きさの差だけで下限を満たせないため計算しない
+			name:     "size difference below the minimum score",
+			src:      "aaaa\nbbbb\n",
+			dst:      "aaaa\nbbbb\ncccc\ndddd\neeee\nffff\n",
+			minScore: DefaultRenameScore,
+			percent:  0,
+		},
+		{
+			name:    "empty",
+			src:     "",
+			dst:     "",
+			percent: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := Similarity([]byte(tt.src), []byte(tt.dst), tt.minScore)
+			if got := score * 100 / MaxScore; got != tt.percent {
+				t.Errorf("Similarity() = %d (%d%%), want %d%%", score, got, tt.percent)
+			}
+		})
+	}
+}
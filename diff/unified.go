@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultContext は差分の前後に表示する変更されていない行の数
+const DefaultContext = 3
+
+// change は連続して削除、追加された行の範囲
+type change struct {
+	a, delLen int
+	b, insLen int
+}
+
+func (r *Result) changes() []change {
+	var cs []change
+	i, j := 0, 0
+	for i < len(r.A) || j < len(r.B) {
+		if i < len(r.A) && j < len(r.B) && !r.ChangedA[i] && !r.ChangedB[j] {
+			i++
+			j++
+			continue
+		}
+		c := change{a: i, b: j}
+		for i < len(r.A) && r.ChangedA[i] {
+			i++
+		}
+		for j < len(r.B) && r.ChangedB[j] {
+			j++
+		}
+		c.delLen, c.insLen = i-c.a, j-c.b
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// Stat は追加された行と削除された行の数を返す
+func (r *Result) Stat() (added, deleted int) {
+	for _, c := range r.ChangedA {
+		if c {
+			deleted++
+		}
+	}
+	for _, c := range r.ChangedB {
+		if c {
+			added++
+		}
+	}
+	return added, deleted
+}
+
+// Hunk は前後の行を含めた差分の出力のまとまり 開始位置は0始まり
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	// Lines は先頭に ' ', '-', '+' のいずれかをつけた行
+	Lines []string
+}
+
+// Hunks は前後にcontext行を含めた差分のまとまりを返す
+// 間の変更されていない行がcontextの2倍以下の変更は1つにまとめる
+func (r *Result) Hunks(context int) []*Hunk {
+	cs := r.changes()
+	var hunks []*Hunk
+	for len(cs) > 0 {
+		n := 1
+		for n < len(cs) && cs[n].a-(cs[n-1].a+cs[n-1].delLen) <= 2*context {
+			n++
+		}
+		first, last := cs[0], cs[n-1]
+
+		pre := minInt(context, minInt(first.a, first.b))
+		post := minInt(context, minInt(len(r.A)-(last.a+last.delLen), len(r.B)-(last.b+last.insLen)))
+		h := &Hunk{OldStart: first.a - pre, NewStart: first.b - pre}
+		h.OldLines = last.a + last.delLen + post - h.OldStart
+		h.NewLines = last.b + last.insLen + post - h.NewStart
+
+		i, j := h.OldStart, h.NewStart
+		for _, c := range cs[:n] {
+			for ; i < c.a; i, j = i+1, j+1 {
+				h.Lines = append(h.Lines, " "+r.A[i])
+			}
+			for ; i < c.a+c.delLen; i++ {
+				h.Lines = append(h.Lines, "-"+r.A[i])
+			}
+			for ; j < c.b+c.insLen; j++ {
+				h.Lines = append(h.Lines, "+"+r.B[j])
+			}
+		}
+		for ; i < h.OldStart+h.OldLines; i++ {
+			h.Lines = append(h.Lines, " "+r.A[i])
+		}
+
+		hunks = append(hunks, h)
+		cs = cs[n:]
+	}
+	return hunks
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// WriteUnified はunified形式で差分のまとまりを書き込む
+// gitと同じくまとまりの前にある英字などで始まる行を関数名として見出しに添える
+func (r *Result) WriteUnified(w io.Writer, context int) error {
+	bw := bufio.NewWriter(w)
+	funcname := ""
+	prev := -1
+	for _, h := range r.Hunks(context) {
+		for i := h.OldStart - 1; i > prev && i >= 0; i-- {
+			if name, ok := funcName(r.A[i]); ok {
+				funcname = name
+				break
+			}
+		}
+		prev = h.OldStart - 1
+
+		fmt.Fprintf(bw, "@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+		if funcname != "" {
+			bw.WriteString(" " + funcname)
+		}
+		bw.WriteByte('\n')
+		for _, l := range h.Lines {
+			bw.WriteString(l)
+			if !strings.HasSuffix(l, "\n") {
+				bw.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
+
+// funcName は英字、'_'、'$'で始まる行を末尾の空白を除いて80バイトまで返す
+func funcName(line string) (string, bool) {
+	if line == "" {
+		return "", false
+	}
+	c := line[0]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$') {
+		return "", false
+	}
+	if len(line) > 80 {
+		line = line[:80]
+	}
+	return strings.TrimRight(line, " \t\n\r\v\f"), true
+}
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 期待する結果は git diff --no-index -U<context> の出力からヘッダーを除いたもの
+func TestWriteUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			b:       "1\n2x\n3\n4\n5\n6\n7\n8\n9\n10x\n11\n",
+			context: DefaultContext,
+			want:    "@@ -1,5 +1,5 @@\n 1\n-2\n+2x\n 3\n 4\n 5\n@@ -7,5 +7,5 @@\n 7\n 8\n 9\n-10\n+10x\n 11\n",
+		},
+		{
+			name:    "merged hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:       "1\n2x\n3\n4\n5\n6\n7\n8\n9x\n10\n",
+			context: DefaultContext,
+			want:    "@@ -1,10 +1,10 @@\n 1\n-2\n+2x\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+9x\n 10\n",
+		},
+		{
+			name:    "one line of context",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "1\n2\n3x\n4\n5\n",
+			context: 1,
+			want:    "@@ -2,3 +2,3 @@\n 2\n-3\n+3x\n 4\n",
+		},
+		{
+			name:    "no context",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "1\n2\n3x\n4\n5\n",
+			context: 0,
+			want:    "@@ -3 +3 @@\n-3\n+3x\n",
+		},
+		{
+			name:    "function name",
+			a:       "func main() {\n1\n2\n3\n4\n5\n6\n}\n",
+			b:       "func main() {\n1\n2\n3\n4\n5x\n6\n}\n",
+			context: DefaultContext,
+			want:    "@@ -3,6 +3,6 @@ func main() {\n 2\n 3\n 4\n-5\n+5x\n 6\n }\n",
+		},
+		{
+			name:    "no newline at end of both",
+			a:       "a\nb",
+			b:       "a\nc",
+			context: DefaultContext,
+			want:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			name:    "newline added at end",
+			a:       "a\nb",
+			b:       "a\nb\n",
+			context: DefaultContext,
+			want:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:    "newline removed at end",
+			a:       "a\nb\n",
+			b:       "a\nb",
+			context: DefaultContext,
+			want:    "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name:    "from empty",
+			a:       "",
+			b:       "a\nb\n",
+			context: DefaultContext,
+			want:    "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := Lines(SplitLines([]byte(tt.a)), SplitLines([]byte(tt.b)))
+			if err := r.WriteUnified(&buf, tt.context); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteUnified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
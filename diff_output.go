@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/showa-93/wyag-go/attr"
+	"github.com/showa-93/wyag-go/diff"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// diffPrinter はツリーの間の変更をgitと同じ形式で出力する
+type diffPrinter struct {
+	repo  *repository.Repository
+	attrs *attr.Matcher
+}
+
+func newDiffPrinter(repo *repository.Repository) (*diffPrinter, error) {
+	m, err := repo.Attributes()
+	if err != nil {
+		return nil, err
+	}
+	return &diffPrinter{repo: repo, attrs: m}, nil
+}
+
+// content はエントリの中身を返す サブモジュールはコミットのshaを中身とする
+func (p *diffPrinter) content(e *repository.DiffEntry) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	if e.Mode == 0160000 {
+		return []byte(fmt.Sprintf("Subproject commit %s\n", e.Sha)), nil
+	}
+	_, data, err := p.repo.Objects().ReadObject(e.Sha)
+	return data, err
+}
+
+// binary はdiff属性かNULを含むかで差分を表示しないファイルか判定する
+func (p *diffPrinter) binary(path string, contents ...[]byte) (bool, error) {
+	attrs, err := p.attrs.Get(path, "diff")
+	if err != nil {
+		return false, err
+	}
+	switch attrs[0].State {
+	case attr.Unset:
+		return true, nil
+	case attr.Set:
+		return false, nil
+	}
+	for _, c := range contents {
+		if diff.IsBinary(c) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *diffPrinter) abbrev(e *repository.DiffEntry) (string, error) {
+	if e == nil {
+		return strings.Repeat("0", repository.DefaultAbbrev), nil
+	}
+	return p.repo.Abbrev(e.Sha, repository.DefaultAbbrev)
+}
+
+// writePatch は変更をunified形式の差分として書き込む
+func (p *diffPrinter) writePatch(w io.Writer, changes []*repository.Change) error {
+	for _, c := range changes {
+		if err := p.writeFilePatch(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *diffPrinter) writeFilePatch(w io.Writer, c *repository.Change) error {
+	if c.Status == repository.TypeChanged {
+		// 種類が変わった場合は削除と追加に分けて表示する
+		if err := p.writeFilePatch(w, &repository.Change{Status: repository.Deleted, From: c.From}); err != nil {
+			return err
+		}
+		return p.writeFilePatch(w, &repository.Change{Status: repository.Added, To: c.To})
+	}
+
+	from, to := c.Path(), c.Path()
+	if c.From != nil {
+		from = c.From.Path
+	}
+	fmt.Fprintf(w, "diff --git %s %s\n", quotePath("a/"+from), quotePath("b/"+to))
+
+	switch c.Status {
+	case repository.Added:
+		fmt.Fprintf(w, "new file mode %06o\n", c.To.Mode)
+	case repository.Deleted:
+		fmt.Fprintf(w, "deleted file mode %06o\n", c.From.Mode)
+	case repository.Renamed:
+		fmt.Fprintf(w, "similarity index %d%%\n", c.Score*100/diff.MaxScore)
+		fmt.Fprintf(w, "rename from %s\n", quotePath(from))
+		fmt.Fprintf(w, "rename to %s\n", quotePath(to))
+	}
+	sameMode := c.From == nil || c.To == nil || c.From.Mode == c.To.Mode
+	if !sameMode {
+		fmt.Fprintf(w, "old mode %06o\n", c.From.Mode)
+		fmt.Fprintf(w, "new mode %06o\n", c.To.Mode)
+	}
+	if c.From != nil && c.To != nil && c.From.Sha == c.To.Sha {
+		return nil
+	}
+
+	a, err := p.abbrev(c.From)
+	if err != nil {
+		return err
+	}
+	b, err := p.abbrev(c.To)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "index %s..%s", a, b)
+	if c.Status == repository.Modified || c.Status == repository.Renamed {
+		if sameMode {
+			fmt.Fprintf(w, " %06o", c.To.Mode)
+		}
+	}
+	fmt.Fprintln(w)
+
+	oldData, err := p.content(c.From)
+	if err != nil {
+		return err
+	}
+	newData, err := p.content(c.To)
+	if err != nil {
+		return err
+	}
+	oldName, newName := quotePath("a/"+from), quotePath("b/"+to)
+	if c.From == nil {
+		oldName = "/dev/null"
+	}
+	if c.To == nil {
+		newName = "/dev/null"
+	}
+
+	binary, err := p.binary(c.Path(), oldData, newData)
+	if err != nil {
+		return err
+	}
+	if binary {
+		fmt.Fprintf(w, "Binary files %s and %s differ\n", oldName, newName)
+		return nil
+	}
+	res := diff.Lines(diff.SplitLines(oldData), diff.SplitLines(newData))
+	if len(res.Hunks(diff.DefaultContext)) == 0 {
+		return nil
+	}
+	// gitと同じく空白を含むパスは区切りがわかるように末尾にタブをつける
+	fmt.Fprintf(w, "--- %s%s\n", oldName, nameTab(oldName, from))
+	fmt.Fprintf(w, "+++ %s%s\n", newName, nameTab(newName, to))
+	return res.WriteUnified(w, diff.DefaultContext)
+}
+
+func nameTab(label, name string) string {
+	if label == "/dev/null" || !strings.Contains(name, " ") {
+		return ""
+	}
+	return "\t"
+}
+
+// writeNameOnly は変更されたファイルのパスを書き込む
+func (p *diffPrinter) writeNameOnly(w io.Writer, changes []*repository.Change) {
+	for _, c := range changes {
+		fmt.Fprintln(w, quotePath(c.Path()))
+	}
+}
+
+// fileStat はdiffstatの1行分
+type fileStat struct {
+	name           string
+	binary         bool
+	added, deleted int
+}
+
+// statWidth はdiffstatの1行の幅
+const statWidth = 80
+
+// writeStat は変更されたファイルごとの追加、削除された行の数をグラフとともに書き込む
+func (p *diffPrinter) writeStat(w io.Writer, changes []*repository.Change) error {
+	stats := make([]*fileStat, 0, len(changes))
+	for _, c := range changes {
+		st := &fileStat{name: quotePath(c.Path())}
+		if c.Status == repository.Renamed {
+			st.name = renameName(c.From.Path, c.To.Path)
+		}
+		oldData, err := p.content(c.From)
+		if err != nil {
+			return err
+		}
+		newData, err := p.content(c.To)
+		if err != nil {
+			return err
+		}
+		if st.binary, err = p.binary(c.Path(), oldData, newData); err != nil {
+			return err
+		}
+		switch {
+		case c.From != nil && c.To != nil && c.From.Sha == c.To.Sha:
+		case st.binary:
+			st.added, st.deleted = len(newData), len(oldData)
+		default:
+			res := diff.Lines(diff.SplitLines(oldData), diff.SplitLines(newData))
+			st.added, st.deleted = res.Stat()
+		}
+		stats = append(stats, st)
+	}
+	writeStats(w, stats)
+	return nil
+}
+
+func writeStats(w io.Writer, stats []*fileStat) {
+	maxLen, maxChange, numberWidth, binWidth := 0, 0, 0, 0
+	for _, st := range stats {
+		if l := utf8.RuneCountInString(st.name); l > maxLen {
+			maxLen = l
+		}
+		if st.binary {
+			// "Bin XXX -> YYY bytes"
+			if w := 14 + decimalWidth(st.added) + decimalWidth(st.deleted); w > binWidth {
+				binWidth = w
+			}
+			numberWidth = 3
+			continue
+		}
+		if c := st.added + st.deleted; c > maxChange {
+			maxChange = c
+		}
+	}
+	if dw := decimalWidth(maxChange); dw > numberWidth {
+		numberWidth = dw
+	}
+
+	width := statWidth
+	if width < 16+6+numberWidth {
+		width = 16 + 6 + numberWidth
+	}
+	graphWidth := maxChange
+	if maxChange+4 <= binWidth {
+		graphWidth = binWidth - 4
+	}
+	nameWidth := maxLen
+	if nameWidth+numberWidth+6+graphWidth > width {
+		if graphWidth > width*3/8-numberWidth-6 {
+			graphWidth = width*3/8 - numberWidth - 6
+			if graphWidth < 6 {
+				graphWidth = 6
+			}
+		}
+		if nameWidth > width-numberWidth-6-graphWidth {
+			nameWidth = width - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = width - numberWidth - 6 - nameWidth
+		}
+	}
+
+	files, insertions, deletions := 0, 0, 0
+	for _, st := range stats {
+		files++
+		name, prefix := st.name, ""
+		l := nameWidth
+		if nameWidth < utf8.RuneCountInString(name) {
+			// 長い名前は先頭を省略する
+			prefix = "..."
+			l -= 3
+			runes := []rune(name)
+			name = string(runes[len(runes)-l:])
+			if i := strings.IndexByte(name, '/'); i >= 0 {
+				name = name[i:]
+			}
+		}
+		padding := l - utf8.RuneCountInString(name)
+		if padding < 0 {
+			padding = 0
+		}
+
+		if st.binary {
+			fmt.Fprintf(w, " %s%s%s | %*s", prefix, name, strings.Repeat(" ", padding), numberWidth, "Bin")
+			if st.added == 0 && st.deleted == 0 {
+				fmt.Fprintln(w)
+				continue
+			}
+			fmt.Fprintf(w, " %d -> %d bytes\n", st.deleted, st.added)
+			continue
+		}
+
+		insertions += st.added
+		deletions += st.deleted
+		add, del := st.added, st.deleted
+		if graphWidth <= maxChange {
+			total := scaleLinear(add+del, graphWidth, maxChange)
+			if total < 2 && add > 0 && del > 0 {
+				total = 2
+			}
+			if add < del {
+				add = scaleLinear(add, graphWidth, maxChange)
+				del = total - add
+			} else {
+				del = scaleLinear(del, graphWidth, maxChange)
+				add = total - del
+			}
+		}
+		sep := ""
+		if st.added+st.deleted > 0 {
+			sep = " "
+		}
+		fmt.Fprintf(w, " %s%s%s | %*d%s%s%s\n", prefix, name, strings.Repeat(" ", padding), numberWidth, st.added+st.deleted, sep,
+			strings.Repeat("+", add), strings.Repeat("-", del))
+	}
+
+	if files == 0 {
+		fmt.Fprintln(w, " 0 files changed")
+		return
+	}
+	fmt.Fprintf(w, " %d file%s changed", files, plural(files))
+	if insertions > 0 || deletions == 0 {
+		fmt.Fprintf(w, ", %d insertion%s(+)", insertions, plural(insertions))
+	}
+	if deletions > 0 || insertions == 0 {
+		fmt.Fprintf(w, ", %d deletion%s(-)", deletions, plural(deletions))
+	}
+	fmt.Fprintln(w)
+}
+
+func scaleLinear(it, width, maxChange int) int {
+	if it == 0 {
+		return 0
+	}
+	return 1 + it*(width-1)/maxChange
+}
+
+func decimalWidth(n int) int {
+	w := 1
+	for ; n >= 10; n /= 10 {
+		w++
+	}
+	return w
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// renameName は名前の変更を共通の前後のディレクトリをまとめて "a/{b => c}/d" の形式で表す
+func renameName(a, b string) string {
+	if quotePath(a) != a || quotePath(b) != b {
+		return quotePath(a) + " => " + quotePath(b)
+	}
+
+	pfx := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			pfx = i + 1
+		}
+	}
+
+	// 末尾の終端文字から比較を始める 共通の前方部分がある場合はその末尾の '/' まで比較する
+	at := func(s string, i int) byte {
+		if i == len(s) {
+			return 0
+		}
+		return s[i]
+	}
+	adjust := 0
+	if pfx > 0 {
+		adjust = 1
+	}
+	sfx := 0
+	for i, j := len(a), len(b); pfx-adjust <= i && pfx-adjust <= j && at(a, i) == at(b, j); i, j = i-1, j-1 {
+		if at(a, i) == '/' {
+			sfx = len(a) - i
+		}
+	}
+
+	aMid := len(a) - pfx - sfx
+	bMid := len(b) - pfx - sfx
+	if aMid < 0 {
+		aMid = 0
+	}
+	if bMid < 0 {
+		bMid = 0
+	}
+	var sb strings.Builder
+	if pfx+sfx > 0 {
+		sb.WriteString(a[:pfx])
+		sb.WriteByte('{')
+	}
+	sb.WriteString(a[pfx : pfx+aMid])
+	sb.WriteString(" => ")
+	sb.WriteString(b[pfx : pfx+bMid])
+	if pfx+sfx > 0 {
+		sb.WriteByte('}')
+		sb.WriteString(a[len(a)-sfx:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type FsckCommand struct {
+	*flag.FlagSet
+	noDangling bool
+}
+
+func NewFsckCommand(args []string) *FsckCommand {
+	c := &FsckCommand{}
+	c.FlagSet = flag.NewFlagSet("fsck", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.noDangling, "no-dangling", false, "Do not print dangling objects")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go fsck [--no-dangling]\n")
+		fmt.Fprint(o, "\tVerifies the connectivity and validity of the objects in the database\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) != 0 {
+		fmt.Printf("expected 0 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+
+	return c
+}
+
+func (c *FsckCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	issues, err := repo.Fsck()
+	if err != nil {
+		return err
+	}
+	broken := false
+	for _, issue := range issues {
+		switch issue.Kind {
+		case repository.FsckDangling:
+			if c.noDangling {
+				continue
+			}
+			fmt.Fprintln(os.Stdout, issue)
+		case repository.FsckCorrupt:
+			broken = true
+			fmt.Fprintln(os.Stderr, issue)
+		default:
+			broken = true
+			fmt.Fprintln(os.Stdout, issue)
+		}
+	}
+	if broken {
+		return ExitCode(1)
+	}
+	return nil
+}
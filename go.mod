@@ -1,7 +1,3 @@
 module github.com/showa-93/wyag-go
 
 go 1.18
-
-require github.com/go-ini/ini v1.66.4
-
-require github.com/stretchr/testify v1.7.1 // indirect
@@ -0,0 +1,99 @@
+package gpg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// armorBlock は "-----BEGIN TYPE-----" から "-----END TYPE-----" までを復号したもの
+type armorBlock struct {
+	Type string
+	Data []byte
+}
+
+// decodeArmor はdataに含まれるASCII armorの塊をすべて復号する
+// OpenPGPの形式の場合は塊の中のヘッダーを読み飛ばし、チェックサムを検証する
+func decodeArmor(data []byte) ([]*armorBlock, error) {
+	var blocks []*armorBlock
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "-----BEGIN ") || !strings.HasSuffix(line, "-----") {
+			continue
+		}
+		t := strings.TrimSuffix(strings.TrimPrefix(line, "-----BEGIN "), "-----")
+		end := "-----END " + t + "-----"
+		pgp := strings.HasPrefix(t, "PGP ")
+
+		// OpenPGPのヘッダーは "Key: value" の形式で、空行で終わる
+		i++
+		if pgp {
+			for j := i; j < len(lines); j++ {
+				l := strings.TrimSpace(lines[j])
+				if l == "" {
+					i = j + 1
+					break
+				}
+				if !strings.Contains(l, ": ") {
+					break
+				}
+			}
+		}
+
+		var body strings.Builder
+		checksum := ""
+		closed := false
+		for ; i < len(lines); i++ {
+			l := strings.TrimSpace(lines[i])
+			if l == end {
+				closed = true
+				break
+			}
+			if pgp && strings.HasPrefix(l, "=") && len(l) == 5 {
+				checksum = l[1:]
+				continue
+			}
+			body.WriteString(l)
+		}
+		if !closed {
+			return nil, fmt.Errorf("%w: missing %s", ErrMalformed, end)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(body.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if checksum != "" {
+			sum, err := base64.StdEncoding.DecodeString(checksum)
+			if err != nil || len(sum) != 3 {
+				return nil, fmt.Errorf("%w: invalid armor checksum", ErrMalformed)
+			}
+			crc := crc24(decoded)
+			if !bytes.Equal(sum, []byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) {
+				return nil, fmt.Errorf("%w: armor checksum mismatch", ErrMalformed)
+			}
+		}
+		blocks = append(blocks, &armorBlock{Type: t, Data: decoded})
+	}
+	return blocks, nil
+}
+
+// crc24 はRFC 4880で定められたASCII armorのチェックサムを計算する
+func crc24(data []byte) uint32 {
+	const (
+		crc24Init = 0xb704ce
+		crc24Poly = 0x1864cfb
+	)
+	crc := uint32(crc24Init)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & 0xffffff
+}
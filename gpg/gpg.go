@@ -0,0 +1,125 @@
+// Package gpg はコミットやタグに付けるOpenPGPとSSHの署名を作成し、検証する
+package gpg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed   = errors.New("malformed signature")
+	ErrUnsupported = errors.New("unsupported signature")
+)
+
+// Format は署名の形式
+type Format string
+
+const (
+	OpenPGP Format = "openpgp"
+	SSH     Format = "ssh"
+)
+
+// FormatOf は署名の先頭の行から署名の形式を判定する
+func FormatOf(signature string) (Format, bool) {
+	switch {
+	case strings.HasPrefix(signature, "-----BEGIN PGP SIGNATURE-----"),
+		strings.HasPrefix(signature, "-----BEGIN PGP MESSAGE-----"):
+		return OpenPGP, true
+	case strings.HasPrefix(signature, "-----BEGIN SSH SIGNATURE-----"):
+		return SSH, true
+	}
+	return "", false
+}
+
+// Status は署名の検証結果
+type Status int
+
+const (
+	// Good は署名が正しく、署名者が信頼できる鍵の持ち主であること
+	Good Status = iota
+	// Bad は署名が署名された内容と一致しないこと
+	Bad
+	// UnknownKey は署名した鍵がキーリングにないため検証できないこと
+	UnknownKey
+	// NoPrincipal は署名は正しいが、allowed_signersに鍵の持ち主がいないこと
+	NoPrincipal
+)
+
+// Result は署名を検証した結果
+type Result struct {
+	Format Format
+	Status Status
+	// Signer はOpenPGPではユーザーID、SSHではallowed_signersのプリンシパル
+	Signer string
+	// KeyType は "RSA" や "ED25519" などの鍵の種類
+	KeyType string
+	// Fingerprint はOpenPGPでは鍵の指紋、SSHでは "SHA256:" から始まる鍵の指紋
+	Fingerprint string
+	// Created はOpenPGPの署名の作成日時
+	Created time.Time
+	// Err は検証に失敗した理由
+	Err error
+}
+
+// Good は署名が正しく、署名者が信頼できるか返す
+func (r *Result) Good() bool {
+	return r.Status == Good
+}
+
+// String はgpgやssh-keygenと同じ形式で検証結果を返す 各行は改行で終わる
+func (r *Result) String() string {
+	var sb strings.Builder
+	if r.Format == SSH {
+		switch r.Status {
+		case Good:
+			fmt.Fprintf(&sb, "Good \"%s\" signature for %s with %s key %s\n", sshNamespace, r.Signer, r.KeyType, r.Fingerprint)
+		case NoPrincipal:
+			fmt.Fprintf(&sb, "Good \"%s\" signature with %s key %s\n", sshNamespace, r.KeyType, r.Fingerprint)
+			sb.WriteString("No principal matched.\n")
+		default:
+			sb.WriteString("Could not verify signature.\n")
+			if r.Err != nil {
+				fmt.Fprintf(&sb, "Signature verification failed: %v\n", r.Err)
+			}
+		}
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "gpg: Signature made %s\n", r.Created.Local().Format("Mon Jan _2 15:04:05 2006 MST"))
+	fmt.Fprintf(&sb, "gpg:                using %s key %s\n", r.KeyType, r.Fingerprint)
+	switch r.Status {
+	case Good:
+		fmt.Fprintf(&sb, "gpg: Good signature from \"%s\"\n", r.Signer)
+	case Bad:
+		fmt.Fprintf(&sb, "gpg: BAD signature from \"%s\"\n", r.Signer)
+	default:
+		sb.WriteString("gpg: Can't check signature: No public key\n")
+	}
+	return sb.String()
+}
+
+// Verifier は信頼する鍵の一覧を使って署名を検証する
+type Verifier struct {
+	// Keyring はOpenPGPの公開鍵
+	Keyring []*PublicKey
+	// AllowedSigners はSSHの署名者と公開鍵の組
+	AllowedSigners []*AllowedSigner
+}
+
+// Verify はpayloadに対するsignatureの署名を検証する
+// whenはSSHの鍵の有効期限の判定に使う署名の日時で、ゼロ値の場合は期限を判定しない
+// 署名を解釈できない場合はエラーを返し、検証に失敗した場合はResultのStatusで表す
+func (v *Verifier) Verify(payload []byte, signature string, when time.Time) (*Result, error) {
+	format, ok := FormatOf(signature)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signature format", ErrUnsupported)
+	}
+	switch format {
+	case SSH:
+		return v.verifySSH(payload, signature, when)
+	default:
+		return v.verifyOpenPGP(payload, signature)
+	}
+}
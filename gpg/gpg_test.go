@@ -0,0 +1,207 @@
+package gpg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// testdataの鍵と署名はgpgとssh-keygenで作成し、gitで署名したコミットとタグを書き出したもの
+func testVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "keyring.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := ReadKeyring(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filepath.Join("testdata", "allowed_signers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := ParseAllowedSigners(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Verifier{Keyring: keyring, AllowedSigners: signers}
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadKeyring(t *testing.T) {
+	v := testVerifier(t)
+	want := map[string]string{
+		"D633E9529C519E006E36EC7698BEC80183B7CFB1": "Alice Example <alice@example.com>",
+		"9FD7BA3D29930797D38C9BD46B1C334764613381": "Bob Example <bob@example.com>",
+		"9332AEAF9644CFB0201C999C613A6CACE9983B02": "Carol Example <carol@example.com>",
+	}
+	found := 0
+	for _, k := range v.Keyring {
+		if uid, ok := want[k.Fingerprint]; ok {
+			found++
+			if k.UserID != uid {
+				t.Errorf("UserID of %s = %q, want %q", k.Fingerprint, k.UserID, uid)
+			}
+			if k.key == nil {
+				t.Errorf("key of %s is not loaded", k.Fingerprint)
+			}
+		}
+	}
+	if found != len(want) {
+		t.Errorf("found %d primary keys, want %d", found, len(want))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		file        string
+		status      Status
+		signer      string
+		keyType     string
+		fingerprint string
+	}{
+		{"openpgp-ed25519.commit", Good, "Alice Example <alice@example.com>", "EDDSA", "D633E9529C519E006E36EC7698BEC80183B7CFB1"},
+		{"openpgp-rsa.commit", Good, "Bob Example <bob@example.com>", "RSA", "9FD7BA3D29930797D38C9BD46B1C334764613381"},
+		{"openpgp-ecdsa.commit", Good, "Carol Example <carol@example.com>", "ECDSA", "9332AEAF9644CFB0201C999C613A6CACE9983B02"},
+		{"openpgp-unknown.commit", UnknownKey, "", "EDDSA", "0B0CB6DE1AEAC728313F13D3E58897C174859770"},
+		{"openpgp-bad.commit", Bad, "Alice Example <alice@example.com>", "EDDSA", "D633E9529C519E006E36EC7698BEC80183B7CFB1"},
+		{"openpgp.tag", Good, "Alice Example <alice@example.com>", "EDDSA", "D633E9529C519E006E36EC7698BEC80183B7CFB1"},
+		{"ssh-ed25519.commit", Good, "alice@example.com", "ED25519", "SHA256:KrxHqURDJmiKw5ESnHuneNgnnavc0xkhQzLoZ0SzJds"},
+		{"ssh-rsa.commit", Good, "bob@example.com", "RSA", "SHA256:hX6eVZhnWwtme8Ci1MVsYnnHAdJs60eozA6iWP1mlhg"},
+		{"ssh-ecdsa.commit", Good, "carol@example.com", "ECDSA", "SHA256:wXaK3rAlxboMvCxg0LaOf/CdWJLHckF8KCuxP40RLK8"},
+		{"ssh-unknown.commit", NoPrincipal, "", "ED25519", "SHA256:lE4MQsIyeycdGQ61BabH8w9WeW2bVpCxBb5dStciOYA"},
+		{"ssh-bad.commit", Bad, "", "ED25519", "SHA256:KrxHqURDJmiKw5ESnHuneNgnnavc0xkhQzLoZ0SzJds"},
+		{"ssh.tag", Good, "alice@example.com", "ED25519", "SHA256:KrxHqURDJmiKw5ESnHuneNgnnavc0xkhQzLoZ0SzJds"},
+	}
+	v := testVerifier(t)
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			raw := readTestdata(t, tt.file)
+			var payload []byte
+			var sig string
+			if strings.HasSuffix(tt.file, ".tag") {
+				tag := object.NewTagObject(raw)
+				payload, sig = tag.Payload(), tag.GPGSig()
+			} else {
+				commit := object.NewCommitObject(raw)
+				payload, sig = commit.Payload(), commit.GPGSig()
+			}
+
+			res, err := v.Verify(payload, sig, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != tt.status {
+				t.Errorf("Status = %d, want %d (err=%v)", res.Status, tt.status, res.Err)
+			}
+			if res.Signer != tt.signer {
+				t.Errorf("Signer = %q, want %q", res.Signer, tt.signer)
+			}
+			if res.KeyType != tt.keyType {
+				t.Errorf("KeyType = %q, want %q", res.KeyType, tt.keyType)
+			}
+			if res.Fingerprint != tt.fingerprint {
+				t.Errorf("Fingerprint = %q, want %q", res.Fingerprint, tt.fingerprint)
+			}
+		})
+	}
+}
+
+func TestAllowedSignerOptions(t *testing.T) {
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOXnvh71DGykCKSsdkCz2QHZZWh02ubwwToP2tyroM2Y"
+	tests := []struct {
+		name string
+		line string
+		when time.Time
+		want Status
+	}{
+		{"namespace matches", `alice@example.com namespaces="file,gi?" ` + key, time.Time{}, Good},
+		{"namespace does not match", `alice@example.com namespaces="file" ` + key, time.Time{}, NoPrincipal},
+		{"negated namespace", `alice@example.com namespaces="*,!git" ` + key, time.Time{}, NoPrincipal},
+		{"within validity", `alice@example.com valid-after="20200101Z",valid-before="20300101Z" ` + key, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Good},
+		{"expired", `alice@example.com valid-before="20200101000000Z" ` + key, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), NoPrincipal},
+		{"not yet valid", `alice@example.com valid-after="203001010000Z" ` + key, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), NoPrincipal},
+	}
+	commit := object.NewCommitObject(readTestdata(t, "ssh-ed25519.commit"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signers, err := ParseAllowedSigners([]byte("# comment\n" + tt.line + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := &Verifier{AllowedSigners: signers}
+			res, err := v.Verify(commit.Payload(), commit.GPGSig(), tt.when)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != tt.want {
+				t.Errorf("Status = %d, want %d", res.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAllowedSignersError(t *testing.T) {
+	for _, line := range []string{
+		"alice@example.com ssh-ed25519 !!!",
+		`alice@example.com unknown="x" ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOXnvh71DGykCKSsdkCz2QHZZWh02ubwwToP2tyroM2Y`,
+		`alice@example.com valid-after="2020" ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOXnvh71DGykCKSsdkCz2QHZZWh02ubwwToP2tyroM2Y`,
+	} {
+		if _, err := ParseAllowedSigners([]byte(line)); err == nil {
+			t.Errorf("ParseAllowedSigners(%q) succeeded", line)
+		}
+	}
+}
+
+func TestSSHSign(t *testing.T) {
+	tests := []struct {
+		key    string
+		signer string
+	}{
+		{"ssh_ed25519", "alice@example.com"},
+		{"ssh_ed25519.pub", "alice@example.com"},
+		{"ssh_rsa", "bob@example.com"},
+		{"ssh_ecdsa", "carol@example.com"},
+	}
+	v := testVerifier(t)
+	payload := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor T <t@e> 1700000000 +0900\ncommitter T <t@e> 1700000000 +0900\n\nsigned\n")
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			s, err := LoadSSHSigner(filepath.Join("testdata", tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig, err := s.Sign(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := v.Verify(payload, sig, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Good() || res.Signer != tt.signer {
+				t.Errorf("Verify() = %d %q, want good signature from %q", res.Status, res.Signer, tt.signer)
+			}
+			res, err = v.Verify(append(payload, '\n'), sig, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != Bad {
+				t.Errorf("Verify() of modified payload = %d, want Bad", res.Status)
+			}
+		})
+	}
+}
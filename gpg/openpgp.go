@@ -0,0 +1,506 @@
+package gpg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// OpenPGPのパケットの種類
+const (
+	packetSignature    = 2
+	packetPublicKey    = 6
+	packetUserID       = 13
+	packetPublicSubkey = 14
+)
+
+// OpenPGPの公開鍵のアルゴリズム
+const (
+	algoRSA        = 1
+	algoRSASign    = 3
+	algoDSA        = 17
+	algoECDSA      = 19
+	algoEdDSA      = 22
+	algoRSAEncrypt = 2
+)
+
+// 署名の種類
+const (
+	sigBinary = 0x00
+	sigText   = 0x01
+)
+
+var (
+	oidEd25519 = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+	oidCurves  = map[string]elliptic.Curve{
+		string([]byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}): elliptic.P256(),
+		string([]byte{0x2b, 0x81, 0x04, 0x00, 0x22}):                   elliptic.P384(),
+		string([]byte{0x2b, 0x81, 0x04, 0x00, 0x23}):                   elliptic.P521(),
+	}
+	hashAlgorithms = map[byte]crypto.Hash{
+		2:  crypto.SHA1,
+		8:  crypto.SHA256,
+		9:  crypto.SHA384,
+		10: crypto.SHA512,
+		11: crypto.SHA224,
+	}
+)
+
+func algorithmName(algo byte) string {
+	switch algo {
+	case algoRSA, algoRSAEncrypt, algoRSASign:
+		return "RSA"
+	case algoDSA:
+		return "DSA"
+	case algoECDSA:
+		return "ECDSA"
+	case algoEdDSA:
+		return "EDDSA"
+	}
+	return fmt.Sprintf("?%d", algo)
+}
+
+// PublicKey はキーリングに含まれるOpenPGPの主鍵または副鍵
+type PublicKey struct {
+	// Fingerprint は大文字の16進数で表した鍵の指紋
+	Fingerprint string
+	Algorithm   byte
+	Created     time.Time
+	// UserID は主鍵の最初のユーザーID 副鍵も主鍵のユーザーIDを持つ
+	UserID string
+	// key は検証に使う公開鍵 扱えないアルゴリズムの場合はnil
+	key crypto.PublicKey
+}
+
+// KeyID は指紋の下位64bitを返す
+func (k *PublicKey) KeyID() string {
+	return k.Fingerprint[len(k.Fingerprint)-16:]
+}
+
+// ReadKeyring はgpg --exportで書き出したバイナリかASCII armorの公開鍵を読み込む
+// 鍵の自己署名は検証せず、キーリングに含まれる鍵をすべて信頼する
+func ReadKeyring(data []byte) ([]*PublicKey, error) {
+	var raws [][]byte
+	if bytes.Contains(data, []byte("-----BEGIN PGP")) {
+		blocks, err := decodeArmor(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blocks {
+			raws = append(raws, b.Data)
+		}
+	} else {
+		raws = append(raws, data)
+	}
+
+	var keys []*PublicKey
+	for _, raw := range raws {
+		packets, err := readPackets(raw)
+		if err != nil {
+			return nil, err
+		}
+		// 主鍵とその副鍵にユーザーIDを設定するため、主鍵ごとにまとめて扱う
+		var group []*PublicKey
+		userID := ""
+		flush := func() {
+			for _, k := range group {
+				k.UserID = userID
+			}
+			keys = append(keys, group...)
+			group, userID = nil, ""
+		}
+		for _, p := range packets {
+			switch p.tag {
+			case packetPublicKey, packetPublicSubkey:
+				if p.tag == packetPublicKey {
+					flush()
+				}
+				k, err := parsePublicKey(p.body)
+				if err != nil {
+					return nil, err
+				}
+				if k != nil {
+					group = append(group, k)
+				}
+			case packetUserID:
+				if userID == "" {
+					userID = string(p.body)
+				}
+			}
+		}
+		flush()
+	}
+	return keys, nil
+}
+
+// packet はOpenPGPのパケット
+type packet struct {
+	tag  byte
+	body []byte
+}
+
+// readPackets はdataを旧形式と新形式のパケットに分ける
+func readPackets(data []byte) ([]*packet, error) {
+	var packets []*packet
+	r := &reader{data: data}
+	for !r.empty() {
+		h := r.byte()
+		if h&0x80 == 0 {
+			return nil, fmt.Errorf("%w: invalid packet header", ErrMalformed)
+		}
+		p := &packet{}
+		if h&0x40 == 0 {
+			// 旧形式
+			p.tag = (h >> 2) & 0x0f
+			var n int
+			switch h & 0x03 {
+			case 0:
+				n = int(r.byte())
+			case 1:
+				n = int(r.uint16())
+			case 2:
+				n = int(r.uint32())
+			default:
+				n = len(r.data)
+			}
+			p.body = r.bytes(n)
+		} else {
+			// 新形式 部分的な長さの場合は続く塊をつなげる
+			p.tag = h & 0x3f
+			var body []byte
+			for {
+				n, partial := r.newLength()
+				body = append(body, r.bytes(n)...)
+				if !partial || r.err != nil {
+					break
+				}
+			}
+			p.body = body
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// parsePublicKey はバージョン4の公開鍵のパケットを解釈する それ以外のバージョンの鍵はnilを返す
+func parsePublicKey(body []byte) (*PublicKey, error) {
+	if len(body) == 0 || body[0] != 4 {
+		return nil, nil
+	}
+	r := &reader{data: body[1:]}
+	created := r.uint32()
+	k := &PublicKey{
+		Algorithm: r.byte(),
+		Created:   time.Unix(int64(created), 0),
+	}
+
+	// v4の鍵の指紋は 0x99、2バイトの長さ、パケットの本体のSHA-1
+	h := crypto.SHA1.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	k.Fingerprint = strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+
+	switch k.Algorithm {
+	case algoRSA, algoRSASign:
+		n, e := r.mpi(), r.mpi()
+		if r.err == nil {
+			k.key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		}
+	case algoEdDSA:
+		oid, point := r.bytes(int(r.byte())), r.mpi()
+		if r.err == nil && bytes.Equal(oid, oidEd25519) && len(point) == ed25519.PublicKeySize+1 && point[0] == 0x40 {
+			k.key = ed25519.PublicKey(point[1:])
+		}
+	case algoECDSA:
+		oid, point := r.bytes(int(r.byte())), r.mpi()
+		if curve, ok := oidCurves[string(oid)]; ok && r.err == nil {
+			if x, y := elliptic.Unmarshal(curve, point); x != nil {
+				k.key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return k, nil
+}
+
+// pgpSignature はバージョン4の署名のパケット
+type pgpSignature struct {
+	sigType  byte
+	pubAlgo  byte
+	hashAlgo byte
+	// hashed は署名のハッシュに含めるバージョンからハッシュされるサブパケットまで
+	hashed  []byte
+	created time.Time
+	// issuer は署名した鍵の指紋か鍵ID
+	issuer string
+	left16 []byte
+	values [][]byte
+}
+
+func parseSignature(body []byte) (*pgpSignature, error) {
+	if len(body) == 0 || body[0] != 4 {
+		return nil, fmt.Errorf("%w: only version 4 signatures are supported", ErrUnsupported)
+	}
+	r := &reader{data: body[1:]}
+	s := &pgpSignature{sigType: r.byte(), pubAlgo: r.byte(), hashAlgo: r.byte()}
+	hashed := r.bytes(int(r.uint16()))
+	if r.err != nil {
+		return nil, r.err
+	}
+	s.hashed = body[:6+len(hashed)]
+	unhashed := r.bytes(int(r.uint16()))
+	s.left16 = r.bytes(2)
+	switch s.pubAlgo {
+	case algoRSA, algoRSASign:
+		s.values = [][]byte{r.mpi()}
+	default:
+		s.values = [][]byte{r.mpi(), r.mpi()}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// 発行者は指紋を優先し、なければ鍵IDを使う
+	var keyID string
+	for _, sub := range [][]byte{hashed, unhashed} {
+		err := eachSubpacket(sub, func(t byte, data []byte) {
+			switch t {
+			case 2:
+				if len(data) == 4 {
+					s.created = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+				}
+			case 16:
+				if len(data) == 8 && keyID == "" {
+					keyID = strings.ToUpper(hex.EncodeToString(data))
+				}
+			case 33:
+				if len(data) == 21 && data[0] == 4 && s.issuer == "" {
+					s.issuer = strings.ToUpper(hex.EncodeToString(data[1:]))
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.issuer == "" {
+		s.issuer = keyID
+	}
+	return s, nil
+}
+
+// eachSubpacket はサブパケットの種類と中身を順にfnに渡す
+func eachSubpacket(data []byte, fn func(t byte, data []byte)) error {
+	r := &reader{data: data}
+	for !r.empty() {
+		var n int
+		switch first := int(r.byte()); {
+		case first < 192:
+			n = first
+		case first < 255:
+			n = (first-192)<<8 + int(r.byte()) + 192
+		default:
+			n = int(r.uint32())
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: empty subpacket", ErrMalformed)
+		}
+		sub := r.bytes(n)
+		if r.err != nil {
+			return r.err
+		}
+		fn(sub[0]&0x7f, sub[1:])
+	}
+	return nil
+}
+
+// findKey は指紋か鍵IDがissuerと一致する鍵を探す
+func (v *Verifier) findKey(issuer string) *PublicKey {
+	for _, k := range v.Keyring {
+		if k.Fingerprint == issuer || (len(issuer) == 16 && k.KeyID() == issuer) {
+			return k
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) verifyOpenPGP(payload []byte, signature string) (*Result, error) {
+	blocks, err := decodeArmor([]byte(signature))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: no signature found", ErrMalformed)
+	}
+	packets, err := readPackets(blocks[0].Data)
+	if err != nil {
+		return nil, err
+	}
+	var sig *pgpSignature
+	for _, p := range packets {
+		if p.tag == packetSignature {
+			if sig, err = parseSignature(p.body); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if sig == nil {
+		return nil, fmt.Errorf("%w: no signature packet", ErrMalformed)
+	}
+
+	res := &Result{
+		Format:      OpenPGP,
+		Status:      UnknownKey,
+		KeyType:     algorithmName(sig.pubAlgo),
+		Fingerprint: sig.issuer,
+		Created:     sig.created,
+	}
+	key := v.findKey(sig.issuer)
+	if key == nil {
+		return res, nil
+	}
+	res.Fingerprint, res.Signer = key.Fingerprint, key.UserID
+	if err := sig.verify(key, payload); err != nil {
+		res.Status, res.Err = Bad, err
+		return res, nil
+	}
+	res.Status = Good
+	return res, nil
+}
+
+// verify はpayloadと署名のハッシュされる部分からハッシュを計算し、鍵で署名を検証する
+func (s *pgpSignature) verify(key *PublicKey, payload []byte) error {
+	hash, ok := hashAlgorithms[s.hashAlgo]
+	if !ok || !hash.Available() {
+		return fmt.Errorf("%w: hash algorithm %d", ErrUnsupported, s.hashAlgo)
+	}
+	h := hash.New()
+	switch s.sigType {
+	case sigBinary:
+		h.Write(payload)
+	case sigText:
+		// テキストの署名は改行をCRLFに揃えて計算する
+		text := bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+		h.Write(bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n")))
+	default:
+		return fmt.Errorf("%w: signature type %#x", ErrUnsupported, s.sigType)
+	}
+	h.Write(s.hashed)
+	trailer := []byte{4, 0xff, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(trailer[2:], uint32(len(s.hashed)))
+	h.Write(trailer)
+	digest := h.Sum(nil)
+	if !bytes.Equal(digest[:2], s.left16) {
+		return fmt.Errorf("hash mismatch")
+	}
+
+	switch pub := key.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hash, digest, leftPad(s.values[0], pub.Size()))
+	case ed25519.PublicKey:
+		sig := append(leftPad(s.values[0], 32), leftPad(s.values[1], 32)...)
+		if !ed25519.Verify(pub, digest, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		r, ss := new(big.Int).SetBytes(s.values[0]), new(big.Int).SetBytes(s.values[1])
+		if !ecdsa.Verify(pub, digest, r, ss) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: public key algorithm %s", ErrUnsupported, algorithmName(key.Algorithm))
+}
+
+// leftPad はbを先頭に0を詰めてnバイトにする
+func leftPad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	padded := make([]byte, n)
+	copy(padded[n-len(b):], b)
+	return padded
+}
+
+// reader はパケットを先頭から読み込む 足りない場合はerrを設定し、以降はゼロ値を返す
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) empty() bool {
+	return r.err != nil || len(r.data) == 0
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// mpi はビット数の後に続く多倍長整数を読み込む
+func (r *reader) mpi() []byte {
+	bits := int(r.uint16())
+	return r.bytes((bits + 7) / 8)
+}
+
+// newLength は新形式のパケットの長さを読み込む 部分的な長さの場合はpartialが真
+func (r *reader) newLength() (n int, partial bool) {
+	switch first := int(r.byte()); {
+	case first < 192:
+		return first, false
+	case first < 224:
+		return (first-192)<<8 + int(r.byte()) + 192, false
+	case first == 255:
+		return int(r.uint32()), false
+	default:
+		return 1 << (first & 0x1f), true
+	}
+}
@@ -0,0 +1,387 @@
+package gpg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/showa-93/wyag-go/wildmatch"
+)
+
+// sshNamespace はgitが署名に使う名前空間
+const sshNamespace = "git"
+
+const sshSigMagic = "SSHSIG"
+
+var errIncorrectSignature = errors.New("incorrect signature")
+
+// sshPublicKey はSSHの公開鍵
+type sshPublicKey struct {
+	// blob はワイヤー形式の公開鍵で、指紋とallowed_signersとの照合に使う
+	blob []byte
+	// keyType は "ssh-ed25519" などの鍵の種類
+	keyType string
+	key     crypto.PublicKey
+}
+
+func parseSSHPublicKey(blob []byte) (*sshPublicKey, error) {
+	r := &sshReader{reader{data: blob}}
+	k := &sshPublicKey{blob: blob, keyType: string(r.string())}
+	switch k.keyType {
+	case "ssh-ed25519":
+		pub := r.string()
+		if r.err == nil && len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid ed25519 key", ErrMalformed)
+		}
+		k.key = ed25519.PublicKey(pub)
+	case "ssh-rsa":
+		e, n := r.string(), r.string()
+		k.key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		curve := sshCurve(k.keyType)
+		r.string()
+		x, y := elliptic.Unmarshal(curve, r.string())
+		if r.err == nil && x == nil {
+			return nil, fmt.Errorf("%w: invalid ecdsa key", ErrMalformed)
+		}
+		k.key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	default:
+		return nil, fmt.Errorf("%w: key type %s", ErrUnsupported, k.keyType)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return k, nil
+}
+
+func sshCurve(keyType string) elliptic.Curve {
+	switch keyType {
+	case "ecdsa-sha2-nistp384":
+		return elliptic.P384()
+	case "ecdsa-sha2-nistp521":
+		return elliptic.P521()
+	}
+	return elliptic.P256()
+}
+
+// Fingerprint はssh-keygenと同じ "SHA256:" から始まる指紋を返す
+func (k *sshPublicKey) Fingerprint() string {
+	sum := sha256.Sum256(k.blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// displayType はssh-keygenが表示する鍵の種類を返す
+func (k *sshPublicKey) displayType() string {
+	switch {
+	case k.keyType == "ssh-ed25519":
+		return "ED25519"
+	case k.keyType == "ssh-rsa":
+		return "RSA"
+	default:
+		return "ECDSA"
+	}
+}
+
+// verify はsignedに対するsigAlgoの署名sigを検証する
+func (k *sshPublicKey) verify(signed []byte, sigAlgo string, sig []byte) error {
+	switch pub := k.key.(type) {
+	case ed25519.PublicKey:
+		if sigAlgo != "ssh-ed25519" || !ed25519.Verify(pub, signed, sig) {
+			return errIncorrectSignature
+		}
+	case *rsa.PublicKey:
+		hash := crypto.SHA512
+		switch sigAlgo {
+		case "rsa-sha2-256":
+			hash = crypto.SHA256
+		case "rsa-sha2-512":
+		default:
+			return fmt.Errorf("%w: signature algorithm %s", ErrUnsupported, sigAlgo)
+		}
+		h := hash.New()
+		h.Write(signed)
+		if rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig) != nil {
+			return errIncorrectSignature
+		}
+	case *ecdsa.PublicKey:
+		hash := crypto.SHA256
+		switch pub.Curve {
+		case elliptic.P384():
+			hash = crypto.SHA384
+		case elliptic.P521():
+			hash = crypto.SHA512
+		}
+		h := hash.New()
+		h.Write(signed)
+		sr := &sshReader{reader{data: sig}}
+		rb, sb := sr.string(), sr.string()
+		if sigAlgo != k.keyType || sr.err != nil ||
+			!ecdsa.Verify(pub, h.Sum(nil), new(big.Int).SetBytes(rb), new(big.Int).SetBytes(sb)) {
+			return errIncorrectSignature
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) verifySSH(payload []byte, signature string, when time.Time) (*Result, error) {
+	blocks, err := decodeArmor([]byte(signature))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 || blocks[0].Type != "SSH SIGNATURE" {
+		return nil, fmt.Errorf("%w: no signature found", ErrMalformed)
+	}
+
+	// SSHSIGの形式 マジック、バージョン、公開鍵、名前空間、予約領域、ハッシュ、署名
+	r := &sshReader{reader{data: blocks[0].Data}}
+	if string(r.bytes(len(sshSigMagic))) != sshSigMagic || r.uint32() != 1 {
+		return nil, fmt.Errorf("%w: not an SSHSIG signature", ErrMalformed)
+	}
+	keyBlob, namespace, reserved, hashAlgo, sigBlob := r.string(), r.string(), r.string(), r.string(), r.string()
+	sr := &sshReader{reader{data: sigBlob}}
+	sigAlgo, sig := string(sr.string()), sr.string()
+	if r.err != nil || sr.err != nil {
+		return nil, fmt.Errorf("%w: truncated SSHSIG signature", ErrMalformed)
+	}
+	key, err := parseSSHPublicKey(keyBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Format:      SSH,
+		Status:      Bad,
+		KeyType:     key.displayType(),
+		Fingerprint: key.Fingerprint(),
+	}
+	var hash crypto.Hash
+	switch string(hashAlgo) {
+	case "sha256":
+		hash = crypto.SHA256
+	case "sha512":
+		hash = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("%w: hash algorithm %s", ErrUnsupported, hashAlgo)
+	}
+	if string(namespace) != sshNamespace {
+		res.Err = fmt.Errorf("namespace mismatch")
+		return res, nil
+	}
+	h := hash.New()
+	h.Write(payload)
+
+	// 署名されるのは名前空間などとメッセージのハッシュ
+	var signed bytes.Buffer
+	signed.WriteString(sshSigMagic)
+	for _, s := range [][]byte{namespace, reserved, hashAlgo, h.Sum(nil)} {
+		writeSSHString(&signed, s)
+	}
+	if err := key.verify(signed.Bytes(), sigAlgo, sig); err != nil {
+		if errors.Is(err, ErrUnsupported) {
+			return nil, err
+		}
+		res.Err = err
+		return res, nil
+	}
+
+	res.Status = NoPrincipal
+	for _, s := range v.AllowedSigners {
+		if s.allows(key, when) {
+			res.Status, res.Signer = Good, s.Principals
+			break
+		}
+	}
+	return res, nil
+}
+
+func writeSSHString(buf *bytes.Buffer, s []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(s)))
+	buf.Write(n[:])
+	buf.Write(s)
+}
+
+// sshReader はSSHのワイヤー形式を読み込む
+type sshReader struct {
+	reader
+}
+
+// string は長さが前に付いたバイト列を読み込む
+func (r *sshReader) string() []byte {
+	return r.bytes(int(r.uint32()))
+}
+
+// AllowedSigner はallowed_signersの1行
+type AllowedSigner struct {
+	// Principals はカンマ区切りのプリンシパルのパターン
+	Principals string
+	// Namespaces はカンマ区切りの名前空間のパターン 空の場合はすべての名前空間を許す
+	Namespaces    string
+	ValidAfter    time.Time
+	ValidBefore   time.Time
+	CertAuthority bool
+	key           *sshPublicKey
+}
+
+// ParseAllowedSigners はssh-keygen(1)のALLOWED SIGNERSの形式を解釈する
+func ParseAllowedSigners(data []byte) ([]*AllowedSigner, error) {
+	var signers []*AllowedSigner
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := parseAllowedSigner(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: allowed signers line %d", err, i+1)
+		}
+		signers = append(signers, s)
+	}
+	return signers, nil
+}
+
+func parseAllowedSigner(line string) (*AllowedSigner, error) {
+	principals, rest := nextField(line)
+	s := &AllowedSigner{Principals: strings.Trim(principals, `"`)}
+
+	// 鍵の種類の前にオプションが続くことがある
+	field, rest := nextField(rest)
+	if !isSSHKeyType(field) {
+		for _, opt := range splitOptions(field) {
+			name, value, _ := strings.Cut(opt, "=")
+			value = strings.Trim(value, `"`)
+			var err error
+			switch strings.ToLower(name) {
+			case "cert-authority":
+				s.CertAuthority = true
+			case "namespaces":
+				s.Namespaces = value
+			case "valid-after":
+				s.ValidAfter, err = parseSSHTime(value)
+			case "valid-before":
+				s.ValidBefore, err = parseSSHTime(value)
+			default:
+				err = fmt.Errorf("%w: unknown option %q", ErrMalformed, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		field, rest = nextField(rest)
+	}
+	encoded, _ := nextField(rest)
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key", ErrMalformed)
+	}
+	if s.key, err = parseSSHPublicKey(blob); err != nil {
+		return nil, err
+	}
+	if s.key.keyType != field {
+		return nil, fmt.Errorf("%w: key type mismatch", ErrMalformed)
+	}
+	return s, nil
+}
+
+// allows はkeyがwhenの時点で名前空間gitの署名に使えるか判定する
+func (s *AllowedSigner) allows(key *sshPublicKey, when time.Time) bool {
+	if s.CertAuthority || !bytes.Equal(s.key.blob, key.blob) {
+		return false
+	}
+	if s.Namespaces != "" && !matchPatternList(sshNamespace, s.Namespaces) {
+		return false
+	}
+	if !when.IsZero() {
+		if !s.ValidAfter.IsZero() && when.Before(s.ValidAfter) {
+			return false
+		}
+		if !s.ValidBefore.IsZero() && when.After(s.ValidBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextField は空白までのフィールドを返す 二重引用符の中の空白は区切りとみなさない
+func nextField(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				return s[:i], s[i:]
+			}
+		}
+	}
+	return s, ""
+}
+
+// splitOptions はカンマ区切りのオプションを分ける 二重引用符の中のカンマは区切りとみなさない
+func splitOptions(s string) []string {
+	var opts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				opts = append(opts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(opts, s[start:])
+}
+
+func isSSHKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-sha2-") || strings.HasPrefix(s, "sk-")
+}
+
+// parseSSHTime は "YYYYMMDD[HHMM[SS]][Z]" の形式の日時を解釈する Zがない場合はローカル時刻とみなす
+func parseSSHTime(s string) (time.Time, error) {
+	loc := time.Local
+	if strings.HasSuffix(s, "Z") {
+		s, loc = strings.TrimSuffix(s, "Z"), time.UTC
+	}
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(s)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q", ErrMalformed, s)
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q", ErrMalformed, s)
+	}
+	return t, nil
+}
+
+// matchPatternList はsがカンマ区切りのパターンのいずれかに一致するか判定する
+// "!" で始まるパターンに一致した場合は一致しないとみなす
+func matchPatternList(s, list string) bool {
+	matched := false
+	for _, p := range strings.Split(list, ",") {
+		if negated := strings.HasPrefix(p, "!"); negated {
+			if wildmatch.Match(p[1:], s, 0) {
+				return false
+			}
+		} else if wildmatch.Match(p, s, 0) {
+			matched = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// grepExpr は -e と --and、--or で指定された順に組み立てる条件
+// gitと同じく--andは--orより強く結びつき、演算子を省略した場合は--orとする
+type grepExpr struct {
+	groups [][]string
+	and    bool
+}
+
+// patternFlag は条件に正規表現を加える -e のフラグ
+type patternFlag struct{ *grepExpr }
+
+func (f patternFlag) String() string {
+	return ""
+}
+
+func (f patternFlag) Set(s string) error {
+	if f.and && len(f.groups) > 0 {
+		last := len(f.groups) - 1
+		f.groups[last] = append(f.groups[last], s)
+	} else {
+		f.groups = append(f.groups, []string{s})
+	}
+	f.and = false
+	return nil
+}
+
+// operatorFlag は次の -e を直前の条件とどう組み合わせるかを指定する --and と --or のフラグ
+type operatorFlag struct {
+	*grepExpr
+	and bool
+}
+
+func (f operatorFlag) String() string {
+	return ""
+}
+
+func (f operatorFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (f operatorFlag) Set(string) error {
+	f.grepExpr.and = f.and
+	return nil
+}
+
+type GrepCommand struct {
+	*flag.FlagSet
+	lineNumber bool
+	ignoreCase bool
+	filesOnly  bool
+	count      bool
+	word       bool
+	cached     bool
+	expr       grepExpr
+	revs       []string
+	paths      []string
+}
+
+func NewGrepCommand(args []string) *GrepCommand {
+	c := &GrepCommand{}
+	c.FlagSet = flag.NewFlagSet("grep", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.lineNumber, "n", false, "Prefix the line number to matching lines")
+	c.FlagSet.BoolVar(&c.ignoreCase, "i", false, "Ignore case differences between the patterns and the files")
+	c.FlagSet.BoolVar(&c.filesOnly, "l", false, "Show only the names of files that contain matches")
+	c.FlagSet.BoolVar(&c.count, "c", false, "Show the number of lines that match instead of every matched line")
+	c.FlagSet.BoolVar(&c.word, "w", false, "Match the pattern only at word boundary")
+	c.FlagSet.BoolVar(&c.cached, "cached", false, "Search blobs registered in the index instead of the working tree")
+	c.FlagSet.Var(patternFlag{&c.expr}, "e", "The next parameter is the pattern")
+	c.FlagSet.Var(operatorFlag{&c.expr, true}, "and", "Combine the patterns before and after with AND")
+	c.FlagSet.Var(operatorFlag{&c.expr, false}, "or", "Combine the patterns before and after with OR (default)")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go grep [-n] [-i] [-l | -c] [-w] [--cached] PATTERN [REV...] [-- PATH...]\n")
+		fmt.Fprint(o, "       wyag-go grep [options] -e PATTERN [(--and | --or) -e PATTERN...] [REV...] [-- PATH...]\n")
+		fmt.Fprint(o, "\tPrint lines matching a pattern\n")
+	}
+
+	// "--" より後はすべてパスとして扱う
+	for i, arg := range args {
+		if arg == "--" {
+			args, c.paths = args[:i], args[i+1:]
+			break
+		}
+	}
+	// パターンとリビジョンの後にもフラグを指定できるようにする
+	var rest []string
+	for c.Parse(args); len(c.Args()) > 0; c.Parse(args) {
+		rest = append(rest, c.Args()[0])
+		args = c.Args()[1:]
+	}
+	if len(c.expr.groups) == 0 {
+		if len(rest) == 0 {
+			c.Usage()
+			os.Exit(1)
+		}
+		c.expr.groups = [][]string{{rest[0]}}
+		rest = rest[1:]
+	}
+	if c.filesOnly && c.count {
+		fmt.Println("-l and -c cannot be used together")
+		os.Exit(1)
+	}
+	c.revs = rest
+	return c
+}
+
+func (c *GrepCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	opts := repository.GrepOptions{WordRegexp: c.word, Cached: c.cached}
+	for _, group := range c.expr.groups {
+		var and []*regexp.Regexp
+		for _, pattern := range group {
+			if c.ignoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			and = append(and, re)
+		}
+		opts.Patterns = append(opts.Patterns, and)
+	}
+
+	// gitと同じくリビジョンでない引数があれば、それ以降をパスとして扱う
+	revs := c.revs
+	for i, rev := range revs {
+		if sha, err := repo.FindObject(rev, "tree", true); err != nil || sha == "" {
+			if _, serr := os.Lstat(filepath.Join(BasePath, rev)); serr != nil {
+				return fmt.Errorf("unable to resolve revision: %s", rev)
+			}
+			revs, c.paths = revs[:i], append(revs[i:], c.paths...)
+			break
+		}
+	}
+	if len(revs) > 0 && c.cached {
+		return fmt.Errorf("--cached cannot be used with revisions")
+	}
+
+	// サブディレクトリで実行した場合はその中だけを検索し、パスはそこからの相対パスで表示する
+	prefix, err := repo.RelativePath(BasePath)
+	if err != nil {
+		return err
+	}
+	opts.Paths = []string{prefix}
+	if len(c.paths) > 0 {
+		opts.Paths = nil
+		for _, p := range c.paths {
+			rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+			if err != nil {
+				return err
+			}
+			opts.Paths = append(opts.Paths, rel)
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	found := false
+	if len(revs) == 0 {
+		revs = []string{""}
+	}
+	for _, rev := range revs {
+		results, err := repo.Grep(rev, opts)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			found = true
+			c.print(out, rev, prefix, res)
+		}
+	}
+	if !found {
+		return ExitCode(1)
+	}
+	return nil
+}
+
+func (c *GrepCommand) print(out *bufio.Writer, rev, prefix string, res *repository.GrepResult) {
+	name := res.Path
+	if rel, err := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(res.Path)); err == nil {
+		name = filepath.ToSlash(rel)
+	}
+	name = quotePath(name)
+	if rev != "" {
+		name = rev + ":" + name
+	}
+
+	switch {
+	case c.filesOnly:
+		fmt.Fprintln(out, name)
+	case c.count:
+		fmt.Fprintf(out, "%s:%d\n", name, len(res.Matches))
+	case res.Binary:
+		fmt.Fprintf(out, "Binary file %s matches\n", name)
+	default:
+		for _, m := range res.Matches {
+			if c.lineNumber {
+				fmt.Fprintf(out, "%s:%d:%s\n", name, m.Line, m.Text)
+			} else {
+				fmt.Fprintf(out, "%s:%s\n", name, m.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	repo, _ := newTestRepository(t, map[string]string{
+		"a.txt":   "foo\nbar\nfoo bar\n",
+		"d/b.txt": "bar foo\nbaz\n",
+		"bin":     "x\x00foo\n",
+	})
+	// インデックスとワークツリーをそれぞれHEADと異なる内容にする
+	writeTestFile(t, repo, "d/b.txt", "foo baz\n")
+	if err := repo.Add([]string{"d/b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, repo, "a.txt", "foo\n")
+
+	// 期待する結果は git grep ARGS の出力と終了コード
+	tests := []struct {
+		args     []string
+		want     string
+		wantCode int
+	}{
+		{args: []string{"foo"}, want: "a.txt:foo\nBinary file bin matches\nd/b.txt:foo baz\n"},
+		{args: []string{"-n", "foo", "HEAD"}, want: "HEAD:a.txt:1:foo\nHEAD:a.txt:3:foo bar\nBinary file HEAD:bin matches\nHEAD:d/b.txt:1:bar foo\n"},
+		{args: []string{"--cached", "-n", "foo"}, want: "a.txt:1:foo\na.txt:3:foo bar\nBinary file bin matches\nd/b.txt:1:foo baz\n"},
+		{args: []string{"-l", "foo"}, want: "a.txt\nbin\nd/b.txt\n"},
+		{args: []string{"-c", "foo"}, want: "a.txt:1\nbin:1\nd/b.txt:1\n"},
+		{args: []string{"-c", "foo", "HEAD"}, want: "HEAD:a.txt:2\nHEAD:bin:1\nHEAD:d/b.txt:1\n"},
+		{args: []string{"-e", "foo", "--and", "-e", "bar", "HEAD"}, want: "HEAD:a.txt:foo bar\nHEAD:d/b.txt:bar foo\n"},
+		{args: []string{"-e", "baz", "--or", "-e", "bar"}, want: "d/b.txt:foo baz\n"},
+		{args: []string{"-e", "foo", "--and", "-e", "bar", "--or", "-e", "baz", "HEAD"}, want: "HEAD:a.txt:foo bar\nHEAD:d/b.txt:bar foo\nHEAD:d/b.txt:baz\n"},
+		{args: []string{"foo", "HEAD", "--", "d"}, want: "HEAD:d/b.txt:bar foo\n"},
+		{args: []string{"foo", "--", "*.txt"}, want: "a.txt:foo\nd/b.txt:foo baz\n"},
+		{args: []string{"-l", "foo", "HEAD", "--", "d", "a.txt"}, want: "HEAD:a.txt\nHEAD:d/b.txt\n"},
+		{args: []string{"nomatch"}, wantCode: 1},
+	}
+	for _, tt := range tests {
+		stdout, _, err := runCommand(t, repo, "", NewGrepCommand(tt.args))
+		code := 0
+		var ec ExitCode
+		if errors.As(err, &ec) {
+			code = int(ec)
+		} else if err != nil {
+			t.Errorf("grep %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want || code != tt.wantCode {
+			t.Errorf("grep %v = %q, %d, want %q, %d", tt.args, stdout, code, tt.want, tt.wantCode)
+		}
+	}
+}
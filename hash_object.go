@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type HashObjectCommand struct {
+	*flag.FlagSet
+	Write     bool
+	Type      object.ObjectType
+	Path      string
+	NoFilters bool
+	// FilterPath は変換に使う属性を決めるパス 指定がなければPathを使う
+	FilterPath string
+}
+
+func NewHashObjectCommand(args []string) *HashObjectCommand {
+	ho := &HashObjectCommand{}
+	ho.FlagSet = flag.NewFlagSet("init", flag.ExitOnError)
+	ho.FlagSet.BoolVar(&ho.Write, "w", false, "Actually write the object into the database")
+	t := ho.FlagSet.String("t", "blob", "Specify the type")
+	ho.FlagSet.BoolVar(&ho.NoFilters, "no-filters", false, "Hash the contents as is, ignoring any input filter")
+	ho.FlagSet.StringVar(&ho.FilterPath, "path", "", "Hash object as it were located at the given path")
+
+	ho.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go hash-object [-w] [-t TYPE] [--no-filters | --path PATH] FILE\n")
+		fmt.Fprint(o, "\tCompute object ID and optionally creates a blob from a file\n")
+	}
+
+	ho.Parse(args)
+	if len(ho.Args()) != 1 {
+		fmt.Printf("expected 1 arguments count=%d\n", len(ho.Args()))
+		os.Exit(1)
+	}
+	ho.Path = ho.Args()[0]
+
+	if !filepath.IsAbs(ho.Path) {
+		p, err := filepath.Abs(ho.Path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ho.Path = p
+	}
+
+	ot, ok := object.ConvertObjectType(*t)
+	if !ok {
+		fmt.Printf("unknown object type %s\n", ot)
+		os.Exit(1)
+	}
+	ho.Type = ot
+
+	return ho
+}
+
+func (ho *HashObjectCommand) Run() error {
+	var (
+		repo *repository.Repository
+		err  error
+	)
+	if ho.Write {
+		if repo, err = repository.NewRepository(BasePath, false); err != nil {
+			return err
+		}
+	} else {
+		// リポジトリの外ではsha1で計算する
+		if repo, err = repository.FindRepository(BasePath, false); err != nil {
+			return err
+		}
+	}
+
+	// リポジトリ内のblobはgitattributesに従って変換する
+	var conv *repository.Converter
+	rel := ""
+	if repo != nil && ho.Type == object.Blob && !ho.NoFilters {
+		path := ho.FilterPath
+		if path == "" {
+			path = ho.Path
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(BasePath, path)
+		}
+		if rel, err = repo.RelativePath(path); err == nil {
+			if conv, err = repo.Converter(); err != nil {
+				return err
+			}
+			converts, err := conv.ConvertsToGit(rel)
+			if err != nil {
+				return err
+			}
+			if !converts {
+				conv = nil
+			}
+		}
+	}
+
+	var sha string
+	if conv == nil && ho.Type == object.Blob {
+		// 変換が不要なblobは中身をすべて読み込まずに計算する
+		sha, err = ho.hashStream(repo)
+	} else {
+		sha, err = ho.hash(repo, conv, rel)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", sha)
+	return nil
+}
+
+func (ho *HashObjectCommand) hashStream(repo *repository.Repository) (string, error) {
+	f, err := os.Open(ho.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return repo.HashObjectStream(f, ho.Type, fi.Size(), ho.Write)
+}
+
+func (ho *HashObjectCommand) hash(repo *repository.Repository, conv *repository.Converter, rel string) (string, error) {
+	data, err := os.ReadFile(ho.Path)
+	if err != nil {
+		return "", err
+	}
+	if conv != nil {
+		if data, err = conv.ToGit(rel, data); err != nil {
+			return "", err
+		}
+	}
+	return repo.HashObject(bytes.NewReader(data), ho.Type, ho.Write)
+}
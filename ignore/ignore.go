@@ -0,0 +1,238 @@
+// Package ignore は.gitignoreなどの除外パターンを扱う
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/wildmatch"
+)
+
+// Pattern は除外パターンの1行
+type Pattern struct {
+	// Source はパターンを読み込んだファイル、Lineはその行番号(1始まり)
+	Source string
+	Line   int
+	// Text は書かれたままのパターン
+	Text string
+	// Base はパターンを読み込んだ.gitignoreのディレクトリ ワークツリーからの相対パスで、ルートは空文字
+	Base string
+	// Negate は "!" で始まり、一致したパスを除外の対象から外すことを表す
+	Negate bool
+	// DirOnly は "/" で終わり、ディレクトリにのみ一致することを表す
+	DirOnly bool
+
+	pattern string
+	// noDir は "/" を含まず、パスの最後の要素のみと照合することを表す
+	noDir bool
+}
+
+// ParsePattern は1行を読み込む 空行とコメントはnilを返す
+func ParsePattern(line, base string) *Pattern {
+	text := trimTrailingSpaces(strings.TrimSuffix(line, "\n"))
+	if text == "" || text[0] == '#' {
+		return nil
+	}
+
+	p := &Pattern{Text: text, Base: base}
+	pattern := text
+	if pattern[0] == '!' {
+		p.Negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.DirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if pattern == "" {
+		return nil
+	}
+	p.noDir = !strings.Contains(pattern, "/")
+	// 先頭の "/" は.gitignoreのあるディレクトリに固定することを表す
+	p.pattern = strings.TrimPrefix(pattern, "/")
+	return p
+}
+
+// trimTrailingSpaces は行末の空白を取り除く "\" でエスケープされた空白は残す
+func trimTrailingSpaces(s string) string {
+	end := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ':
+			continue
+		case '\\':
+			i++
+		}
+		end = i + 1
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	return s[:end]
+}
+
+// ParsePatterns はファイルの内容を読み込む
+// sourceは一致したパターンの表示に使う
+func ParsePatterns(data []byte, base, source string) []*Pattern {
+	var patterns []*Pattern
+	// UTF-8のBOMは読み飛ばす
+	text := strings.TrimPrefix(string(data), "\xef\xbb\xbf")
+	for i, line := range strings.Split(text, "\n") {
+		p := ParsePattern(line, base)
+		if p == nil {
+			continue
+		}
+		p.Source = source
+		p.Line = i + 1
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// ReadPatterns はファイルからパターンを読み込む ファイルが存在しない場合は空を返す
+func ReadPatterns(path, base, source string) ([]*Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ParsePatterns(data, base, source), nil
+}
+
+// Match はワークツリーからの相対パスpathがパターンに一致するか判定する
+// 否定パターンでも一致すればtrueを返す
+func (p *Pattern) Match(path string, isDir bool, flags wildmatch.Flag) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	if p.noDir {
+		return wildmatch.Match(p.pattern, path[strings.LastIndex(path, "/")+1:], flags&wildmatch.CaseFold)
+	}
+
+	name := path
+	if p.Base != "" {
+		prefix := p.Base + "/"
+		if len(path) <= len(prefix) {
+			return false
+		}
+		if flags&wildmatch.CaseFold != 0 {
+			if !strings.EqualFold(path[:len(prefix)], prefix) {
+				return false
+			}
+		} else if path[:len(prefix)] != prefix {
+			return false
+		}
+		name = path[len(prefix):]
+	}
+	return wildmatch.Match(p.pattern, name, flags|wildmatch.PathName)
+}
+
+// Matcher はワークツリーの.gitignoreと追加のパターンを組み合わせて判定する
+// 優先度は深いディレクトリの.gitignore、浅いディレクトリの.gitignore、追加のパターンの順に高く、
+// 同じファイル中では後に書かれたものが優先される
+type Matcher struct {
+	worktree string
+	flags    wildmatch.Flag
+	global   []*Pattern
+	dirs     map[string][]*Pattern
+}
+
+// NewMatcher はMatcherを作る
+// globalは優先度の低い順に並べたcore.excludesFileや.git/info/excludeのパターン
+// worktreeが空の場合は.gitignoreを読み込まない
+func NewMatcher(worktree string, caseFold bool, global ...[]*Pattern) *Matcher {
+	m := &Matcher{worktree: worktree, dirs: make(map[string][]*Pattern)}
+	if caseFold {
+		m.flags = wildmatch.CaseFold
+	}
+	for _, g := range global {
+		m.global = append(m.global, g...)
+	}
+	return m
+}
+
+// AddPatterns はdirの.gitignoreの代わりにpatternsを使う
+func (m *Matcher) AddPatterns(dir string, patterns []*Pattern) {
+	m.dirs[dir] = patterns
+}
+
+func (m *Matcher) gitignore(dir string) ([]*Pattern, error) {
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns, nil
+	}
+	var patterns []*Pattern
+	if m.worktree != "" {
+		source := ".gitignore"
+		if dir != "" {
+			source = dir + "/.gitignore"
+		}
+		p, err := ReadPatterns(filepath.Join(m.worktree, filepath.FromSlash(source)), dir, source)
+		if err != nil {
+			return nil, err
+		}
+		patterns = p
+	}
+	m.dirs[dir] = patterns
+	return patterns, nil
+}
+
+// Match はワークツリーからの相対パスpathに最後に一致したパターンを返す 一致しなければnilを返す
+// 親ディレクトリが除外されている場合はそのディレクトリに一致したパターンを返す
+// 除外されるのは返されたパターンが否定でない場合となる
+func (m *Matcher) Match(path string, isDir bool) (*Pattern, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil
+	}
+	elems := strings.Split(path, "/")
+
+	var stack [][]*Pattern
+	root, err := m.gitignore("")
+	if err != nil {
+		return nil, err
+	}
+	stack = append(stack, root)
+	for i := 1; i < len(elems); i++ {
+		// 除外されたディレクトリの中のファイルは否定パターンでも除外を取り消せない
+		dir := strings.Join(elems[:i], "/")
+		if p := m.last(stack, dir, true); p != nil && !p.Negate {
+			return p, nil
+		}
+		patterns, err := m.gitignore(dir)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, patterns)
+	}
+	return m.last(stack, path, isDir), nil
+}
+
+func (m *Matcher) last(stack [][]*Pattern, path string, isDir bool) *Pattern {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if p := lastMatch(stack[i], path, isDir, m.flags); p != nil {
+			return p
+		}
+	}
+	return lastMatch(m.global, path, isDir, m.flags)
+}
+
+func lastMatch(patterns []*Pattern, path string, isDir bool, flags wildmatch.Flag) *Pattern {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].Match(path, isDir, flags) {
+			return patterns[i]
+		}
+	}
+	return nil
+}
+
+// Ignored はpathが除外されるか判定する
+func (m *Matcher) Ignored(path string, isDir bool) (bool, error) {
+	p, err := m.Match(path, isDir)
+	if err != nil {
+		return false, err
+	}
+	return p != nil && !p.Negate, nil
+}
@@ -0,0 +1,184 @@
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestMatcher はgitのt/t0008-ignores.shと同じ.gitignoreに、アンカーや"**"、エスケープを試すc/.gitignoreを加えたワークツリーを作る
+func newTestMatcher(t *testing.T) *Matcher {
+	t.Helper()
+	worktree := t.TempDir()
+	files := map[string]string{
+		".gitignore":   "one\nignored-*\ntop-level-dir/\n",
+		"a/.gitignore": "two*\n*three\n",
+		"a/b/.gitignore": "four\nfive\n# this comment should affect the line numbers\nsix\nignored-dir/\n" +
+			"# and so should this blank line:\n\n!on*\n!two\n",
+		"c/.gitignore": "/anchored\n**/deep\nx/**/y\n\\#hash\n\\!bang\ntrailing\\ \nspaces   \n[Bb]racket\n" +
+			"doc/*.txt\n*.log\n!keep.log\nbuild/\n!build/\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(worktree, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	global := ParsePatterns([]byte("globalone\n!globaltwo\nglobalthree\n"), "", "global")
+	exclude := ParsePatterns([]byte("per-repo\n"), "", ".git/info/exclude")
+	return NewMatcher(worktree, false, global, exclude)
+}
+
+func TestMatcher(t *testing.T) {
+	// 期待する結果は同じワークツリーでの git check-ignore -v -n --no-index の出力
+	tests := []struct {
+		path  string
+		isDir bool
+		want  string
+	}{
+		{path: "one", want: ".gitignore:1:one"},
+		{path: "not-ignored"},
+		{path: "ignored-and-untracked", want: ".gitignore:2:ignored-*"},
+		{path: "a/one", want: ".gitignore:1:one"},
+		{path: "a/not-ignored"},
+		{path: "a/ignored-and-untracked", want: ".gitignore:2:ignored-*"},
+		{path: "a/b/on", want: "a/b/.gitignore:8:!on*"},
+		{path: "a/b/one", want: "a/b/.gitignore:8:!on*"},
+		{path: "a/b/one one", want: "a/b/.gitignore:8:!on*"},
+		{path: "a/b/two", want: "a/b/.gitignore:9:!two"},
+		{path: "a/b/twooo", want: "a/.gitignore:1:two*"},
+		{path: "a/b/three", want: "a/.gitignore:2:*three"},
+		{path: "a/b/four", want: "a/b/.gitignore:1:four"},
+		{path: "a/b/six", want: "a/b/.gitignore:4:six"},
+		{path: "a/three", want: "a/.gitignore:2:*three"},
+		{path: "a/b/ignored-dir", isDir: true, want: "a/b/.gitignore:5:ignored-dir/"},
+		{path: "a/b/ignored-dir/foo", want: "a/b/.gitignore:5:ignored-dir/"},
+		{path: "top-level-dir", isDir: true, want: ".gitignore:3:top-level-dir/"},
+		{path: "top-level-dir/f", want: ".gitignore:3:top-level-dir/"},
+		// ディレクトリのみのパターンはファイルに一致しない
+		{path: "a/top-level-dir"},
+		{path: "globalone", want: "global:1:globalone"},
+		{path: "a/globalone", want: "global:1:globalone"},
+		{path: "globaltwo", want: "global:2:!globaltwo"},
+		{path: "a/b/globaltwo", want: "global:2:!globaltwo"},
+		{path: "globalthree", want: "global:3:globalthree"},
+		{path: "a/b/globalthree", want: "a/.gitignore:2:*three"},
+		{path: "per-repo", want: ".git/info/exclude:1:per-repo"},
+		{path: "a/per-repo", want: ".git/info/exclude:1:per-repo"},
+		// 先頭の"/"は.gitignoreのディレクトリに固定する
+		{path: "c/anchored", want: "c/.gitignore:1:/anchored"},
+		{path: "c/sub/anchored"},
+		{path: "anchored"},
+		{path: "c/deep", want: "c/.gitignore:2:**/deep"},
+		{path: "c/sub/deep", isDir: true, want: "c/.gitignore:2:**/deep"},
+		{path: "c/sub/deep/f", want: "c/.gitignore:2:**/deep"},
+		{path: "c/x/y", want: "c/.gitignore:3:x/**/y"},
+		{path: "c/x/p/q/y", isDir: true, want: "c/.gitignore:3:x/**/y"},
+		{path: "c/x/y/z", want: "c/.gitignore:3:x/**/y"},
+		// "\"でエスケープした"#"と"!"は通常の文字として扱う
+		{path: "c/#hash", want: "c/.gitignore:4:\\#hash"},
+		{path: "c/!bang", want: "c/.gitignore:5:\\!bang"},
+		{path: "c/bang"},
+		// 行末の空白は取り除き、"\"でエスケープした空白は残す
+		{path: "c/trailing ", want: "c/.gitignore:6:trailing\\ "},
+		{path: "c/trailing"},
+		{path: "c/spaces", want: "c/.gitignore:7:spaces"},
+		{path: "c/bracket", want: "c/.gitignore:8:[Bb]racket"},
+		{path: "c/Bracket", want: "c/.gitignore:8:[Bb]racket"},
+		{path: "c/racket"},
+		// "/"を含むパターンは.gitignoreのディレクトリからの相対パスと照合し、"*"は"/"に一致しない
+		{path: "c/doc/a.txt", want: "c/.gitignore:9:doc/*.txt"},
+		{path: "c/doc/sub/a.txt"},
+		{path: "c/sub/doc/a.txt"},
+		{path: "c/a.log", want: "c/.gitignore:10:*.log"},
+		{path: "c/keep.log", want: "c/.gitignore:11:!keep.log"},
+		{path: "c/sub/keep.log", want: "c/.gitignore:11:!keep.log"},
+		{path: "c/build", isDir: true, want: "c/.gitignore:13:!build/"},
+		{path: "c/build/f"},
+	}
+	m := newTestMatcher(t)
+	for _, tt := range tests {
+		p, err := m.Match(tt.path, tt.isDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if p != nil {
+			got = fmt.Sprintf("%s:%d:%s", p.Source, p.Line, p.Text)
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %v) = %q, want %q", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherIgnored(t *testing.T) {
+	m := newTestMatcher(t)
+	for path, want := range map[string]bool{
+		"one":                 true,
+		"a/b/one":             false,
+		"globaltwo":           false,
+		"a/b/ignored-dir/foo": true,
+		"c/keep.log":          false,
+		"not-ignored":         false,
+	} {
+		got, err := m.Ignored(path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestMatcherCaseFold(t *testing.T) {
+	m := NewMatcher("", true, ParsePatterns([]byte("Foo\nsub/*.TXT\n"), "", "global"))
+	for path, want := range map[string]bool{
+		"foo":       true,
+		"a/FOO":     true,
+		"SUB/a.txt": true,
+		"sub/a.md":  false,
+	} {
+		got, err := m.Ignored(path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		line    string
+		nil     bool
+		negate  bool
+		dirOnly bool
+	}{
+		{line: "", nil: true},
+		{line: "   ", nil: true},
+		{line: "# comment", nil: true},
+		{line: "!", nil: true},
+		{line: "/", nil: true},
+		{line: "\\#not-comment"},
+		{line: "!negated", negate: true},
+		{line: "dir/", dirOnly: true},
+		{line: "!dir/", negate: true, dirOnly: true},
+	}
+	for _, tt := range tests {
+		p := ParsePattern(tt.line, "")
+		if (p == nil) != tt.nil {
+			t.Errorf("ParsePattern(%q) = %v, want nil %v", tt.line, p, tt.nil)
+			continue
+		}
+		if p != nil && (p.Negate != tt.negate || p.DirOnly != tt.dirOnly) {
+			t.Errorf("ParsePattern(%q) negate=%v dirOnly=%v, want %v %v", tt.line, p.Negate, p.DirOnly, tt.negate, tt.dirOnly)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package index はステージングエリア(.git/index)を扱う
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+var (
+	ErrInvalidIndex = errors.New("invalid index")
+)
+
+const (
+	flagAssumeValid = 0x8000
+	flagExtended    = 0x4000
+	flagStageMask   = 0x3000
+	flagStageShift  = 12
+	flagNameMask    = 0x0fff
+
+	extFlagSkipWorktree = 0x4000
+	extFlagIntentToAdd  = 0x2000
+)
+
+// Entry はインデックスに登録された1ファイルの情報
+type Entry struct {
+	CTime time.Time
+	MTime time.Time
+	Dev   uint32
+	Ino   uint32
+	Mode  uint32
+	UID   uint32
+	GID   uint32
+	Size  uint32
+	Sha   string
+	Path  string
+
+	// Stage はマージ中のコンフリクトを表す 0は通常、1はbase、2はours、3はtheirs
+	Stage        int
+	AssumeValid  bool
+	SkipWorktree bool
+	IntentToAdd  bool
+}
+
+type Index struct {
+	format  oid.Format
+	version uint32
+	entries []*Entry
+}
+
+// New は空のインデックスを作る
+// エントリのIDとチェックサムはfのハッシュで計算する
+func New(f oid.Format) *Index {
+	return &Index{format: f, version: 2}
+}
+
+// Version はインデックスのフォーマットのバージョンを返す
+func (idx *Index) Version() uint32 {
+	return idx.version
+}
+
+// Entries はパスとステージの順に並んだエントリを返す
+func (idx *Index) Entries() []*Entry {
+	return idx.entries
+}
+
+func (idx *Index) search(path string, stage int) int {
+	return sort.Search(len(idx.entries), func(i int) bool {
+		e := idx.entries[i]
+		if e.Path != path {
+			return e.Path > path
+		}
+		return e.Stage >= stage
+	})
+}
+
+// Entry はpathのステージ0のエントリを返す
+func (idx *Index) Entry(path string) (*Entry, bool) {
+	i := idx.search(path, 0)
+	if i < len(idx.entries) && idx.entries[i].Path == path && idx.entries[i].Stage == 0 {
+		return idx.entries[i], true
+	}
+	return nil, false
+}
+
+// Stages はpathのすべてのステージのエントリを返す
+func (idx *Index) Stages(path string) []*Entry {
+	var entries []*Entry
+	for i := idx.search(path, 0); i < len(idx.entries) && idx.entries[i].Path == path; i++ {
+		entries = append(entries, idx.entries[i])
+	}
+	return entries
+}
+
+// Add はエントリを追加する
+// 同じパスとステージのエントリがあれば置き換える
+// ステージ0を追加した場合は、コンフリクトは解決したとみなして他のステージを取り除く
+func (idx *Index) Add(e *Entry) {
+	if e.Stage == 0 {
+		idx.Remove(e.Path)
+	}
+	i := idx.search(e.Path, e.Stage)
+	if i < len(idx.entries) && idx.entries[i].Path == e.Path && idx.entries[i].Stage == e.Stage {
+		idx.entries[i] = e
+		return
+	}
+	idx.entries = append(idx.entries, nil)
+	copy(idx.entries[i+1:], idx.entries[i:])
+	idx.entries[i] = e
+}
+
+// Remove はpathのすべてのステージのエントリを取り除く
+func (idx *Index) Remove(path string) bool {
+	i := idx.search(path, 0)
+	j := i
+	for j < len(idx.entries) && idx.entries[j].Path == path {
+		j++
+	}
+	if i == j {
+		return false
+	}
+	idx.entries = append(idx.entries[:i], idx.entries[j:]...)
+	return true
+}
+
+// Unmerged はコンフリクトしているエントリが存在するか判定する
+func (idx *Index) Unmerged() bool {
+	for _, e := range idx.entries {
+		if e.Stage != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Read はインデックスファイルを読み込む
+// バージョン2から4に対応し、省略できる拡張は読み飛ばす
+func Read(r io.Reader, f oid.Format) (*Index, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 12+f.Size() {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidIndex)
+	}
+
+	body, sum := raw[:len(raw)-f.Size()], raw[len(raw)-f.Size():]
+	if s := f.Sum(body); s != hex.EncodeToString(sum) {
+		return nil, fmt.Errorf("%w: bad checksum", ErrInvalidIndex)
+	}
+
+	if string(body[:4]) != "DIRC" {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidIndex)
+	}
+	idx := &Index{format: f, version: binary.BigEndian.Uint32(body[4:8])}
+	if idx.version < 2 || idx.version > 4 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, idx.version)
+	}
+	n := int(binary.BigEndian.Uint32(body[8:12]))
+
+	pos := 12
+	prev := ""
+	for i := 0; i < n; i++ {
+		e, next, err := readEntry(body, pos, idx.version, f.Size(), prev)
+		if err != nil {
+			return nil, err
+		}
+		idx.entries = append(idx.entries, e)
+		prev = e.Path
+		pos = next
+	}
+
+	// 拡張は4byteの署名と4byteの長さに続くデータで表される
+	// gitと同じく署名が大文字で始まる拡張は省略できるため読み飛ばし、それ以外の拡張は理解できなければ読み込まない
+	for pos < len(body) {
+		if len(body) < pos+8 {
+			return nil, fmt.Errorf("%w: truncated extension", ErrInvalidIndex)
+		}
+		sig := body[pos : pos+4]
+		size := int(binary.BigEndian.Uint32(body[pos+4:]))
+		pos += 8
+		if size > len(body)-pos {
+			return nil, fmt.Errorf("%w: truncated extension %q", ErrInvalidIndex, sig)
+		}
+		if sig[0] < 'A' || sig[0] > 'Z' {
+			return nil, fmt.Errorf("%w: unsupported extension %q", ErrInvalidIndex, sig)
+		}
+		pos += size
+	}
+
+	return idx, nil
+}
+
+func readEntry(body []byte, pos int, version uint32, hashSize int, prev string) (*Entry, int, error) {
+	// 固定長部分はstat情報(40byte)、ID、フラグ(2byte)
+	fixed := 40 + hashSize + 2
+	if len(body) < pos+fixed {
+		return nil, 0, fmt.Errorf("%w: truncated entry", ErrInvalidIndex)
+	}
+	start := pos
+	u32 := func() uint32 {
+		v := binary.BigEndian.Uint32(body[pos:])
+		pos += 4
+		return v
+	}
+
+	e := &Entry{}
+	ctimeSec, ctimeNano := u32(), u32()
+	mtimeSec, mtimeNano := u32(), u32()
+	e.CTime = time.Unix(int64(ctimeSec), int64(ctimeNano))
+	e.MTime = time.Unix(int64(mtimeSec), int64(mtimeNano))
+	e.Dev, e.Ino, e.Mode = u32(), u32(), u32()
+	e.UID, e.GID, e.Size = u32(), u32(), u32()
+	e.Sha = hex.EncodeToString(body[pos : pos+hashSize])
+	pos += hashSize
+
+	flags := binary.BigEndian.Uint16(body[pos:])
+	pos += 2
+	e.AssumeValid = flags&flagAssumeValid != 0
+	e.Stage = int(flags&flagStageMask) >> flagStageShift
+
+	if flags&flagExtended != 0 {
+		if version < 3 {
+			return nil, 0, fmt.Errorf("%w: extended flag in version %d", ErrInvalidIndex, version)
+		}
+		if len(body) < pos+2 {
+			return nil, 0, fmt.Errorf("%w: truncated entry", ErrInvalidIndex)
+		}
+		ext := binary.BigEndian.Uint16(body[pos:])
+		pos += 2
+		e.SkipWorktree = ext&extFlagSkipWorktree != 0
+		e.IntentToAdd = ext&extFlagIntentToAdd != 0
+	}
+
+	if version == 4 {
+		// 直前のエントリのパスから取り除く長さと、追加する文字列で表される
+		strip, n := binary.Uvarint(body[pos:])
+		if n <= 0 || int(strip) > len(prev) {
+			return nil, 0, fmt.Errorf("%w: bad path prefix", ErrInvalidIndex)
+		}
+		pos += n
+		end := bytes.IndexByte(body[pos:], 0)
+		if end < 0 {
+			return nil, 0, fmt.Errorf("%w: unterminated path", ErrInvalidIndex)
+		}
+		e.Path = prev[:len(prev)-int(strip)] + string(body[pos:pos+end])
+		return e, pos + end + 1, nil
+	}
+
+	end := bytes.IndexByte(body[pos:], 0)
+	if end < 0 {
+		return nil, 0, fmt.Errorf("%w: unterminated path", ErrInvalidIndex)
+	}
+	e.Path = string(body[pos : pos+end])
+	pos += end + 1
+
+	// エントリは8バイト境界までNULで埋められる
+	size := pos - start
+	pos = start + (size+7)/8*8
+	return e, pos, nil
+}
+
+// Write はインデックスをバージョン2(拡張フラグがあれば3)の形式で書き込む
+func (idx *Index) Write(w io.Writer) error {
+	version := uint32(2)
+	for _, e := range idx.entries {
+		if e.SkipWorktree || e.IntentToAdd {
+			version = 3
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(idx.entries)))
+
+	for _, e := range idx.entries {
+		start := buf.Len()
+		sha, err := hex.DecodeString(e.Sha)
+		if err != nil || len(sha) != idx.format.Size() {
+			return fmt.Errorf("%w: bad sha %s path=%s", ErrInvalidIndex, e.Sha, e.Path)
+		}
+
+		for _, v := range []uint32{
+			uint32(e.CTime.Unix()), uint32(e.CTime.Nanosecond()),
+			uint32(e.MTime.Unix()), uint32(e.MTime.Nanosecond()),
+			e.Dev, e.Ino, e.Mode, e.UID, e.GID, e.Size,
+		} {
+			binary.Write(&buf, binary.BigEndian, v)
+		}
+		buf.Write(sha)
+
+		flags := uint16(e.Stage<<flagStageShift) & flagStageMask
+		if len(e.Path) < flagNameMask {
+			flags |= uint16(len(e.Path))
+		} else {
+			flags |= flagNameMask
+		}
+		if e.AssumeValid {
+			flags |= flagAssumeValid
+		}
+		var ext uint16
+		if e.SkipWorktree {
+			ext |= extFlagSkipWorktree
+		}
+		if e.IntentToAdd {
+			ext |= extFlagIntentToAdd
+		}
+		if ext != 0 {
+			flags |= flagExtended
+		}
+		binary.Write(&buf, binary.BigEndian, flags)
+		if ext != 0 {
+			binary.Write(&buf, binary.BigEndian, ext)
+		}
+
+		buf.WriteString(e.Path)
+		size := buf.Len() - start
+		buf.Write(make([]byte, (size+8)/8*8-size))
+	}
+
+	h := idx.format.New()
+	h.Write(buf.Bytes())
+	buf.Write(h.Sum(nil))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,127 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// testdata のインデックスは git init --object-format=FORMAT で作成したリポジトリで
+// git update-index --add a.txt d/b.txt を実行して作成した 拡張は含まない
+// tree.index は続けて git write-tree を実行してTREE拡張を加え、
+// split.index はさらに git update-index --split-index を実行してlink拡張を加えた
+
+func TestReadWrite(t *testing.T) {
+	tests := []struct {
+		format oid.Format
+		file   string
+		want   map[string]string
+	}{
+		{
+			format: oid.SHA1,
+			file:   "sha1.index",
+			want: map[string]string{
+				"a.txt":   "ce013625030ba8dba906f756967f9e9ca394464a",
+				"d/b.txt": "587be6b4c3f93f93c489c0111bba5596147a26cb",
+			},
+		},
+		{
+			format: oid.SHA256,
+			file:   "sha256.index",
+			want: map[string]string{
+				"a.txt":   "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4",
+				"d/b.txt": "14f5162e2fe3d240d0d37aaab0f90e4af9a7cfa79639f3bab005b5bfb4174d9f",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx, err := Read(bytes.NewReader(raw), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries := idx.Entries()
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Read() returned %d entries, want %d", len(entries), len(tt.want))
+			}
+			for _, e := range entries {
+				if e.Sha != tt.want[e.Path] || e.Mode != 0100644 || e.Stage != 0 {
+					t.Errorf("entry %s = %o %s %d, want 100644 %s 0", e.Path, e.Mode, e.Sha, e.Stage, tt.want[e.Path])
+				}
+			}
+
+			// gitが書き込んだものと同じ内容を書き込む
+			var buf bytes.Buffer
+			if err := idx.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), raw) {
+				t.Errorf("Write() differs from the index written by git\ngot  %x\nwant %x", buf.Bytes(), raw)
+			}
+		})
+	}
+}
+
+func TestReadWrongFormat(t *testing.T) {
+	// チェックサムの長さが異なるため、別のフォーマットのインデックスは読み込めない
+	raw, err := os.ReadFile(filepath.Join("testdata", "sha256.index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(bytes.NewReader(raw), oid.SHA1); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Read() error = %v, want ErrInvalidIndex", err)
+	}
+}
+
+func TestWriteRejectsShortSha(t *testing.T) {
+	idx := New(oid.SHA256)
+	idx.Add(&Entry{Mode: 0100644, Sha: "ce013625030ba8dba906f756967f9e9ca394464a", Path: "a.txt"})
+	if err := idx.Write(&bytes.Buffer{}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Write() error = %v, want ErrInvalidIndex", err)
+	}
+}
+
+func TestReadExtensions(t *testing.T) {
+	tests := []struct {
+		file    string
+		wantErr bool
+	}{
+		// TREEは省略できる拡張のため読み飛ばす
+		{file: "tree.index"},
+		// linkは省略できない拡張のため、エントリを失わないよう読み込まない
+		{file: "split.index", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx, err := Read(bytes.NewReader(raw), oid.SHA1)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIndex) {
+					t.Errorf("Read() error = %v, want ErrInvalidIndex", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, e := range idx.Entries() {
+				paths = append(paths, e.Path)
+			}
+			if len(paths) != 2 || paths[0] != "a.txt" || paths[1] != "d/b.txt" {
+				t.Errorf("Entries() = %v, want [a.txt d/b.txt]", paths)
+			}
+		})
+	}
+}
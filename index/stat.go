@@ -0,0 +1,23 @@
+package index
+
+import (
+	"os"
+)
+
+// SetStat はファイルの情報をエントリに記録する
+// 変更日時やinodeなどOSに依存する値は取得できる場合のみ記録する
+func (e *Entry) SetStat(fi os.FileInfo) {
+	e.MTime = fi.ModTime()
+	e.CTime = fi.ModTime()
+	e.Size = uint32(fi.Size())
+	fillStat(e, fi)
+}
+
+// StatChanged はファイルの情報がエントリに記録したものから変わったか判定する
+// 変わっていなければ内容も変わっていないとみなせる
+func (e *Entry) StatChanged(fi os.FileInfo) bool {
+	cur := &Entry{}
+	cur.SetStat(fi)
+	return !cur.MTime.Equal(e.MTime) || !cur.CTime.Equal(e.CTime) ||
+		cur.Size != e.Size || cur.Ino != e.Ino || cur.UID != e.UID || cur.GID != e.GID
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package index
+
+import (
+	"os"
+)
+
+func fillStat(e *Entry, fi os.FileInfo) {}
@@ -0,0 +1,90 @@
+package index
+
+import (
+	"os"
+	"sync"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// Storage はインデックスの保存先のインターフェース
+type Storage interface {
+	// ReadIndex はインデックスを読み込む 存在しない場合は空のインデックスを返す
+	ReadIndex() (*Index, error)
+	// WriteIndex はインデックスを保存する
+	WriteIndex(idx *Index) error
+}
+
+// FileStorage は.git/indexにインデックスを保存する
+type FileStorage struct {
+	path   string
+	format oid.Format
+}
+
+func NewFileStorage(path string, f oid.Format) *FileStorage {
+	return &FileStorage{path: path, format: f}
+}
+
+func (s *FileStorage) ReadIndex() (*Index, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(s.format), nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, s.format)
+}
+
+func (s *FileStorage) WriteIndex(idx *Index) error {
+	// 書き込み途中のインデックスを読まれないようにlockファイルから置き換える
+	lock := s.path + ".lock"
+	f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+	if err := idx.Write(f); err != nil {
+		f.Close()
+		os.Remove(lock)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(lock)
+		return err
+	}
+	return os.Rename(lock, s.path)
+}
+
+// MemoryStorage はインデックスをメモリ上に保持する
+type MemoryStorage struct {
+	mu  sync.Mutex
+	idx *Index
+}
+
+func NewMemoryStorage(f oid.Format) *MemoryStorage {
+	return &MemoryStorage{idx: New(f)}
+}
+
+func (s *MemoryStorage) ReadIndex() (*Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idx.clone(), nil
+}
+
+func (s *MemoryStorage) WriteIndex(idx *Index) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idx = idx.clone()
+	return nil
+}
+
+// clone は保存したインデックスが呼び出し元の変更の影響を受けないように複製する
+func (idx *Index) clone() *Index {
+	c := &Index{format: idx.format, version: idx.version, entries: make([]*Entry, len(idx.entries))}
+	for i, e := range idx.entries {
+		ce := *e
+		c.entries[i] = &ce
+	}
+	return c
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/showa-93/wyag-go/oid"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type Init struct {
+	*flag.FlagSet
+	Path   string
+	Format oid.Format
+}
+
+func NewInit(args []string) *Init {
+	i := &Init{}
+	i.FlagSet = flag.NewFlagSet("init", flag.ExitOnError)
+	format := i.FlagSet.String("object-format", string(oid.SHA1), "Specify the hash algorithm (sha1 or sha256)")
+	i.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: init [--object-format=FORMAT] [path]\n")
+		fmt.Fprint(o, "\tInitialize a new, empty repository.\n")
+	}
+
+	i.Parse(args)
+	if len(i.Args()) != 1 {
+		fmt.Printf("expected 1 arguments count=%d\n", len(i.Args()))
+		os.Exit(1)
+	}
+	i.Path = i.Args()[0]
+
+	f, ok := oid.ParseFormat(*format)
+	if !ok {
+		fmt.Printf("unknown hash algorithm %s\n", *format)
+		os.Exit(1)
+	}
+	i.Format = f
+
+	if !filepath.IsAbs(i.Path) {
+		p, err := filepath.Abs(i.Path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		i.Path = p
+	}
+
+	return i
+}
+
+func (i *Init) Run() error {
+	_, err := repository.CreateRepository(i.Path, i.Format)
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/gpg"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type LogCommand struct {
+	*flag.FlagSet
+	showSignature bool
+	sha           string
+}
+
+func NewLogCommand(args []string) *LogCommand {
+	lc := &LogCommand{}
+	lc.FlagSet = flag.NewFlagSet("log", flag.ExitOnError)
+	lc.FlagSet.BoolVar(&lc.showSignature, "show-signature", false, "Label signed commits with the result of verifying the signature")
+
+	lc.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go log [--show-signature] COMMIT\n")
+		fmt.Fprint(o, "\tDisplay history of a given commit.\n")
+	}
+
+	lc.Parse(args)
+	if len(lc.Args()) != 1 {
+		fmt.Printf("expected 1 arguments count=%d\n", len(lc.Args()))
+		os.Exit(1)
+	}
+	lc.sha = lc.Args()[0]
+
+	return lc
+}
+
+func (lc *LogCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	sha, err := repo.FindObject(lc.sha, string(object.Commit), true)
+	if err != nil {
+		return err
+	}
+	if sha == "" {
+		return fmt.Errorf("%s is not a commit", lc.sha)
+	}
+	var verifier *gpg.Verifier
+	if lc.showSignature {
+		if verifier, err = repo.Verifier(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(os.Stdout, "digraph wyaglog{")
+	if err := LogGraphviz(repo, sha, verifier); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, "}")
+	return nil
+}
+
+// LogGraphviz はshaから辿れるコミットと親の関係をgraphvizの形式で出力する
+// 深い履歴でもスタックを消費しないように再帰せずに辿る
+// verifierを指定した場合は署名されたコミットに検証の結果をラベルとして添える
+func LogGraphviz(repo *repository.Repository, sha string, verifier *gpg.Verifier) error {
+	walk := repo.NewRevWalk(repository.RevWalkOptions{MaxCount: -1})
+	if err := walk.Push(sha, sha); err != nil {
+		return err
+	}
+	for {
+		sha, err := walk.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		o, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		commit := o.(*object.CommitObject)
+		if verifier != nil {
+			if err := writeSignatureLabel(repo, verifier, sha, commit); err != nil {
+				return err
+			}
+		}
+		for _, p := range commit.Parents() {
+			fmt.Fprintf(os.Stdout, "c_%s -> c_%s\n", sha, p)
+		}
+	}
+}
+
+// writeSignatureLabel は署名されたコミットのノードに短縮したshaと検証の結果を左揃えの行で表示するラベルをつける
+// 検証できない署名があっても履歴の表示は続ける
+func writeSignatureLabel(repo *repository.Repository, verifier *gpg.Verifier, sha string, commit *object.CommitObject) error {
+	var signature string
+	result, err := repo.VerifyCommit(verifier, commit)
+	switch {
+	case err != nil:
+		signature = fmt.Sprintf("error: %v\n", err)
+	case result != nil:
+		signature = result.String()
+	default:
+		return nil
+	}
+	abbrev, err := repo.Abbrev(sha, repository.DefaultAbbrev)
+	if err != nil {
+		return err
+	}
+	label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\l`).Replace(abbrev + "\n" + signature)
+	fmt.Fprintf(os.Stdout, "c_%s [label=\"%s\"]\n", sha, label)
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/ignore"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ListFilesCommand struct {
+	*flag.FlagSet
+	cached          bool
+	stage           bool
+	modified        bool
+	deleted         bool
+	others          bool
+	ignored         bool
+	excludeStandard bool
+	unmerged        bool
+	nullTerminated  bool
+	paths           []string
+}
+
+func NewListFilesCommand(args []string) *ListFilesCommand {
+	c := &ListFilesCommand{}
+	c.FlagSet = flag.NewFlagSet("ls-files", flag.ExitOnError)
+	for _, name := range []string{"c", "cached"} {
+		c.FlagSet.BoolVar(&c.cached, name, false, "Show cached files in the output (default)")
+	}
+	for _, name := range []string{"s", "stage"} {
+		c.FlagSet.BoolVar(&c.stage, name, false, "Show staged contents' mode bits, object name and stage number")
+	}
+	for _, name := range []string{"m", "modified"} {
+		c.FlagSet.BoolVar(&c.modified, name, false, "Show modified files in the output")
+	}
+	for _, name := range []string{"d", "deleted"} {
+		c.FlagSet.BoolVar(&c.deleted, name, false, "Show deleted files in the output")
+	}
+	for _, name := range []string{"o", "others"} {
+		c.FlagSet.BoolVar(&c.others, name, false, "Show other (i.e. untracked) files in the output")
+	}
+	for _, name := range []string{"i", "ignored"} {
+		c.FlagSet.BoolVar(&c.ignored, name, false, "Show only ignored files in the output")
+	}
+	c.FlagSet.BoolVar(&c.excludeStandard, "exclude-standard", false, "Add the standard Git exclusions")
+	for _, name := range []string{"u", "unmerged"} {
+		c.FlagSet.BoolVar(&c.unmerged, name, false, "Show information about unmerged files (forces --stage)")
+	}
+	c.FlagSet.BoolVar(&c.nullTerminated, "z", false, "\\0 line termination on output and do not quote filenames")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go ls-files [-c] [-s] [-m] [-d] [-o] [-i] [--exclude-standard] [-u] [-z] [PATH...]\n")
+		fmt.Fprint(o, "\tShow information about files in the index and the working tree\n")
+	}
+
+	c.Parse(args)
+	if c.unmerged {
+		c.stage = true
+	}
+	if !c.stage && !c.modified && !c.deleted && !c.others && !c.ignored {
+		c.cached = true
+	}
+	c.paths = c.Args()
+
+	return c
+}
+
+func (c *ListFilesCommand) Run() error {
+	// gitと同じく除外されたファイルは一覧の対象と除外のパターンを明示した場合のみ表示する
+	switch {
+	case c.ignored && !c.cached && !c.stage && !c.others:
+		fmt.Fprintln(os.Stderr, "fatal: ls-files -i must be used with either -o or -c")
+		return ExitCode(128)
+	case c.ignored && !c.excludeStandard:
+		fmt.Fprintln(os.Stderr, "fatal: ls-files --ignored needs some exclude pattern")
+		return ExitCode(128)
+	}
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+
+	// パスは現在のディレクトリからの相対パスで表示し、指定がなければ現在のディレクトリ配下に限る
+	prefix, err := repo.RelativePath(BasePath)
+	if err != nil {
+		return err
+	}
+	specs := []string{prefix}
+	if len(c.paths) > 0 {
+		specs = specs[:0]
+		for _, p := range c.paths {
+			rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+			if err != nil {
+				return err
+			}
+			specs = append(specs, rel)
+		}
+	}
+	match := func(path string) bool {
+		for _, s := range specs {
+			if s == "" || path == s || strings.HasPrefix(path, s+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// -iの場合は除外されたファイルのみを表示する
+	var ignore *ignore.Matcher
+	if c.ignored {
+		if ignore, err = repo.Ignore(); err != nil {
+			return err
+		}
+	}
+	skip := func(path string) (bool, error) {
+		if ignore == nil {
+			return false, nil
+		}
+		ignored, err := ignore.Ignored(strings.TrimSuffix(path, "/"), strings.HasSuffix(path, "/"))
+		return !ignored, err
+	}
+
+	if c.others {
+		for _, s := range specs {
+			paths, err := repo.Untracked(idx, s, c.excludeStandard && !c.ignored)
+			if err != nil {
+				return err
+			}
+			for _, p := range paths {
+				if ok, err := skip(p); err != nil {
+					return err
+				} else if ok {
+					continue
+				}
+				c.print(prefix, p, "")
+			}
+		}
+	}
+
+	var conv *repository.Converter
+	if c.modified {
+		if conv, err = repo.Converter(); err != nil {
+			return err
+		}
+	}
+	for _, e := range idx.Entries() {
+		if !match(e.Path) {
+			continue
+		}
+		if ok, err := skip(e.Path); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		if (c.cached || c.stage) && (!c.unmerged || e.Stage != 0) {
+			c.printEntry(prefix, e)
+		}
+		if !c.modified && !c.deleted {
+			continue
+		}
+
+		if _, err := os.Lstat(filepath.Join(repo.Worktree(), filepath.FromSlash(e.Path))); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			if c.deleted {
+				c.printEntry(prefix, e)
+			}
+			if c.modified {
+				c.printEntry(prefix, e)
+			}
+			continue
+		}
+		if c.modified {
+			modified, err := repo.Modified(conv, e)
+			if err != nil {
+				return err
+			}
+			if modified {
+				c.printEntry(prefix, e)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ListFilesCommand) printEntry(prefix string, e *index.Entry) {
+	if !c.stage {
+		c.print(prefix, e.Path, "")
+		return
+	}
+	c.print(prefix, e.Path, fmt.Sprintf("%06o %s %d\t", e.Mode, e.Sha, e.Stage))
+}
+
+func (c *ListFilesCommand) print(prefix, path, head string) {
+	if prefix != "" {
+		rel, err := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(path))
+		if err == nil {
+			if strings.HasSuffix(path, "/") {
+				rel += "/"
+			}
+			path = filepath.ToSlash(rel)
+		}
+	}
+	if c.nullTerminated {
+		fmt.Fprintf(os.Stdout, "%s%s\x00", head, path)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%s%s\n", head, quotePath(path))
+}
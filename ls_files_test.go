@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/index"
+)
+
+func TestListFiles(t *testing.T) {
+	// 除外されたファイルもインデックスにあれば追跡される
+	repo, _ := newTestRepository(t, map[string]string{"a.txt": "a\n", "d/b.txt": "b\n", "t.log": "t\n"})
+	commitTestFiles(t, repo, "second", map[string]string{".gitignore": "*.log\nbuild/\n"})
+	writeTestFile(t, repo, "a.txt", "changed\n")
+	if err := os.Remove(filepath.Join(repo.Worktree(), "d", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"u.txt", "x.log", "build/o.bin", "d/u2.txt"} {
+		writeTestFile(t, repo, p, "u\n")
+	}
+
+	// 期待する結果は git ls-files ARGS の出力と終了コード
+	tests := []struct {
+		args       []string
+		want       string
+		wantStderr string
+		wantCode   int
+	}{
+		{args: nil, want: ".gitignore\na.txt\nd/b.txt\nt.log\n"},
+		{
+			args: []string{"-s"},
+			want: "100644 749504ca09128068ab007183124f9aa5d3fe5dad 0\t.gitignore\n" +
+				"100644 78981922613b2afb6025042ff6bd878ac1994e85 0\ta.txt\n" +
+				"100644 61780798228d17af2d34fce4cfbdf35556832472 0\td/b.txt\n" +
+				"100644 718f4d2ff533cf8ead8d3556cf43912bd245fbc4 0\tt.log\n",
+		},
+		{args: []string{"-o"}, want: "build/o.bin\nd/u2.txt\nu.txt\nx.log\n"},
+		{args: []string{"-o", "--exclude-standard"}, want: "d/u2.txt\nu.txt\n"},
+		{args: []string{"-o", "--exclude-standard", "d"}, want: "d/u2.txt\n"},
+		{args: []string{"-c", "-o", "--exclude-standard"}, want: "d/u2.txt\nu.txt\n.gitignore\na.txt\nd/b.txt\nt.log\n"},
+		{args: []string{"-o", "-i", "--exclude-standard"}, want: "build/o.bin\nx.log\n"},
+		{args: []string{"-c", "-i", "--exclude-standard"}, want: "t.log\n"},
+		{args: []string{"-i", "--exclude-standard"}, wantStderr: "fatal: ls-files -i must be used with either -o or -c\n", wantCode: 128},
+		{args: []string{"-o", "-i"}, wantStderr: "fatal: ls-files --ignored needs some exclude pattern\n", wantCode: 128},
+		{args: []string{"-m"}, want: "a.txt\nd/b.txt\n"},
+		{args: []string{"-d"}, want: "d/b.txt\n"},
+		{args: []string{"-m", "-d"}, want: "a.txt\nd/b.txt\nd/b.txt\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := runCommand(t, repo, "", NewListFilesCommand(tt.args))
+		code := 0
+		var ec ExitCode
+		if errors.As(err, &ec) {
+			code = int(ec)
+		} else if err != nil {
+			t.Errorf("ls-files %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want || stderr != tt.wantStderr || code != tt.wantCode {
+			t.Errorf("ls-files %v = %q, %q, %d, want %q, %q, %d", tt.args, stdout, stderr, code, tt.want, tt.wantStderr, tt.wantCode)
+		}
+	}
+}
+
+func TestListFilesUnmerged(t *testing.T) {
+	repo, _ := newTestRepository(t, map[string]string{"a.txt": "a\n", "c.txt": "c\n"})
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Remove("c.txt")
+	for stage, sha := range map[int]string{
+		1: "78981922613b2afb6025042ff6bd878ac1994e85",
+		2: "61780798228d17af2d34fce4cfbdf35556832472",
+		3: "f2ad6c76f0115a6ba5b00456a849810e7ec0af20",
+	} {
+		idx.Add(&index.Entry{Mode: 0100644, Sha: sha, Path: "c.txt", Stage: stage})
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 期待する結果は git ls-files ARGS の出力
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "a.txt\nc.txt\nc.txt\nc.txt\n"},
+		{
+			args: []string{"-s"},
+			want: "100644 78981922613b2afb6025042ff6bd878ac1994e85 0\ta.txt\n" +
+				"100644 78981922613b2afb6025042ff6bd878ac1994e85 1\tc.txt\n" +
+				"100644 61780798228d17af2d34fce4cfbdf35556832472 2\tc.txt\n" +
+				"100644 f2ad6c76f0115a6ba5b00456a849810e7ec0af20 3\tc.txt\n",
+		},
+		{
+			args: []string{"-u"},
+			want: "100644 78981922613b2afb6025042ff6bd878ac1994e85 1\tc.txt\n" +
+				"100644 61780798228d17af2d34fce4cfbdf35556832472 2\tc.txt\n" +
+				"100644 f2ad6c76f0115a6ba5b00456a849810e7ec0af20 3\tc.txt\n",
+		},
+	}
+	for _, tt := range tests {
+		stdout, _, err := runCommand(t, repo, "", NewListFilesCommand(tt.args))
+		if err != nil {
+			t.Errorf("ls-files %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want {
+			t.Errorf("ls-files %v = %q, want %q", tt.args, stdout, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ListTreeCommand struct {
+	*flag.FlagSet
+	sha            string
+	recursive      bool
+	showTrees      bool
+	treeOnly       bool
+	nameOnly       bool
+	long           bool
+	nullTerminated bool
+	paths          []string
+}
+
+func NewListTreeCommand(args []string) *ListTreeCommand {
+	lc := &ListTreeCommand{}
+	lc.FlagSet = flag.NewFlagSet("ls-tree", flag.ExitOnError)
+	lc.FlagSet.BoolVar(&lc.recursive, "r", false, "Recurse into sub-trees")
+	lc.FlagSet.BoolVar(&lc.showTrees, "t", false, "Show tree entries even when going to recurse them")
+	lc.FlagSet.BoolVar(&lc.treeOnly, "d", false, "Show only the named tree entry itself, not its children")
+	lc.FlagSet.BoolVar(&lc.nameOnly, "name-only", false, "List only filenames")
+	lc.FlagSet.BoolVar(&lc.long, "l", false, "Show object size of blob entries")
+	lc.FlagSet.BoolVar(&lc.long, "long", false, "Show object size of blob entries")
+	lc.FlagSet.BoolVar(&lc.nullTerminated, "z", false, "\\0 line termination on output and do not quote filenames")
+
+	lc.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go ls-tree [-r] [-t] [-d] [-l] [-z] [--name-only] TREE [PATH...]\n")
+		fmt.Fprint(o, "\tPretty-print a tree object.\n")
+	}
+
+	lc.Parse(args)
+	if len(lc.Args()) < 1 {
+		fmt.Printf("expected at least 1 arguments count=%d\n", len(lc.Args()))
+		os.Exit(1)
+	}
+	lc.sha = lc.Args()[0]
+	lc.paths = lc.Args()[1:]
+	// -rと-dを同時に指定した場合は-tも指定したものとする
+	if lc.recursive && lc.treeOnly {
+		lc.showTrees = true
+	}
+
+	return lc
+}
+
+func (lc *ListTreeCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	sha, err := repo.FindObject(lc.sha, string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	if sha == "" {
+		return fmt.Errorf("not a tree object %s", lc.sha)
+	}
+	o, err := repo.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+
+	// パスは現在のディレクトリからの相対パスで指定、表示する
+	prefix, err := repo.RelativePath(BasePath)
+	if err != nil {
+		return err
+	}
+	var specs []string
+	for _, p := range lc.paths {
+		rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(p, "/") && rel != "" {
+			rel += "/"
+		}
+		specs = append(specs, rel)
+	}
+	if len(specs) == 0 && prefix != "" {
+		specs = []string{prefix + "/"}
+	}
+
+	w := &treeLister{ListTreeCommand: lc, repo: repo, prefix: prefix, specs: specs}
+	return w.list(o.(*object.TreeObject), "")
+}
+
+type treeLister struct {
+	*ListTreeCommand
+	repo   *repository.Repository
+	prefix string
+	specs  []string
+}
+
+// match はpathが指定されたパスに含まれるか、その親ディレクトリであるか判定する
+func (w *treeLister) match(p string) bool {
+	if len(w.specs) == 0 {
+		return true
+	}
+	for _, s := range w.specs {
+		trimmed := strings.TrimSuffix(s, "/")
+		if trimmed == "" || p == trimmed || strings.HasPrefix(p, trimmed+"/") || strings.HasPrefix(s, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// descend は指定されたパスがpathより深い場合に真を返す
+func (w *treeLister) descend(p string) bool {
+	if w.recursive {
+		return true
+	}
+	for _, s := range w.specs {
+		if len(s) > len(p) && strings.HasPrefix(s, p) && s[len(p)] == '/' {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *treeLister) list(tree *object.TreeObject, base string) error {
+	for _, item := range tree.Items() {
+		p := path.Join(base, item.Path())
+		if !w.match(p) {
+			continue
+		}
+
+		t := item.Type()
+		if t == object.Tree && w.descend(p) {
+			sub, err := w.repo.ReadObject(item.Sha())
+			if err != nil {
+				return err
+			}
+			if w.showTrees {
+				if err := w.print(item, t, p); err != nil {
+					return err
+				}
+			}
+			if err := w.list(sub.(*object.TreeObject), p); err != nil {
+				return err
+			}
+			continue
+		}
+		if w.treeOnly && t != object.Tree {
+			continue
+		}
+		if err := w.print(item, t, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *treeLister) print(item *object.TreeLeafObject, t object.ObjectType, p string) error {
+	if w.prefix != "" {
+		if rel, err := filepath.Rel(filepath.FromSlash(w.prefix), filepath.FromSlash(p)); err == nil {
+			p = filepath.ToSlash(rel)
+			if p == "." {
+				p = "./"
+			}
+		}
+	}
+	if !w.nullTerminated {
+		p = quotePath(p)
+	}
+	term := "\n"
+	if w.nullTerminated {
+		term = "\x00"
+	}
+
+	if w.nameOnly {
+		fmt.Fprintf(os.Stdout, "%s%s", p, term)
+		return nil
+	}
+
+	mode := strings.Repeat("0", 6-len(item.Mode())) + item.Mode()
+	if !w.long {
+		fmt.Fprintf(os.Stdout, "%s %s %s\t%s%s", mode, t, item.Sha(), p, term)
+		return nil
+	}
+
+	size := "-"
+	if t == object.Blob {
+		o, err := w.repo.ReadObject(item.Sha())
+		if err != nil {
+			return err
+		}
+		size = strconv.Itoa(len(o.(*object.BlobObject).Data()))
+	}
+	fmt.Fprintf(os.Stdout, "%s %s %s %7s\t%s%s", mode, t, item.Sha(), size, p, term)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestListTree(t *testing.T) {
+	repo, _ := newTestRepository(t, map[string]string{
+		"a.txt":     "a\n",
+		"d/b.txt":   "b\n",
+		"d/e/c.txt": "c\n",
+		"f/g":       "f\n",
+	})
+	const (
+		a = "100644 blob 78981922613b2afb6025042ff6bd878ac1994e85\ta.txt\n"
+		d = "040000 tree 767898db065f9d63575f02efb1b17bb1ad3e0334\td\n"
+		b = "100644 blob 61780798228d17af2d34fce4cfbdf35556832472\td/b.txt\n"
+		e = "040000 tree cf67e9ef3a0fc6d858423fc177f2fbbe985a6f17\td/e\n"
+		c = "100644 blob f2ad6c76f0115a6ba5b00456a849810e7ec0af20\td/e/c.txt\n"
+		f = "040000 tree 8c3932f930c8b3c7bbf74f4ff525d18cbd721d7c\tf\n"
+		g = "100644 blob 6a69f92020f5df77af6e8813ff1232493383b708\tf/g\n"
+	)
+
+	// 期待する結果は git ls-tree ARGS の出力
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"HEAD"}, want: a + d + f},
+		{args: []string{"-r", "HEAD"}, want: a + b + c + g},
+		{args: []string{"-t", "HEAD"}, want: a + d + f},
+		{args: []string{"-d", "HEAD"}, want: d + f},
+		{args: []string{"-r", "-t", "HEAD"}, want: a + d + b + e + c + f + g},
+		{args: []string{"-r", "-d", "HEAD"}, want: d + e + f},
+		{args: []string{"HEAD", "d"}, want: d},
+		{args: []string{"HEAD", "d/"}, want: b + e},
+		{args: []string{"-d", "HEAD", "d"}, want: d},
+		{args: []string{"-d", "HEAD", "d/"}, want: e},
+		{args: []string{"-r", "HEAD", "d"}, want: b + c},
+		{args: []string{"-r", "-t", "HEAD", "d"}, want: d + b + e + c},
+		{args: []string{"HEAD", "d/e/c.txt"}, want: c},
+		{args: []string{"-t", "HEAD", "d/e/c.txt"}, want: d + e + c},
+		{args: []string{"HEAD", "d/e", "f/g"}, want: e + g},
+		{args: []string{"--name-only", "-r", "HEAD"}, want: "a.txt\nd/b.txt\nd/e/c.txt\nf/g\n"},
+		{
+			args: []string{"-l", "HEAD"},
+			want: "100644 blob 78981922613b2afb6025042ff6bd878ac1994e85       2\ta.txt\n" +
+				"040000 tree 767898db065f9d63575f02efb1b17bb1ad3e0334       -\td\n" +
+				"040000 tree 8c3932f930c8b3c7bbf74f4ff525d18cbd721d7c       -\tf\n",
+		},
+	}
+	for _, tt := range tests {
+		stdout, _, err := runCommand(t, repo, "", NewListTreeCommand(tt.args))
+		if err != nil {
+			t.Errorf("ls-tree %v error = %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want {
+			t.Errorf("ls-tree %v =\n%s\nwant\n%s", tt.args, stdout, tt.want)
+		}
+	}
+}
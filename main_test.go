@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/showa-93/wyag-go/oid"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// newTestRepository は作者と日時を固定したリポジトリを作り、filesをコミットしてshaを返す
+func newTestRepository(t *testing.T, files map[string]string) (*repository.Repository, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, kind := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+kind+"_NAME", "T")
+		t.Setenv("GIT_"+kind+"_EMAIL", "t@example.com")
+		t.Setenv("GIT_"+kind+"_DATE", "1700000000 +0900")
+	}
+	repo, err := repository.CreateRepository(t.TempDir(), oid.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, commitTestFiles(t, repo, "first", files)
+}
+
+// commitTestFiles はfilesの内容をワークツリーに書き込んでコミットし、コミットのshaを返す
+func commitTestFiles(t *testing.T, repo *repository.Repository, message string, files map[string]string) string {
+	t.Helper()
+	var paths []string
+	for p, content := range files {
+		writeTestFile(t, repo, p, content)
+		paths = append(paths, p)
+	}
+	if err := repo.Add(paths); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := repo.Commit(repository.CommitOptions{Message: message + "\n", AllowEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// writeTestFile はワークツリーにファイルを書き込む インデックスには追加しない
+func writeTestFile(t *testing.T, repo *repository.Repository, p, content string) {
+	t.Helper()
+	full := filepath.Join(repo.Worktree(), filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runCommand はリポジトリのワークツリーでcmdを実行し、標準出力と標準エラー出力とエラーを返す
+// stdinは標準入力に渡す内容
+func runCommand(t *testing.T, repo *repository.Repository, stdin string, cmd Command) (string, string, error) {
+	t.Helper()
+	base := BasePath
+	BasePath = repo.Worktree()
+	defer func() { BasePath = base }()
+
+	in, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if _, err := io.WriteString(in, stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := capture(t, &os.Stdout), capture(t, &os.Stderr)
+	origIn := os.Stdin
+	os.Stdin = in
+	runErr := cmd.Run()
+	os.Stdin = origIn
+	return stdout(), stderr(), runErr
+}
+
+// capture はfに書き込まれる内容を読み込み、返した関数で元に戻してその内容を返す
+func capture(t *testing.T, f **os.File) func() string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := *f
+	*f = w
+	done := make(chan string)
+	go func() {
+		var sb strings.Builder
+		io.Copy(&sb, r)
+		r.Close()
+		done <- sb.String()
+	}()
+	return func() string {
+		*f = orig
+		w.Close()
+		return <-done
+	}
+}
@@ -0,0 +1,29 @@
+package object
+
+type BlobObject struct {
+	blobdata []byte
+}
+
+func NewBlobObject(raw []byte) *BlobObject {
+	bo := &BlobObject{}
+	bo.DeSerialize(raw)
+	return bo
+}
+
+func (o *BlobObject) Serialize() ([]byte, error) {
+	return o.blobdata, nil
+}
+
+func (o *BlobObject) DeSerialize(data []byte) error {
+	o.blobdata = data
+	return nil
+}
+
+func (o *BlobObject) TypeHeader() ObjectType {
+	return Blob
+}
+
+// Data はblobの中身を返す
+func (o *BlobObject) Data() []byte {
+	return o.blobdata
+}
@@ -0,0 +1,152 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kvlmObject はヘッダーとメッセージからなるコミットとタグに共通する部分
+type kvlmObject struct {
+	kvlm *Kvlm
+}
+
+func (o *kvlmObject) Serialize() ([]byte, error) {
+	return o.kvlm.Serialize(), nil
+}
+
+func (o *kvlmObject) DeSerialize(data []byte) error {
+	o.kvlm = ParseKvlm(data)
+	return nil
+}
+
+// Kvlm はヘッダーとメッセージを返す
+func (o *kvlmObject) Kvlm() *Kvlm {
+	return o.kvlm
+}
+
+// header はkeyの最初の値を返す 存在しない場合は空を返す
+func (o *kvlmObject) header(key string) string {
+	if v, ok := o.kvlm.Get(key); ok && len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// signature はkeyのヘッダーをSignatureとして解釈する
+func (o *kvlmObject) signature(key string) (*Signature, error) {
+	v, ok := o.kvlm.Get(key)
+	if !ok || len(v) == 0 {
+		return nil, fmt.Errorf("%w: missing %s", ErrMalformed, key)
+	}
+	return ParseSignature(v[0])
+}
+
+type CommitObject struct {
+	kvlmObject
+}
+
+func NewCommitObject(raw []byte) *CommitObject {
+	o := &CommitObject{}
+	o.DeSerialize(raw)
+	return o
+}
+
+func (o *CommitObject) TypeHeader() ObjectType {
+	return Commit
+}
+
+// Tree はコミットのツリーのshaを返す
+func (o *CommitObject) Tree() string {
+	return o.header("tree")
+}
+
+// Parents は親のコミットのshaを順に返す
+func (o *CommitObject) Parents() []string {
+	parents, _ := o.kvlm.Get("parent")
+	return parents
+}
+
+func (o *CommitObject) Author() (*Signature, error) {
+	return o.signature("author")
+}
+
+func (o *CommitObject) Committer() (*Signature, error) {
+	return o.signature("committer")
+}
+
+// Encoding はメッセージの文字コードを返す 指定がない場合はUTF-8を意味する空を返す
+func (o *CommitObject) Encoding() string {
+	return o.header("encoding")
+}
+
+// GPGSig は改行で終わるコミットの署名を返す 署名されていない場合は空を返す
+func (o *CommitObject) GPGSig() string {
+	if sig := o.header("gpgsig"); sig != "" {
+		return sig + "\n"
+	}
+	return ""
+}
+
+// Message はコミットメッセージを返す
+func (o *CommitObject) Message() string {
+	return o.kvlm.Message()
+}
+
+// Subject はgitと同じくメッセージの先頭の空行を除いた最初の段落を、改行を空白にして1行にまとめて返す
+func (o *CommitObject) Subject() string {
+	var lines []string
+	for _, line := range strings.Split(o.Message(), "\n") {
+		line = strings.TrimRight(line, " \t\r\v\f")
+		if line == "" {
+			if len(lines) > 0 {
+				break
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+// Payload は署名の対象となる、署名のヘッダーを除いたコミットを返す
+func (o *CommitObject) Payload() []byte {
+	payload := &Kvlm{message: o.kvlm.message, hasMessage: o.kvlm.hasMessage}
+	for _, e := range o.kvlm.entries {
+		if e.key != "gpgsig" && e.key != "gpgsig-sha256" {
+			payload.entries = append(payload.entries, e)
+		}
+	}
+	return payload.Serialize()
+}
+
+// CommitBuilder はgitと同じ形式のコミットを作成する
+type CommitBuilder struct {
+	Tree      string
+	Parents   []string
+	Author    *Signature
+	Committer *Signature
+	// Encoding はUTF-8以外の場合のみ指定する
+	Encoding string
+	// GPGSig は改行で終わる署名 空なら署名しない
+	GPGSig  string
+	Message string
+}
+
+// Build はヘッダーをgitと同じ順に並べたコミットを作成する
+func (b *CommitBuilder) Build() *CommitObject {
+	kvlm := NewKvlm()
+	kvlm.Add("tree", b.Tree)
+	for _, p := range b.Parents {
+		kvlm.Add("parent", p)
+	}
+	kvlm.Add("author", b.Author.String())
+	kvlm.Add("committer", b.Committer.String())
+	if b.Encoding != "" {
+		kvlm.Add("encoding", b.Encoding)
+	}
+	if b.GPGSig != "" {
+		kvlm.Add("gpgsig", strings.TrimSuffix(b.GPGSig, "\n"))
+	}
+	kvlm.Add("", b.Message)
+	return &CommitObject{kvlmObject{kvlm: kvlm}}
+}
@@ -0,0 +1,132 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// kvlmEntry はヘッダーの1項目 複数行の値は改行でつなげて保持する
+type kvlmEntry struct {
+	key   string
+	value string
+}
+
+// Key Value List With Message
+// ヘッダーは出現順に保持し、シリアライズすると元のバイト列に戻る
+type Kvlm struct {
+	entries []kvlmEntry
+	message string
+	// hasMessage はヘッダーとメッセージを区切る空行があるか
+	hasMessage bool
+}
+
+func NewKvlm() *Kvlm {
+	return &Kvlm{}
+}
+
+// Add はヘッダーを末尾に加える keyが空の場合はメッセージに追記する
+func (k *Kvlm) Add(key string, value string) {
+	if key == "" {
+		k.message += value
+		k.hasMessage = true
+		return
+	}
+	k.entries = append(k.entries, kvlmEntry{key: key, value: value})
+}
+
+func (k *Kvlm) Get(key string) ([]string, bool) {
+	if key == "" {
+		return []string{k.message}, k.hasMessage
+	}
+	var values []string
+	for _, e := range k.entries {
+		if e.key == key {
+			values = append(values, e.value)
+		}
+	}
+	return values, values != nil
+}
+
+// Keys はメッセージを除いたキーを出現順で返す
+func (k *Kvlm) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, e := range k.entries {
+		if !seen[e.key] {
+			seen[e.key] = true
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Message はヘッダーの後に続くメッセージを返す
+func (k *Kvlm) Message() string {
+	return k.message
+}
+
+func (k *Kvlm) Serialize() []byte {
+	var sb strings.Builder
+	for _, e := range k.entries {
+		sb.WriteString(e.key)
+		sb.WriteByte(' ')
+		// 2行目以降は先頭に空白を付けて続きの行であることを表す
+		sb.WriteString(strings.ReplaceAll(e.value, "\n", "\n "))
+		sb.WriteByte('\n')
+	}
+	if k.hasMessage {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(k.message)
+	return []byte(sb.String())
+}
+
+// ParseKvlm はコミットやタグのヘッダーとメッセージを解釈する
+// ヘッダーとして解釈できない行以降は区切りの空行がないメッセージとして扱い、元のバイト列に戻せるようにする
+func ParseKvlm(raw []byte) *Kvlm {
+	kvlm := NewKvlm()
+	pos := 0
+	for pos < len(raw) {
+		if raw[pos] == '\n' {
+			kvlm.message = string(raw[pos+1:])
+			kvlm.hasMessage = true
+			return kvlm
+		}
+		entry, next, ok := parseKvlmEntry(raw, pos)
+		if !ok {
+			break
+		}
+		kvlm.entries = append(kvlm.entries, entry)
+		pos = next
+	}
+	kvlm.message = string(raw[pos:])
+	return kvlm
+}
+
+// parseKvlmEntry はposから始まるヘッダーを続きの行を含めて解釈し、次の行の位置を返す
+// 空白を含まない行や改行で終わらない行の場合は偽を返す
+func parseKvlmEntry(raw []byte, pos int) (kvlmEntry, int, bool) {
+	eol := bytes.IndexByte(raw[pos:], '\n')
+	if eol < 0 {
+		return kvlmEntry{}, 0, false
+	}
+	line := raw[pos : pos+eol]
+	spc := bytes.IndexByte(line, ' ')
+	if spc <= 0 {
+		return kvlmEntry{}, 0, false
+	}
+
+	var value bytes.Buffer
+	value.Write(line[spc+1:])
+	next := pos + eol + 1
+	for next < len(raw) && raw[next] == ' ' {
+		eol := bytes.IndexByte(raw[next:], '\n')
+		if eol < 0 {
+			return kvlmEntry{}, 0, false
+		}
+		value.WriteByte('\n')
+		value.Write(raw[next+1 : next+eol])
+		next += eol + 1
+	}
+	return kvlmEntry{key: string(line[:spc]), value: value.String()}, next, true
+}
@@ -0,0 +1,199 @@
+package object
+
+import (
+	"bytes"
+	"testing"
+)
+
+const (
+	testCommit = "tree 29ff16c9c14e2652b22f8b78bb08a5a07930c147\n" +
+		"parent 206941306e8a8af65b66eaaaea388a7ae24d49a0\n" +
+		"author Thibault Polge <thibault@thb.lt> 1527025023 +0200\n" +
+		"committer Thibault Polge <thibault@thb.lt> 1527025044 +0200\n" +
+		"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+		" \n" +
+		" iQIzBAABCAAdFiEExwXquOM8bWb4Q2zVGxM2FxoLkGQFAlsEjZQACgkQGxM2FxoL\n" +
+		" =lgTX\n" +
+		" -----END PGP SIGNATURE-----\n" +
+		"\n" +
+		"Create first draft\n"
+
+	testMergeCommit = "tree 29ff16c9c14e2652b22f8b78bb08a5a07930c147\n" +
+		"parent 206941306e8a8af65b66eaaaea388a7ae24d49a0\n" +
+		"parent 3c8b2bf1a7c4e0e3d8a0a8f7e2d1c0b9a8f7e6d5\n" +
+		"author A U Thor <author@example.com> 1650000000 +0900\n" +
+		"committer C O Mitter <committer@example.com> 1650000000 -0000\n" +
+		"encoding ISO-8859-1\n" +
+		"mergetag object 3c8b2bf1a7c4e0e3d8a0a8f7e2d1c0b9a8f7e6d5\n" +
+		" type commit\n" +
+		" tag v1.0\n" +
+		" tagger T <t@example.com> 1650000000 +0900\n" +
+		" \n" +
+		" release\n" +
+		" -----BEGIN PGP SIGNATURE-----\n" +
+		" =abcd\n" +
+		" -----END PGP SIGNATURE-----\n" +
+		"x-extra value\n" +
+		"\n" +
+		"Merge tag 'v1.0'"
+)
+
+func TestParseKvlm(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		key     string
+		values  []string
+		message string
+	}{
+		{
+			name:    "gpgsig",
+			raw:     testCommit,
+			key:     "gpgsig",
+			values:  []string{"-----BEGIN PGP SIGNATURE-----\n\niQIzBAABCAAdFiEExwXquOM8bWb4Q2zVGxM2FxoLkGQFAlsEjZQACgkQGxM2FxoL\n=lgTX\n-----END PGP SIGNATURE-----"},
+			message: "Create first draft\n",
+		},
+		{
+			name:    "mergetag and message without trailing newline",
+			raw:     testMergeCommit,
+			key:     "parent",
+			values:  []string{"206941306e8a8af65b66eaaaea388a7ae24d49a0", "3c8b2bf1a7c4e0e3d8a0a8f7e2d1c0b9a8f7e6d5"},
+			message: "Merge tag 'v1.0'",
+		},
+		{
+			name:    "empty message",
+			raw:     "tree 29ff16c9c14e2652b22f8b78bb08a5a07930c147\n\n",
+			key:     "tree",
+			values:  []string{"29ff16c9c14e2652b22f8b78bb08a5a07930c147"},
+			message: "",
+		},
+		{
+			name:    "headers reach the end",
+			raw:     "tree 29ff16c9c14e2652b22f8b78bb08a5a07930c147\ngpgsig a\n b",
+			key:     "tree",
+			values:  []string{"29ff16c9c14e2652b22f8b78bb08a5a07930c147"},
+			message: "gpgsig a\n b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kvlm := ParseKvlm([]byte(tt.raw))
+			values, _ := kvlm.Get(tt.key)
+			if len(values) != len(tt.values) {
+				t.Fatalf("Get(%q) = %q, want %q", tt.key, values, tt.values)
+			}
+			for i := range values {
+				if values[i] != tt.values[i] {
+					t.Errorf("Get(%q)[%d] = %q, want %q", tt.key, i, values[i], tt.values[i])
+				}
+			}
+			if got := kvlm.Message(); got != tt.message {
+				t.Errorf("Message() = %q, want %q", got, tt.message)
+			}
+			if got := kvlm.Serialize(); string(got) != tt.raw {
+				t.Errorf("Serialize() = %q, want %q", got, tt.raw)
+			}
+		})
+	}
+}
+
+func TestCommitAccessors(t *testing.T) {
+	c := NewCommitObject([]byte(testMergeCommit))
+	if c.Encoding() != "ISO-8859-1" {
+		t.Errorf("Encoding() = %q", c.Encoding())
+	}
+	committer, err := c.Committer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committer.String() != "C O Mitter <committer@example.com> 1650000000 -0000" {
+		t.Errorf("Committer() = %q", committer)
+	}
+
+	b := &CommitBuilder{Tree: c.Tree(), Parents: c.Parents(), Committer: committer, Message: c.Message()}
+	if b.Author, err = c.Author(); err != nil {
+		t.Fatal(err)
+	}
+	signed := NewCommitObject([]byte(testCommit))
+	b.GPGSig = signed.GPGSig()
+	want := "tree 29ff16c9c14e2652b22f8b78bb08a5a07930c147\n" +
+		"parent 206941306e8a8af65b66eaaaea388a7ae24d49a0\n" +
+		"parent 3c8b2bf1a7c4e0e3d8a0a8f7e2d1c0b9a8f7e6d5\n" +
+		"author A U Thor <author@example.com> 1650000000 +0900\n" +
+		"committer C O Mitter <committer@example.com> 1650000000 -0000\n" +
+		"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+		" \n" +
+		" iQIzBAABCAAdFiEExwXquOM8bWb4Q2zVGxM2FxoLkGQFAlsEjZQACgkQGxM2FxoL\n" +
+		" =lgTX\n" +
+		" -----END PGP SIGNATURE-----\n" +
+		"\n" +
+		"Merge tag 'v1.0'"
+	if got, _ := b.Build().Serialize(); string(got) != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func FuzzParseKvlm(f *testing.F) {
+	for _, seed := range []string{
+		testCommit,
+		testMergeCommit,
+		"",
+		"\n",
+		"tree x\n\n",
+		"tree x",
+		"tree x\n y",
+		"no-space\n\nmessage",
+		" leading space\n\n",
+		"object x\ntype commit\ntag v1\ntagger T <t@e> 1 +0000\n\nmsg\n-----BEGIN PGP SIGNATURE-----\nx\n-----END PGP SIGNATURE-----\n",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		kvlm := ParseKvlm(raw)
+		got := kvlm.Serialize()
+		if !bytes.Equal(got, raw) {
+			t.Fatalf("Serialize(ParseKvlm(%q)) = %q", raw, got)
+		}
+	})
+}
+
+func FuzzKvlmSerialize(f *testing.F) {
+	f.Add("parent", "206941306e8a8af65b66eaaaea388a7ae24d49a0", "gpgsig", "-----BEGIN-----\n\nx\n-----END-----", "message\n")
+	f.Add("tree", "", "x", "\n", "")
+	f.Fuzz(func(t *testing.T, k1, v1, k2, v2, message string) {
+		for _, k := range []string{k1, k2} {
+			// キーは空白と改行を含まない空でない文字列に限る
+			if k == "" || bytes.ContainsAny([]byte(k), " \n") {
+				t.Skip()
+			}
+		}
+		kvlm := NewKvlm()
+		kvlm.Add(k1, v1)
+		kvlm.Add(k2, v2)
+		kvlm.Add("", message)
+
+		parsed := ParseKvlm(kvlm.Serialize())
+		if parsed.Message() != message {
+			t.Errorf("Message() = %q, want %q", parsed.Message(), message)
+		}
+		got1, _ := parsed.Get(k1)
+		got2, _ := parsed.Get(k2)
+		want1, _ := kvlm.Get(k1)
+		want2, _ := kvlm.Get(k2)
+		if !equalStrings(got1, want1) || !equalStrings(got2, want2) {
+			t.Errorf("Get() = %q %q, want %q %q", got1, got2, want1, want2)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package object
+
+import (
+	"strings"
+)
+
+// CleanupMessage はgitのcleanup=whitespaceと同じくメッセージの空白を整える
+// 行末の空白を取り除き、連続する空行を1行にまとめ、前後の空行を除いて改行で終わらせる
+// 空白だけのメッセージは空を返す
+func CleanupMessage(message string) string {
+	var sb strings.Builder
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, " \t\r\v\f")
+		if line == "" {
+			blank = sb.Len() > 0
+			continue
+		}
+		if blank {
+			sb.WriteByte('\n')
+			blank = false
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
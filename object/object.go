@@ -0,0 +1,122 @@
+// Package object はGitオブジェクトの型とシリアライズ処理を提供する
+package object
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+type ObjectType string
+
+const (
+	Commit = ObjectType("commit")
+	Tree   = ObjectType("tree")
+	Tag    = ObjectType("tag")
+	Blob   = ObjectType("blob")
+)
+
+var (
+	ObjectTypes = []ObjectType{Commit, Tree, Tag, Blob}
+
+	ErrUnknownType = errors.New("unknown object type")
+	ErrMalformed   = errors.New("malformed object")
+)
+
+func ConvertObjectType(target string) (ObjectType, bool) {
+	for _, t := range ObjectTypes {
+		if string(t) == target {
+			return ObjectType(target), true
+		}
+	}
+	return ObjectType(""), false
+}
+
+// loose objects
+// Gitではpackfileと呼ばれるloose objectsを
+// コンパイルしたような保存メカニズムがある
+// 複雑な処理のため、実装は省く
+type Object interface {
+	Serialize() ([]byte, error)
+	DeSerialize(data []byte) error
+	TypeHeader() ObjectType
+}
+
+// NewObject はシリアライズされた中身からオブジェクトを作成する
+// ツリーに含まれるIDの長さはfに従う
+func NewObject(f oid.Format, typeHeader ObjectType, raw []byte) (Object, error) {
+	switch typeHeader {
+	case Commit:
+		return NewCommitObject(raw), nil
+	case Tree:
+		return NewTreeObject(f, raw)
+	case Tag:
+		return NewTagObject(raw), nil
+	case Blob:
+		return NewBlobObject(raw), nil
+	}
+	return nil, ErrUnknownType
+}
+
+// Encode はオブジェクトを "TYPE SIZE\x00DATA" の形式に変換する
+func Encode(o Object) ([]byte, error) {
+	data, err := o.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeRaw(o.TypeHeader(), data), nil
+}
+
+// EncodeRaw は型と中身から "TYPE SIZE\x00DATA" の形式のバイト列を作る
+func EncodeRaw(t ObjectType, data []byte) []byte {
+	return []byte(fmt.Sprintf("%s %d\x00%s", t, len(data), data))
+}
+
+// Hash はオブジェクトのIDを計算する
+func Hash(f oid.Format, o Object) (string, error) {
+	data, err := o.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return HashRaw(f, o.TypeHeader(), data), nil
+}
+
+// HashRaw は型と中身からIDを計算する
+func HashRaw(f oid.Format, t ObjectType, data []byte) string {
+	return f.Sum(EncodeRaw(t, data))
+}
+
+// Decode は "TYPE SIZE\x00DATA" の形式からオブジェクトの型と中身を取り出す
+func Decode(raw []byte) (ObjectType, []byte, error) {
+	// 00000000  63 6f 6d 6d 69 74 20 31  30 38 36 00 74 72 65 65  |commit 1086.tree|
+	// 00000010  20 32 39 66 66 31 36 63  39 63 31 34 65 32 36 35  | 29ff16c9c14e265|
+	// 00000020  32 62 32 32 66 38 62 37  38 62 62 30 38 61 35 61  |2b22f8b78bb08a5a|
+
+	// 最初の'commit'の位置を探す
+	x := bytes.Index(raw, []byte(" "))
+	if x < 0 {
+		return "", nil, fmt.Errorf("%w: missing type", ErrMalformed)
+	}
+	typeHeader, ok := ConvertObjectType(string(raw[:x]))
+	if !ok {
+		return "", nil, fmt.Errorf("%w tag=%s", ErrUnknownType, raw[:x])
+	}
+
+	// オブジェクトのサイズを読み込む
+	y := bytes.Index(raw[x:], []byte("\x00"))
+	if y < 0 {
+		return "", nil, fmt.Errorf("%w: missing size", ErrMalformed)
+	}
+	size, err := strconv.Atoi(string(raw[x+1 : x+y]))
+	if err != nil {
+		return "", nil, err
+	}
+	if size != len(raw)-x-y-1 {
+		return "", nil, fmt.Errorf("%w: bad length", ErrMalformed)
+	}
+
+	return typeHeader, raw[x+y+1:], nil
+}
@@ -0,0 +1,97 @@
+package object
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature はコミットの作者やコミッター、タグの作成者と日時
+type Signature struct {
+	Name  string
+	Email string
+	// When のタイムゾーンは記録された時差を保持する
+	When time.Time
+}
+
+var tzReg = regexp.MustCompile(`^[+-][0-9]{4}$`)
+
+// NewSignature はnameとemailのwhenの時点のSignatureを作成する
+// gitと同じく前後の空白や記号と、ヘッダーを壊す '<'、'>'、改行は取り除く
+func NewSignature(name, email string, when time.Time) *Signature {
+	return &Signature{Name: sanitizeIdent(name), Email: sanitizeIdent(email), When: when}
+}
+
+func sanitizeIdent(s string) string {
+	s = strings.TrimFunc(s, func(c rune) bool {
+		return c <= ' ' || strings.ContainsRune(".,:;<>\"\\'", c)
+	})
+	return strings.Map(func(c rune) rune {
+		if c == '<' || c == '>' || c == '\n' {
+			return -1
+		}
+		return c
+	}, s)
+}
+
+// ParseSignature は "名前 <メール> 時刻 タイムゾーン" の形式の値を解釈する
+func ParseSignature(value string) (*Signature, error) {
+	lt := strings.IndexByte(value, '<')
+	if lt < 0 {
+		return nil, fmt.Errorf("%w: missing email in %q", ErrMalformed, value)
+	}
+	gt := strings.IndexByte(value[lt:], '>')
+	if gt < 0 {
+		return nil, fmt.Errorf("%w: missing email in %q", ErrMalformed, value)
+	}
+	gt += lt
+	s := &Signature{
+		Name:  strings.TrimRight(value[:lt], " "),
+		Email: value[lt+1 : gt],
+	}
+
+	// 日時は最後の '>' の後に続く
+	fields := strings.Fields(value[strings.LastIndexByte(value, '>')+1:])
+	if len(fields) != 2 || !tzReg.MatchString(fields[1]) {
+		return nil, fmt.Errorf("%w: invalid date in %q", ErrMalformed, value)
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date in %q", ErrMalformed, value)
+	}
+	tz := fields[1]
+	hours, _ := strconv.Atoi(tz[1:3])
+	minutes, _ := strconv.Atoi(tz[3:5])
+	offset := (hours*60 + minutes) * 60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	// "-0000" のように時差から復元できない表記を保つため、ゾーン名に元の表記を使う
+	s.When = time.Unix(sec, 0).In(time.FixedZone(tz, offset))
+	return s, nil
+}
+
+// Timezone は "+0900" の形式のタイムゾーンを返す
+func (s *Signature) Timezone() string {
+	name, offset := s.When.Zone()
+	if tzReg.MatchString(name) {
+		return name
+	}
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset/60%60)
+}
+
+// Ident は "名前 <メール>" を返す
+func (s *Signature) Ident() string {
+	return fmt.Sprintf("%s <%s>", s.Name, s.Email)
+}
+
+// String はgitのヘッダーと同じ "名前 <メール> 時刻 タイムゾーン" の形式で返す
+func (s *Signature) String() string {
+	return fmt.Sprintf("%s %d %s", s.Ident(), s.When.Unix(), s.Timezone())
+}
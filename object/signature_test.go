@@ -0,0 +1,161 @@
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		timezone string
+		offset   int
+	}{
+		{name: "positive", value: "A U Thor <author@example.com> 1700000000 +0900", timezone: "+0900", offset: 9 * 3600},
+		{name: "negative zero", value: "A U Thor <author@example.com> 1700000000 -0000", timezone: "-0000", offset: 0},
+		{name: "odd minutes", value: "A U Thor <author@example.com> 1700000000 +0530", timezone: "+0530", offset: 5*3600 + 30*60},
+		{name: "negative odd minutes", value: "A U Thor <author@example.com> 1700000000 -0330", timezone: "-0330", offset: -(3*3600 + 30*60)},
+		{name: "far east", value: "A U Thor <author@example.com> 1700000000 +1400", timezone: "+1400", offset: 14 * 3600},
+		{name: "name with greater-than", value: "x > y <e@example.com> 1700000000 +0000", timezone: "+0000", offset: 0},
+		{name: "empty email", value: "A U Thor <> 0 +0000", timezone: "+0000", offset: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSignature(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.String(); got != tt.value {
+				t.Errorf("String() = %q, want %q", got, tt.value)
+			}
+			if got := s.Timezone(); got != tt.timezone {
+				t.Errorf("Timezone() = %q, want %q", got, tt.timezone)
+			}
+			if _, offset := s.When.Zone(); offset != tt.offset {
+				t.Errorf("offset = %d, want %d", offset, tt.offset)
+			}
+		})
+	}
+}
+
+func TestParseSignatureName(t *testing.T) {
+	tests := []struct {
+		value       string
+		name, email string
+	}{
+		{value: "x > y <e@example.com> 1700000000 +0000", name: "x > y", email: "e@example.com"},
+		{value: "A U Thor  <a@example.com> 1700000000 +0000", name: "A U Thor", email: "a@example.com"},
+		{value: "<a@example.com> 1700000000 +0000", name: "", email: "a@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			s, err := ParseSignature(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Name != tt.name || s.Email != tt.email {
+				t.Errorf("ParseSignature() = %q <%q>, want %q <%q>", s.Name, s.Email, tt.name, tt.email)
+			}
+		})
+	}
+}
+
+func TestParseSignatureError(t *testing.T) {
+	for _, value := range []string{
+		"A U Thor 1700000000 +0000",
+		"A U Thor <a@example.com 1700000000 +0000",
+		"A U Thor <a@example.com> 1700000000",
+		"A U Thor <a@example.com> 1700000000 +900",
+		"A U Thor <a@example.com> now +0900",
+	} {
+		if _, err := ParseSignature(value); err == nil {
+			t.Errorf("ParseSignature(%q) error = nil", value)
+		}
+	}
+}
+
+func TestNewSignature(t *testing.T) {
+	// 期待する結果は GIT_AUTHOR_NAME と GIT_AUTHOR_EMAIL に指定して git commit-tree で作成したコミットのauthor
+	tests := []struct {
+		name, email string
+		want        string
+	}{
+		{name: "a <b> c", email: "e<@x>", want: "a b c <e@x>"},
+		{name: "x > y", email: "e@x", want: "x  y <e@x>"},
+		{name: "<lead", email: "e@x", want: "lead <e@x>"},
+		{name: "J. R.", email: " <e@x>. ", want: "J. R <e@x>"},
+		{name: " ,x; ", email: "e@x", want: "x <e@x>"},
+		{name: "it's", email: "e@x", want: "it's <e@x>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSignature(tt.name, tt.email, time.Unix(0, 0))
+			if got := s.Ident(); got != tt.want {
+				t.Errorf("Ident() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilderHash(t *testing.T) {
+	author, err := ParseSignature("A U Thor <author@example.com> 1700000000 +0530")
+	if err != nil {
+		t.Fatal(err)
+	}
+	committer := NewSignature("C O Mitter", "committer@example.com", time.Unix(1700003600, 0).In(time.FixedZone("", 0)))
+	negativeZero, err := ParseSignature("C O Mitter <committer@example.com> 1700003600 -0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger := NewSignature("T Agger", "tagger@example.com", time.Unix(1700007200, 0).In(time.FixedZone("", -(3*3600+30*60))))
+
+	commit := &CommitBuilder{
+		Tree:      "12ed70cbd523efd5f5209b9d6fb20bc9fd7fbb4c",
+		Parents:   []string{"7fc2c05dbc7ce63a21ff3b43040d14ef946302f4", "616a7e7b8fbef1382b616ed5b221fd29090eb38a"},
+		Author:    author,
+		Committer: committer,
+		Message:   "subject\n\nbody\n",
+	}
+	withNegativeZero := *commit
+	withNegativeZero.Committer = negativeZero
+
+	// 期待するshaは同じ内容で git commit-tree と git tag -a で作成したオブジェクトのもの
+	// "-0000" のコミットはgitが作成時に "+0000" にするため、git hash-object -t commit で求めた
+	tests := []struct {
+		name   string
+		object Object
+		want   string
+	}{
+		{name: "commit", object: commit.Build(), want: "1f6590a0265dd3ece02cab7fc430c1afcb802045"},
+		{name: "commit with -0000", object: withNegativeZero.Build(), want: "da6c88d92f985c8995b5b0146a6f726ce74c8f16"},
+		{
+			name: "tag",
+			object: (&TagBuilder{
+				Target:     "1f6590a0265dd3ece02cab7fc430c1afcb802045",
+				TargetType: Commit,
+				Name:       "v1",
+				Tagger:     tagger,
+				Message:    "release\n",
+			}).Build(),
+			want: "b268f42b26985050ceb116e61ef9654fde307c17",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Hash(oid.SHA1, tt.object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Hash() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
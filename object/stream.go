@@ -0,0 +1,52 @@
+package object
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// EncodeHeader は "TYPE SIZE\x00" の形式のヘッダーを作る
+func EncodeHeader(t ObjectType, size int64) []byte {
+	return []byte(fmt.Sprintf("%s %d\x00", t, size))
+}
+
+// ReadHeader は "TYPE SIZE\x00" の形式のヘッダーを読み込む
+// 読み込んだ後のbrは中身の先頭を指す
+func ReadHeader(br *bufio.Reader) (ObjectType, int64, error) {
+	name, err := br.ReadString(' ')
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: missing type", ErrMalformed)
+	}
+	t, ok := ConvertObjectType(name[:len(name)-1])
+	if !ok {
+		return "", 0, fmt.Errorf("%w tag=%s", ErrUnknownType, name[:len(name)-1])
+	}
+	sizeStr, err := br.ReadString(0)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: missing size", ErrMalformed)
+	}
+	size, err := strconv.ParseInt(sizeStr[:len(sizeStr)-1], 10, 64)
+	if err != nil || size < 0 {
+		return "", 0, fmt.Errorf("%w: bad size", ErrMalformed)
+	}
+	return t, size, nil
+}
+
+// HashStream はrからsizeバイトを読み込みながらIDを計算する
+func HashStream(f oid.Format, t ObjectType, size int64, r io.Reader) (string, error) {
+	h := f.New()
+	h.Write(EncodeHeader(t, size))
+	n, err := io.Copy(h, io.LimitReader(r, size))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("%w: short read %d/%d", ErrMalformed, n, size)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
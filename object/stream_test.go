@@ -0,0 +1,78 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestHashStream(t *testing.T) {
+	data := []byte("hello\n")
+	for _, f := range []oid.Format{oid.SHA1, oid.SHA256} {
+		want := HashRaw(f, Blob, data)
+
+		// 1バイトずつしか読めない場合も同じIDになる
+		got, err := HashStream(f, Blob, int64(len(data)), iotest.OneByteReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: HashStream() = %s, want %s", f, got, want)
+		}
+
+		// sizeより先は読み込まない
+		r := strings.NewReader(string(data) + "trailing")
+		if got, err := HashStream(f, Blob, int64(len(data)), r); err != nil || got != want {
+			t.Errorf("%s: HashStream() with trailing data = %s, %v, want %s", f, got, err, want)
+		}
+		if r.Len() != len("trailing") {
+			t.Errorf("%s: HashStream() left %d bytes, want %d", f, r.Len(), len("trailing"))
+		}
+
+		// sizeより短い場合はエラーとする
+		if _, err := HashStream(f, Blob, int64(len(data))+1, bytes.NewReader(data)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: HashStream() with short data error = %v, want ErrMalformed", f, err)
+		}
+		if _, err := HashStream(f, Blob, 1, iotest.ErrReader(errors.New("boom"))); err == nil {
+			t.Errorf("%s: HashStream() ignored a read error", f)
+		}
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		raw     string
+		typ     ObjectType
+		size    int64
+		wantErr error
+	}{
+		{raw: "blob 6\x00hello\n", typ: Blob, size: 6},
+		{raw: "tree 0\x00", typ: Tree, size: 0},
+		{raw: "blob", wantErr: ErrMalformed},
+		{raw: "blob 6", wantErr: ErrMalformed},
+		{raw: "blob -1\x00", wantErr: ErrMalformed},
+		{raw: "blob x\x00", wantErr: ErrMalformed},
+		{raw: "bogus 1\x00a", wantErr: ErrUnknownType},
+	}
+	for _, tt := range tests {
+		br := bufio.NewReader(strings.NewReader(tt.raw))
+		typ, size, err := ReadHeader(br)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadHeader(%q) error = %v, want %v", tt.raw, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || typ != tt.typ || size != tt.size {
+			t.Errorf("ReadHeader(%q) = %s %d %v, want %s %d", tt.raw, typ, size, err, tt.typ, tt.size)
+		}
+		if got := string(EncodeHeader(typ, size)); !strings.HasPrefix(tt.raw, got) {
+			t.Errorf("EncodeHeader(%s, %d) = %q", typ, size, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package object
+
+import (
+	"strings"
+)
+
+type TagObject struct {
+	kvlmObject
+}
+
+func NewTagObject(raw []byte) *TagObject {
+	o := &TagObject{}
+	o.DeSerialize(raw)
+	return o
+}
+
+func (o *TagObject) TypeHeader() ObjectType {
+	return Tag
+}
+
+// Target はタグが指すオブジェクトのshaを返す
+func (o *TagObject) Target() string {
+	return o.header("object")
+}
+
+// TargetType はタグが指すオブジェクトの型を返す
+func (o *TagObject) TargetType() ObjectType {
+	return ObjectType(o.header("type"))
+}
+
+// Name はタグの名前を返す
+func (o *TagObject) Name() string {
+	return o.header("tag")
+}
+
+func (o *TagObject) Tagger() (*Signature, error) {
+	return o.signature("tagger")
+}
+
+// Message は署名を除いたタグのメッセージを返す
+func (o *TagObject) Message() string {
+	message := o.kvlm.Message()
+	return message[:signatureStart(message)]
+}
+
+// GPGSig はメッセージの末尾に付けられた署名を返す 署名されていない場合は空を返す
+func (o *TagObject) GPGSig() string {
+	message := o.kvlm.Message()
+	return message[signatureStart(message):]
+}
+
+// Payload は署名の対象となる、末尾の署名を除いたタグを返す
+func (o *TagObject) Payload() []byte {
+	raw := o.kvlm.Serialize()
+	return raw[:len(raw)-len(o.GPGSig())]
+}
+
+// signatureHeaders は署名の始まりを表す行
+var signatureHeaders = []string{
+	"-----BEGIN PGP SIGNATURE-----",
+	"-----BEGIN PGP MESSAGE-----",
+	"-----BEGIN SIGNED MESSAGE-----",
+	"-----BEGIN SSH SIGNATURE-----",
+}
+
+// signatureStart はメッセージの末尾の署名の開始位置を返す 署名がなければメッセージの長さを返す
+// gitと同じく行頭にある最後の署名の始まりを探す
+func signatureStart(message string) int {
+	match := len(message)
+	for start := 0; start < len(message); {
+		for _, h := range signatureHeaders {
+			if strings.HasPrefix(message[start:], h) {
+				match = start
+			}
+		}
+		i := strings.IndexByte(message[start:], '\n')
+		if i < 0 {
+			break
+		}
+		start += i + 1
+	}
+	return match
+}
+
+// TagBuilder はgitと同じ形式のタグを作成する
+type TagBuilder struct {
+	Target     string
+	TargetType ObjectType
+	Name       string
+	Tagger     *Signature
+	Message    string
+	// GPGSig はメッセージの後に続けて書き込まれる
+	GPGSig string
+}
+
+// Build はヘッダーをgitと同じ順に並べたタグを作成する
+func (b *TagBuilder) Build() *TagObject {
+	kvlm := NewKvlm()
+	kvlm.Add("object", b.Target)
+	kvlm.Add("type", string(b.TargetType))
+	kvlm.Add("tag", b.Name)
+	if b.Tagger != nil {
+		kvlm.Add("tagger", b.Tagger.String())
+	}
+	kvlm.Add("", b.Message+b.GPGSig)
+	return &TagObject{kvlmObject{kvlm: kvlm}}
+}
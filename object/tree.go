@@ -0,0 +1,148 @@
+package object
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// 複数のファイルをまとめて格納するオブジェクト
+type TreeObject struct {
+	format oid.Format
+	items  []*TreeLeafObject
+}
+
+func NewTreeObject(f oid.Format, raw []byte) (*TreeObject, error) {
+	o := &TreeObject{format: f}
+	return o, o.DeSerialize(raw)
+}
+
+// BuildTree はitemsをgitと同じ順に並べたツリーを作成する
+// ディレクトリは名前の末尾に "/" があるものとして比較する
+func BuildTree(f oid.Format, items []*TreeLeafObject) *TreeObject {
+	sorted := make([]*TreeLeafObject, len(items))
+	copy(sorted, items)
+	sortKey := func(l *TreeLeafObject) string {
+		if l.Type() == Tree {
+			return l.path + "/"
+		}
+		return l.path
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sortKey(sorted[i]) < sortKey(sorted[j])
+	})
+	return &TreeObject{format: f, items: sorted}
+}
+
+func (o *TreeObject) Serialize() ([]byte, error) {
+	var sb strings.Builder
+	for _, i := range o.items {
+		sb.WriteString(i.mode)
+		sb.WriteString(" ")
+		sb.WriteString(i.path)
+		sb.WriteString("\x00")
+		buf, err := hex.DecodeString(i.sha)
+		if err != nil || len(buf) != o.format.Size() {
+			return nil, fmt.Errorf("invalid sha %s path=%s", i.sha, i.path)
+		}
+		sb.Write(buf)
+	}
+	return []byte(sb.String()), nil
+}
+
+func (o *TreeObject) DeSerialize(data []byte) error {
+	items, err := ParseTree(o.format, data)
+	if err != nil {
+		return err
+	}
+	o.items = items
+	return nil
+}
+
+func (o *TreeObject) TypeHeader() ObjectType {
+	return Tree
+}
+
+// Items はツリーに含まれるエントリを返す
+func (o *TreeObject) Items() []*TreeLeafObject {
+	return o.items
+}
+
+type TreeLeafObject struct {
+	mode string // ファイルモード
+	path string // ファイルのパス
+	sha  string // バイナリエンコーディングされたobjectのID(sha1は20byte、sha256は32byte)
+}
+
+func NewTreeLeafObject(mode, path, sha string) *TreeLeafObject {
+	return &TreeLeafObject{
+		mode: mode,
+		path: path,
+		sha:  sha,
+	}
+}
+
+func (l *TreeLeafObject) Mode() string {
+	return l.mode
+}
+
+func (l *TreeLeafObject) Path() string {
+	return l.path
+}
+
+func (l *TreeLeafObject) Sha() string {
+	return l.sha
+}
+
+// Type はモードからエントリの指すオブジェクトの種類を返す
+// オブジェクトを読み込まずに判定できる
+func (l *TreeLeafObject) Type() ObjectType {
+	switch strings.TrimLeft(l.mode, "0") {
+	case "40000":
+		return Tree
+	case "160000":
+		return Commit
+	}
+	return Blob
+}
+
+func ParseTree(f oid.Format, raw []byte) (list []*TreeLeafObject, err error) {
+	var (
+		pos  = 0
+		max  = len(raw)
+		leaf *TreeLeafObject
+	)
+
+	for pos < max {
+		pos, leaf, err = ParseLeaf(f, raw, pos)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, leaf)
+	}
+
+	return list, nil
+}
+
+func ParseLeaf(f oid.Format, raw []byte, start int) (int, *TreeLeafObject, error) {
+	// modeの位置を取得
+	x := bytes.Index(raw[start:], []byte(" ")) + start
+	if !(x-start == 5 || x-start == 6) {
+		return -1, nil, errors.New("invalid leaf")
+	}
+	mode := string(raw[start:x])
+
+	y := bytes.Index(raw[x:], []byte("\x00")) + x
+	if y < x || y+1+f.Size() > len(raw) {
+		return -1, nil, errors.New("invalid leaf")
+	}
+	path := string(raw[x+1 : y])
+
+	end := y + 1 + f.Size()
+	return end, NewTreeLeafObject(mode, path, hex.EncodeToString(raw[y+1:end])), nil
+}
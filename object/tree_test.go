@@ -0,0 +1,56 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestTreeSHA256(t *testing.T) {
+	// 期待するIDは git init --object-format=sha256 で作成したリポジトリの git rev-parse HEAD^{tree}
+	const (
+		blob    = "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4"
+		subtree = "6101b778c5bc3277f57f668fe2b7bf7b279ff2789791e2ac8f6ab8a3f175ff6a"
+		want    = "1647badc52b844d1077580cc0a46149005265a4621bb516e86fd681880635ed0"
+	)
+	tree := BuildTree(oid.SHA256, []*TreeLeafObject{
+		NewTreeLeafObject("40000", "d", subtree),
+		NewTreeLeafObject("100644", "a.txt", blob),
+	})
+	sha, err := Hash(oid.SHA256, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != want {
+		t.Errorf("Hash() = %s, want %s", sha, want)
+	}
+
+	data, err := tree.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 各エントリのIDは32バイト
+	if n := len("100644 a.txt\x00") + len("40000 d\x00") + 2*32; len(data) != n {
+		t.Errorf("Serialize() returned %d bytes, want %d", len(data), n)
+	}
+	parsed, err := NewTreeObject(oid.SHA256, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := parsed.Items()
+	if len(items) != 2 ||
+		items[0].Path() != "a.txt" || items[0].Sha() != blob || items[0].Type() != Blob ||
+		items[1].Path() != "d" || items[1].Sha() != subtree || items[1].Type() != Tree {
+		t.Errorf("Items() = %v", items)
+	}
+
+	// sha1のツリーとしては解釈できない長さになる
+	if _, err := NewTreeObject(oid.SHA1, data); err == nil {
+		t.Error("NewTreeObject(sha1) succeeded on a sha256 tree")
+	}
+	// 32バイトでないIDは書き込めない
+	short := BuildTree(oid.SHA256, []*TreeLeafObject{NewTreeLeafObject("100644", "a", blob[:40])})
+	if _, err := short.Serialize(); err == nil {
+		t.Error("Serialize() accepted a 20-byte id in a sha256 tree")
+	}
+}
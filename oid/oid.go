@@ -0,0 +1,73 @@
+// Package oid はオブジェクトIDを計算するハッシュアルゴリズムを扱う
+package oid
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// Format はリポジトリのオブジェクトフォーマット(extensions.objectFormat)
+type Format string
+
+const (
+	SHA1   = Format("sha1")
+	SHA256 = Format("sha256")
+)
+
+var (
+	Formats = []Format{SHA1, SHA256}
+)
+
+func ParseFormat(name string) (Format, bool) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(name) {
+			return f, true
+		}
+	}
+	return Format(""), false
+}
+
+// New はハッシュの計算器を作成する
+func (f Format) New() hash.Hash {
+	if f == SHA256 {
+		return sha256.New()
+	}
+	return sha1.New()
+}
+
+// Size はバイナリ表現のハッシュの長さを返す
+func (f Format) Size() int {
+	if f == SHA256 {
+		return sha256.Size
+	}
+	return sha1.Size
+}
+
+// HexSize は16進数表現のハッシュの長さを返す
+func (f Format) HexSize() int {
+	return f.Size() * 2
+}
+
+// Sum はdataのハッシュを16進数表現で返す
+func (f Format) Sum(data []byte) string {
+	h := f.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Zero はすべて0のIDを返す
+func (f Format) Zero() string {
+	return strings.Repeat("0", f.HexSize())
+}
+
+// IsValid はidがこのフォーマットの完全なIDか判定する
+func (f Format) IsValid(id string) bool {
+	if len(id) != f.HexSize() {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package oid
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format   Format
+		size     int
+		emptySum string
+	}{
+		{format: SHA1, size: 20, emptySum: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{format: SHA256, size: 32, emptySum: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			if got := tt.format.Size(); got != tt.size {
+				t.Errorf("Size() = %d, want %d", got, tt.size)
+			}
+			if got := tt.format.HexSize(); got != tt.size*2 {
+				t.Errorf("HexSize() = %d, want %d", got, tt.size*2)
+			}
+			if got := tt.format.Sum(nil); got != tt.emptySum {
+				t.Errorf("Sum(nil) = %s, want %s", got, tt.emptySum)
+			}
+			zero := tt.format.Zero()
+			if len(zero) != tt.size*2 || !tt.format.IsValid(zero) {
+				t.Errorf("Zero() = %s is not a valid id", zero)
+			}
+			if !tt.format.IsValid(tt.emptySum) {
+				t.Errorf("IsValid(%s) = false", tt.emptySum)
+			}
+		})
+	}
+
+	// 長さが異なるIDや16進数でないIDは不正
+	for _, id := range []string{
+		"da39a3ee5e6b4b0d3255bfef95601890afd8070",
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"za39a3ee5e6b4b0d3255bfef95601890afd80709",
+	} {
+		if SHA1.IsValid(id) {
+			t.Errorf("SHA1.IsValid(%s) = true", id)
+		}
+	}
+	if SHA256.IsValid("da39a3ee5e6b4b0d3255bfef95601890afd80709") {
+		t.Error("SHA256.IsValid(sha1 id) = true")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"sha1": SHA1, "SHA256": SHA256} {
+		if f, ok := ParseFormat(name); !ok || f != want {
+			t.Errorf("ParseFormat(%s) = %s, %v, want %s", name, f, ok, want)
+		}
+	}
+	if f, ok := ParseFormat("md5"); ok {
+		t.Errorf("ParseFormat(md5) = %s, true, want false", f)
+	}
+}
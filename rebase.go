@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type RebaseCommand struct {
+	*flag.FlagSet
+	onto     string
+	todo     string
+	cont     bool
+	abort    bool
+	skip     bool
+	upstream string
+}
+
+func NewRebaseCommand(args []string) *RebaseCommand {
+	c := &RebaseCommand{}
+	c.FlagSet = flag.NewFlagSet("rebase", flag.ExitOnError)
+	c.FlagSet.StringVar(&c.onto, "onto", "", "Starting point at which to create the new commits")
+	c.FlagSet.StringVar(&c.todo, "todo", "", "Read the todo list (pick, reword, squash, fixup, drop, exec) from the given file")
+	c.FlagSet.BoolVar(&c.cont, "continue", false, "Restart the rebasing process after having resolved a merge conflict")
+	c.FlagSet.BoolVar(&c.abort, "abort", false, "Abort the rebase operation and reset HEAD to the original branch")
+	c.FlagSet.BoolVar(&c.skip, "skip", false, "Restart the rebasing process by skipping the current patch")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go rebase [--onto NEWBASE] [--todo FILE] UPSTREAM\n")
+		fmt.Fprint(o, "       wyag-go rebase (--continue | --skip | --abort)\n")
+		fmt.Fprint(o, "\tReapply commits on top of another base tip\n")
+	}
+
+	// gitと同じくUPSTREAMの後にもフラグを指定できるようにする
+	c.Parse(args)
+	if rest := c.Args(); len(rest) > 0 {
+		c.upstream = rest[0]
+		c.Parse(rest[1:])
+	}
+	n := 0
+	for _, b := range []bool{c.cont, c.abort, c.skip} {
+		if b {
+			n++
+		}
+	}
+	if len(c.Args()) > 0 || n > 1 || n == 1 && c.upstream != "" || n == 0 && c.upstream == "" {
+		c.Usage()
+		os.Exit(1)
+	}
+	return c
+}
+
+func (c *RebaseCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	var res *repository.RebaseResult
+	switch {
+	case c.abort:
+		return repo.AbortRebase()
+	case c.cont:
+		res, err = repo.ContinueRebase()
+	case c.skip:
+		res, err = repo.SkipRebase()
+	default:
+		opts := repository.RebaseOptions{Onto: c.onto}
+		if c.todo != "" {
+			opts.EditTodo = func(string) (string, error) {
+				data, err := os.ReadFile(c.todo)
+				return string(data), err
+			}
+		}
+		res, err = repo.StartRebase(c.upstream, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	branch := strings.TrimPrefix(res.Branch, "refs/heads/")
+	switch {
+	case res.UpToDate:
+		fmt.Fprintf(os.Stdout, "Current branch %s is up to date.\n", branch)
+	case res.Stopped == nil:
+		name := res.Branch
+		if name == "" {
+			name = "detached HEAD"
+		}
+		fmt.Fprintf(os.Stdout, "Successfully rebased and updated %s.\n", name)
+	default:
+		if err := c.printStop(repo, res); err != nil {
+			return err
+		}
+		return ExitCode(1)
+	}
+	return nil
+}
+
+// printStop は止まった理由と再開の方法を表示する
+func (c *RebaseCommand) printStop(repo *repository.Repository, res *repository.RebaseResult) error {
+	step := res.Stopped
+	if step.Action == repository.RebaseExec {
+		fmt.Fprintf(os.Stderr, "warning: execution failed: %s\n", step.Command)
+		fmt.Fprint(os.Stderr, "You can fix the problem, and then run\n\n  wyag-go rebase --continue\n\n")
+		return nil
+	}
+
+	o, err := repo.ReadObject(step.Commit)
+	if err != nil {
+		return err
+	}
+	abbrev, err := repo.Abbrev(step.Commit, repository.DefaultAbbrev)
+	if err != nil {
+		return err
+	}
+	subject := o.(*object.CommitObject).Subject()
+	if res.Merge != nil && !res.Merge.Clean() {
+		for _, msg := range res.Merge.Messages {
+			fmt.Fprintln(os.Stdout, msg)
+		}
+		fmt.Fprintf(os.Stderr, "error: could not apply %s... %s\n", abbrev, subject)
+		fmt.Fprint(os.Stderr, "hint: Resolve all conflicts manually, mark them as resolved with\n")
+		fmt.Fprint(os.Stderr, "hint: \"wyag-go add <conflicted_files>\", then run \"wyag-go rebase --continue\".\n")
+		fmt.Fprint(os.Stderr, "hint: You can instead skip this commit: run \"wyag-go rebase --skip\".\n")
+		fmt.Fprint(os.Stderr, "hint: To abort and get back to the state before \"wyag-go rebase\", run \"wyag-go rebase --abort\".\n")
+		return nil
+	}
+	// エディタを開かないため、rewordはメッセージを編集できるように止まる
+	fmt.Fprintf(os.Stdout, "Stopped at %s... %s\n", abbrev, subject)
+	fmt.Fprint(os.Stdout, "Edit the commit message in .git/MERGE_MSG, and then run\n\n  wyag-go rebase --continue\n\n")
+	return nil
+}
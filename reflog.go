@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ReflogCommand struct {
+	*flag.FlagSet
+	ref string
+}
+
+func NewReflogCommand(args []string) *ReflogCommand {
+	c := &ReflogCommand{}
+	c.FlagSet = flag.NewFlagSet("reflog", flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go reflog [show] [REF]\n")
+		fmt.Fprint(o, "\tShow the history of updates to a reference\n")
+	}
+
+	c.Parse(args)
+	rest := c.Args()
+	if len(rest) > 0 && rest[0] == "show" {
+		rest = rest[1:]
+	}
+	if len(rest) > 1 {
+		c.Usage()
+		os.Exit(1)
+	}
+	c.ref = "HEAD"
+	if len(rest) == 1 {
+		c.ref = rest[0]
+	}
+
+	return c
+}
+
+func (c *ReflogCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	ref, err := repo.FullRefName(c.ref)
+	if err != nil {
+		return err
+	}
+	entries, err := repo.Reflog(ref)
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		abbrev, err := repo.Abbrev(e.New, repository.DefaultAbbrev)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s %s@{%d}: %s\n", abbrev, c.ref, i, e.Message)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package refs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore はgitdir配下のファイルとpacked-refsで参照を管理する
+type FileStore struct {
+	gitdir string
+}
+
+func NewFileStore(gitdir string) *FileStore {
+	return &FileStore{gitdir: gitdir}
+}
+
+func (s *FileStore) ReadRef(name string) (string, error) {
+	p := filepath.Join(s.gitdir, name)
+	b, err := os.ReadFile(p)
+	if err == nil {
+		return strings.TrimRight(string(b), "\n"), nil
+	}
+	// refs/headsのようなディレクトリは参照ではない
+	if fi, serr := os.Stat(p); !os.IsNotExist(err) && (serr != nil || !fi.IsDir()) {
+		return "", err
+	}
+
+	// gcなどでまとめられた参照を探す
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return "", err
+	}
+	if sha, ok := packed[name]; ok {
+		return sha, nil
+	}
+	return "", fmt.Errorf("%w ref=%s", ErrNotExist, name)
+}
+
+func (s *FileStore) WriteRef(name, value string) error {
+	p := filepath.Join(s.gitdir, name)
+	if err := os.MkdirAll(filepath.Dir(p), os.FileMode(0755)); err != nil {
+		return err
+	}
+
+	// 書き込み途中の参照を読まれないようにlockファイルに書いてから置き換える
+	lock := p + ".lock"
+	f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(0644))
+	if err != nil {
+		return fmt.Errorf("unable to lock ref=%s error=%w", name, err)
+	}
+	if _, err := fmt.Fprintf(f, "%s\n", value); err != nil {
+		f.Close()
+		os.Remove(lock)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(lock)
+		return err
+	}
+	return os.Rename(lock, p)
+}
+
+func (s *FileStore) DeleteRef(name string) error {
+	// gitと同じく参照と一緒に更新履歴も削除する
+	if err := os.Remove(s.reflogPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	found := false
+	if err := os.Remove(filepath.Join(s.gitdir, name)); err == nil {
+		found = true
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return err
+	}
+	if _, ok := packed[name]; ok {
+		found = true
+		if err := s.removePackedRef(name); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("%w ref=%s", ErrNotExist, name)
+	}
+	return nil
+}
+
+func (s *FileStore) ListRefs(prefix string) ([]string, error) {
+	exist := make(map[string]struct{})
+	root := filepath.Join(s.gitdir, prefix)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.gitdir, p)
+		if err != nil {
+			return err
+		}
+		exist[filepath.ToSlash(rel)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	packed, err := s.readPackedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for name := range packed {
+		if name == prefix || strings.HasPrefix(name, strings.TrimSuffix(prefix, "/")+"/") {
+			exist[name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(exist))
+	for name := range exist {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readPackedRefs はpacked-refsを読み込む
+// "^"から始まるpeel済みの行は読み飛ばす
+func (s *FileStore) readPackedRefs() (map[string]string, error) {
+	packed := make(map[string]string)
+	b, err := os.ReadFile(filepath.Join(s.gitdir, "packed-refs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return packed, nil
+		}
+		return nil, err
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		packed[name] = sha
+	}
+	return packed, sc.Err()
+}
+
+func (s *FileStore) removePackedRef(name string) error {
+	p := filepath.Join(s.gitdir, "packed-refs")
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buf     bytes.Buffer
+		removed bool
+	)
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "^") && removed {
+			// 削除した参照のpeel済みの行も取り除く
+			continue
+		}
+		removed = false
+		if _, n, ok := strings.Cut(strings.TrimRight(line, "\n"), " "); ok && n == name {
+			removed = true
+			continue
+		}
+		buf.WriteString(line)
+	}
+
+	lock := p + ".lock"
+	if err := os.WriteFile(lock, buf.Bytes(), os.FileMode(0644)); err != nil {
+		return err
+	}
+	return os.Rename(lock, p)
+}
+
+// reflogPath は参照の更新履歴のファイルのパスを返す
+func (s *FileStore) reflogPath(name string) string {
+	return filepath.Join(s.gitdir, "logs", filepath.FromSlash(name))
+}
+
+func (s *FileStore) ReadReflog(name string) ([]*ReflogEntry, error) {
+	b, err := os.ReadFile(s.reflogPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []*ReflogEntry
+	for _, line := range strings.Split(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		e, err := ParseReflogEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w ref=%s", err, name)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (s *FileStore) AppendReflog(name string, e *ReflogEntry) error {
+	p := s.reflogPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), os.FileMode(0755)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s\n", e); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FileStore) WriteReflog(name string, entries []*ReflogEntry) error {
+	p := s.reflogPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), os.FileMode(0755)); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s\n", e)
+	}
+	lock := p + ".lock"
+	if err := os.WriteFile(lock, buf.Bytes(), os.FileMode(0644)); err != nil {
+		return err
+	}
+	return os.Rename(lock, p)
+}
+
+func (s *FileStore) HasReflog(name string) bool {
+	fi, err := os.Stat(s.reflogPath(name))
+	return err == nil && fi.Mode().IsRegular()
+}
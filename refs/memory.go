@@ -0,0 +1,92 @@
+package refs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore は参照をメモリ上に保持する
+type MemoryStore struct {
+	mu     sync.RWMutex
+	refs   map[string]string
+	reflog map[string][]*ReflogEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		refs:   make(map[string]string),
+		reflog: make(map[string][]*ReflogEntry),
+	}
+}
+
+func (s *MemoryStore) ReadRef(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.refs[name]
+	if !ok {
+		return "", fmt.Errorf("%w ref=%s", ErrNotExist, name)
+	}
+	return v, nil
+}
+
+func (s *MemoryStore) WriteRef(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs[name] = value
+	return nil
+}
+
+func (s *MemoryStore) DeleteRef(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.refs[name]; !ok {
+		return fmt.Errorf("%w ref=%s", ErrNotExist, name)
+	}
+	delete(s.refs, name)
+	delete(s.reflog, name)
+	return nil
+}
+
+func (s *MemoryStore) ListRefs(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := []string{}
+	for name := range s.refs {
+		if name == prefix || strings.HasPrefix(name, strings.TrimSuffix(prefix, "/")+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemoryStore) ReadReflog(name string) ([]*ReflogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make([]*ReflogEntry, len(s.reflog[name]))
+	copy(entries, s.reflog[name])
+	return entries, nil
+}
+
+func (s *MemoryStore) AppendReflog(name string, e *ReflogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reflog[name] = append(s.reflog[name], e)
+	return nil
+}
+
+func (s *MemoryStore) WriteReflog(name string, entries []*ReflogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reflog[name] = append([]*ReflogEntry(nil), entries...)
+	return nil
+}
+
+func (s *MemoryStore) HasReflog(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.reflog[name]
+	return ok
+}
@@ -0,0 +1,117 @@
+// Package refs はrefs配下の参照を扱う
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+var (
+	ErrNotExist      = errors.New("not exist such reference")
+	ErrInvalidReflog = errors.New("invalid reflog entry")
+)
+
+// Store は参照の保存先のインターフェース
+// 参照の値はshaまたは "ref: " から始まるシンボリック参照で、末尾の改行は含まない
+type Store interface {
+	// ReadRef は参照の値を返す 存在しない場合はErrNotExistを返す
+	ReadRef(name string) (string, error)
+	// WriteRef は参照の値を書き込む
+	WriteRef(name, value string) error
+	// DeleteRef は参照を削除する
+	DeleteRef(name string) error
+	// ListRefs はprefix配下の参照名を名前順で返す
+	ListRefs(prefix string) ([]string, error)
+	// ReadReflog は参照の更新履歴を古い順に返す 履歴がない場合は空を返す
+	ReadReflog(name string) ([]*ReflogEntry, error)
+	// AppendReflog は参照の更新履歴を追記する
+	AppendReflog(name string, e *ReflogEntry) error
+	// WriteReflog は参照の更新履歴を古い順のentriesで置き換える
+	WriteReflog(name string, entries []*ReflogEntry) error
+	// HasReflog は参照の更新履歴があるか判定する
+	HasReflog(name string) bool
+}
+
+type Ref struct {
+	sha  string
+	path string
+}
+
+func NewRef(sha, path string) Ref {
+	return Ref{
+		sha:  sha,
+		path: path,
+	}
+}
+
+// Sha は参照先のオブジェクトのshaを返す
+func (r Ref) Sha() string {
+	return r.sha
+}
+
+// Path はgitdirからの参照のパスを返す
+func (r Ref) Path() string {
+	return r.path
+}
+
+// ResolveRef は参照をシンボリック参照も含めて解決する
+func ResolveRef(s Store, ref string) ([]byte, error) {
+	data, err := s.ReadRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(data, "ref: ") {
+		return ResolveRef(s, data[5:])
+	} else {
+		return []byte(data), nil
+	}
+}
+
+// ListRef はpath以下にある参照を解決して列挙する
+func ListRef(s Store, path string) ([]Ref, error) {
+	names, err := s.ListRefs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]Ref, 0, len(names))
+	for _, name := range names {
+		b, err := ResolveRef(s, name)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, NewRef(string(b), name))
+	}
+	return refs, nil
+}
+
+// ReflogEntry は参照の更新履歴の1行
+type ReflogEntry struct {
+	Old       string
+	New       string
+	Committer *object.Signature
+	Message   string
+}
+
+// String は "旧sha 新sha 名前 <メール> 時刻 タイムゾーン\tメッセージ" の形式で返す
+func (e *ReflogEntry) String() string {
+	return fmt.Sprintf("%s %s %s\t%s", e.Old, e.New, e.Committer, e.Message)
+}
+
+// ParseReflogEntry はreflogの1行を解釈する
+func ParseReflogEntry(line string) (*ReflogEntry, error) {
+	head, message, _ := strings.Cut(line, "\t")
+	fields := strings.SplitN(head, " ", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidReflog, line)
+	}
+	committer, err := object.ParseSignature(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReflog, err)
+	}
+	return &ReflogEntry{Old: fields[0], New: fields[1], Committer: committer, Message: message}, nil
+}
@@ -0,0 +1,192 @@
+package refs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+const (
+	sha1 = "ce013625030ba8dba906f756967f9e9ca394464a"
+	sha2 = "587be6b4c3f93f93c489c0111bba5596147a26cb"
+)
+
+// testStore は参照と更新履歴の読み書きを確認する
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	for name, value := range map[string]string{
+		"HEAD":              "ref: refs/heads/master",
+		"refs/heads/master": sha1,
+		"refs/heads/a/b":    sha2,
+		"refs/tags/v1":      sha1,
+	} {
+		if err := s.WriteRef(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if v, err := s.ReadRef("HEAD"); err != nil || v != "ref: refs/heads/master" {
+		t.Errorf("ReadRef(HEAD) = %q, %v", v, err)
+	}
+	if b, err := ResolveRef(s, "HEAD"); err != nil || string(b) != sha1 {
+		t.Errorf("ResolveRef(HEAD) = %q, %v, want %s", b, err, sha1)
+	}
+	if _, err := s.ReadRef("refs/heads/missing"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("ReadRef(missing) error = %v, want ErrNotExist", err)
+	}
+
+	names, err := s.ListRefs("refs/heads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"refs/heads/a/b", "refs/heads/master"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListRefs(refs/heads) = %v, want %v", names, want)
+	}
+	list, err := ListRef(s, "refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[2].Path() != "refs/tags/v1" || list[2].Sha() != sha1 {
+		t.Errorf("ListRef(refs) = %v", list)
+	}
+	// 名前の途中までは前方一致としない
+	if names, err := s.ListRefs("refs/head"); err != nil || len(names) != 0 {
+		t.Errorf("ListRefs(refs/head) = %v, %v, want empty", names, err)
+	}
+
+	// 更新履歴
+	if s.HasReflog("refs/heads/master") {
+		t.Error("HasReflog(refs/heads/master) = true before writing")
+	}
+	if entries, err := s.ReadReflog("refs/heads/master"); err != nil || len(entries) != 0 {
+		t.Errorf("ReadReflog() = %v, %v, want empty", entries, err)
+	}
+	sig := object.NewSignature("T", "t@example.com", time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60)))
+	first := &ReflogEntry{Old: "0000000000000000000000000000000000000000", New: sha1, Committer: sig, Message: "commit (initial): a"}
+	second := &ReflogEntry{Old: sha1, New: sha2, Committer: sig, Message: "commit: b"}
+	for _, e := range []*ReflogEntry{first, second} {
+		if err := s.AppendReflog("refs/heads/master", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.HasReflog("refs/heads/master") {
+		t.Error("HasReflog(refs/heads/master) = false")
+	}
+	entries, err := s.ReadReflog("refs/heads/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].String() != first.String() || entries[1].String() != second.String() {
+		t.Errorf("ReadReflog() = %v, want %v %v", entries, first, second)
+	}
+	if err := s.WriteReflog("refs/heads/master", []*ReflogEntry{second}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := s.ReadReflog("refs/heads/master"); err != nil || len(entries) != 1 || entries[0].New != sha2 {
+		t.Errorf("ReadReflog() after WriteReflog = %v, %v", entries, err)
+	}
+
+	// 参照を削除すると更新履歴も削除する
+	if err := s.DeleteRef("refs/heads/master"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadRef("refs/heads/master"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("ReadRef() after DeleteRef error = %v, want ErrNotExist", err)
+	}
+	if s.HasReflog("refs/heads/master") {
+		t.Error("HasReflog() = true after DeleteRef")
+	}
+	if err := s.DeleteRef("refs/heads/master"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("DeleteRef() again error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, NewFileStore(t.TempDir()))
+}
+
+func TestFileStorePackedRefs(t *testing.T) {
+	gitdir := t.TempDir()
+	packed := "# pack-refs with: peeled fully-peeled sorted \n" +
+		sha1 + " refs/heads/packed\n" +
+		sha2 + " refs/tags/annotated\n" +
+		"^" + sha1 + "\n" +
+		sha2 + " refs/tags/loose\n"
+	if err := os.WriteFile(filepath.Join(gitdir, "packed-refs"), []byte(packed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewFileStore(gitdir)
+	// ファイルの参照はpacked-refsより優先する
+	if err := s.WriteRef("refs/tags/loose", sha1); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"refs/heads/packed":   sha1,
+		"refs/tags/annotated": sha2,
+		"refs/tags/loose":     sha1,
+	} {
+		if v, err := s.ReadRef(name); err != nil || v != want {
+			t.Errorf("ReadRef(%s) = %q, %v, want %s", name, v, err, want)
+		}
+	}
+	names, err := s.ListRefs("refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"refs/heads/packed", "refs/tags/annotated", "refs/tags/loose"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListRefs(refs) = %v, want %v", names, want)
+	}
+	// refs/headsのようなディレクトリは参照ではない
+	if _, err := s.ReadRef("refs/tags"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("ReadRef(refs/tags) error = %v, want ErrNotExist", err)
+	}
+
+	// 削除するとpeel済みの行も取り除く
+	if err := s.DeleteRef("refs/tags/annotated"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(gitdir, "packed-refs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# pack-refs with: peeled fully-peeled sorted \n" +
+		sha1 + " refs/heads/packed\n" +
+		sha2 + " refs/tags/loose\n"
+	if string(b) != want {
+		t.Errorf("packed-refs = %q, want %q", b, want)
+	}
+	// ファイルとpacked-refsの両方から削除する
+	if err := s.DeleteRef("refs/tags/loose"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadRef("refs/tags/loose"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("ReadRef(refs/tags/loose) error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestParseReflogEntry(t *testing.T) {
+	line := "0000000000000000000000000000000000000000 " + sha1 + " A U Thor <a@example.com> 1700000000 +0900\tcommit (initial): a"
+	e, err := ParseReflogEntry(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.New != sha1 || e.Committer.Name != "A U Thor" || e.Message != "commit (initial): a" {
+		t.Errorf("ParseReflogEntry() = %+v", e)
+	}
+	if e.String() != line {
+		t.Errorf("String() = %q, want %q", e.String(), line)
+	}
+	if _, err := ParseReflogEntry("broken"); !errors.Is(err, ErrInvalidReflog) {
+		t.Errorf("ParseReflogEntry(broken) error = %v, want ErrInvalidReflog", err)
+	}
+}
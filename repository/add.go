@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/ignore"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+var ErrIgnoredPath = errors.New("the following paths are ignored by one of your .gitignore files")
+
+// Add はワークツリーからの相対パスpathsのファイルをインデックスに追加する
+// ディレクトリは除外されていないファイルを再帰的に追加し、
+// ワークツリーから削除された追跡中のファイルはインデックスからも取り除く
+func (r *Repository) Add(paths []string) error {
+	if r.worktree == "" {
+		return ErrInMemory
+	}
+	idx, err := r.Index()
+	if err != nil {
+		return err
+	}
+	m, err := r.Ignore()
+	if err != nil {
+		return err
+	}
+	conv, err := r.Converter()
+	if err != nil {
+		return err
+	}
+
+	a := &adder{repo: r, idx: idx, ignore: m, conv: conv}
+	for _, p := range paths {
+		if err := a.add(p); err != nil {
+			return err
+		}
+	}
+	return r.WriteIndex(idx)
+}
+
+type adder struct {
+	repo   *Repository
+	idx    *index.Index
+	ignore *ignore.Matcher
+	conv   *Converter
+}
+
+func (a *adder) tracked(path string) bool {
+	for _, e := range a.idx.Entries() {
+		if path == "" || e.Path == path || strings.HasPrefix(e.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *adder) add(path string) error {
+	full := filepath.Join(a.repo.worktree, filepath.FromSlash(path))
+	fi, err := os.Lstat(full)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if !a.tracked(path) {
+			return fmt.Errorf("pathspec '%s' did not match any files", path)
+		}
+		a.removeMissing(path)
+		return nil
+	}
+
+	if !fi.IsDir() {
+		if ignored, err := a.ignore.Ignored(path, false); err != nil {
+			return err
+		} else if ignored && !a.tracked(path) {
+			return fmt.Errorf("%w: %s", ErrIgnoredPath, path)
+		}
+		return a.addFile(path, full, fi)
+	}
+
+	err = filepath.WalkDir(full, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := a.repo.RelativePath(p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if rel == "" {
+				return nil
+			}
+			// 別のリポジトリは追加しない
+			if _, err := os.Stat(filepath.Join(p, ".git")); err == nil {
+				return filepath.SkipDir
+			}
+			ignored, err := a.ignore.Ignored(rel, true)
+			if err != nil {
+				return err
+			}
+			if ignored && !a.tracked(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ignored, err := a.ignore.Ignored(rel, false)
+		if err != nil {
+			return err
+		}
+		if ignored && !a.tracked(rel) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return a.addFile(rel, p, fi)
+	})
+	if err != nil {
+		return err
+	}
+	a.removeMissing(path)
+	return nil
+}
+
+// removeMissing はpath配下でワークツリーに存在しないエントリを取り除く
+func (a *adder) removeMissing(path string) {
+	var missing []string
+	for _, e := range a.idx.Entries() {
+		if path != "" && e.Path != path && !strings.HasPrefix(e.Path, path+"/") {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(a.repo.worktree, filepath.FromSlash(e.Path))); os.IsNotExist(err) {
+			missing = append(missing, e.Path)
+		}
+	}
+	for _, p := range missing {
+		a.idx.Remove(p)
+	}
+}
+
+func (a *adder) addFile(path, full string, fi os.FileInfo) error {
+	var (
+		data []byte
+		mode uint32
+		err  error
+	)
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(full)
+		if err != nil {
+			return err
+		}
+		data = []byte(target)
+		mode = 0120000
+	case fi.Mode().IsRegular():
+		if mode, err = a.fileMode(path, fi); err != nil {
+			return err
+		}
+		converts, err := a.conv.ConvertsToGit(path)
+		if err != nil {
+			return err
+		}
+		if !converts {
+			return a.addStream(path, full, mode, fi)
+		}
+		if data, err = os.ReadFile(full); err != nil {
+			return err
+		}
+		if data, err = a.conv.ToGit(path, data); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	sha, err := a.repo.WriteObject(object.NewBlobObject(data), true)
+	if err != nil {
+		return err
+	}
+	a.addEntry(path, sha, mode, fi)
+	return nil
+}
+
+// addStream は変換が不要なファイルを中身をすべて読み込まずに追加する
+func (a *adder) addStream(path, full string, mode uint32, fi os.FileInfo) error {
+	f, err := os.Open(full)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sha, err := a.repo.HashObjectStream(f, object.Blob, fi.Size(), true)
+	if err != nil {
+		return err
+	}
+	a.addEntry(path, sha, mode, fi)
+	return nil
+}
+
+func (a *adder) addEntry(path, sha string, mode uint32, fi os.FileInfo) {
+	e := &index.Entry{Mode: mode, Sha: sha, Path: path}
+	e.SetStat(fi)
+	a.idx.Add(e)
+}
+
+// fileMode は実行権限の有無からモードを決める
+// core.filemodeが偽の場合は既存のエントリのモードを引き継ぐ
+func (a *adder) fileMode(path string, fi os.FileInfo) (uint32, error) {
+	filemode, err := a.repo.conf.GetBool("core.filemode", true)
+	if err != nil {
+		return 0, err
+	}
+	if !filemode {
+		if e, ok := a.idx.Entry(path); ok && e.Mode&0170000 == 0100000 {
+			return e.Mode, nil
+		}
+		return 0100644, nil
+	}
+	if fi.Mode()&0111 != 0 {
+		return 0100755, nil
+	}
+	return 0100644, nil
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/showa-93/wyag-go/diff"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// BlameOptions はBlameの設定
+type BlameOptions struct {
+	// Start と End は対象にする1始まりの行の範囲 0の場合はファイルの先頭と末尾とする
+	Start, End int
+	// IgnoreWhitespace は空白の違いを無視して行を比較するか
+	IgnoreWhitespace bool
+}
+
+// BlameLine はファイルの1行と、その行を追加したコミット
+type BlameLine struct {
+	Commit string
+	// Path と OrigLine は追加したコミットでのパスと1始まりの行番号
+	Path     string
+	OrigLine int
+	// FinalLine は対象のファイルでの1始まりの行番号
+	FinalLine int
+	Content   string
+	// Previous と PreviousPath は追加したコミットの親とそのパス 親にファイルがない場合は空
+	Previous, PreviousPath string
+}
+
+// blameSuspect はある時点のファイルと、まだ追加したコミットが決まっていない行
+type blameSuspect struct {
+	commit string
+	path   string
+	blob   string
+	date   int64
+	lines  []string
+	// entries はファイルの行番号と対象のファイルの行番号の組 いずれも0始まり
+	entries []blameEntry
+}
+
+type blameEntry struct {
+	line, final int
+}
+
+// Blame はrevのpathの各行を、その行を追加したコミットに対応づける
+// 新しいコミットから順に親との差分で変わっていない行を親に引き継ぎ、引き継げなかった行をそのコミットが追加したとする
+// 親にpathがない場合は名前の変更を探して変更前のパスを辿る
+func (r *Repository) Blame(p, rev string, opts BlameOptions) ([]*BlameLine, error) {
+	commit, err := r.FindObject(rev, string(object.Commit), true)
+	if err != nil {
+		return nil, err
+	}
+	if commit == "" {
+		return nil, fmt.Errorf("%s is not a commit", rev)
+	}
+	tree, err := r.FindObject(commit, string(object.Tree), true)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := r.TreeEntry(tree, p)
+	if errors.Is(err, ErrNotExist) {
+		return nil, fmt.Errorf("no such path %s in %s", p, rev)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type() != object.Blob {
+		return nil, fmt.Errorf("%s is not a file in %s", p, rev)
+	}
+
+	final, err := r.newBlameSuspect(commit, p, entry.Sha())
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadBlameLines(final); err != nil {
+		return nil, err
+	}
+	start, end := opts.Start, opts.End
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 {
+		end = len(final.lines)
+	}
+	if end > len(final.lines) || start > end && len(final.lines) > 0 {
+		return nil, fmt.Errorf("file %s has only %d lines", p, len(final.lines))
+	}
+	for i := start - 1; i < end; i++ {
+		final.entries = append(final.entries, blameEntry{line: i, final: i})
+	}
+
+	result := make([]*BlameLine, 0, len(final.entries))
+	suspects := map[string]*blameSuspect{commit + "\x00" + p: final}
+	for len(suspects) > 0 {
+		// 子が親より先になるようにコミット日時の新しいものから調べる
+		var s *blameSuspect
+		for _, c := range suspects {
+			if s == nil || c.date > s.date || c.date == s.date && c.commit < s.commit {
+				s = c
+			}
+		}
+		delete(suspects, s.commit+"\x00"+s.path)
+
+		previous, err := r.passBlame(s, suspects, opts.IgnoreWhitespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range s.entries {
+			line := &BlameLine{
+				Commit:    s.commit,
+				Path:      s.path,
+				OrigLine:  e.line + 1,
+				FinalLine: e.final + 1,
+				Content:   final.lines[e.final],
+			}
+			if previous != nil {
+				line.Previous, line.PreviousPath = previous.commit, previous.path
+			}
+			result = append(result, line)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].FinalLine < result[j].FinalLine })
+	return result, nil
+}
+
+func (r *Repository) newBlameSuspect(commit, p, blob string) (*blameSuspect, error) {
+	o, err := r.ReadObject(commit)
+	if err != nil {
+		return nil, err
+	}
+	committer, err := o.(*object.CommitObject).Committer()
+	if err != nil {
+		return nil, err
+	}
+	return &blameSuspect{commit: commit, path: p, blob: blob, date: committer.When.Unix()}, nil
+}
+
+func (r *Repository) loadBlameLines(s *blameSuspect) error {
+	if s.lines != nil {
+		return nil
+	}
+	o, err := r.ReadObject(s.blob)
+	if err != nil {
+		return err
+	}
+	blob, ok := o.(*object.BlobObject)
+	if !ok {
+		return fmt.Errorf("%s is not a blob", s.blob)
+	}
+	s.lines = diff.SplitLines(blob.Data())
+	if s.lines == nil {
+		s.lines = []string{}
+	}
+	return nil
+}
+
+// passBlame はsの行のうち親と変わっていない行を親の候補に引き継ぎ、sには引き継げなかった行を残す
+// 戻り値は最初の親でのファイル 親にファイルがない場合はnil
+func (r *Repository) passBlame(s *blameSuspect, suspects map[string]*blameSuspect, ignoreWhitespace bool) (*blameSuspect, error) {
+	o, err := r.ReadObject(s.commit)
+	if err != nil {
+		return nil, err
+	}
+	commit := o.(*object.CommitObject)
+	var parents []*blameSuspect
+	for _, parent := range commit.Parents() {
+		ps, err := r.blameParent(commit, parent, s.path)
+		if err != nil {
+			return nil, err
+		}
+		if ps != nil {
+			parents = append(parents, ps)
+		}
+	}
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	pass := func(ps *blameSuspect, entries []blameEntry) {
+		key := ps.commit + "\x00" + ps.path
+		if existing, ok := suspects[key]; ok {
+			existing.entries = append(existing.entries, entries...)
+			return
+		}
+		ps.entries = entries
+		suspects[key] = ps
+	}
+	// 内容が同じ親があればすべての行をその親に引き継ぐ
+	for _, ps := range parents {
+		if ps.blob == s.blob {
+			ps.lines = s.lines
+			pass(ps, s.entries)
+			s.entries = nil
+			return parents[0], nil
+		}
+	}
+
+	if err := r.loadBlameLines(s); err != nil {
+		return nil, err
+	}
+	for _, ps := range parents {
+		if len(s.entries) == 0 {
+			break
+		}
+		if err := r.loadBlameLines(ps); err != nil {
+			return nil, err
+		}
+		m := lineMap(ps.lines, s.lines, ignoreWhitespace)
+		var passed, kept []blameEntry
+		for _, e := range s.entries {
+			if m[e.line] < 0 {
+				kept = append(kept, e)
+				continue
+			}
+			passed = append(passed, blameEntry{line: m[e.line], final: e.final})
+		}
+		if len(passed) > 0 {
+			pass(ps, passed)
+		}
+		s.entries = kept
+	}
+	return parents[0], nil
+}
+
+// blameParent は親のコミットでのpのファイルを返す 親にない場合は名前の変更前のファイルを探し、見つからなければnilを返す
+func (r *Repository) blameParent(commit *object.CommitObject, parent, p string) (*blameSuspect, error) {
+	tree, err := r.FindObject(parent, string(object.Tree), true)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := r.TreeEntry(tree, p)
+	if err == nil && entry.Type() == object.Blob {
+		return r.newBlameSuspect(parent, p, entry.Sha())
+	}
+	if err != nil && !errors.Is(err, ErrNotExist) {
+		return nil, err
+	}
+
+	changes, err := r.DiffTrees(tree, commit.Tree(), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if c.Status == Renamed && c.To.Path == p {
+			return r.newBlameSuspect(parent, c.From.Path, c.From.Sha)
+		}
+	}
+	return nil, nil
+}
+
+// lineMap はbの各行に対応するaの行番号を返す 対応する行がない場合は-1とする
+func lineMap(a, b []string, ignoreWhitespace bool) []int {
+	if ignoreWhitespace {
+		a, b = stripWhitespace(a), stripWhitespace(b)
+	}
+	res := diff.Lines(a, b)
+	m := make([]int, len(b))
+	i := 0
+	for j := range b {
+		if res.ChangedB[j] {
+			m[j] = -1
+			continue
+		}
+		for res.ChangedA[i] {
+			i++
+		}
+		m[j] = i
+		i++
+	}
+	return m
+}
+
+func stripWhitespace(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.Join(strings.Fields(l), "")
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineMap(t *testing.T) {
+	tests := []struct {
+		name             string
+		a, b             []string
+		ignoreWhitespace bool
+		want             []int
+	}{
+		{
+			name: "added and removed lines",
+			a:    []string{"a\n", "b\n", "c\n"},
+			b:    []string{"a\n", "x\n", "c\n", "d\n"},
+			want: []int{0, -1, 2, -1},
+		},
+		{
+			name: "whitespace change",
+			a:    []string{"a\n", "  b\n"},
+			b:    []string{"a\n", "b \n"},
+			want: []int{0, -1},
+		},
+		{
+			name:             "ignore whitespace",
+			a:                []string{"a\n", "  b\n"},
+			b:                []string{"a\n", "b \n"},
+			ignoreWhitespace: true,
+			want:             []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineMap(tt.a, tt.b, tt.ignoreWhitespace); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlame(t *testing.T) {
+	// 期待する結果は git blame [-w] [-L START,END] g.txt の出力
+	r := newTestRepository(t)
+	first := commitTestFiles(t, r, "first", map[string]string{"f.txt": "a\nb\nc\nd\n"})
+	rename := commitTestFiles(t, r, "rename", map[string]string{"f.txt": "", "g.txt": "a\nb\nC\nd\n"})
+	third := commitTestFiles(t, r, "third", map[string]string{"g.txt": "a\nB\nC\n  d\ne\n"})
+
+	a := &BlameLine{Commit: first, Path: "f.txt", OrigLine: 1, FinalLine: 1, Content: "a\n"}
+	b := &BlameLine{Commit: third, Path: "g.txt", OrigLine: 2, FinalLine: 2, Content: "B\n", Previous: rename, PreviousPath: "g.txt"}
+	c := &BlameLine{Commit: rename, Path: "g.txt", OrigLine: 3, FinalLine: 3, Content: "C\n", Previous: first, PreviousPath: "f.txt"}
+	d := &BlameLine{Commit: third, Path: "g.txt", OrigLine: 4, FinalLine: 4, Content: "  d\n", Previous: rename, PreviousPath: "g.txt"}
+	e := &BlameLine{Commit: third, Path: "g.txt", OrigLine: 5, FinalLine: 5, Content: "e\n", Previous: rename, PreviousPath: "g.txt"}
+	tests := []struct {
+		name string
+		opts BlameOptions
+		want []*BlameLine
+	}{
+		{name: "follow rename", want: []*BlameLine{a, b, c, d, e}},
+		{name: "line range", opts: BlameOptions{Start: 2, End: 4}, want: []*BlameLine{b, c, d}},
+		{name: "line range to the end", opts: BlameOptions{Start: 4}, want: []*BlameLine{d, e}},
+		{
+			name: "ignore whitespace",
+			opts: BlameOptions{IgnoreWhitespace: true},
+			want: []*BlameLine{a, b, c, {Commit: first, Path: "f.txt", OrigLine: 4, FinalLine: 4, Content: "  d\n"}, e},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Blame("g.txt", "HEAD", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Blame() returned %d lines, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if *got[i] != *tt.want[i] {
+					t.Errorf("Blame() line %d = %+v, want %+v", i+1, *got[i], *tt.want[i])
+				}
+			}
+		})
+	}
+
+	// 名前を変更する前のリビジョンでは変更前のパスで辿る
+	got, err := r.Blame("f.txt", first, BlameOptions{Start: 3, End: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BlameLine{Commit: first, Path: "f.txt", OrigLine: 3, FinalLine: 3, Content: "c\n"}
+	if len(got) != 1 || *got[0] != want {
+		t.Errorf("Blame(f.txt) = %v, want %+v", got, want)
+	}
+}
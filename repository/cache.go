@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// defaultObjectCacheLimit はキャッシュするオブジェクトの中身の大きさの合計の上限
+const defaultObjectCacheLimit = 64 << 20
+
+// objectCache は読み込んだオブジェクトを保持するLRUキャッシュ
+// オブジェクトの中身の大きさの合計がlimitを超えると古いものから捨てる
+// 複数のgoroutineから同時に使える
+type objectCache struct {
+	mu    sync.Mutex
+	limit int64
+	size  int64
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	sha  string
+	obj  object.Object
+	size int64
+}
+
+func newObjectCache(limit int64) *objectCache {
+	return &objectCache{
+		limit: limit,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *objectCache) get(sha string) (object.Object, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[sha]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*cacheEntry).obj, true
+}
+
+func (c *objectCache) add(sha string, o object.Object, size int64) {
+	// 上限より大きいオブジェクトは他をすべて追い出すだけなので保持しない
+	if size > c.limit {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[sha]; ok {
+		return
+	}
+	c.items[sha] = c.ll.PushFront(&cacheEntry{sha: sha, obj: o, size: size})
+	c.size += size
+	for c.size > c.limit {
+		e := c.ll.Back()
+		entry := e.Value.(*cacheEntry)
+		c.ll.Remove(e)
+		delete(c.items, entry.sha)
+		c.size -= entry.size
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+func TestObjectCacheEviction(t *testing.T) {
+	c := newObjectCache(10)
+	blob := object.NewBlobObject(nil)
+	c.add("a", blob, 4)
+	c.add("b", blob, 4)
+	// aを使うとbが最も古くなる
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("a is not cached")
+	}
+	c.add("c", blob, 4)
+
+	for sha, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.get(sha); ok != want {
+			t.Errorf("get(%s) = %v, want %v", sha, ok, want)
+		}
+	}
+	if c.size != 8 {
+		t.Errorf("size = %d, want 8", c.size)
+	}
+
+	// 上限を超えるオブジェクトは保持せず、他のオブジェクトも追い出さない
+	c.add("d", blob, 11)
+	if _, ok := c.get("d"); ok {
+		t.Error("d is cached")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("a is evicted by an oversized object")
+	}
+
+	// 複数を追い出して上限に収める
+	c.add("e", blob, 10)
+	for sha, want := range map[string]bool{"a": false, "c": false, "e": true} {
+		if _, ok := c.get(sha); ok != want {
+			t.Errorf("get(%s) = %v, want %v", sha, ok, want)
+		}
+	}
+	if c.size != 10 || c.ll.Len() != 1 || len(c.items) != 1 {
+		t.Errorf("size = %d, len = %d, items = %d, want 10, 1, 1", c.size, c.ll.Len(), len(c.items))
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// CheckoutTree はツリーの内容をpath配下に展開する
+// ファイルの内容はツリーの.gitattributesを優先したgitattributesに従って変換する
+// ディレクトリを作成した後、ファイルはcheckout.workersの数だけ並列に書き込む
+func (r *Repository) CheckoutTree(tree *object.TreeObject, path string) error {
+	workers, err := r.workers("checkout.workers")
+	if err != nil {
+		return err
+	}
+
+	var blobs []checkoutEntry
+	if err := r.checkoutTree(tree, path, "", &blobs); err != nil {
+		return err
+	}
+	attributes := make(map[string]string)
+	for _, b := range blobs {
+		if b.mode != 0120000 && filepath.Base(b.name) == ".gitattributes" {
+			attributes[b.name] = b.sha
+		}
+	}
+	conv, err := r.checkoutConverter(attributes)
+	if err != nil {
+		return err
+	}
+	return parallel(workers, len(blobs), func(i int) error {
+		b := blobs[i]
+		return r.checkoutBlob(conv, b.sha, b.mode, b.dest, b.name)
+	})
+}
+
+// checkoutEntry は書き込むファイル
+type checkoutEntry struct {
+	sha, dest, name string
+	mode            uint32
+}
+
+// checkoutTree はディレクトリを作成し、書き込むファイルをblobsに集める
+func (r *Repository) checkoutTree(tree *object.TreeObject, dir, prefix string, blobs *[]checkoutEntry) error {
+	for _, item := range tree.Items() {
+		dest := filepath.Join(dir, item.Path())
+		name := path.Join(prefix, item.Path())
+
+		switch item.Type() {
+		case object.Tree:
+			o, err := r.ReadObject(item.Sha())
+			if err != nil {
+				return err
+			}
+			if err := os.Mkdir(dest, os.FileMode(0755)); err != nil {
+				return err
+			}
+			if err := r.checkoutTree(o.(*object.TreeObject), dest, name, blobs); err != nil {
+				return err
+			}
+		case object.Blob:
+			mode, err := strconv.ParseUint(item.Mode(), 8, 32)
+			if err != nil {
+				return fmt.Errorf("invalid mode %s path=%s", item.Mode(), name)
+			}
+			*blobs = append(*blobs, checkoutEntry{sha: item.Sha(), dest: dest, name: name, mode: uint32(mode)})
+		}
+	}
+
+	return nil
+}
+
+// checkoutBlob はblobをmodeに従ってファイルかシンボリックリンクとしてdestに書き込む
+// 変換が不要な場合は中身をすべて読み込まずに書き込む
+func (r *Repository) checkoutBlob(conv *Converter, sha string, mode uint32, dest, name string) error {
+	if mode == 0120000 {
+		_, target, err := r.objects.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(string(target), dest)
+	}
+	perm := os.FileMode(0666)
+	if mode&0111 != 0 {
+		perm = 0777
+	}
+
+	converts, err := conv.ConvertsToWorktree(name)
+	if err != nil {
+		return err
+	}
+	if converts {
+		_, data, err := r.objects.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		if data, err = conv.ToWorktree(name, data); err != nil {
+			return err
+		}
+		return os.WriteFile(dest, data, perm)
+	}
+
+	_, rc, err := r.OpenObject(sha)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
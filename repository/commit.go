@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/showa-93/wyag-go/config"
+	"github.com/showa-93/wyag-go/gpg"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+var (
+	ErrUnmergedIndex   = errors.New("you have unmerged paths")
+	ErrNothingToCommit = errors.New("nothing to commit")
+)
+
+// WriteTree はインデックスのエントリからツリーを作成して書き込み、ルートのツリーのshaを返す
+func (r *Repository) WriteTree(idx *index.Index) (string, error) {
+	if idx.Unmerged() {
+		return "", ErrUnmergedIndex
+	}
+	dirs := map[string][]*object.TreeLeafObject{"": nil}
+	for _, e := range idx.Entries() {
+		if e.IntentToAdd {
+			continue
+		}
+		dir := parentDir(e.Path)
+		for d := dir; d != ""; d = parentDir(d) {
+			if _, ok := dirs[d]; ok {
+				break
+			}
+			dirs[d] = nil
+		}
+		dirs[dir] = append(dirs[dir], object.NewTreeLeafObject(fmt.Sprintf("%o", e.Mode), path.Base(e.Path), e.Sha))
+	}
+
+	// 子のツリーのshaが親のツリーに必要なため、深いディレクトリから書き込む
+	depth := func(d string) int {
+		if d == "" {
+			return -1
+		}
+		return strings.Count(d, "/")
+	}
+	order := make([]string, 0, len(dirs))
+	for d := range dirs {
+		order = append(order, d)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if di, dj := depth(order[i]), depth(order[j]); di != dj {
+			return di > dj
+		}
+		return order[i] < order[j]
+	})
+	var sha string
+	for _, d := range order {
+		var err error
+		if sha, err = r.WriteObject(object.BuildTree(r.format, dirs[d]), true); err != nil {
+			return "", err
+		}
+		if d != "" {
+			parent := parentDir(d)
+			dirs[parent] = append(dirs[parent], object.NewTreeLeafObject("40000", path.Base(d), sha))
+		}
+	}
+	return sha, nil
+}
+
+// parentDir はpathの親ディレクトリを返す ワークツリーの直下の場合は空を返す
+func parentDir(p string) string {
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		return p[:i]
+	}
+	return ""
+}
+
+// Signer はgpg.formatに従ってコミットやタグに署名するSignerを返す
+// keyが空の場合はuser.signingKeyを使い、OpenPGPで未設定の場合はコミッターを鍵の指定に使う
+func (r *Repository) Signer(key string) (gpg.Signer, error) {
+	if key == "" {
+		key, _ = r.conf.Get("user.signingkey")
+	}
+	format, _ := r.conf.Get("gpg.format")
+	switch format {
+	case "", string(gpg.OpenPGP):
+		program, ok := r.conf.Get("gpg.openpgp.program")
+		if !ok {
+			if program, ok = r.conf.Get("gpg.program"); !ok {
+				program = "gpg"
+			}
+		}
+		if key == "" {
+			committer, err := r.Identity(Committer)
+			if err != nil {
+				return nil, err
+			}
+			key = committer.Ident()
+		}
+		return &gpg.ProgramSigner{Program: program, KeyID: key}, nil
+	case string(gpg.SSH):
+		if key == "" {
+			return nil, errors.New("user.signingKey needs to be set for ssh signing")
+		}
+		// ssh-agentを使う "key::" から始まる鍵の指定には対応しない
+		if strings.HasPrefix(key, "key::") || strings.HasPrefix(key, "ssh-") {
+			return nil, fmt.Errorf("%w: user.signingKey must be a path to a private key file", gpg.ErrUnsupported)
+		}
+		keyPath, err := config.ExpandPath(key)
+		if err != nil {
+			return nil, err
+		}
+		return gpg.LoadSSHSigner(keyPath)
+	}
+	return nil, fmt.Errorf("%w: gpg.format=%s", gpg.ErrUnsupported, format)
+}
+
+// CreateCommit はコミットを書き込む signerがnilでなければ署名をgpgsigのヘッダーに埋め込む
+func (r *Repository) CreateCommit(b *object.CommitBuilder, signer gpg.Signer) (string, error) {
+	if signer != nil {
+		unsigned := *b
+		unsigned.GPGSig = ""
+		payload, _ := unsigned.Build().Serialize()
+		sig, err := signer.Sign(payload)
+		if err != nil {
+			return "", err
+		}
+		b.GPGSig = sig
+	}
+	return r.WriteObject(b.Build(), true)
+}
+
+// CommitOptions はCommitの設定
+type CommitOptions struct {
+	Message string
+	// AllowEmpty はHEADとツリーが変わらなくてもコミットするか
+	AllowEmpty bool
+	// Signer はコミットに署名する nilの場合は署名しない
+	Signer gpg.Signer
+	// Author はコミットの作者 nilの場合は設定や環境変数から決める
+	Author *object.Signature
+	// Reason は更新履歴に記録する操作の名前 空の場合は "commit" とする
+	Reason string
+	// Amend はHEADの子ではなくHEADを置き換えるコミットを作成するか 作者を指定しない場合はHEADの作者を引き継ぐ
+	Amend bool
+}
+
+// Commit はインデックスの内容をHEADの子のコミットとして書き込み、HEADが指すブランチを更新する
+func (r *Repository) Commit(opts CommitOptions) (string, error) {
+	ref, head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	// 名前とメールアドレスがわからない場合にツリーだけが残らないよう、オブジェクトを書き込む前に作者とコミッターを決める
+	b := &object.CommitBuilder{Message: opts.Message, Author: opts.Author}
+	var headTree string
+	switch {
+	case opts.Amend:
+		if head == "" {
+			return "", errors.New("You have nothing to amend.")
+		}
+		o, err := r.ReadObject(head)
+		if err != nil {
+			return "", err
+		}
+		commit := o.(*object.CommitObject)
+		b.Parents = commit.Parents()
+		if b.Author == nil {
+			if b.Author, err = commit.Author(); err != nil {
+				return "", err
+			}
+		}
+	case head != "":
+		b.Parents = []string{head}
+		o, err := r.ReadObject(head)
+		if err != nil {
+			return "", err
+		}
+		headTree = o.(*object.CommitObject).Tree()
+	}
+	if b.Author == nil {
+		if b.Author, err = r.Identity(Author); err != nil {
+			return "", err
+		}
+	}
+	if b.Committer, err = r.Identity(Committer); err != nil {
+		return "", err
+	}
+
+	idx, err := r.Index()
+	if err != nil {
+		return "", err
+	}
+	if b.Tree, err = r.WriteTree(idx); err != nil {
+		return "", err
+	}
+	if headTree != "" && !opts.AllowEmpty && headTree == b.Tree {
+		return "", ErrNothingToCommit
+	}
+
+	sha, err := r.CreateCommit(b, opts.Signer)
+	if err != nil {
+		return "", err
+	}
+	reason := opts.Reason
+	switch {
+	case reason != "":
+	case opts.Amend:
+		reason = "commit (amend)"
+	case head == "":
+		reason = "commit (initial)"
+	default:
+		reason = "commit"
+	}
+	subject, _, _ := strings.Cut(opts.Message, "\n")
+	return sha, r.UpdateRefWithLog(ref, sha, reason+": "+subject)
+}
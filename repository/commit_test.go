@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitUnknownIdentityWritesNoObjects(t *testing.T) {
+	// 名前とメールアドレスがわからない場合はツリーも書き込まずにエラーにする
+	r := newTestRepository(t)
+	if err := os.WriteFile(filepath.Join(r.worktree, "f.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"f.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+kind+"_NAME", "")
+		t.Setenv("GIT_"+kind+"_EMAIL", "")
+	}
+
+	if _, err := r.Commit(CommitOptions{Message: "first\n"}); !errors.Is(err, ErrUnknownIdentity) {
+		t.Fatalf("Commit = %v, want %v", err, ErrUnknownIdentity)
+	}
+	// 追加したblobのみが残る
+	var objects []string
+	err := filepath.WalkDir(r.Path("objects"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(r.Path("objects"), p)
+		if err != nil {
+			return err
+		}
+		if dir := filepath.Dir(rel); len(dir) == 2 {
+			objects = append(objects, dir+filepath.Base(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 期待する結果は echo a | git hash-object --stdin の出力
+	want := "78981922613b2afb6025042ff6bd878ac1994e85"
+	if len(objects) != 1 || objects[0] != want {
+		t.Errorf("objects = %v, want [%s]", objects, want)
+	}
+}
@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/showa-93/wyag-go/attr"
+	"github.com/showa-93/wyag-go/index"
+)
+
+// Attributes はcore.attributesFile、.gitattributes、.git/info/attributesを読み込むMatcherを返す
+func (r *Repository) Attributes() (*attr.Matcher, error) {
+	caseFold, err := r.conf.GetBool("core.ignorecase", false)
+	if err != nil {
+		return nil, err
+	}
+
+	attributesFile, ok, err := r.conf.GetPath("core.attributesfile")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		attributesFile = defaultAttributesFile()
+	}
+	var global []*attr.Rule
+	if attributesFile != "" {
+		global, err = attr.ReadRules(attributesFile, "", attributesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var info []*attr.Rule
+	if r.gitdir != "" {
+		info, err = attr.ReadRules(r.Path(filepath.Join("info", "attributes")), "", "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return attr.NewMatcher(r.worktree, caseFold, global, info), nil
+}
+
+func defaultAttributesFile() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "attributes")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "attributes")
+}
+
+// textAction はファイルを改行コードの変換対象とするか
+type textAction int
+
+const (
+	textNone textAction = iota
+	textBinary
+	textOn
+	textAuto
+)
+
+// Converter はファイルをリポジトリに格納する際とワークツリーに展開する際の変換を行う
+// gitattributesのtext, eol, filterとcore.autocrlf, core.eolに従う
+// 複数のgoroutineから同時に使える
+type Converter struct {
+	repo     *Repository
+	mu       sync.Mutex
+	attrs    *attr.Matcher
+	autocrlf string
+	eol      string
+}
+
+// Converter は変換に使う属性と設定を読み込む
+func (r *Repository) Converter() (*Converter, error) {
+	m, err := r.Attributes()
+	if err != nil {
+		return nil, err
+	}
+
+	autocrlf, _ := r.conf.Get("core.autocrlf")
+	autocrlf = strings.ToLower(autocrlf)
+	if autocrlf != "input" {
+		b, err := r.conf.GetBool("core.autocrlf", false)
+		if err != nil {
+			return nil, err
+		}
+		autocrlf = "false"
+		if b {
+			autocrlf = "true"
+		}
+	}
+
+	eol, _ := r.conf.Get("core.eol")
+	return &Converter{repo: r, attrs: m, autocrlf: autocrlf, eol: strings.ToLower(eol)}, nil
+}
+
+// checkoutConverter はワークツリーに書き込む際のConverterを返す
+// gitと同じく.gitattributesは書き込む内容にあるものを優先し、ない場合はワークツリーから読み込む
+// attributesは書き込む内容の.gitattributesのパスとblobの組
+func (r *Repository) checkoutConverter(attributes map[string]string) (*Converter, error) {
+	conv, err := r.Converter()
+	if err != nil {
+		return nil, err
+	}
+	for p, sha := range attributes {
+		_, data, err := r.objects.ReadObject(sha)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		conv.attrs.AddRules(dir, attr.ParseRules(data, dir, p))
+	}
+	return conv, nil
+}
+
+// indexAttributes はidxの.gitattributesのパスとblobの組を返す 衝突しているものは含めない
+func indexAttributes(idx *index.Index) map[string]string {
+	attributes := make(map[string]string)
+	for _, e := range idx.Entries() {
+		if e.Stage == 0 && e.Mode != 0120000 && path.Base(e.Path) == ".gitattributes" {
+			attributes[e.Path] = e.Sha
+		}
+	}
+	return attributes
+}
+
+type conversion struct {
+	text   textAction
+	crlf   bool
+	filter string
+}
+
+func (c *Converter) lookup(path string) (conversion, error) {
+	// Matcherは.gitattributesを読み込みながらキャッシュするため排他する
+	c.mu.Lock()
+	attrs, err := c.attrs.Get(path, "text", "eol", "filter")
+	c.mu.Unlock()
+	if err != nil {
+		return conversion{}, err
+	}
+	text, eol, filter := attrs[0], attrs[1], attrs[2]
+
+	var conv conversion
+	switch {
+	case text.State == attr.Set:
+		conv.text = textOn
+	case text.State == attr.Unset:
+		conv.text = textBinary
+	case text.State == attr.Value && text.Value == "auto":
+		conv.text = textAuto
+	case eol.State == attr.Value:
+		// eolの指定はtextの指定を兼ねる
+		conv.text = textOn
+	case c.autocrlf == "true" || c.autocrlf == "input":
+		conv.text = textAuto
+	}
+
+	switch {
+	case eol.State == attr.Value:
+		conv.crlf = eol.Value == "crlf"
+	case c.autocrlf == "true":
+		conv.crlf = true
+	case c.autocrlf == "input":
+		conv.crlf = false
+	default:
+		conv.crlf = c.eol == "crlf"
+	}
+
+	if filter.State == attr.Value {
+		conv.filter = filter.Value
+	}
+	return conv, nil
+}
+
+// ConvertsToGit はpathのファイルを格納する際に変換が必要か判定する
+// 変換が不要であれば中身を読み込まずにそのまま格納できる
+func (c *Converter) ConvertsToGit(path string) (bool, error) {
+	conv, err := c.lookup(path)
+	if err != nil {
+		return false, err
+	}
+	return conv.filter != "" || conv.text == textOn || conv.text == textAuto, nil
+}
+
+// ConvertsToWorktree はpathのファイルをワークツリーに書き込む際に変換が必要か判定する
+func (c *Converter) ConvertsToWorktree(path string) (bool, error) {
+	conv, err := c.lookup(path)
+	if err != nil {
+		return false, err
+	}
+	return conv.filter != "" || (conv.crlf && (conv.text == textOn || conv.text == textAuto)), nil
+}
+
+// ToGit はワークツリーからの相対パスpathのファイルの内容をリポジトリに格納する形式に変換する
+// cleanフィルタを実行した後、テキストファイルの改行コードをLFにする
+func (c *Converter) ToGit(path string, data []byte) ([]byte, error) {
+	conv, err := c.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = c.runFilter(conv.filter, "clean", path, data); err != nil {
+		return nil, err
+	}
+	if conv.text != textOn && conv.text != textAuto {
+		return data, nil
+	}
+	st := gatherStats(data)
+	if st.crlf == 0 || (conv.text == textAuto && st.binary()) {
+		return data, nil
+	}
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), nil
+}
+
+// ToWorktree はリポジトリに格納された内容をpathのファイルに書き込む形式に変換する
+// 改行コードをCRLFにする必要があればLFを置き換えた後、smudgeフィルタを実行する
+func (c *Converter) ToWorktree(path string, data []byte) ([]byte, error) {
+	conv, err := c.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if conv.crlf && (conv.text == textOn || conv.text == textAuto) {
+		st := gatherStats(data)
+		convert := st.loneLF > 0
+		if conv.text == textAuto && (st.crlf > 0 || st.loneCR > 0 || st.binary()) {
+			// 既にCRLFを含むものやバイナリは書き換えない
+			convert = false
+		}
+		if convert {
+			data = toCRLF(data)
+		}
+	}
+	return c.runFilter(conv.filter, "smudge", path, data)
+}
+
+func toCRLF(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	for i, b := range data {
+		if b == '\n' && (i == 0 || data[i-1] != '\r') {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(b)
+	}
+	return buf.Bytes()
+}
+
+// runFilter はfilter.<name>.<kind>に設定されたコマンドに内容を渡して変換する
+// コマンド中の "%f" はファイルのパスに置き換える
+// filter.<name>.requiredが真でない場合はコマンドが失敗しても内容をそのまま返す
+func (c *Converter) runFilter(name, kind, path string, data []byte) ([]byte, error) {
+	if name == "" {
+		return data, nil
+	}
+	conf := c.repo.conf
+	required, err := conf.GetBool(fmt.Sprintf("filter.%s.required", name), false)
+	if err != nil {
+		return nil, err
+	}
+	command, ok := conf.Get(fmt.Sprintf("filter.%s.%s", name, kind))
+	if !ok || command == "" {
+		if required {
+			return nil, fmt.Errorf("%s: %s filter '%s' is not defined", path, kind, name)
+		}
+		return data, nil
+	}
+
+	command = strings.ReplaceAll(command, "%f", shellQuote(path))
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = c.repo.worktree
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if required {
+			return nil, fmt.Errorf("%s: %s filter '%s' failed: %w", path, kind, name, err)
+		}
+		return data, nil
+	}
+	return out, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// textStats は改行コードの変換の判定に使う文字の数
+type textStats struct {
+	nul, loneCR, loneLF, crlf int
+	printable, nonprintable   int
+}
+
+func gatherStats(data []byte) textStats {
+	var st textStats
+	for i, b := range data {
+		switch {
+		case b == '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				st.crlf++
+			} else {
+				st.loneCR++
+			}
+		case b == '\n':
+			if i == 0 || data[i-1] != '\r' {
+				st.loneLF++
+			}
+		case b == 0x7f:
+			st.nonprintable++
+		case b < 0x20:
+			switch b {
+			case '\b', '\t', '\033', '\014':
+				st.printable++
+			case 0:
+				st.nul++
+				st.nonprintable++
+			default:
+				st.nonprintable++
+			}
+		default:
+			st.printable++
+		}
+	}
+	// 末尾のEOFは無視する
+	if len(data) > 0 && data[len(data)-1] == '\032' {
+		st.nonprintable--
+	}
+	return st
+}
+
+// binary はgitと同じ基準でバイナリファイルか判定する
+func (st textStats) binary() bool {
+	return st.loneCR > 0 || st.nul > 0 || (st.printable>>7) < st.nonprintable
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/showa-93/wyag-go/diff"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// ChangeStatus はファイルの変更の種類
+type ChangeStatus byte
+
+const (
+	Added    ChangeStatus = 'A'
+	Deleted  ChangeStatus = 'D'
+	Modified ChangeStatus = 'M'
+	Renamed  ChangeStatus = 'R'
+	// TypeChanged はファイルとシンボリックリンクのように種類が変わった変更
+	TypeChanged ChangeStatus = 'T'
+)
+
+// DiffEntry は差分の片側のファイル
+type DiffEntry struct {
+	Path string
+	Mode uint32
+	Sha  string
+}
+
+// Change は2つのツリーの間のファイルの変更
+// 追加されたファイルはFromが、削除されたファイルはToがnil
+type Change struct {
+	Status ChangeStatus
+	From   *DiffEntry
+	To     *DiffEntry
+	// Score は名前の変更の類似度(0〜diff.MaxScore)
+	Score int
+}
+
+// Path は変更後のパスを返す 削除された場合は変更前のパスを返す
+func (c *Change) Path() string {
+	if c.To != nil {
+		return c.To.Path
+	}
+	return c.From.Path
+}
+
+// defaultRenameLimit は類似度で名前の変更を探すファイルの組み合わせの上限
+const defaultRenameLimit = 1000
+
+// DiffTrees はツリーfromからtoへの変更をパスの順に返す
+// shaが空のツリーは空のツリーとして扱う
+// renamesが真の場合は削除と追加の組から名前の変更を探す
+func (r *Repository) DiffTrees(from, to string, renames bool) ([]*Change, error) {
+	a, err := r.readTree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.readTree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*Change
+	if err := r.diffTree(a, b, "", &changes); err != nil {
+		return nil, err
+	}
+	if renames {
+		return r.detectRenames(changes)
+	}
+	return changes, nil
+}
+
+func (r *Repository) readTree(sha string) ([]*object.TreeLeafObject, error) {
+	if sha == "" {
+		return nil, nil
+	}
+	o, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	tree, ok := o.(*object.TreeObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a tree", sha)
+	}
+	return tree.Items(), nil
+}
+
+// treeKey はツリーの並び順の比較に使う名前 ディレクトリは末尾に "/" をつけて比較する
+func treeKey(item *object.TreeLeafObject) string {
+	if item.Type() == object.Tree {
+		return item.Path() + "/"
+	}
+	return item.Path()
+}
+
+func newDiffEntry(prefix string, item *object.TreeLeafObject) (*DiffEntry, error) {
+	mode, err := strconv.ParseUint(item.Mode(), 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode %s path=%s", item.Mode(), item.Path())
+	}
+	return &DiffEntry{Path: path.Join(prefix, item.Path()), Mode: uint32(mode), Sha: item.Sha()}, nil
+}
+
+func (r *Repository) diffTree(a, b []*object.TreeLeafObject, prefix string, changes *[]*Change) error {
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && treeKey(a[0]) < treeKey(b[0])):
+			if err := r.diffOne(a[0], nil, prefix, changes); err != nil {
+				return err
+			}
+			a = a[1:]
+		case len(a) == 0 || treeKey(a[0]) > treeKey(b[0]):
+			if err := r.diffOne(nil, b[0], prefix, changes); err != nil {
+				return err
+			}
+			b = b[1:]
+		default:
+			if a[0].Sha() != b[0].Sha() || a[0].Mode() != b[0].Mode() {
+				if err := r.diffOne(a[0], b[0], prefix, changes); err != nil {
+					return err
+				}
+			}
+			a, b = a[1:], b[1:]
+		}
+	}
+	return nil
+}
+
+// diffOne は同じ名前のエントリの変更を追加する どちらかがnilの場合は追加か削除
+func (r *Repository) diffOne(a, b *object.TreeLeafObject, prefix string, changes *[]*Change) error {
+	if (a != nil && a.Type() == object.Tree) || (b != nil && b.Type() == object.Tree) {
+		var sa, sb string
+		name := ""
+		if a != nil {
+			sa, name = a.Sha(), a.Path()
+		}
+		if b != nil {
+			sb, name = b.Sha(), b.Path()
+		}
+		ta, err := r.readTree(sa)
+		if err != nil {
+			return err
+		}
+		tb, err := r.readTree(sb)
+		if err != nil {
+			return err
+		}
+		return r.diffTree(ta, tb, path.Join(prefix, name), changes)
+	}
+
+	var from, to *DiffEntry
+	var err error
+	if a != nil {
+		if from, err = newDiffEntry(prefix, a); err != nil {
+			return err
+		}
+	}
+	if b != nil {
+		if to, err = newDiffEntry(prefix, b); err != nil {
+			return err
+		}
+	}
+	switch {
+	case from == nil:
+		*changes = append(*changes, &Change{Status: Added, To: to})
+	case to == nil:
+		*changes = append(*changes, &Change{Status: Deleted, From: from})
+	case from.Mode&0170000 != to.Mode&0170000:
+		*changes = append(*changes, &Change{Status: TypeChanged, From: from, To: to})
+	default:
+		*changes = append(*changes, &Change{Status: Modified, From: from, To: to})
+	}
+	return nil
+}
+
+type renameCandidate struct {
+	src, dst int
+	score    int
+	sameName bool
+}
+
+// detectRenames は削除されたファイルと追加されたファイルの組から名前の変更を探す
+// 同じ内容の組を優先し、残りは類似度がdiff.DefaultRenameScore以上の組を類似度の高い順に選ぶ
+func (r *Repository) detectRenames(changes []*Change) ([]*Change, error) {
+	var srcs, dsts []int
+	for i, c := range changes {
+		switch {
+		case c.Status == Deleted && c.From.Mode != 0160000:
+			srcs = append(srcs, i)
+		case c.Status == Added && c.To.Mode != 0160000:
+			dsts = append(dsts, i)
+		}
+	}
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	pairs := make(map[int]*renameCandidate)
+	used := make(map[int]bool)
+
+	// 内容が同じ組 同じファイル名のものを優先する
+	for _, d := range dsts {
+		to := changes[d].To
+		best := -1
+		for _, s := range srcs {
+			from := changes[s].From
+			if used[s] || from.Sha != to.Sha {
+				continue
+			}
+			if best == -1 || (path.Base(from.Path) == path.Base(to.Path) && path.Base(changes[best].From.Path) != path.Base(to.Path)) {
+				best = s
+			}
+		}
+		if best != -1 {
+			used[best] = true
+			pairs[d] = &renameCandidate{src: best, dst: d, score: diff.MaxScore}
+		}
+	}
+
+	var restSrcs, restDsts []int
+	for _, s := range srcs {
+		if !used[s] && changes[s].From.Mode&0170000 == 0100000 {
+			restSrcs = append(restSrcs, s)
+		}
+	}
+	for _, d := range dsts {
+		if pairs[d] == nil && changes[d].To.Mode&0170000 == 0100000 {
+			restDsts = append(restDsts, d)
+		}
+	}
+	if len(restSrcs) > 0 && len(restDsts) > 0 && len(restSrcs)*len(restDsts) <= defaultRenameLimit*defaultRenameLimit {
+		contents := make(map[string][]byte)
+		read := func(sha string) ([]byte, error) {
+			if data, ok := contents[sha]; ok {
+				return data, nil
+			}
+			_, data, err := r.objects.ReadObject(sha)
+			if err != nil {
+				return nil, err
+			}
+			contents[sha] = data
+			return data, nil
+		}
+
+		var candidates []*renameCandidate
+		for _, d := range restDsts {
+			to := changes[d].To
+			dst, err := read(to.Sha)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range restSrcs {
+				from := changes[s].From
+				src, err := read(from.Sha)
+				if err != nil {
+					return nil, err
+				}
+				score := diff.Similarity(src, dst, diff.DefaultRenameScore)
+				if score < diff.DefaultRenameScore {
+					continue
+				}
+				candidates = append(candidates, &renameCandidate{
+					src: s, dst: d, score: score,
+					sameName: path.Base(from.Path) == path.Base(to.Path),
+				})
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].score != candidates[j].score {
+				return candidates[i].score > candidates[j].score
+			}
+			return candidates[i].sameName && !candidates[j].sameName
+		})
+		for _, c := range candidates {
+			if used[c.src] || pairs[c.dst] != nil {
+				continue
+			}
+			used[c.src] = true
+			pairs[c.dst] = c
+		}
+	}
+
+	// 名前の変更は変更後のパスの位置に置き、元の削除は取り除く
+	result := make([]*Change, 0, len(changes))
+	for i, c := range changes {
+		if used[i] {
+			continue
+		}
+		if p, ok := pairs[i]; ok {
+			result = append(result, &Change{Status: Renamed, From: changes[p.src].From, To: c.To, Score: p.score})
+			continue
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// TreeEntry はツリーからルートからの相対パスpathのエントリを探す
+func (r *Repository) TreeEntry(tree, p string) (*object.TreeLeafObject, error) {
+	items, err := r.readTree(tree)
+	if err != nil {
+		return nil, err
+	}
+	dir, rest := p, ""
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			dir, rest = p[:i], p[i+1:]
+			break
+		}
+	}
+	for _, item := range items {
+		if item.Path() != dir {
+			continue
+		}
+		if rest == "" {
+			return item, nil
+		}
+		if item.Type() != object.Tree {
+			break
+		}
+		return r.TreeEntry(item.Sha(), rest)
+	}
+	return nil, fmt.Errorf("%w path=%s", ErrNotExist, p)
+}
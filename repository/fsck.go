@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+)
+
+// FsckKind はfsckで見つかった問題の種類
+type FsckKind string
+
+const (
+	// FsckCorrupt は読み込めないか、shaが中身と一致しないオブジェクト
+	FsckCorrupt FsckKind = "error"
+	// FsckMissing は参照されているが存在しないオブジェクト
+	FsckMissing FsckKind = "missing"
+	// FsckDangling はどこからも参照されていないオブジェクト
+	FsckDangling FsckKind = "dangling"
+)
+
+// FsckIssue はfsckで見つかった問題
+type FsckIssue struct {
+	Kind FsckKind
+	Type object.ObjectType
+	Sha  string
+	Err  error
+}
+
+func (i *FsckIssue) String() string {
+	if i.Kind == FsckCorrupt {
+		return fmt.Sprintf("error: %s: %v", i.Sha, i.Err)
+	}
+	return fmt.Sprintf("%s %s %s", i.Kind, i.Type, i.Sha)
+}
+
+// objectLink はオブジェクトが参照する別のオブジェクト
+type objectLink struct {
+	sha string
+	t   object.ObjectType
+}
+
+// Fsck はすべてのオブジェクトを並列に読み込んで検証し、参照のつながりを調べる
+// 問題はshaの順に返す
+func (r *Repository) Fsck() ([]*FsckIssue, error) {
+	shas, err := r.allObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]object.ObjectType, len(shas))
+	links := make([][]objectLink, len(shas))
+	errs := make([]error, len(shas))
+	err = parallel(0, len(shas), func(i int) error {
+		types[i], links[i], errs[i] = r.verifyObject(shas[i])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []*FsckIssue
+	present := make(map[string]bool, len(shas))
+	for i, sha := range shas {
+		if errs[i] != nil {
+			issues = append(issues, &FsckIssue{Kind: FsckCorrupt, Sha: sha, Err: errs[i]})
+			continue
+		}
+		present[sha] = true
+	}
+
+	roots, err := r.fsckRoots()
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	missing := make(map[string]object.ObjectType)
+	for _, l := range roots {
+		referenced[l.sha] = true
+		if !present[l.sha] {
+			missing[l.sha] = l.t
+		}
+	}
+	for i := range shas {
+		for _, l := range links[i] {
+			referenced[l.sha] = true
+			if !present[l.sha] {
+				missing[l.sha] = l.t
+			}
+		}
+	}
+
+	for sha, t := range missing {
+		issues = append(issues, &FsckIssue{Kind: FsckMissing, Type: t, Sha: sha})
+	}
+	for i, sha := range shas {
+		if present[sha] && !referenced[sha] {
+			issues = append(issues, &FsckIssue{Kind: FsckDangling, Type: types[i], Sha: sha})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Sha < issues[j].Sha
+	})
+	return issues, nil
+}
+
+// allObjects はloose、packを含むすべてのオブジェクトのshaを返す
+func (r *Repository) allObjects() ([]string, error) {
+	var shas []string
+	for i := 0; i < 256; i++ {
+		found, err := r.objects.FindObjects(fmt.Sprintf("%02x", i))
+		if err != nil {
+			return nil, err
+		}
+		shas = append(shas, found...)
+	}
+	return shas, nil
+}
+
+// verifyObject はオブジェクトを読み込んでshaを検証し、参照しているオブジェクトを返す
+func (r *Repository) verifyObject(sha string) (object.ObjectType, []objectLink, error) {
+	t, data, err := r.objects.ReadObject(sha)
+	if err != nil {
+		return "", nil, err
+	}
+	if actual := object.HashRaw(r.format, t, data); actual != sha {
+		return "", nil, fmt.Errorf("hash mismatch, got %s", actual)
+	}
+	o, err := object.NewObject(r.format, t, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var links []objectLink
+	switch o := o.(type) {
+	case *object.TreeObject:
+		for _, item := range o.Items() {
+			// サブモジュールのコミットは別のリポジトリにある
+			if item.Type() != object.Commit {
+				links = append(links, objectLink{sha: item.Sha(), t: item.Type()})
+			}
+		}
+	case *object.TagObject:
+		if o.Target() != "" && o.TargetType() != "" {
+			links = append(links, objectLink{sha: o.Target(), t: o.TargetType()})
+		}
+	case *object.CommitObject:
+		if o.Tree() != "" {
+			links = append(links, objectLink{sha: o.Tree(), t: object.Tree})
+		}
+		for _, p := range o.Parents() {
+			links = append(links, objectLink{sha: p, t: object.Commit})
+		}
+	}
+	return t, links, nil
+}
+
+// fsckRoots はHEAD、refs配下の参照とそのreflog、インデックスが指すオブジェクトを返す
+func (r *Repository) fsckRoots() ([]objectLink, error) {
+	var roots []objectLink
+	head, err := r.ResolveRef("HEAD")
+	if err == nil {
+		roots = append(roots, objectLink{sha: string(head), t: object.Commit})
+	} else if !errors.Is(err, refs.ErrNotExist) {
+		return nil, err
+	}
+
+	list, err := r.ListRef("refs")
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range list {
+		roots = append(roots, objectLink{sha: ref.Sha(), t: object.Commit})
+	}
+
+	// gitと同じくreflogに残っているコミットも到達可能とみなす
+	names := []string{"HEAD"}
+	for _, ref := range list {
+		names = append(names, ref.Path())
+	}
+	for _, name := range names {
+		entries, err := r.refs.ReadReflog(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			for _, sha := range []string{e.Old, e.New} {
+				if sha != r.format.Zero() {
+					roots = append(roots, objectLink{sha: sha, t: object.Commit})
+				}
+			}
+		}
+	}
+
+	idx, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range idx.Entries() {
+		if e.Mode != 0160000 {
+			roots = append(roots, objectLink{sha: e.Sha, t: object.Blob})
+		}
+	}
+	return roots, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+func TestFsckReflogRoots(t *testing.T) {
+	// gitと同じくreflogからのみ到達できるコミットはdanglingとしない
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, r, "second", map[string]string{"a.txt": "b\n"})
+	if _, err := r.Reset("HEAD~1", ResetHard); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := r.Fsck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Fsck() = %v, want no issues", issues)
+	}
+
+	// どこからも参照されていないコミットはdanglingとなる
+	tree, err := r.FindObject("HEAD", string(object.Tree), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := object.NewSignature("T", "t@example.com", time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60)))
+	b := &object.CommitBuilder{Tree: tree, Author: sig, Committer: sig, Message: "dangling\n"}
+	sha, err := r.CreateCommit(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err = r.Fsck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Kind != FsckDangling || issues[0].Sha != sha {
+		t.Errorf("Fsck() = %v, want dangling commit %s", issues, sha)
+	}
+}
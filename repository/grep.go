@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/showa-93/wyag-go/diff"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/wildmatch"
+)
+
+// GrepOptions はGrepの条件
+type GrepOptions struct {
+	// Patterns はいずれかに一致する行を探す条件 各条件はすべての正規表現が一致する場合に一致とする
+	Patterns [][]*regexp.Regexp
+	// WordRegexp は単語の境界で始まり単語の境界で終わる一致のみを一致とするか
+	WordRegexp bool
+	// Paths は検索するパス ディレクトリかglobパターンを指定でき、空の場合はすべてのファイルを検索する
+	Paths []string
+	// Cached はリビジョンを指定しない場合にワークツリーの代わりにインデックスを検索するか
+	Cached bool
+}
+
+// GrepMatch は一致した行
+type GrepMatch struct {
+	// Line は1始まりの行番号、Text は改行を除いた行の内容
+	Line int
+	Text string
+}
+
+// GrepResult は1つのファイルで一致した行
+type GrepResult struct {
+	Path string
+	// Binary はNULを含むバイナリファイルか
+	Binary  bool
+	Matches []GrepMatch
+}
+
+// grepFile は検索するファイル shaが空の場合はワークツリーから読み込む
+type grepFile struct {
+	path, sha string
+	mode      uint32
+}
+
+// Grep はrevのツリーのファイルから条件に一致する行を探し、一致したファイルをパスの順に返す
+// revが空の場合はインデックスが追跡しているワークツリーのファイルか、opts.Cachedが真の場合はインデックスのファイルを検索する
+// ファイルはgrep.threadsの数だけ並列に検索する
+func (r *Repository) Grep(rev string, opts GrepOptions) ([]*GrepResult, error) {
+	var files []grepFile
+	if rev != "" {
+		tree, err := r.FindObject(rev, string(object.Tree), true)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := r.ListTree(tree)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			files = append(files, grepFile{path: e.Path, sha: e.Sha, mode: e.Mode})
+		}
+	} else {
+		if !opts.Cached && r.worktree == "" {
+			return nil, ErrInMemory
+		}
+		idx, err := r.Index()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range idx.Entries() {
+			// 衝突しているファイルはワークツリーから1度だけ検索する
+			if n := len(files); n > 0 && files[n-1].path == e.Path {
+				continue
+			}
+			f := grepFile{path: e.Path, mode: e.Mode}
+			if opts.Cached {
+				f.sha = e.Sha
+			}
+			files = append(files, f)
+		}
+	}
+
+	var targets []grepFile
+	for _, f := range files {
+		if f.mode == 0160000 || !matchPathspec(f.path, opts.Paths) {
+			continue
+		}
+		targets = append(targets, f)
+	}
+
+	workers, err := r.workers("grep.threads")
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*GrepResult, len(targets))
+	err = parallel(workers, len(targets), func(i int) error {
+		data, err := r.grepData(targets[i])
+		if err != nil {
+			return err
+		}
+		results[i] = grepLines(targets[i].path, data, opts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*GrepResult
+	for _, res := range results {
+		if res != nil {
+			matched = append(matched, res)
+		}
+	}
+	return matched, nil
+}
+
+// matchPathspec はpがpathsのディレクトリの中にあるか、globパターンに一致するか判定する
+func matchPathspec(p string, paths []string) bool {
+	if len(paths) == 0 || matchPaths(p, paths) {
+		return true
+	}
+	for _, path := range paths {
+		if strings.ContainsAny(path, "*?[") && wildmatch.Match(path, p, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repository) grepData(f grepFile) ([]byte, error) {
+	if f.sha != "" {
+		o, err := r.ReadObject(f.sha)
+		if err != nil {
+			return nil, err
+		}
+		return o.(*object.BlobObject).Data(), nil
+	}
+
+	full := filepath.Join(r.worktree, filepath.FromSlash(f.path))
+	if f.mode == 0120000 {
+		target, err := os.Readlink(full)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return []byte(target), err
+	}
+	data, err := os.ReadFile(full)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// grepLines はdataの各行を条件と照合し、一致した行がなければnilを返す
+func grepLines(p string, data []byte, opts GrepOptions) *GrepResult {
+	res := &GrepResult{Path: p, Binary: diff.IsBinary(data)}
+	for i, line := range diff.SplitLines(data) {
+		line = strings.TrimSuffix(line, "\n")
+		if matchGrepLine(line, opts) {
+			res.Matches = append(res.Matches, GrepMatch{Line: i + 1, Text: line})
+		}
+	}
+	if len(res.Matches) == 0 {
+		return nil
+	}
+	return res
+}
+
+func matchGrepLine(line string, opts GrepOptions) bool {
+	for _, and := range opts.Patterns {
+		ok := len(and) > 0
+		for _, re := range and {
+			if !matchRegexp(re, line, opts.WordRegexp) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRegexp はlineがreに一致するか判定する
+// wordが真の場合はgitと同じく、一致の前後が単語の文字であれば一致の開始位置の次から探し直す
+func matchRegexp(re *regexp.Regexp, line string, word bool) bool {
+	if !word {
+		return re.MatchString(line)
+	}
+	for start := 0; start <= len(line); {
+		loc := re.FindStringIndex(line[start:])
+		if loc == nil {
+			return false
+		}
+		begin, end := start+loc[0], start+loc[1]
+		if (begin == 0 || !isWordChar(line[begin-1])) && (end == len(line) || !isWordChar(line[end])) && end > begin {
+			return true
+		}
+		start = begin + 1
+	}
+	return false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
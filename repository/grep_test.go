@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchRegexpWord(t *testing.T) {
+	tests := []struct {
+		pattern, line string
+		want          bool
+	}{
+		{pattern: "foo", line: "foo bar", want: true},
+		{pattern: "foo", line: "foobar", want: false},
+		// 最初の一致が単語の途中でも、後の一致が単語であれば一致とする
+		{pattern: "foo", line: "foobar foo", want: true},
+		{pattern: "o+", line: "foo o", want: true},
+		{pattern: "bar", line: "foo_bar", want: false},
+	}
+	for _, tt := range tests {
+		if got := matchRegexp(regexp.MustCompile(tt.pattern), tt.line, true); got != tt.want {
+			t.Errorf("matchRegexp(%q, %q) = %v, want %v", tt.pattern, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{
+		"a.txt":   "foo\nbar\nfoo bar\n",
+		"d/b.txt": "bar foo\nbaz\n",
+		"bin":     "x\x00foo\n",
+	})
+	// インデックスとワークツリーをそれぞれHEADと異なる内容にする
+	writeTestFile(t, r, "d/b.txt", "foo baz\n")
+	if err := r.Add([]string{"d/b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, r, "a.txt", "foo\n")
+
+	foo := [][]*regexp.Regexp{{regexp.MustCompile("foo")}}
+	// 期待する結果は git grep -n [--cached] PATTERN [REV] [-- PATH] の出力
+	tests := []struct {
+		name string
+		rev  string
+		opts GrepOptions
+		want []*GrepResult
+	}{
+		{
+			name: "revision",
+			rev:  "HEAD",
+			opts: GrepOptions{Patterns: foo},
+			want: []*GrepResult{
+				{Path: "a.txt", Matches: []GrepMatch{{Line: 1, Text: "foo"}, {Line: 3, Text: "foo bar"}}},
+				{Path: "bin", Binary: true, Matches: []GrepMatch{{Line: 1, Text: "x\x00foo"}}},
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "bar foo"}}},
+			},
+		},
+		{
+			name: "cached",
+			opts: GrepOptions{Patterns: foo, Cached: true},
+			want: []*GrepResult{
+				{Path: "a.txt", Matches: []GrepMatch{{Line: 1, Text: "foo"}, {Line: 3, Text: "foo bar"}}},
+				{Path: "bin", Binary: true, Matches: []GrepMatch{{Line: 1, Text: "x\x00foo"}}},
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "foo baz"}}},
+			},
+		},
+		{
+			name: "worktree",
+			opts: GrepOptions{Patterns: foo},
+			want: []*GrepResult{
+				{Path: "a.txt", Matches: []GrepMatch{{Line: 1, Text: "foo"}}},
+				{Path: "bin", Binary: true, Matches: []GrepMatch{{Line: 1, Text: "x\x00foo"}}},
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "foo baz"}}},
+			},
+		},
+		{
+			name: "directory pathspec",
+			rev:  "HEAD",
+			opts: GrepOptions{Patterns: foo, Paths: []string{"d"}},
+			want: []*GrepResult{
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "bar foo"}}},
+			},
+		},
+		{
+			name: "glob pathspec",
+			rev:  "HEAD",
+			opts: GrepOptions{Patterns: foo, Paths: []string{"*.txt"}},
+			want: []*GrepResult{
+				{Path: "a.txt", Matches: []GrepMatch{{Line: 1, Text: "foo"}, {Line: 3, Text: "foo bar"}}},
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "bar foo"}}},
+			},
+		},
+		{
+			// -e foo --and -e bar --or -e baz
+			name: "and or",
+			rev:  "HEAD",
+			opts: GrepOptions{Patterns: [][]*regexp.Regexp{
+				{regexp.MustCompile("foo"), regexp.MustCompile("bar")},
+				{regexp.MustCompile("baz")},
+			}},
+			want: []*GrepResult{
+				{Path: "a.txt", Matches: []GrepMatch{{Line: 3, Text: "foo bar"}}},
+				{Path: "d/b.txt", Matches: []GrepMatch{{Line: 1, Text: "bar foo"}, {Line: 2, Text: "baz"}}},
+			},
+		},
+		{
+			name: "no match",
+			rev:  "HEAD",
+			opts: GrepOptions{Patterns: [][]*regexp.Regexp{{regexp.MustCompile("nomatch")}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Grep(tt.rev, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Grep() = %s, want %s", formatGrepResults(got), formatGrepResults(tt.want))
+			}
+		})
+	}
+}
+
+func formatGrepResults(results []*GrepResult) string {
+	var s []string
+	for _, res := range results {
+		s = append(s, fmt.Sprintf("%+v", *res))
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+var ErrUnknownIdentity = errors.New("identity unknown, please set user.name and user.email")
+
+// Identity の種類
+const (
+	Author    = "author"
+	Committer = "committer"
+)
+
+// Identity はkindの名前とメールアドレスと日時を返す
+// GIT_AUTHOR_NAMEなどの環境変数、author.nameなどの設定、user.nameの設定の順に探す
+func (r *Repository) Identity(kind string) (*object.Signature, error) {
+	return r.identity(kind, true)
+}
+
+// reflogIdentity は更新履歴に記録するコミッターを返す
+// gitと同じく名前やメールアドレスが設定されていなければ、エラーにせずユーザー名とホスト名から作る
+func (r *Repository) reflogIdentity() (*object.Signature, error) {
+	return r.identity(Committer, false)
+}
+
+func (r *Repository) identity(kind string, strict bool) (*object.Signature, error) {
+	env := "GIT_" + strings.ToUpper(kind) + "_"
+	lookup := func(key string) string {
+		if v := os.Getenv(env + strings.ToUpper(key)); v != "" {
+			return v
+		}
+		if v, ok := r.conf.Get(kind + "." + key); ok && v != "" {
+			return v
+		}
+		v, _ := r.conf.Get("user." + key)
+		return v
+	}
+	name, email := lookup("name"), lookup("email")
+	if name == "" || email == "" {
+		if strict {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownIdentity, kind)
+		}
+		defName, defEmail := defaultIdentity()
+		if name == "" {
+			name = defName
+		}
+		if email == "" {
+			email = defEmail
+		}
+	}
+
+	when := time.Now()
+	if date := os.Getenv(env + "DATE"); date != "" {
+		var err error
+		if when, err = parseIdentDate(date); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, env+"DATE")
+		}
+	}
+	sig := object.NewSignature(name, email, when)
+	if sig.Name == "" {
+		if strict {
+			return nil, fmt.Errorf("%w: name consists only of disallowed characters: %s", ErrUnknownIdentity, name)
+		}
+		defName, _ := defaultIdentity()
+		sig.Name = object.NewSignature(defName, email, when).Name
+	}
+	return sig, nil
+}
+
+// defaultIdentity はgitの既定の名前とメールアドレスを返す
+// 名前はpasswdのGECOSかユーザー名、メールアドレスは "ユーザー名@ホスト名" で、ホスト名にドメインがなければ ".(none)" を付ける
+func defaultIdentity() (string, string) {
+	name, login := "unknown", "unknown"
+	if u, err := user.Current(); err == nil {
+		login, name = u.Username, u.Username
+		if gecos, _, _ := strings.Cut(u.Name, ","); gecos != "" {
+			name = gecos
+		}
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "(none)"
+	}
+	if !strings.Contains(host, ".") {
+		host += ".(none)"
+	}
+	return name, login + "@" + host
+}
+
+var rawDateReg = regexp.MustCompile(`^@?([0-9]+)(?: ([+-][0-9]{4}))?$`)
+
+// parseIdentDate はGIT_AUTHOR_DATEなどの日時を解釈する
+// gitの内部形式の "秒 タイムゾーン"、RFC 2822、ISO 8601に対応する
+func parseIdentDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if m := rawDateReg.FindStringSubmatch(s); m != nil {
+		sec, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if m[2] == "" {
+			return time.Unix(sec, 0), nil
+		}
+		sig, err := object.ParseSignature(fmt.Sprintf("<> %d %s", sec, m[2]))
+		if err != nil {
+			return time.Time{}, err
+		}
+		// gitと同じく "-0000" は "+0000" として記録する
+		_, offset := sig.When.Zone()
+		return sig.When.In(time.FixedZone("", offset)), nil
+	}
+	for _, layout := range []string{
+		time.RFC1123Z,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		time.RFC3339,
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02T15:04:05-0700",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", s)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/showa-93/wyag-go/ignore"
+)
+
+// Ignore はcore.excludesFile、.git/info/exclude、ワークツリーの.gitignoreを読み込むMatcherを返す
+func (r *Repository) Ignore() (*ignore.Matcher, error) {
+	caseFold, err := r.conf.GetBool("core.ignorecase", false)
+	if err != nil {
+		return nil, err
+	}
+
+	excludesFile, ok, err := r.conf.GetPath("core.excludesfile")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		excludesFile = defaultExcludesFile()
+	}
+	var global []*ignore.Pattern
+	if excludesFile != "" {
+		global, err = ignore.ReadPatterns(excludesFile, "", excludesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var exclude []*ignore.Pattern
+	if r.gitdir != "" {
+		path := r.Path(filepath.Join("info", "exclude"))
+		source := path
+		if rel, err := filepath.Rel(r.worktree, path); err == nil {
+			source = filepath.ToSlash(rel)
+		}
+		exclude, err = ignore.ReadPatterns(path, "", source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ignore.NewMatcher(r.worktree, caseFold, global, exclude), nil
+}
+
+// defaultExcludesFile はcore.excludesFileが未設定の場合に読み込むファイルを返す
+func defaultExcludesFile() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
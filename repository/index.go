@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"github.com/showa-93/wyag-go/index"
+)
+
+// Index はステージングエリアを読み込む
+func (r *Repository) Index() (*index.Index, error) {
+	return r.index.ReadIndex()
+}
+
+// WriteIndex はステージングエリアを保存する
+func (r *Repository) WriteIndex(idx *index.Index) error {
+	return r.index.WriteIndex(idx)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"github.com/showa-93/wyag-go/config"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/oid"
+	"github.com/showa-93/wyag-go/refs"
+	"github.com/showa-93/wyag-go/storage"
+)
+
+// NewMemoryRepository はオブジェクト、参照、設定、インデックスをメモリ上に持つリポジトリを作る
+// ディスクには一切書き込まないため、テストや一時的な処理に使える
+// gitdirもワークツリーも持たないため、MakeFileなどファイルを扱う操作はErrInMemoryを返す
+func NewMemoryRepository() *Repository {
+	return NewMemoryRepositoryWithFormat(oid.SHA1)
+}
+
+// NewMemoryRepositoryWithFormat は指定のオブジェクトフォーマットでメモリ上のリポジトリを作る
+func NewMemoryRepositoryWithFormat(format oid.Format) *Repository {
+	conf := config.New()
+	if format == oid.SHA1 {
+		conf.Set("core.repositoryformatversion", "0")
+	} else {
+		conf.Set("core.repositoryformatversion", "1")
+		conf.Set("extensions.objectformat", string(format))
+	}
+	r := &Repository{
+		conf:    conf,
+		format:  format,
+		objects: storage.NewMemoryStore(format),
+		refs:    refs.NewMemoryStore(),
+		index:   index.NewMemoryStorage(format),
+		cache:   newObjectCache(defaultObjectCacheLimit),
+	}
+	r.refs.WriteRef("HEAD", "ref: refs/heads/master")
+	return r
+}
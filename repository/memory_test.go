@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	for _, format := range []oid.Format{oid.SHA1, oid.SHA256} {
+		t.Run(string(format), func(t *testing.T) {
+			r := NewMemoryRepositoryWithFormat(format)
+			if r.Format() != format {
+				t.Fatalf("Format() = %s, want %s", r.Format(), format)
+			}
+
+			// インデックス
+			blob, err := r.WriteObject(object.NewBlobObject([]byte("hello\n")), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx := index.New(format)
+			idx.Add(&index.Entry{Mode: 0100644, Sha: blob, Path: "a/hello.txt"})
+			if err := r.WriteIndex(idx); err != nil {
+				t.Fatal(err)
+			}
+			// 書き込んだ後の変更は保存したインデックスに影響しない
+			idx.Add(&index.Entry{Mode: 0100644, Sha: blob, Path: "b.txt"})
+			got, err := r.Index()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entries := got.Entries(); len(entries) != 1 || entries[0].Path != "a/hello.txt" || entries[0].Sha != blob {
+				t.Fatalf("Index() entries = %v, want a/hello.txt %s", entries, blob)
+			}
+
+			// オブジェクト
+			tree, err := r.WriteTree(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig := object.NewSignature("T", "t@example.com", time.Unix(1700000000, 0).In(time.FixedZone("", 9*60*60)))
+			commit, err := r.CreateCommit(&object.CommitBuilder{Tree: tree, Author: sig, Committer: sig, Message: "first\n"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(commit) != format.HexSize() {
+				t.Errorf("commit sha %s has length %d, want %d", commit, len(commit), format.HexSize())
+			}
+			for sha, want := range map[string]object.ObjectType{blob: object.Blob, tree: object.Tree, commit: object.Commit} {
+				tp, _, err := r.Objects().ReadObject(sha)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tp != want {
+					t.Errorf("ReadObject(%s) type = %s, want %s", sha, tp, want)
+				}
+			}
+			entry, err := r.TreeEntry(tree, "a/hello.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Sha() != blob {
+				t.Errorf("TreeEntry(a/hello.txt) = %s, want %s", entry.Sha(), blob)
+			}
+
+			// 参照
+			if err := r.UpdateRefWithLog("refs/heads/master", commit, "commit (initial): first"); err != nil {
+				t.Fatal(err)
+			}
+			name, sha, err := r.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != "refs/heads/master" || sha != commit {
+				t.Errorf("Head() = %s, %s, want refs/heads/master, %s", name, sha, commit)
+			}
+			shas, err := r.ResolveObject("master^{tree}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shas) != 1 || shas[0] != tree {
+				t.Errorf("ResolveObject(master^{tree}) = %v, want %s", shas, tree)
+			}
+			list, err := r.ListRef("refs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list[0].Path() != "refs/heads/master" || list[0].Sha() != commit {
+				t.Errorf("ListRef(refs) = %v, want refs/heads/master", list)
+			}
+			log, err := r.Reflog("HEAD")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(log) != 1 || log[0].Old != format.Zero() || log[0].New != commit {
+				t.Errorf("Reflog(HEAD) = %v, want one entry to %s", log, commit)
+			}
+
+			// ディスクを扱う操作はできない
+			if _, err := r.MakeFile("HEAD", false); !errors.Is(err, ErrInMemory) {
+				t.Errorf("MakeFile() error = %v, want ErrInMemory", err)
+			}
+		})
+	}
+}
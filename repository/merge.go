@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/showa-93/wyag-go/diff"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+var (
+	ErrLocalChanges       = errors.New("your local changes to the following files would be overwritten by merge")
+	ErrUntrackedOverwrite = errors.New("the following untracked working tree files would be overwritten by merge")
+	ErrUnsupportedMerge   = errors.New("unsupported merge")
+)
+
+// MergeResult はツリーの3方向マージの結果
+type MergeResult struct {
+	// Index はマージ後のツリーの内容 衝突したパスはステージ1〜3のエントリを持つ
+	Index *index.Index
+	// Conflicts は衝突したパス
+	Conflicts []string
+	// Messages はgitと同じ "Auto-merging" や "CONFLICT" から始まるマージの経過
+	Messages []string
+
+	// ours はoursのツリーのファイル changed はそこから変わったパス
+	ours    map[string]*DiffEntry
+	changed map[string]struct{}
+	// files は衝突したパスにワークツリーへ書き込む内容
+	files map[string]*mergedFile
+}
+
+// Clean は衝突がなかったか判定する
+func (m *MergeResult) Clean() bool {
+	return len(m.Conflicts) == 0
+}
+
+type mergedFile struct {
+	mode uint32
+	data []byte
+}
+
+// MergeTrees はbaseからoursとtheirsへの変更をパスごとに3方向マージする
+// 両方で変更されたファイルは行単位でマージし、衝突した行はourLabelとtheirLabelの目印で囲む
+// 名前の変更は検出しない baseが空の場合は空のツリーとして扱う
+func (r *Repository) MergeTrees(base, ours, theirs, ourLabel, theirLabel string) (*MergeResult, error) {
+	trees := make([]map[string]*DiffEntry, 3)
+	paths := map[string]struct{}{}
+	for i, tree := range []string{base, ours, theirs} {
+		files, err := r.ListTree(tree)
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = make(map[string]*DiffEntry, len(files))
+		for _, f := range files {
+			trees[i][f.Path] = f
+			paths[f.Path] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	m := &MergeResult{
+		Index:   index.New(r.format),
+		ours:    trees[1],
+		changed: map[string]struct{}{},
+		files:   map[string]*mergedFile{},
+	}
+	for _, p := range sorted {
+		if err := r.mergeEntry(m, p, trees[0][p], trees[1][p], trees[2][p], ourLabel, theirLabel); err != nil {
+			return nil, err
+		}
+	}
+
+	// ファイルとディレクトリの衝突には対応しない
+	for _, e := range m.Index.Entries() {
+		for d := parentDir(e.Path); d != ""; d = parentDir(d) {
+			if len(m.Index.Stages(d)) > 0 {
+				return nil, fmt.Errorf("%w: %s is a file on one side and a directory on the other", ErrUnsupportedMerge, d)
+			}
+		}
+	}
+	return m, nil
+}
+
+func sameEntry(a, b *DiffEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Sha == b.Sha && a.Mode == b.Mode
+}
+
+func isRegular(e *DiffEntry) bool {
+	return e != nil && e.Mode&0170000 == 0100000
+}
+
+func (r *Repository) mergeEntry(m *MergeResult, p string, base, ours, theirs *DiffEntry, ourLabel, theirLabel string) error {
+	var result *DiffEntry
+	switch {
+	case sameEntry(ours, theirs), sameEntry(base, theirs):
+		result = ours
+	case sameEntry(base, ours):
+		result = theirs
+	case isRegular(ours) && isRegular(theirs) && (base == nil || isRegular(base)):
+		return r.mergeContent(m, p, base, ours, theirs, ourLabel, theirLabel)
+	default:
+		return r.mergeConflict(m, p, base, ours, theirs, ourLabel, theirLabel)
+	}
+
+	if !sameEntry(result, ours) {
+		m.changed[p] = struct{}{}
+	}
+	if result != nil {
+		m.Index.Add(&index.Entry{Mode: result.Mode, Sha: result.Sha, Path: p})
+	}
+	return nil
+}
+
+// mergeContent は両方で変更されたファイルの内容を行単位でマージする
+func (r *Repository) mergeContent(m *MergeResult, p string, base, ours, theirs *DiffEntry, ourLabel, theirLabel string) error {
+	var data [3][]byte
+	for i, e := range []*DiffEntry{base, ours, theirs} {
+		if e == nil {
+			continue
+		}
+		_, raw, err := r.objects.ReadObject(e.Sha)
+		if err != nil {
+			return err
+		}
+		data[i] = raw
+	}
+
+	// 実行権限は変更された側に合わせ、両方で異なる変更をした場合は衝突とする
+	mode, modeConflict := ours.Mode, false
+	if ours.Mode != theirs.Mode {
+		switch {
+		case base != nil && base.Mode == ours.Mode:
+			mode = theirs.Mode
+		case base != nil && base.Mode == theirs.Mode:
+		default:
+			modeConflict = true
+		}
+	}
+
+	m.Messages = append(m.Messages, "Auto-merging "+p)
+	merged, conflicts := data[1], 1
+	if diff.IsBinary(data[0]) || diff.IsBinary(data[1]) || diff.IsBinary(data[2]) {
+		m.Messages = append(m.Messages, fmt.Sprintf("warning: Cannot merge binary files: %s (%s vs. %s)", p, ourLabel, theirLabel))
+	} else {
+		merged, conflicts = diff.Merge3(data[0], data[1], data[2], ourLabel, theirLabel)
+	}
+
+	if conflicts == 0 && !modeConflict {
+		sha, err := r.WriteObject(object.NewBlobObject(merged), true)
+		if err != nil {
+			return err
+		}
+		if sha != ours.Sha || mode != ours.Mode {
+			m.changed[p] = struct{}{}
+		}
+		m.Index.Add(&index.Entry{Mode: mode, Sha: sha, Path: p})
+		return nil
+	}
+
+	kind := "content"
+	if base == nil {
+		kind = "add/add"
+	}
+	if modeConflict && conflicts == 0 {
+		m.Messages = append(m.Messages, fmt.Sprintf("CONFLICT (mode): %s changed mode differently in %s and %s", p, ourLabel, theirLabel))
+	} else {
+		m.Messages = append(m.Messages, fmt.Sprintf("CONFLICT (%s): Merge conflict in %s", kind, p))
+	}
+	m.addConflict(p, base, ours, theirs, &mergedFile{mode: ours.Mode, data: merged})
+	return nil
+}
+
+// mergeConflict は片側で削除されたファイルや種類の異なる変更を衝突とし、残っている側の内容をワークツリーに残す
+func (r *Repository) mergeConflict(m *MergeResult, p string, base, ours, theirs *DiffEntry, ourLabel, theirLabel string) error {
+	left := ours
+	switch {
+	case ours == nil:
+		left = theirs
+		m.Messages = append(m.Messages, fmt.Sprintf("CONFLICT (modify/delete): %s deleted in %s and modified in %s.  Version %s of %s left in tree.", p, ourLabel, theirLabel, theirLabel, p))
+	case theirs == nil:
+		m.Messages = append(m.Messages, fmt.Sprintf("CONFLICT (modify/delete): %s deleted in %s and modified in %s.  Version %s of %s left in tree.", p, theirLabel, ourLabel, ourLabel, p))
+	default:
+		m.Messages = append(m.Messages, fmt.Sprintf("CONFLICT (distinct types): %s had different types on each side of the merge", p))
+	}
+
+	var data []byte
+	if left.Mode != 0160000 {
+		_, raw, err := r.objects.ReadObject(left.Sha)
+		if err != nil {
+			return err
+		}
+		data = raw
+	}
+	m.addConflict(p, base, ours, theirs, &mergedFile{mode: left.Mode, data: data})
+	return nil
+}
+
+func (m *MergeResult) addConflict(p string, base, ours, theirs *DiffEntry, file *mergedFile) {
+	for i, e := range []*DiffEntry{base, ours, theirs} {
+		if e != nil {
+			m.Index.Add(&index.Entry{Mode: e.Mode, Sha: e.Sha, Path: p, Stage: i + 1})
+		}
+	}
+	m.Conflicts = append(m.Conflicts, p)
+	m.changed[p] = struct{}{}
+	m.files[p] = file
+}
+
+// CheckoutMerge はマージでoursのツリーから変わったパスをインデックスとワークツリーに反映する
+// 衝突したパスはステージ1〜3のエントリを記録し、ワークツリーには衝突の目印を含む内容を書き込む
+// 変わるパスのインデックスかワークツリーにoursと異なる変更がある場合は、何も変更せずにエラーを返す
+func (r *Repository) CheckoutMerge(m *MergeResult) error {
+	if r.worktree == "" {
+		return ErrInMemory
+	}
+	idx, err := r.Index()
+	if err != nil {
+		return err
+	}
+	conv, err := r.Converter()
+	if err != nil {
+		return err
+	}
+	changed := make([]string, 0, len(m.changed))
+	for p := range m.changed {
+		changed = append(changed, p)
+	}
+	sort.Strings(changed)
+	out, err := r.checkoutConverter(indexAttributes(m.Index))
+	if err != nil {
+		return err
+	}
+
+	var dirty, untracked []string
+	for _, p := range changed {
+		e, ok := idx.Entry(p)
+		if !ok {
+			if len(idx.Stages(p)) > 0 {
+				return ErrUnmergedIndex
+			}
+			if len(m.Index.Stages(p)) == 0 {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(r.worktree, filepath.FromSlash(p))); err == nil {
+				untracked = append(untracked, p)
+			}
+			continue
+		}
+		// oursに存在しないパスがインデックスにある場合も変更とみなす
+		if o := m.ours[p]; o == nil || o.Sha != e.Sha || o.Mode != e.Mode {
+			dirty = append(dirty, p)
+			continue
+		}
+		modified, err := r.Modified(conv, e)
+		if err != nil {
+			return err
+		}
+		if modified {
+			dirty = append(dirty, p)
+		}
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("%w:\n\t%s", ErrLocalChanges, strings.Join(dirty, "\n\t"))
+	}
+	if len(untracked) > 0 {
+		return fmt.Errorf("%w:\n\t%s", ErrUntrackedOverwrite, strings.Join(untracked, "\n\t"))
+	}
+
+	for _, p := range changed {
+		if _, ok := idx.Entry(p); ok && len(m.Index.Stages(p)) == 0 {
+			idx.Remove(p)
+			if err := r.removeWorktreeFile(p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range changed {
+		stages := m.Index.Stages(p)
+		if len(stages) == 0 {
+			continue
+		}
+		idx.Remove(p)
+		for _, e := range stages {
+			entry := *e
+			idx.Add(&entry)
+		}
+		if f, ok := m.files[p]; ok {
+			if err := r.writeWorktreeData(out, p, f.mode, f.data); err != nil {
+				return err
+			}
+			continue
+		}
+		e, _ := idx.Entry(p)
+		if err := r.writeWorktreeFile(out, e); err != nil {
+			return err
+		}
+	}
+	return r.WriteIndex(idx)
+}
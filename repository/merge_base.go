@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// MergeBases はコミットaとbの共通の祖先のうち、他の共通の祖先の祖先でないものをshaの順に返す
+func (r *Repository) MergeBases(a, b string) ([]string, error) {
+	parents := func(sha string) ([]string, error) {
+		o, err := r.ReadObject(sha)
+		if err != nil {
+			return nil, err
+		}
+		commit, ok := o.(*object.CommitObject)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a commit", sha)
+		}
+		return commit.Parents(), nil
+	}
+	// walk はstartから辿れるコミットをvisitに渡す visitが偽を返したコミットの親は辿らない
+	walk := func(start []string, visit func(string) bool) error {
+		seen := make(map[string]bool)
+		stack := append([]string{}, start...)
+		for len(stack) > 0 {
+			sha := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[sha] {
+				continue
+			}
+			seen[sha] = true
+			if !visit(sha) {
+				continue
+			}
+			ps, err := parents(sha)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, ps...)
+		}
+		return nil
+	}
+
+	ancestors := make(map[string]bool)
+	if err := walk([]string{a}, func(sha string) bool {
+		ancestors[sha] = true
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	// bから辿って最初に見つかるaの祖先が候補となる
+	var candidates []string
+	if err := walk([]string{b}, func(sha string) bool {
+		if ancestors[sha] {
+			candidates = append(candidates, sha)
+			return false
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	// 他の候補の祖先である候補を除く
+	var starts []string
+	for _, c := range candidates {
+		ps, err := parents(c)
+		if err != nil {
+			return nil, err
+		}
+		starts = append(starts, ps...)
+	}
+	redundant := make(map[string]bool)
+	if err := walk(starts, func(sha string) bool {
+		redundant[sha] = true
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	var bases []string
+	for _, c := range candidates {
+		if !redundant[c] {
+			bases = append(bases, c)
+		}
+	}
+	sort.Strings(bases)
+	return bases, nil
+}
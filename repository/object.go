@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+	"github.com/showa-93/wyag-go/storage"
+)
+
+func (r *Repository) WriteObject(o object.Object, acctually bool) (string, error) {
+	if !acctually {
+		return object.Hash(r.Format(), o)
+	}
+
+	data, err := o.Serialize()
+	if err != nil {
+		return "", err
+	}
+	// パックに含まれているオブジェクトをルーズオブジェクトとして書き直さないよう、全てのストアから探す
+	sha := object.HashRaw(r.Format(), o.TypeHeader(), data)
+	if ok, err := r.objects.HasObject(sha); err != nil || ok {
+		return sha, err
+	}
+	return r.objects.WriteObject(o.TypeHeader(), data)
+}
+
+// ReadObject はshaのオブジェクトを読み込む
+// 読み込んだオブジェクトはキャッシュして共有するため、呼び出し側で変更してはならない
+func (r *Repository) ReadObject(sha string) (object.Object, error) {
+	if o, ok := r.cache.get(sha); ok {
+		return o, nil
+	}
+	t, data, err := r.objects.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	o, err := object.NewObject(r.format, t, data)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.add(sha, o, int64(len(data)))
+	return o, nil
+}
+
+func (r *Repository) FindObject(name, typeHeader string, follow bool) (string, error) {
+	shaList, err := r.ResolveObject(name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(shaList) == 0 {
+		return "", fmt.Errorf("no such reference %s", name)
+	}
+
+	if len(shaList) > 1 {
+		return "", fmt.Errorf("ambiguous reference %s, candidates are %v", name, shaList)
+	}
+
+	sha := shaList[0]
+	t, ok := object.ConvertObjectType(typeHeader)
+	if !ok {
+		return sha, nil
+	}
+
+	for {
+		// 型の判定のためにオブジェクト全体を読み込まないようにヘッダーのみ読み込む
+		h, rc, err := r.objects.OpenObject(sha)
+		if err != nil {
+			return "", err
+		}
+		rc.Close()
+
+		if h.Type == t {
+			return sha, nil
+		}
+
+		if !follow || (h.Type != object.Tag && h.Type != object.Commit) {
+			return "", nil
+		}
+		o, err := r.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+
+		if o.TypeHeader() == object.Tag {
+			sha = o.(*object.TagObject).Target()
+		} else if o.TypeHeader() == object.Commit && t == object.Tree {
+			sha = o.(*object.CommitObject).Tree()
+		} else {
+			return "", nil
+		}
+	}
+}
+
+var hashReg = regexp.MustCompile("^[0-9A-Fa-f]{4,64}$")
+
+func (r *Repository) ResolveObject(name string) ([]string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil
+	}
+
+	// REV:pathはREVのツリーのpathにあるオブジェクト、:pathはインデックスのエントリを表す
+	if i := strings.Index(name, ":"); i >= 0 {
+		return r.resolvePath(name[:i], name[i+1:])
+	}
+
+	// REV~n、REV^n、REV^{type}はREVから辿ったオブジェクトを表す
+	if i := strings.IndexAny(name, "~^"); i > 0 {
+		return r.resolveSuffix(name, i)
+	}
+
+	// REF@{n}はREFの更新履歴のn個前の値を表す
+	if i := strings.Index(name, "@{"); i >= 0 {
+		return r.resolveReflog(name, i)
+	}
+
+	if r.Format().IsValid(strings.ToLower(name)) {
+		return []string{strings.ToLower(name)}, nil
+	}
+
+	// 参照名はshaの短縮形より優先する
+	sha, err := r.resolveRefName(name)
+	if err != nil {
+		return nil, err
+	}
+	if sha != "" {
+		return []string{sha}, nil
+	}
+
+	if hashReg.Match([]byte(name)) {
+		return r.objects.FindObjects(strings.ToLower(name))
+	}
+
+	return nil, nil
+}
+
+var stageReg = regexp.MustCompile("^[0-3]:")
+
+func (r *Repository) resolvePath(rev, p string) ([]string, error) {
+	if rev == "" {
+		stage := 0
+		if stageReg.MatchString(p) {
+			stage, p = int(p[0]-'0'), p[2:]
+		}
+		idx, err := r.Index()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range idx.Entries() {
+			if e.Path == p && e.Stage == stage {
+				return []string{e.Sha}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: path '%s' is not in the index", ErrNotExist, p)
+	}
+
+	tree, err := r.FindObject(rev, string(object.Tree), true)
+	if err != nil {
+		return nil, err
+	}
+	if tree == "" {
+		return nil, fmt.Errorf("%s is not a tree-ish", rev)
+	}
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return []string{tree}, nil
+	}
+	entry, err := r.TreeEntry(tree, p)
+	if err != nil {
+		if errors.Is(err, ErrNotExist) {
+			return nil, fmt.Errorf("%w: path '%s' does not exist in '%s'", ErrNotExist, p, rev)
+		}
+		return nil, err
+	}
+	return []string{entry.Sha()}, nil
+}
+
+// resolveSuffix はname[:i]のオブジェクトからname[i:]の接尾辞の順に辿る
+// 辿る先の親が存在しない場合は空を返す
+func (r *Repository) resolveSuffix(name string, i int) ([]string, error) {
+	shas, err := r.ResolveObject(name[:i])
+	if err != nil || len(shas) != 1 {
+		return shas, err
+	}
+	sha := shas[0]
+	for rest := name[i:]; rest != "" && sha != ""; {
+		if strings.HasPrefix(rest, "^{") {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid revision %s", name)
+			}
+			t := rest[2:end]
+			if sha, err = r.peel(sha, t); err != nil {
+				return nil, err
+			}
+			if sha == "" {
+				return nil, fmt.Errorf("%s: expected %s type", name, t)
+			}
+			rest = rest[end+1:]
+			continue
+		}
+
+		j := 1
+		for j < len(rest) && '0' <= rest[j] && rest[j] <= '9' {
+			j++
+		}
+		n := 1
+		if j > 1 {
+			if n, err = strconv.Atoi(rest[1:j]); err != nil {
+				return nil, fmt.Errorf("invalid revision %s", name)
+			}
+		}
+		switch rest[0] {
+		case '^':
+			sha, err = r.nthParent(sha, n)
+		case '~':
+			for k := 0; k < n && sha != "" && err == nil; k++ {
+				sha, err = r.nthParent(sha, 1)
+			}
+		default:
+			return nil, fmt.Errorf("invalid revision %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[j:]
+	}
+	if sha == "" {
+		return nil, nil
+	}
+	return []string{sha}, nil
+}
+
+// peel はタグをたどってtの型のオブジェクトを返す tが空の場合はタグ以外のオブジェクトまでたどる
+func (r *Repository) peel(sha, t string) (string, error) {
+	switch t {
+	case "object":
+		return sha, nil
+	case "":
+		for {
+			o, err := r.ReadObject(sha)
+			if err != nil {
+				return "", err
+			}
+			tag, ok := o.(*object.TagObject)
+			if !ok {
+				return sha, nil
+			}
+			target := tag.Target()
+			if target == "" {
+				return "", fmt.Errorf("malformed tag %s", sha)
+			}
+			sha = target
+		}
+	}
+	if _, ok := object.ConvertObjectType(t); !ok {
+		return "", fmt.Errorf("invalid object type %s", t)
+	}
+	return r.FindObject(sha, t, true)
+}
+
+// nthParent はコミットのn番目の親を返す 0の場合はコミット自身を返し、親が存在しない場合は空を返す
+func (r *Repository) nthParent(sha string, n int) (string, error) {
+	commit, err := r.FindObject(sha, string(object.Commit), true)
+	if err != nil {
+		return "", err
+	}
+	if commit == "" {
+		return "", fmt.Errorf("%s is not a commit", sha)
+	}
+	if n == 0 {
+		return commit, nil
+	}
+	o, err := r.ReadObject(commit)
+	if err != nil {
+		return "", err
+	}
+	parents := o.(*object.CommitObject).Parents()
+	if n > len(parents) {
+		return "", nil
+	}
+	return parents[n-1], nil
+}
+
+// refRules はgitと同じく短縮された参照名を探す順序
+var refRules = []string{
+	"%s",
+	"refs/%s",
+	"refs/tags/%s",
+	"refs/heads/%s",
+	"refs/remotes/%s",
+	"refs/remotes/%s/HEAD",
+}
+
+var pseudoRefReg = regexp.MustCompile("^[A-Z_]+$")
+
+// resolveRefName は短縮された参照名をshaに解決する 見つからなければ空文字を返す
+func (r *Repository) resolveRefName(name string) (string, error) {
+	if strings.Contains(name, "..") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+		return "", nil
+	}
+	for i, rule := range refRules {
+		// gitdir直下はHEADなどの大文字の名前のみを参照として扱う
+		if i == 0 && !pseudoRefReg.MatchString(name) && !strings.HasPrefix(name, "refs/") {
+			continue
+		}
+		sha, err := refs.ResolveRef(r.refs, fmt.Sprintf(rule, name))
+		if err == nil {
+			return string(sha), nil
+		}
+		if !errors.Is(err, refs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// HashObject はrdの内容からオブジェクトを作成し、writeが真ならリポジトリに書き込む
+// writeが偽の場合はrがnilでもsha1でshaの計算のみ行える
+func (r *Repository) HashObject(rd io.Reader, t object.ObjectType, write bool) (string, error) {
+	raw, err := io.ReadAll(rd)
+	if err != nil {
+		return "", err
+	}
+	o, err := object.NewObject(r.Format(), t, raw)
+	if err != nil {
+		return "", fmt.Errorf("unknown type tag=%s %w", t, err)
+	}
+
+	return r.WriteObject(o, write)
+}
+
+// OpenObject はオブジェクトの中身をすべて読み込まずに開く
+func (r *Repository) OpenObject(sha string) (storage.Header, io.ReadCloser, error) {
+	return r.objects.OpenObject(sha)
+}
+
+// HashObjectStream はrdからsizeバイトを読み込みながらshaを計算し、writeが真ならリポジトリに書き込む
+// 中身をすべてメモリに読み込まない writeが偽の場合はrがnilでもsha1で計算できる
+// rdがシーク可能な場合は先にshaを計算し、既に存在するオブジェクトは書き込まない
+func (r *Repository) HashObjectStream(rd io.Reader, t object.ObjectType, size int64, write bool) (string, error) {
+	if !write {
+		return object.HashStream(r.Format(), t, size, rd)
+	}
+	if rs, ok := rd.(io.ReadSeeker); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", err
+		}
+		sha, err := object.HashStream(r.Format(), t, size, rs)
+		if err != nil {
+			return "", err
+		}
+		if ok, err := r.objects.HasObject(sha); err != nil || ok {
+			return sha, err
+		}
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+	return r.objects.WriteObjectStream(t, size, rd)
+}
+
+// DefaultAbbrev は短縮したshaの最小の長さ
+const DefaultAbbrev = 7
+
+// Abbrev はshaを他のオブジェクトと区別できる最短の長さ(min以上)に短縮する
+func (r *Repository) Abbrev(sha string, min int) (string, error) {
+	for n := min; n < len(sha); n++ {
+		found, err := r.objects.FindObjects(sha[:n])
+		if err != nil {
+			return "", err
+		}
+		if len(found) <= 1 {
+			return sha[:n], nil
+		}
+	}
+	return sha, nil
+}
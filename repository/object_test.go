@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/storage"
+)
+
+func TestWriteObjectSkipsObjectsInOtherStores(t *testing.T) {
+	// パックなど読み込み専用のストアにあるオブジェクトはルーズオブジェクトとして書き直さない
+	r := newTestRepository(t)
+	packed := storage.NewMemoryStore(r.format)
+	data := []byte("packed\n")
+	sha, err := packed.WriteObject(object.Blob, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loose := storage.NewLooseStore(r.Path("objects"), r.format)
+	r.objects = storage.NewOverlayStore(loose, packed)
+
+	got, err := r.WriteObject(object.NewBlobObject(data), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sha {
+		t.Errorf("WriteObject = %s, want %s", got, sha)
+	}
+	if ok, err := loose.HasObject(sha); err != nil || ok {
+		t.Errorf("loose HasObject = %v, %v after WriteObject, want false", ok, err)
+	}
+
+	p := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err = r.HashObjectStream(f, object.Blob, int64(len(data)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != sha {
+		t.Errorf("HashObjectStream = %s, want %s", got, sha)
+	}
+	if ok, err := loose.HasObject(sha); err != nil || ok {
+		t.Errorf("loose HasObject = %v, %v after HashObjectStream, want false", ok, err)
+	}
+
+	// 存在しないオブジェクトはシークし直して書き込む
+	other := []byte("loose\n")
+	if err := os.WriteFile(p, other, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f2, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	got, err = r.HashObjectStream(f2, object.Blob, int64(len(other)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := loose.ReadObject(got); err != nil || string(data) != string(other) {
+		t.Errorf("loose ReadObject(%s) = %q, %v, want %q", got, data, err, other)
+	}
+}
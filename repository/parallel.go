@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallel はn個の処理fn(0)〜fn(n-1)をworkers個のgoroutineで実行する
+// いずれかがエラーを返した場合は残りの処理を始めずに最初のエラーを返す
+func parallel(workers, n int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		jobs     = make(chan int)
+		done     = make(chan struct{})
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-done:
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
+
+// workers は設定keyから並列に処理するgoroutineの数を決める
+// 設定がない場合や1未満の場合はCPUの数とする
+func (r *Repository) workers(key string) (int, error) {
+	n, err := r.conf.GetInt(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		return runtime.NumCPU(), nil
+	}
+	return int(n), nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	const n = 1000
+	var calls [n]int32
+	if err := parallel(4, n, func(i int) error {
+		atomic.AddInt32(&calls[i], 1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range calls {
+		if c != 1 {
+			t.Fatalf("fn(%d) called %d times, want 1", i, c)
+		}
+	}
+
+	if err := parallel(0, 0, func(i int) error {
+		t.Errorf("fn(%d) called with n = 0", i)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParallelError(t *testing.T) {
+	// 1つのgoroutineでは処理が順に行われるため、最初のエラーはfn(0)のもの
+	const n = 1000
+	var count int32
+	err := parallel(1, n, func(i int) error {
+		atomic.AddInt32(&count, 1)
+		return fmt.Errorf("error %d", i)
+	})
+	if err == nil || err.Error() != "error 0" {
+		t.Errorf("parallel() = %v, want error 0", err)
+	}
+	if c := atomic.LoadInt32(&count); c >= n {
+		t.Errorf("fn called %d times, want to stop after the first error", c)
+	}
+
+	// エラーの後に残りの処理を始めない
+	count = 0
+	err = parallel(4, n, func(i int) error {
+		atomic.AddInt32(&count, 1)
+		if i == 10 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "error 10" {
+		t.Errorf("parallel() = %v, want error 10", err)
+	}
+	if c := atomic.LoadInt32(&count); c >= n {
+		t.Errorf("fn called %d times, want to stop after the first error", c)
+	}
+}
@@ -0,0 +1,726 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+)
+
+// RebaseAction は再適用の手順で行う操作
+type RebaseAction string
+
+const (
+	RebasePick   RebaseAction = "pick"
+	RebaseReword RebaseAction = "reword"
+	RebaseSquash RebaseAction = "squash"
+	RebaseFixup  RebaseAction = "fixup"
+	RebaseDrop   RebaseAction = "drop"
+	RebaseExec   RebaseAction = "exec"
+)
+
+// rebaseAbbrevs は手順の一覧で使える操作の短縮形
+var rebaseAbbrevs = map[string]RebaseAction{
+	"p": RebasePick, "r": RebaseReword, "s": RebaseSquash, "f": RebaseFixup, "d": RebaseDrop, "x": RebaseExec,
+}
+
+var (
+	ErrNoRebase         = errors.New("No rebase in progress?")
+	ErrRebaseInProgress = errors.New("It seems that there is already a rebase-merge directory")
+	ErrUnstagedChanges  = errors.New("cannot rebase: You have unstaged changes.")
+	ErrUncommitted      = errors.New("cannot rebase: Your index contains uncommitted changes.")
+	ErrNothingToRebase  = errors.New("nothing to do")
+)
+
+// rebaseDir は途中で止まった再適用の状態を保存するgitdir配下のディレクトリ
+const rebaseDir = "rebase-merge"
+
+// RebaseStep は再適用の手順の一覧の1行
+type RebaseStep struct {
+	Action RebaseAction
+	// Commit は適用するコミット、Command はexecで実行するコマンド
+	Commit  string
+	Command string
+}
+
+// RebaseOptions は再適用の設定
+type RebaseOptions struct {
+	// Onto はコミットを積み直す先 空の場合はupstreamに積み直す
+	Onto string
+	// EditTodo は作成した手順の一覧を受け取り、実行する手順の一覧を返す nilの場合はそのまま実行する
+	EditTodo func(todo string) (string, error)
+}
+
+// RebaseResult は再適用を実行した結果
+type RebaseResult struct {
+	// Branch は再適用したブランチ HEADが切り離されていた場合は空
+	Branch string
+	// UpToDate は積み直す必要がなかったか
+	UpToDate bool
+	// Stopped は止まった手順 すべて適用した場合はnil
+	Stopped *RebaseStep
+	// Merge は止まった手順のコミットを3方向マージした結果
+	Merge *MergeResult
+}
+
+// rebase は再適用の状態
+type rebase struct {
+	// headName は再適用するブランチ HEADが切り離されていた場合は "detached HEAD"
+	headName string
+	onto     string
+	origHead string
+	todo     []*RebaseStep
+	done     []*RebaseStep
+}
+
+// StartRebase はupstreamから辿れずHEADから辿れるコミットを、onto(省略時はupstream)の上に1つずつ積み直す
+// マージコミットは積み直さない 衝突した場合などは状態を保存して止まり、ContinueRebaseなどで再開できる
+func (r *Repository) StartRebase(upstream string, opts RebaseOptions) (*RebaseResult, error) {
+	if r.gitdir == "" {
+		return nil, ErrInMemory
+	}
+	if _, err := os.Stat(r.Path(rebaseDir)); err == nil {
+		return nil, ErrRebaseInProgress
+	}
+	// gitと同じくcherry-pickやrevertが止まっている間は始めない
+	if _, err := os.Stat(r.Path(sequencerDir)); err == nil {
+		return nil, ErrSequenceInProgress
+	}
+	for _, name := range []string{"CHERRY_PICK_HEAD", "REVERT_HEAD"} {
+		if _, err := r.refs.ReadRef(name); err == nil {
+			return nil, fmt.Errorf("%w: %s exists", ErrSequenceInProgress, name)
+		} else if !errors.Is(err, refs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	ref, head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	if head == "" {
+		return nil, errors.New("cannot rebase an unborn branch")
+	}
+	upstreamSha, err := r.FindObject(upstream, string(object.Commit), true)
+	if err != nil {
+		return nil, err
+	}
+	if upstreamSha == "" {
+		return nil, fmt.Errorf("invalid upstream '%s'", upstream)
+	}
+	ontoName := upstream
+	if opts.Onto != "" {
+		ontoName = opts.Onto
+	}
+	onto, err := r.FindObject(ontoName, string(object.Commit), true)
+	if err != nil {
+		return nil, err
+	}
+	if onto == "" {
+		return nil, fmt.Errorf("Does not point to a valid commit '%s'", ontoName)
+	}
+	if err := r.checkCleanWorktree(); err != nil {
+		return nil, err
+	}
+
+	state := &rebase{headName: "detached HEAD", onto: onto, origHead: head}
+	res := &RebaseResult{}
+	if ref != "HEAD" {
+		state.headName = ref
+		res.Branch = ref
+	}
+	commits, err := r.rebaseCommits(upstreamSha, head)
+	if err != nil {
+		return nil, err
+	}
+	if opts.EditTodo == nil {
+		if ok, err := r.rebaseUpToDate(onto, commits); err != nil || ok {
+			res.UpToDate = ok
+			return res, err
+		}
+	}
+
+	var todo strings.Builder
+	for _, c := range commits {
+		line, err := r.todoLine(&RebaseStep{Action: RebasePick, Commit: c})
+		if err != nil {
+			return nil, err
+		}
+		todo.WriteString(line)
+	}
+	text := todo.String()
+	if opts.EditTodo != nil {
+		if text, err = opts.EditTodo(text); err != nil {
+			return nil, err
+		}
+	}
+	if state.todo, err = r.parseTodo(text); err != nil {
+		return nil, err
+	}
+	if opts.EditTodo != nil && len(state.todo) == 0 {
+		return nil, ErrNothingToRebase
+	}
+	for _, step := range state.todo {
+		if step.Action == RebaseSquash || step.Action == RebaseFixup {
+			return nil, fmt.Errorf("cannot '%s' without a previous commit", step.Action)
+		}
+		if step.Action != RebaseDrop && step.Action != RebaseExec {
+			break
+		}
+	}
+
+	if err := r.saveRebase(state); err != nil {
+		return nil, err
+	}
+	if err := r.refs.WriteRef("ORIG_HEAD", head); err != nil {
+		return nil, err
+	}
+	if err := r.moveHead(onto, "rebase (start): checkout "+ontoName); err != nil {
+		return nil, err
+	}
+	return r.runRebase(state, res)
+}
+
+// checkCleanWorktree はインデックスとワークツリーに未コミットの変更がないか確認する
+func (r *Repository) checkCleanWorktree() error {
+	idx, err := r.Index()
+	if err != nil {
+		return err
+	}
+	if idx.Unmerged() {
+		return fmt.Errorf("%w: rebase is not possible because you have unmerged files", ErrUnmergedIndex)
+	}
+	conv, err := r.Converter()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries() {
+		if e.Mode == 0160000 {
+			continue
+		}
+		modified, err := r.Modified(conv, e)
+		if err != nil {
+			return err
+		}
+		if modified {
+			return ErrUnstagedChanges
+		}
+	}
+	tree, err := r.WriteTree(idx)
+	if err != nil {
+		return err
+	}
+	headTree, err := r.FindObject("HEAD", string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	if tree != headTree {
+		return ErrUncommitted
+	}
+	return nil
+}
+
+// rebaseCommits はupstreamから辿れずheadから辿れるマージコミット以外のコミットを古い順に返す
+func (r *Repository) rebaseCommits(upstream, head string) ([]string, error) {
+	walk := r.NewRevWalk(RevWalkOptions{MaxCount: -1, Reverse: true})
+	if err := walk.Push(head, head); err != nil {
+		return nil, err
+	}
+	if err := walk.Hide(upstream); err != nil {
+		return nil, err
+	}
+	var commits []string
+	for {
+		sha, err := walk.Next()
+		if err == io.EOF {
+			return commits, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		o, err := r.ReadObject(sha)
+		if err != nil {
+			return nil, err
+		}
+		if len(o.(*object.CommitObject).Parents()) > 1 {
+			continue
+		}
+		commits = append(commits, sha)
+	}
+}
+
+// rebaseUpToDate はcommitsがすでにontoの上に順に積まれているか返す
+func (r *Repository) rebaseUpToDate(onto string, commits []string) (bool, error) {
+	parent := onto
+	for _, c := range commits {
+		o, err := r.ReadObject(c)
+		if err != nil {
+			return false, err
+		}
+		if parents := o.(*object.CommitObject).Parents(); len(parents) != 1 || parents[0] != parent {
+			return false, nil
+		}
+		parent = c
+	}
+	_, head, err := r.Head()
+	return head == parent, err
+}
+
+// todoLine はgitと同じく "操作 短縮sha 件名" の形式の手順の行を返す
+func (r *Repository) todoLine(step *RebaseStep) (string, error) {
+	if step.Action == RebaseExec {
+		return fmt.Sprintf("%s %s\n", step.Action, step.Command), nil
+	}
+	abbrev, err := r.Abbrev(step.Commit, DefaultAbbrev)
+	if err != nil {
+		return "", err
+	}
+	o, err := r.ReadObject(step.Commit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s\n", step.Action, abbrev, firstLine(o.(*object.CommitObject).Message())), nil
+}
+
+// parseTodo は手順の一覧を読み込む 空行と "#" から始まる行は無視する
+func (r *Repository) parseTodo(text string) ([]*RebaseStep, error) {
+	var steps []*RebaseStep
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, _ := strings.Cut(line, " ")
+		action := RebaseAction(name)
+		if a, ok := rebaseAbbrevs[name]; ok {
+			action = a
+		}
+		step := &RebaseStep{Action: action}
+		switch action {
+		case RebaseExec:
+			if step.Command = strings.TrimSpace(rest); step.Command == "" {
+				return nil, fmt.Errorf("missing command: %s", line)
+			}
+			steps = append(steps, step)
+			continue
+		case RebasePick, RebaseReword, RebaseDrop, RebaseSquash, RebaseFixup:
+		default:
+			return nil, fmt.Errorf("invalid command '%s': %s", name, line)
+		}
+		rev, _, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		sha, err := r.FindObject(rev, string(object.Commit), true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, line)
+		}
+		if sha == "" {
+			return nil, fmt.Errorf("invalid commit '%s': %s", rev, line)
+		}
+		step.Commit = sha
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// moveHead はHEADを切り離してcommitに移動し、インデックスとワークツリーをそのツリーに置き換える
+func (r *Repository) moveHead(commit, message string) error {
+	tree, err := r.FindObject(commit, string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	if err := r.resetIndex(tree, true); err != nil {
+		return err
+	}
+	return r.UpdateRefWithLog("HEAD", commit, message)
+}
+
+// runRebase は残りの手順を順に実行し、すべて実行した場合はブランチを更新して状態を削除する
+func (r *Repository) runRebase(state *rebase, res *RebaseResult) (*RebaseResult, error) {
+	for len(state.todo) > 0 {
+		step := state.todo[0]
+		stopped, err := r.rebaseStep(step, res)
+		if err != nil {
+			// 実行済みの手順を中止や再開で扱えるように、失敗した手順を残して状態を保存する
+			if serr := r.saveRebase(state); serr != nil {
+				return nil, serr
+			}
+			return nil, err
+		}
+		if !stopped {
+			state.done = append(state.done, step)
+			state.todo = state.todo[1:]
+			continue
+		}
+		res.Stopped = step
+		if step.Action == RebaseExec {
+			// gitと同じく失敗したexecは実行済みとし、再開した場合は次の手順から続ける
+			state.done = append(state.done, step)
+			state.todo = state.todo[1:]
+		}
+		return res, r.saveRebase(state)
+	}
+	return res, r.finishRebase(state)
+}
+
+// rebaseStep は1つの手順を実行し、止まる必要があるか返す
+func (r *Repository) rebaseStep(step *RebaseStep, res *RebaseResult) (bool, error) {
+	switch step.Action {
+	case RebaseDrop:
+		return false, nil
+	case RebaseExec:
+		cmd := exec.Command("sh", "-c", step.Command)
+		cmd.Dir = r.worktree
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run() != nil, nil
+	}
+
+	o, err := r.ReadObject(step.Commit)
+	if err != nil {
+		return false, err
+	}
+	commit := o.(*object.CommitObject)
+	parents := commit.Parents()
+	if len(parents) > 1 {
+		return false, fmt.Errorf("commit %s is a merge which is not supported", step.Commit)
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return false, err
+	}
+	reason := fmt.Sprintf("rebase (%s)", step.Action)
+
+	// 親がHEADのコミットはそのままHEADを進める
+	if step.Action == RebasePick && len(parents) == 1 && parents[0] == head {
+		return false, r.moveHead(step.Commit, reason+": "+commit.Subject())
+	}
+
+	var parentTree string
+	if len(parents) == 1 {
+		if parentTree, err = r.FindObject(parents[0], string(object.Tree), true); err != nil {
+			return false, err
+		}
+	}
+	headTree, err := r.FindObject(head, string(object.Tree), true)
+	if err != nil {
+		return false, err
+	}
+	abbrev, err := r.Abbrev(step.Commit, DefaultAbbrev)
+	if err != nil {
+		return false, err
+	}
+	label := fmt.Sprintf("%s (%s)", abbrev, firstLine(commit.Message()))
+	m, err := r.MergeTrees(parentTree, headTree, commit.Tree(), "HEAD", label)
+	if err != nil {
+		return false, err
+	}
+	if err := r.CheckoutMerge(m); err != nil {
+		return false, err
+	}
+
+	message, err := r.rebaseMessage(step)
+	if err != nil {
+		return false, err
+	}
+	if !m.Clean() || step.Action == RebaseReword {
+		res.Merge = m
+		if !m.Clean() {
+			message += "\n# Conflicts:\n"
+			for _, p := range m.Conflicts {
+				message += "#\t" + p + "\n"
+			}
+			if err := r.refs.WriteRef("REBASE_HEAD", step.Commit); err != nil {
+				return false, err
+			}
+		}
+		if err := os.WriteFile(r.Path(rebaseDir+"/stopped-sha"), []byte(step.Commit+"\n"), os.FileMode(0644)); err != nil {
+			return false, err
+		}
+		return true, os.WriteFile(r.Path("MERGE_MSG"), []byte(message), os.FileMode(0644))
+	}
+	_, err = r.commitStep(step, message, reason)
+	return false, err
+}
+
+// rebaseMessage は手順のコミットのメッセージを返す
+// squashの場合は直前のコミットとメッセージをつなげ、fixupの場合は直前のコミットのメッセージを使う
+func (r *Repository) rebaseMessage(step *RebaseStep) (string, error) {
+	o, err := r.ReadObject(step.Commit)
+	if err != nil {
+		return "", err
+	}
+	message := o.(*object.CommitObject).Message()
+	if step.Action != RebaseSquash && step.Action != RebaseFixup {
+		return message, nil
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	if o, err = r.ReadObject(head); err != nil {
+		return "", err
+	}
+	prev := o.(*object.CommitObject).Message()
+	if step.Action == RebaseFixup {
+		return prev, nil
+	}
+	return object.CleanupMessage(prev) + "\n" + object.CleanupMessage(message), nil
+}
+
+// commitStep はインデックスの内容で手順のコミットを作成する squashとfixupは直前のコミットを置き換える
+// 積み直した結果変更が空になったコミットは作成しない
+func (r *Repository) commitStep(step *RebaseStep, message, reason string) (string, error) {
+	o, err := r.ReadObject(step.Commit)
+	if err != nil {
+		return "", err
+	}
+	commit := o.(*object.CommitObject)
+	opt := CommitOptions{Message: message, Reason: reason}
+	if step.Action == RebaseSquash || step.Action == RebaseFixup {
+		opt.Amend = true
+	} else if opt.Author, err = commit.Author(); err != nil {
+		return "", err
+	}
+
+	// 元から変更が空のコミットはそのまま残す
+	parents := commit.Parents()
+	if len(parents) == 1 {
+		parentTree, err := r.FindObject(parents[0], string(object.Tree), true)
+		if err != nil {
+			return "", err
+		}
+		opt.AllowEmpty = parentTree == commit.Tree()
+	}
+	sha, err := r.Commit(opt)
+	if errors.Is(err, ErrNothingToCommit) {
+		return "", nil
+	}
+	return sha, err
+}
+
+// finishRebase はブランチを積み直したHEADに更新してHEADをブランチに戻し、状態を削除する
+func (r *Repository) finishRebase(state *rebase) error {
+	_, head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if state.headName != "detached HEAD" {
+		if err := r.UpdateRefWithLog(state.headName, head, fmt.Sprintf("rebase (finish): %s onto %s", state.headName, state.onto)); err != nil {
+			return err
+		}
+		if err := r.returnHead(state.headName, "rebase (finish): returning to "+state.headName); err != nil {
+			return err
+		}
+	}
+	return r.clearRebase()
+}
+
+// returnHead はHEADをブランチに戻し、HEADの更新履歴に記録する
+func (r *Repository) returnHead(branch, message string) error {
+	_, old, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if err := r.refs.WriteRef("HEAD", "ref: "+branch); err != nil {
+		return err
+	}
+	_, sha, err := r.Head()
+	if err != nil {
+		return err
+	}
+	ok, err := r.shouldLogRef("HEAD")
+	if err != nil || !ok {
+		return err
+	}
+	return r.appendReflog("HEAD", old, sha, message)
+}
+
+// clearRebase は再適用の状態を削除する
+func (r *Repository) clearRebase() error {
+	if err := r.refs.DeleteRef("REBASE_HEAD"); err != nil && !errors.Is(err, refs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(r.Path("MERGE_MSG")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(r.Path(rebaseDir))
+}
+
+// saveRebase は再適用の状態をgitと同じ形式で保存する
+func (r *Repository) saveRebase(state *rebase) error {
+	dir := r.Path(rebaseDir)
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return err
+	}
+	lines := func(steps []*RebaseStep) (string, error) {
+		var b strings.Builder
+		for _, step := range steps {
+			line, err := r.todoLine(step)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(line)
+		}
+		return b.String(), nil
+	}
+	todo, err := lines(state.todo)
+	if err != nil {
+		return err
+	}
+	done, err := lines(state.done)
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"head-name":       state.headName + "\n",
+		"onto":            state.onto + "\n",
+		"orig-head":       state.origHead + "\n",
+		"git-rebase-todo": todo,
+		"done":            done,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(r.Path(rebaseDir+"/"+name), []byte(data), os.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRebase は保存した状態を読み込む
+func (r *Repository) loadRebase() (*rebase, error) {
+	if r.gitdir == "" {
+		return nil, ErrInMemory
+	}
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(r.Path(rebaseDir + "/" + name))
+		if os.IsNotExist(err) {
+			return "", ErrNoRebase
+		}
+		return strings.TrimRight(string(data), "\n"), err
+	}
+	state := &rebase{}
+	var err error
+	if state.headName, err = read("head-name"); err != nil {
+		return nil, err
+	}
+	if state.onto, err = read("onto"); err != nil {
+		return nil, err
+	}
+	if state.origHead, err = read("orig-head"); err != nil {
+		return nil, err
+	}
+	for name, steps := range map[string]*[]*RebaseStep{"git-rebase-todo": &state.todo, "done": &state.done} {
+		text, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if *steps, err = r.parseTodo(text); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// ContinueRebase は止まった手順のコミットを衝突を解決したインデックスの内容とMERGE_MSGのメッセージで作成し、残りの手順を実行する
+func (r *Repository) ContinueRebase() (*RebaseResult, error) {
+	state, err := r.loadRebase()
+	if err != nil {
+		return nil, err
+	}
+	res := &RebaseResult{}
+	if state.headName != "detached HEAD" {
+		res.Branch = state.headName
+	}
+	if _, err := os.Stat(r.Path(rebaseDir + "/stopped-sha")); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		return r.runRebase(state, res)
+	}
+
+	idx, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	if idx.Unmerged() {
+		return nil, fmt.Errorf("%w: committing is not possible because you have unmerged files", ErrUnmergedIndex)
+	}
+	data, err := os.ReadFile(r.Path("MERGE_MSG"))
+	if err != nil {
+		return nil, err
+	}
+	step := state.todo[0]
+	if _, err := r.commitStep(step, stripComments(string(data)), "rebase (continue)"); err != nil {
+		return nil, err
+	}
+	if err := r.clearStop(); err != nil {
+		return nil, err
+	}
+	state.done = append(state.done, step)
+	state.todo = state.todo[1:]
+	return r.runRebase(state, res)
+}
+
+// clearStop は止まった手順の記録を削除する
+func (r *Repository) clearStop() error {
+	if err := r.refs.DeleteRef("REBASE_HEAD"); err != nil && !errors.Is(err, refs.ErrNotExist) {
+		return err
+	}
+	for _, p := range []string{r.Path(rebaseDir + "/stopped-sha"), r.Path("MERGE_MSG")} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipRebase は止まった手順の変更を取り消し、残りの手順を実行する
+func (r *Repository) SkipRebase() (*RebaseResult, error) {
+	state, err := r.loadRebase()
+	if err != nil {
+		return nil, err
+	}
+	res := &RebaseResult{}
+	if state.headName != "detached HEAD" {
+		res.Branch = state.headName
+	}
+	if err := r.resetHead(); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(r.Path(rebaseDir + "/stopped-sha")); err == nil {
+		if err := r.clearStop(); err != nil {
+			return nil, err
+		}
+		state.done = append(state.done, state.todo[0])
+		state.todo = state.todo[1:]
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return r.runRebase(state, res)
+}
+
+// AbortRebase は再適用を中止し、HEADとインデックスとワークツリーを始める前の状態に戻す
+func (r *Repository) AbortRebase() error {
+	state, err := r.loadRebase()
+	if err != nil {
+		return err
+	}
+	tree, err := r.FindObject(state.origHead, string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	if err := r.resetIndex(tree, true); err != nil {
+		return err
+	}
+	if state.headName == "detached HEAD" {
+		if err := r.UpdateRefWithLog("HEAD", state.origHead, "rebase (abort): returning to "+state.origHead); err != nil {
+			return err
+		}
+	} else if err := r.returnHead(state.headName, "rebase (abort): returning to "+state.headName); err != nil {
+		return err
+	}
+	return r.clearRebase()
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// setupRebase はbaseの上にupstreamのコミットを1つ、masterにtopicのコミットを順に作成し、
+// upstreamと名前とコミットのshaの組を返す
+func setupRebase(t *testing.T, r *Repository, upstream map[string]string, topic []string, files []map[string]string) (string, map[string]string) {
+	t.Helper()
+	base := commitTestFiles(t, r, "base", map[string]string{"f.txt": "base\n"})
+	up := commitTestFiles(t, r, "up", upstream)
+	if _, err := r.Reset(base, ResetHard); err != nil {
+		t.Fatal(err)
+	}
+	shas := map[string]string{"base": base}
+	for i, name := range topic {
+		shas[name] = commitTestFiles(t, r, name, files[i])
+	}
+	return up, shas
+}
+
+// rebaseLog はHEADから最初の親を辿ったコミットの件名を新しい順に返す
+func rebaseLog(t *testing.T, r *Repository, n int) []string {
+	t.Helper()
+	var subjects []string
+	sha := "HEAD"
+	for i := 0; i < n; i++ {
+		c, err := r.FindObject(sha, string(object.Commit), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := r.ReadObject(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commit := o.(*object.CommitObject)
+		subjects = append(subjects, commit.Subject())
+		if len(commit.Parents()) == 0 {
+			break
+		}
+		sha = commit.Parents()[0]
+	}
+	return subjects
+}
+
+func TestParseTodo(t *testing.T) {
+	r := newTestRepository(t)
+	one := commitTestFiles(t, r, "one", map[string]string{"one.txt": "1\n"})
+	two := commitTestFiles(t, r, "two", map[string]string{"two.txt": "2\n"})
+	abbrev, err := r.Abbrev(one, DefaultAbbrev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    []RebaseStep
+		wantErr bool
+	}{
+		{
+			name: "git format",
+			text: fmt.Sprintf("pick %s one\n\n# comment\nreword %s two\n", abbrev, two),
+			want: []RebaseStep{{Action: RebasePick, Commit: one}, {Action: RebaseReword, Commit: two}},
+		},
+		{
+			name: "abbreviated actions",
+			text: fmt.Sprintf("p %s\nr %s\ns %s\nf %s\nd %s\nx make test\n", one, one, one, one, one),
+			want: []RebaseStep{
+				{Action: RebasePick, Commit: one},
+				{Action: RebaseReword, Commit: one},
+				{Action: RebaseSquash, Commit: one},
+				{Action: RebaseFixup, Commit: one},
+				{Action: RebaseDrop, Commit: one},
+				{Action: RebaseExec, Command: "make test"},
+			},
+		},
+		{
+			name: "revision",
+			text: "  pick HEAD~1 subject is ignored  \nexec   echo  ok \n",
+			want: []RebaseStep{{Action: RebasePick, Commit: one}, {Action: RebaseExec, Command: "echo  ok"}},
+		},
+		{name: "empty", text: "# only comments\n\n"},
+		{name: "invalid command", text: "edit " + one + "\n", wantErr: true},
+		{name: "missing command", text: "exec\n", wantErr: true},
+		{name: "invalid commit", text: "pick 0000000 missing\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := r.parseTodo(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTodo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []RebaseStep
+			for _, step := range steps {
+				got = append(got, *step)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseTodo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebaseEditTodo(t *testing.T) {
+	r := newTestRepository(t)
+	up, shas := setupRebase(t, r, map[string]string{"up.txt": "up\n"}, []string{"one", "two", "three"}, []map[string]string{
+		{"one.txt": "1\n"}, {"two.txt": "2\n"}, {"three.txt": "3\n"},
+	})
+
+	var want strings.Builder
+	for _, name := range []string{"one", "two", "three"} {
+		abbrev, err := r.Abbrev(shas[name], DefaultAbbrev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&want, "pick %s %s\n", abbrev, name)
+	}
+	res, err := r.StartRebase(up, RebaseOptions{EditTodo: func(todo string) (string, error) {
+		if todo != want.String() {
+			t.Errorf("todo = %q, want %q", todo, want.String())
+		}
+		return fmt.Sprintf("pick %s\ns %s\nd %s\n", shas["three"], shas["one"], shas["two"]), nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stopped != nil || res.Branch != "refs/heads/master" {
+		t.Errorf("StartRebase() = stopped %v branch %s, want finished on refs/heads/master", res.Stopped, res.Branch)
+	}
+
+	if got := rebaseLog(t, r, 3); strings.Join(got, ",") != "three,up,base" {
+		t.Errorf("log = %v, want [three up base]", got)
+	}
+	head, err := r.FindObject("HEAD", string(object.Commit), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := r.ReadObject(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := o.(*object.CommitObject).Message(), "three\n\none\n"; got != want {
+		t.Errorf("squashed message = %q, want %q", got, want)
+	}
+	for _, p := range []string{"one.txt", "three.txt", "up.txt"} {
+		readTestFile(t, r, p)
+	}
+	if _, err := os.Stat(filepath.Join(r.worktree, "two.txt")); !os.IsNotExist(err) {
+		t.Errorf("dropped two.txt exists: %v", err)
+	}
+	if _, err := os.Stat(r.Path(rebaseDir)); !os.IsNotExist(err) {
+		t.Errorf("%s remains after rebase: %v", rebaseDir, err)
+	}
+}
+
+func TestRebaseContinue(t *testing.T) {
+	r := newTestRepository(t)
+	up, _ := setupRebase(t, r, map[string]string{"f.txt": "up\n"}, []string{"one", "two"}, []map[string]string{
+		{"f.txt": "one\n"}, {"two.txt": "2\n"},
+	})
+
+	res, err := r.StartRebase(up, RebaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stopped == nil || res.Merge == nil || res.Merge.Clean() {
+		t.Fatalf("StartRebase() = %+v, want stopped with conflicts", res)
+	}
+	if _, err := r.ContinueRebase(); err == nil {
+		t.Error("ContinueRebase() succeeded with unmerged files")
+	}
+
+	if err := os.WriteFile(filepath.Join(r.worktree, "f.txt"), []byte("resolved\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add([]string{"f.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = r.ContinueRebase(); err != nil {
+		t.Fatal(err)
+	}
+	if res.Stopped != nil {
+		t.Fatalf("ContinueRebase() stopped at %v", res.Stopped)
+	}
+	if got := rebaseLog(t, r, 4); strings.Join(got, ",") != "two,one,up,base" {
+		t.Errorf("log = %v, want [two one up base]", got)
+	}
+	if ref, _, err := r.Head(); err != nil || ref != "refs/heads/master" {
+		t.Errorf("HEAD = %s, %v, want refs/heads/master", ref, err)
+	}
+	if got := readTestFile(t, r, "f.txt"); got != "resolved\n" {
+		t.Errorf("f.txt = %q, want %q", got, "resolved\n")
+	}
+}
+
+func TestRebaseAbort(t *testing.T) {
+	r := newTestRepository(t)
+	up, shas := setupRebase(t, r, map[string]string{"f.txt": "up\n"}, []string{"one", "two"}, []map[string]string{
+		{"two.txt": "2\n"}, {"f.txt": "two\n"},
+	})
+	tree, err := r.FindObject(shas["one"], string(object.Tree), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := r.Identity(Committer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := r.CreateCommit(&object.CommitBuilder{
+		Tree:      tree,
+		Parents:   []string{shas["one"], shas["base"]},
+		Author:    sig,
+		Committer: sig,
+		Message:   "merge\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		todo string
+	}{
+		{name: "conflict", todo: fmt.Sprintf("pick %s\npick %s\n", shas["one"], shas["two"])},
+		// 途中で失敗した手順も中止で始める前の状態に戻す
+		{name: "error", todo: fmt.Sprintf("pick %s\npick %s\npick %s\n", shas["one"], merge, shas["two"])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.StartRebase(up, RebaseOptions{EditTodo: func(string) (string, error) {
+				return tt.todo, nil
+			}})
+			if tt.name == "error" && err == nil {
+				t.Fatal("StartRebase() succeeded with a merge commit")
+			} else if tt.name != "error" && err != nil {
+				t.Fatal(err)
+			}
+			state, err := r.loadRebase()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state.done) != 1 || state.done[0].Commit != shas["one"] {
+				t.Errorf("done = %v, want [pick %s]", state.done, shas["one"])
+			}
+
+			if err := r.AbortRebase(); err != nil {
+				t.Fatal(err)
+			}
+			if ref, head, err := r.Head(); err != nil || ref != "refs/heads/master" || head != shas["two"] {
+				t.Errorf("HEAD = %s %s, %v, want refs/heads/master %s", ref, head, err, shas["two"])
+			}
+			if got := readTestFile(t, r, "f.txt"); got != "two\n" {
+				t.Errorf("f.txt = %q, want %q", got, "two\n")
+			}
+			if _, err := os.Stat(r.Path(rebaseDir)); !os.IsNotExist(err) {
+				t.Errorf("%s remains after abort: %v", rebaseDir, err)
+			}
+		})
+	}
+}
+
+func TestStartRebaseDuringSequence(t *testing.T) {
+	// gitと同じくcherry-pickやrevertが止まっている間はリベースを始めない
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, r *Repository, sha string)
+	}{
+		{name: "sequencer", setup: func(t *testing.T, r *Repository, sha string) {
+			if err := os.MkdirAll(r.Path(sequencerDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "CHERRY_PICK_HEAD", setup: func(t *testing.T, r *Repository, sha string) {
+			if err := r.refs.WriteRef("CHERRY_PICK_HEAD", sha); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "REVERT_HEAD", setup: func(t *testing.T, r *Repository, sha string) {
+			if err := r.refs.WriteRef("REVERT_HEAD", sha); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t)
+			up, shas := setupRebase(t, r, map[string]string{"up.txt": "up\n"}, []string{"one"}, []map[string]string{
+				{"one.txt": "1\n"},
+			})
+			tt.setup(t, r, up)
+
+			if _, err := r.StartRebase(up, RebaseOptions{}); !errors.Is(err, ErrSequenceInProgress) {
+				t.Fatalf("StartRebase() = %v, want %v", err, ErrSequenceInProgress)
+			}
+			if _, err := os.Stat(r.Path(rebaseDir)); !os.IsNotExist(err) {
+				t.Errorf("%s exists: %v", rebaseDir, err)
+			}
+			if _, head, err := r.Head(); err != nil || head != shas["one"] {
+				t.Errorf("HEAD = %s, %v, want %s", head, err, shas["one"])
+			}
+		})
+	}
+}
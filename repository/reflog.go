@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/refs"
+)
+
+// UpdateRefWithLog は参照をshaに更新し、core.logAllRefUpdatesに従って更新履歴に記録する
+// HEADが指すブランチを更新した場合はHEADの更新履歴にも記録する
+func (r *Repository) UpdateRefWithLog(name, sha, message string) error {
+	return r.updateRef(name, sha, message, false)
+}
+
+// updateRef は参照を更新して履歴に記録する forceLogが真の場合は設定によらず参照の更新履歴を作成する
+func (r *Repository) updateRef(name, sha, message string, forceLog bool) error {
+	old := r.format.Zero()
+	if b, err := r.ResolveRef(name); err == nil {
+		old = string(b)
+	} else if !errors.Is(err, refs.ErrNotExist) {
+		return err
+	}
+	if err := r.refs.WriteRef(name, sha); err != nil {
+		return err
+	}
+
+	logged := []string{name}
+	if name != "HEAD" {
+		if head, err := r.refs.ReadRef("HEAD"); err == nil && head == "ref: "+name {
+			logged = append(logged, "HEAD")
+		}
+	}
+	for _, ref := range logged {
+		ok, err := r.shouldLogRef(ref)
+		if err != nil {
+			return err
+		}
+		ok = ok || forceLog && ref == name
+		if !ok {
+			continue
+		}
+		if err := r.appendReflog(ref, old, sha, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) appendReflog(name, old, sha, message string) error {
+	committer, err := r.reflogIdentity()
+	if err != nil {
+		return err
+	}
+	// 更新履歴は1行に収めるため、メッセージの改行を空白に置き換える
+	message = strings.ReplaceAll(strings.TrimRight(message, "\n"), "\n", " ")
+	return r.refs.AppendReflog(name, &refs.ReflogEntry{Old: old, New: sha, Committer: committer, Message: message})
+}
+
+// shouldLogRef はcore.logAllRefUpdatesに従って参照の更新を記録するか判定する
+// 既定ではワークツリーがあればHEADとブランチを記録し、"always"の場合はすべての参照を記録する
+// 更新履歴がすでにある参照は常に記録する
+func (r *Repository) shouldLogRef(name string) (bool, error) {
+	if r.refs.HasReflog(name) {
+		return true, nil
+	}
+	if v, ok := r.conf.Get("core.logallrefupdates"); ok && strings.EqualFold(v, "always") {
+		return true, nil
+	}
+	bare, err := r.conf.GetBool("core.bare", false)
+	if err != nil {
+		return false, err
+	}
+	enabled, err := r.conf.GetBool("core.logallrefupdates", !bare)
+	if err != nil || !enabled {
+		return false, err
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/notes/"} {
+		if strings.HasPrefix(name, prefix) {
+			return true, nil
+		}
+	}
+	return name == "HEAD", nil
+}
+
+// Reflog は参照の更新履歴を新しい順に返す
+func (r *Repository) Reflog(name string) ([]*refs.ReflogEntry, error) {
+	entries, err := r.refs.ReadReflog(name)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// resolveReflog は "REF@{n}" をREFの更新履歴のn個前の値に解決する
+// REFを省略した場合はgitと同じく現在のブランチとみなす
+func (r *Repository) resolveReflog(name string, i int) ([]string, error) {
+	if !strings.HasSuffix(name, "}") {
+		return nil, fmt.Errorf("invalid revision %s", name)
+	}
+	n, err := strconv.Atoi(name[i+2 : len(name)-1])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid revision %s: only @{n} is supported", name)
+	}
+	ref, err := r.FullRefName(name[:i])
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.Reflog(ref)
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(entries) {
+		return nil, fmt.Errorf("log for '%s' only has %d entries", name[:i], len(entries))
+	}
+	return []string{entries[n].New}, nil
+}
+
+// FullRefName は短縮された参照名を "refs/heads/master" のような完全な名前に変換する
+// 空の場合はHEADが指すブランチを返す
+func (r *Repository) FullRefName(name string) (string, error) {
+	if name == "" {
+		ref, _, err := r.Head()
+		return ref, err
+	}
+	for i, rule := range refRules {
+		if i == 0 && !pseudoRefReg.MatchString(name) && !strings.HasPrefix(name, "refs/") {
+			continue
+		}
+		ref := fmt.Sprintf(rule, name)
+		if _, err := r.refs.ReadRef(ref); err == nil {
+			return ref, nil
+		} else if !errors.Is(err, refs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w ref=%s", refs.ErrNotExist, name)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/showa-93/wyag-go/gpg"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+)
+
+var ErrTagExists = errors.New("tag already exists")
+
+func (r *Repository) ResolveRef(ref string) ([]byte, error) {
+	return refs.ResolveRef(r.refs, ref)
+}
+
+func (r *Repository) ListRef(path string) ([]refs.Ref, error) {
+	return refs.ListRef(r.refs, path)
+}
+
+// UpdateRef は参照の値をshaまたはシンボリック参照に更新する
+func (r *Repository) UpdateRef(name, value string) error {
+	return r.refs.WriteRef(name, value)
+}
+
+// Head はHEADが指すブランチの参照名とコミットのshaを返す
+// HEADが切り離されている場合の参照名は "HEAD"、まだコミットがないブランチのshaは空を返す
+func (r *Repository) Head() (string, string, error) {
+	name := "HEAD"
+	for {
+		value, err := r.refs.ReadRef(name)
+		if errors.Is(err, refs.ErrNotExist) {
+			return name, "", nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if !strings.HasPrefix(value, "ref: ") {
+			return name, value, nil
+		}
+		name = value[5:]
+	}
+}
+
+// CreateTag はtargetを指す軽量タグを作成する forceが偽の場合は既存のタグを上書きしない
+func (r *Repository) CreateTag(name, target string, force bool) error {
+	ref := "refs/tags/" + name
+	if !force {
+		if _, err := r.refs.ReadRef(ref); err == nil {
+			return fmt.Errorf("%w: %s", ErrTagExists, name)
+		} else if !errors.Is(err, refs.ErrNotExist) {
+			return err
+		}
+	}
+	return r.refs.WriteRef(ref, target)
+}
+
+// CreateAnnotatedTag はtargetを指すタグオブジェクトを作成し、タグの参照を作成する
+// signerがnilでなければメッセージの後に署名を付ける
+func (r *Repository) CreateAnnotatedTag(name, target, message string, signer gpg.Signer, force bool) (string, error) {
+	h, rc, err := r.objects.OpenObject(target)
+	if err != nil {
+		return "", err
+	}
+	rc.Close()
+	tagger, err := r.Identity(Committer)
+	if err != nil {
+		return "", err
+	}
+
+	b := &object.TagBuilder{
+		Target:     target,
+		TargetType: h.Type,
+		Name:       name,
+		Tagger:     tagger,
+		Message:    message,
+	}
+	if signer != nil {
+		payload, _ := b.Build().Serialize()
+		if b.GPGSig, err = signer.Sign(payload); err != nil {
+			return "", err
+		}
+	}
+	sha, err := r.WriteObject(b.Build(), true)
+	if err != nil {
+		return "", err
+	}
+	return sha, r.CreateTag(name, sha, force)
+}
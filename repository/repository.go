@@ -0,0 +1,362 @@
+// Package repository はGitリポジトリの作成・探索とオブジェクトの読み書きを提供する
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/config"
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/oid"
+	"github.com/showa-93/wyag-go/refs"
+	"github.com/showa-93/wyag-go/storage"
+)
+
+var (
+	// repositoryformatversion 1で理解できるextensions
+	knownExtensions = map[string]struct{}{
+		"noop":           {},
+		"objectformat":   {},
+		"worktreeconfig": {},
+	}
+)
+
+var (
+	ErrNotRepositry         = errors.New("not a git repository")
+	ErrMissingConfiguration = errors.New("missing a config file")
+	ErrNotExist             = errors.New("not exist such file or directory")
+	ErrInMemory             = errors.New("repository has no git directory")
+	ErrOutsideWorktree      = errors.New("outside repository")
+)
+
+type Repository struct {
+	worktree string
+	gitdir   string
+	conf     *config.Config
+	format   oid.Format
+	objects  storage.ObjectStore
+	refs     refs.Store
+	index    index.Storage
+	cache    *objectCache
+}
+
+func NewRepository(path string, force bool) (*Repository, error) {
+	r := &Repository{
+		worktree: path,
+		gitdir:   filepath.Join(path, ".git"),
+	}
+
+	if dir, err := os.Stat(r.gitdir); !(force || err == nil || (dir != nil && dir.IsDir())) {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w path=%s", ErrNotRepositry, path)
+	}
+
+	if _, err := os.Stat(r.Path("config")); !force && err != nil {
+		return nil, fmt.Errorf("%w path=%s", ErrMissingConfiguration, r.Path("config"))
+	}
+
+	r.format = oid.SHA1
+	if err := r.loadConfig(); err != nil && !force {
+		return nil, err
+	}
+	r.setupStorage()
+
+	return r, nil
+}
+
+// loadConfig は設定を読み込み、リポジトリのフォーマットを扱えるか確認する
+func (r *Repository) loadConfig() error {
+	conf, err := config.Load(r.gitdir)
+	if err != nil {
+		return fmt.Errorf("設定ファイルの読み込みに失敗しました path=%s error=%w", r.Path("config"), err)
+	}
+	r.conf = conf
+
+	// フォーマットに関する設定はリポジトリの設定ファイルのみを参照する
+	local := conf.Scoped(config.ScopeLocal)
+	if err := checkFormatVersion(local); err != nil {
+		return err
+	}
+	format, err := local.ObjectFormat()
+	if err != nil {
+		return err
+	}
+	r.format = format
+	return nil
+}
+
+// checkFormatVersion はリポジトリのフォーマットを扱えるか確認する
+// version 1は理解できるextensionsのみが指定されている場合に限り扱う
+func checkFormatVersion(conf *config.Config) error {
+	version, err := conf.RepositoryFormatVersion()
+	if err != nil {
+		return err
+	}
+	switch version {
+	case 0:
+		return nil
+	case 1:
+		for name := range conf.Extensions() {
+			if _, ok := knownExtensions[name]; !ok {
+				return fmt.Errorf("Unknown repository extension %s", name)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported repositoryformatversion %d", version)
+}
+
+// setupStorage はオブジェクトフォーマットに合わせて.git配下の保存先を用意する
+func (r *Repository) setupStorage() {
+	r.objects = storage.NewOverlayStore(
+		storage.NewLooseStore(r.Path("objects"), r.format),
+		storage.NewPackedStore(r.Path("objects/pack"), r.format),
+	)
+	r.refs = refs.NewFileStore(r.gitdir)
+	r.index = index.NewFileStorage(r.Path("index"), r.format)
+	r.cache = newObjectCache(defaultObjectCacheLimit)
+}
+
+// CreateRepository はpathに指定のオブジェクトフォーマットで空のリポジトリを作成する
+func CreateRepository(path string, format oid.Format) (*Repository, error) {
+	r, err := NewRepository(path, true)
+	if err != nil {
+		return nil, err
+	}
+	r.format = format
+	r.setupStorage()
+
+	fi, err := os.Stat(r.worktree)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("指定のパスがディレクトリではありません path=%s", r.worktree)
+	}
+
+	{ // フォルダ作成
+		if _, err := r.MakeDirectories("", true); err != nil {
+			return nil, err
+		}
+		if _, err := r.MakeDirectories("branches", true); err != nil {
+			return nil, err
+		}
+		if _, err := r.MakeDirectories("objects", true); err != nil {
+			return nil, err
+		}
+		if _, err := r.MakeDirectories("refs/tags", true); err != nil {
+			return nil, err
+		}
+		if _, err := r.MakeDirectories("refs/heads", true); err != nil {
+			return nil, err
+		}
+	}
+
+	{ // ファイル作成
+		if f, err := r.MakeFile("description", true); err != nil {
+			return nil, err
+		} else if f == nil {
+			return nil, errors.New("file already exists")
+		} else {
+			_, err := fmt.Fprint(f, "Unnamed repository; edit this file 'description' to name the repository.\n")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if f, err := r.MakeFile("HEAD", true); err != nil {
+			return nil, err
+		} else if f == nil {
+			return nil, errors.New("file already exists")
+		} else {
+			_, err := fmt.Fprint(f, "ref: refs/heads/master\n")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if f, err := r.MakeFile("config", true); err != nil {
+			return nil, err
+		} else if f == nil {
+			return nil, errors.New("file already exists")
+		} else {
+			if err := config.DefaultConfigure(f, format); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := r.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Worktree はワークツリーのパスを返す
+func (r *Repository) Worktree() string {
+	return r.worktree
+}
+
+// GitDir は.gitディレクトリのパスを返す
+func (r *Repository) GitDir() string {
+	return r.gitdir
+}
+
+// Config はsystem, global, リポジトリ, ワークツリーの設定を重ねたものを返す
+func (r *Repository) Config() *config.Config {
+	return r.conf
+}
+
+// Format はオブジェクトフォーマットを返す
+func (r *Repository) Format() oid.Format {
+	if r == nil {
+		return oid.SHA1
+	}
+	return r.format
+}
+
+// Objects はオブジェクトの保存先を返す
+func (r *Repository) Objects() storage.ObjectStore {
+	return r.objects
+}
+
+// Refs は参照の保存先を返す
+func (r *Repository) Refs() refs.Store {
+	return r.refs
+}
+
+func (r *Repository) Path(path string) string {
+	return filepath.Join(r.gitdir, path)
+}
+
+func (r *Repository) MakeFile(path string, mkdir bool) (f *os.File, err error) {
+	if r.gitdir == "" {
+		return nil, ErrInMemory
+	}
+	if _, err := r.MakeDirectories(filepath.Dir(path), mkdir); err != nil {
+		return nil, err
+	}
+
+	flag := os.O_RDWR
+	if mkdir {
+		flag = flag | os.O_CREATE
+	}
+
+	path = r.Path(path)
+	f, err = os.OpenFile(path, flag, os.FileMode(0644))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotExist
+		}
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("指定のパスがファイルでなくディレクトリです path=%s", path)
+	}
+
+	return
+}
+
+func (r *Repository) MakeDirectories(path string, mkdir bool) (string, error) {
+	if r.gitdir == "" {
+		return "", ErrInMemory
+	}
+	path = strings.Trim(path, string(os.PathSeparator)) + string(os.PathSeparator)
+
+	var i int
+	for {
+		if i < 0 || i >= len(path) {
+			break
+		}
+		i += strings.Index(path[i:], string(os.PathSeparator))
+		if err := r.makeDirectory(path[:i], mkdir); err != nil {
+			return "", err
+		}
+		i++
+	}
+
+	return r.Path(path), nil
+}
+
+func (r *Repository) makeDirectory(path string, mkdir bool) error {
+	path = r.Path(path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if mkdir {
+				return os.Mkdir(path, os.FileMode(0755))
+			}
+			return nil
+		}
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("not a directory path=%s", path)
+	}
+
+	return nil
+}
+
+func FindRepository(path string, requred bool) (*Repository, error) {
+	var err error
+	if !filepath.IsAbs(path) {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := NewRepository(path, false)
+	if err == nil {
+		return r, nil
+	}
+	if !errors.Is(err, ErrNotRepositry) {
+		return nil, err
+	}
+
+	parenet := filepath.Join(path, "..")
+	if parenet == path {
+		if requred {
+			return nil, fmt.Errorf("%w path=%s", ErrNotRepositry, path)
+		}
+		return nil, nil
+	}
+
+	return FindRepository(parenet, requred)
+}
+
+// RelativePath はpathをワークツリーからの"/"区切りの相対パスに変換する
+// ワークツリーのルートは空文字となり、ワークツリーの外のパスはエラーを返す
+func (r *Repository) RelativePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	worktree, err := filepath.Abs(r.worktree)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(worktree, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w path=%s", ErrOutsideWorktree, path)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
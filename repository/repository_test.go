@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// newTestRepository はテスト用の空のリポジトリを作成する
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	setTestEnv(t)
+	r, err := CreateRepository(t.TempDir(), oid.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// setTestEnv は実行する環境の設定ファイルの影響を受けないようにし、作者とコミッターと日時を固定する
+func setTestEnv(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, kind := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+kind+"_NAME", "T")
+		t.Setenv("GIT_"+kind+"_EMAIL", "t@example.com")
+		t.Setenv("GIT_"+kind+"_DATE", "1700000000 +0900")
+	}
+}
+
+// commitTestFiles はfilesの内容をワークツリーに書き込んでコミットし、コミットのshaを返す
+// 内容が空のファイルは削除する
+func commitTestFiles(t *testing.T, r *Repository, message string, files map[string]string) string {
+	t.Helper()
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		full := filepath.Join(r.worktree, filepath.FromSlash(p))
+		if files[p] == "" {
+			if err := os.Remove(full); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(files[p]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Add(paths); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := r.Commit(CommitOptions{Message: message + "\n", AllowEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// readTestFile はワークツリーのファイルの内容を返す
+func readTestFile(t *testing.T, r *Repository, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(r.worktree, filepath.FromSlash(p)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNewRepositoryFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    oid.Format
+		wantErr bool
+	}{
+		{name: "version 0", config: "[core]\n\trepositoryformatversion = 0\n", want: oid.SHA1},
+		{name: "no version", config: "[core]\n\tbare = false\n", want: oid.SHA1},
+		// gitと同じくversion 0ではextensionsを無視する
+		{name: "version 0 ignores extensions", config: "[core]\n\trepositoryformatversion = 0\n[extensions]\n\tobjectformat = sha256\n\tunknown = true\n", want: oid.SHA1},
+		{name: "version 1 sha256", config: "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tobjectformat = sha256\n", want: oid.SHA256},
+		{name: "version 1 without extensions", config: "[core]\n\trepositoryformatversion = 1\n", want: oid.SHA1},
+		{name: "version 1 known extensions", config: "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tnoop = true\n\tworktreeConfig = true\n", want: oid.SHA1},
+		{name: "version 1 unknown extension", config: "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tpartialclone = origin\n", wantErr: true},
+		{name: "version 1 unknown object format", config: "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tobjectformat = md5\n", wantErr: true},
+		{name: "version 2", config: "[core]\n\trepositoryformatversion = 2\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t)
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, ".git", "config"), []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := NewRepository(dir, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && r.Format() != tt.want {
+				t.Errorf("Format() = %s, want %s", r.Format(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRepositorySHA256(t *testing.T) {
+	setTestEnv(t)
+	dir := t.TempDir()
+	if _, err := CreateRepository(dir, oid.SHA256); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRepository(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format() != oid.SHA256 {
+		t.Fatalf("Format() = %s, want sha256", r.Format())
+	}
+
+	// 空のblobのIDはgitと同じ
+	sha, err := r.WriteObject(object.NewBlobObject(nil), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"; sha != want {
+		t.Errorf("WriteObject(empty blob) = %s, want %s", sha, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "objects", sha[:2], sha[2:])); err != nil {
+		t.Error(err)
+	}
+}
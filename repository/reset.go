@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// ResetMode はresetでHEADの他に何を戻すか
+type ResetMode int
+
+const (
+	// ResetSoft はHEADのみを移動する
+	ResetSoft ResetMode = iota
+	// ResetMixed はHEADを移動し、インデックスを移動先のツリーに戻す
+	ResetMixed
+	// ResetHard はHEADを移動し、インデックスとワークツリーを移動先のツリーに戻す
+	ResetHard
+)
+
+// Reset はHEADが指すブランチをrevのコミットに移動し、modeに従ってインデックスとワークツリーを戻す
+// 移動前のコミットはORIG_HEADに記録する
+func (r *Repository) Reset(rev string, mode ResetMode) (string, error) {
+	commit, err := r.FindObject(rev, string(object.Commit), true)
+	if err != nil {
+		return "", err
+	}
+	if commit == "" {
+		return "", fmt.Errorf("%s is not a commit", rev)
+	}
+	ref, head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	if mode != ResetSoft {
+		tree, err := r.FindObject(commit, string(object.Tree), true)
+		if err != nil {
+			return "", err
+		}
+		if err := r.resetIndex(tree, mode == ResetHard); err != nil {
+			return "", err
+		}
+	}
+
+	if head != "" {
+		if err := r.refs.WriteRef("ORIG_HEAD", head); err != nil {
+			return "", err
+		}
+	}
+	return commit, r.UpdateRefWithLog(ref, commit, "reset: moving to "+rev)
+}
+
+// resetIndex はインデックスをtreeの内容に置き換える worktreeが真の場合はワークツリーも置き換える
+func (r *Repository) resetIndex(tree string, worktree bool) error {
+	old, err := r.Index()
+	if err != nil {
+		return err
+	}
+	files, err := r.ListTree(tree)
+	if err != nil {
+		return err
+	}
+	idx := index.New(r.format)
+	for _, f := range files {
+		idx.Add(reuseEntry(old, f))
+	}
+	if worktree {
+		if err := r.checkoutIndex(old, idx); err != nil {
+			return err
+		}
+	}
+	return r.WriteIndex(idx)
+}
+
+// ResetPaths はインデックスのpathsのエントリをrevのツリーの内容に戻す HEADとワークツリーは変更しない
+// pathsはワークツリーからの相対パスで、ディレクトリの場合は配下のエントリをすべて戻す
+func (r *Repository) ResetPaths(rev string, paths []string) error {
+	var files []*DiffEntry
+	_, head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	// まだコミットがない場合はHEADを空のツリーとみなす
+	if rev != "HEAD" || head != "" {
+		tree, err := r.FindObject(rev, string(object.Tree), true)
+		if err != nil {
+			return err
+		}
+		if tree == "" {
+			return fmt.Errorf("%s is not a tree-ish", rev)
+		}
+		if files, err = r.ListTree(tree); err != nil {
+			return err
+		}
+	}
+
+	idx, err := r.Index()
+	if err != nil {
+		return err
+	}
+	var removed []string
+	for _, e := range idx.Entries() {
+		if matchPaths(e.Path, paths) {
+			removed = append(removed, e.Path)
+		}
+	}
+	old := index.New(r.format)
+	for _, p := range removed {
+		if e, ok := idx.Entry(p); ok {
+			old.Add(e)
+		}
+		idx.Remove(p)
+	}
+	for _, f := range files {
+		if matchPaths(f.Path, paths) {
+			idx.Add(reuseEntry(old, f))
+		}
+	}
+	return r.WriteIndex(idx)
+}
+
+// matchPaths はpがpathsのいずれかのパスかその配下にあるか判定する 空のパスはすべてに一致する
+func matchPaths(p string, paths []string) bool {
+	for _, path := range paths {
+		if path == "" || p == path || strings.HasPrefix(p, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// reuseEntry はfのエントリを作成する oldに同じ内容のエントリがあればファイルの情報を引き継ぐ
+func reuseEntry(old *index.Index, f *DiffEntry) *index.Entry {
+	if e, ok := old.Entry(f.Path); ok && e.Sha == f.Sha && e.Mode == f.Mode {
+		reused := *e
+		return &reused
+	}
+	return &index.Entry{Mode: f.Mode, Sha: f.Sha, Path: f.Path}
+}
+
+// ListTree はツリーに含まれるファイルをサブツリーも含めてパスの順に返す
+func (r *Repository) ListTree(tree string) ([]*DiffEntry, error) {
+	var files []*DiffEntry
+	var walk func(sha, prefix string) error
+	walk = func(sha, prefix string) error {
+		items, err := r.readTree(sha)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if item.Type() == object.Tree {
+				if err := walk(item.Sha(), prefix+item.Path()+"/"); err != nil {
+					return err
+				}
+				continue
+			}
+			e, err := newDiffEntry(prefix, item)
+			if err != nil {
+				return err
+			}
+			files = append(files, e)
+		}
+		return nil
+	}
+	return files, walk(tree, "")
+}
+
+// checkoutIndex はワークツリーをoldの内容からidxの内容に置き換え、書き込んだファイルの情報をidxに記録する
+// oldにだけあるファイルは削除し、oldと同じ内容で変更されていないファイルは書き込まない
+// どちらのインデックスにもないファイルは変更しない
+func (r *Repository) checkoutIndex(old, idx *index.Index) error {
+	if r.worktree == "" {
+		return ErrInMemory
+	}
+	conv, err := r.Converter()
+	if err != nil {
+		return err
+	}
+	out, err := r.checkoutConverter(indexAttributes(idx))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range old.Entries() {
+		if _, ok := idx.Entry(e.Path); ok {
+			continue
+		}
+		if err := r.removeWorktreeFile(e.Path); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range idx.Entries() {
+		if oe, ok := old.Entry(e.Path); ok && oe.Sha == e.Sha && oe.Mode == e.Mode {
+			modified, err := r.Modified(conv, oe)
+			if err != nil {
+				return err
+			}
+			if !modified {
+				continue
+			}
+		}
+		if err := r.writeWorktreeFile(out, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWorktreeFile はエントリの内容をワークツリーに書き込み、ファイルの情報をエントリに記録する
+func (r *Repository) writeWorktreeFile(conv *Converter, e *index.Entry) error {
+	dest, err := r.prepareWorktreePath(e.Path)
+	if err != nil {
+		return err
+	}
+
+	// サブモジュールは空のディレクトリのみ作成する
+	if e.Mode == 0160000 {
+		return os.Mkdir(dest, os.FileMode(0755))
+	}
+	if err := r.checkoutBlob(conv, e.Sha, e.Mode, dest, e.Path); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(dest)
+	if err != nil {
+		return err
+	}
+	e.SetStat(fi)
+	return nil
+}
+
+// writeWorktreeData はリポジトリに格納する形式のdataをmodeに従ってワークツリーのpに書き込む
+func (r *Repository) writeWorktreeData(conv *Converter, p string, mode uint32, data []byte) error {
+	dest, err := r.prepareWorktreePath(p)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case 0160000:
+		return os.Mkdir(dest, os.FileMode(0755))
+	case 0120000:
+		return os.Symlink(string(data), dest)
+	}
+	if data, err = conv.ToWorktree(p, data); err != nil {
+		return err
+	}
+	perm := os.FileMode(0666)
+	if mode&0111 != 0 {
+		perm = 0777
+	}
+	return os.WriteFile(dest, data, perm)
+}
+
+// prepareWorktreePath はワークツリーのpに書き込めるように、その場所にあるファイルやディレクトリ、
+// 親のディレクトリの位置にあるファイルを取り除いて親のディレクトリを作成する
+func (r *Repository) prepareWorktreePath(p string) (string, error) {
+	dest := filepath.Join(r.worktree, filepath.FromSlash(p))
+	dir := r.worktree
+	for _, name := range strings.Split(p, "/")[:strings.Count(p, "/")] {
+		dir = filepath.Join(dir, name)
+		if fi, err := os.Lstat(dir); err == nil && !fi.IsDir() {
+			if err := os.Remove(dir); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), os.FileMode(0755)); err != nil {
+		return "", err
+	}
+	return dest, os.RemoveAll(dest)
+}
+
+// removeWorktreeFile はワークツリーのファイルを削除し、空になった親のディレクトリも削除する
+func (r *Repository) removeWorktreeFile(p string) error {
+	full := filepath.Join(r.worktree, filepath.FromSlash(p))
+	if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+		// 中身のあるディレクトリは残す
+		if fi, serr := os.Lstat(full); serr == nil && fi.IsDir() {
+			return nil
+		}
+		return err
+	}
+	for dir := filepath.Dir(full); dir != r.worktree && strings.HasPrefix(dir, r.worktree); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResetHardUsesTargetAttributes(t *testing.T) {
+	// gitと同じく書き込む内容の.gitattributesを、ワークツリーの.gitattributesより優先する
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "lf", map[string]string{"f.txt": "a\nb\n"})
+	target := commitTestFiles(t, r, "crlf", map[string]string{
+		".gitattributes": "* eol=crlf\n",
+		"f.txt":          "a\nb\nc\n",
+	})
+	if _, err := r.Reset("HEAD~1", ResetHard); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reset(target, ResetHard); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readTestFile(t, r, "f.txt"), "a\r\nb\r\nc\r\n"; got != want {
+		t.Errorf("f.txt = %q, want %q", got, want)
+	}
+}
+
+func TestResetWithoutIdentity(t *testing.T) {
+	// gitと同じく名前とメールアドレスが設定されていなくても、既定の値で更新履歴に記録する
+	r := newTestRepository(t)
+	first := commitTestFiles(t, r, "first", map[string]string{"f.txt": "a\n"})
+	commitTestFiles(t, r, "second", map[string]string{"f.txt": "b\n"})
+	for _, kind := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+kind+"_NAME", "")
+		t.Setenv("GIT_"+kind+"_EMAIL", "")
+	}
+
+	if _, err := r.Reset("HEAD~1", ResetHard); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, r, "f.txt"); got != "a\n" {
+		t.Errorf("f.txt = %q, want %q", got, "a\n")
+	}
+	for _, ref := range []string{"HEAD", "refs/heads/master"} {
+		entries, err := r.Reflog(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := entries[0]
+		if e.New != first || e.Message != "reset: moving to HEAD~1" {
+			t.Errorf("%s reflog = %s %q, want %s %q", ref, e.New, e.Message, first, "reset: moving to HEAD~1")
+		}
+		if e.Committer.Name == "" || !strings.Contains(e.Committer.Email, "@") {
+			t.Errorf("%s reflog committer = %s <%s>", ref, e.Committer.Name, e.Committer.Email)
+		}
+	}
+}
@@ -0,0 +1,650 @@
+package repository
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// SortOrder はコミットを出力する順序
+type SortOrder int
+
+const (
+	// SortDefault はコミット日時の新しいものから順に辿った順序
+	SortDefault SortOrder = iota
+	// SortTopo は子を親より先に出力し、同じ系統のコミットをまとめて出力する
+	SortTopo
+	// SortDate は子を親より先に出力し、それ以外はコミット日時の新しい順に出力する
+	SortDate
+)
+
+// RevWalkOptions はコミットの辿り方と絞り込みの条件
+type RevWalkOptions struct {
+	Order   SortOrder
+	Reverse bool
+	// FirstParent が真の場合はマージコミットの最初の親のみ辿る
+	FirstParent bool
+	// MaxCount は出力するコミットの数の上限 負の値なら制限しない
+	MaxCount int
+	// Since より古いコミットは辿らず、Until より新しいコミットは出力しない ゼロ値なら制限しない
+	Since, Until time.Time
+	// Authors のいずれかに作者が一致し、Greps のいずれかにメッセージが一致するコミットのみ出力する
+	Authors, Greps []*regexp.Regexp
+}
+
+const (
+	walkSeen = 1 << iota
+	walkUninteresting
+)
+
+// walkCommit は辿る途中のコミット 親を読み込むまでparsedは偽
+type walkCommit struct {
+	sha     string
+	parsed  bool
+	parents []*walkCommit
+	tree    string
+	date    int64
+	flags   int
+	commit  *object.CommitObject
+}
+
+// RevWalk はコミットの履歴を再帰を使わずに辿る
+// Push と Hide で起点を指定した後、Next で io.EOF が返るまでコミットを取り出す
+type RevWalk struct {
+	r       *Repository
+	opts    RevWalkOptions
+	commits map[string]*walkCommit
+	queue   *commitQueue
+	// limited は出力の前にすべてのコミットを辿る必要があるか
+	limited  bool
+	prepared bool
+	// output は limited の場合や逆順の場合に事前に求めた出力順のコミット
+	output []*walkCommit
+	count  int
+	// pending は起点に指定されたコミット以外のオブジェクト
+	pending []pendingObject
+	// shown は Next で返したコミット
+	shown []*walkCommit
+}
+
+type pendingObject struct {
+	sha, name     string
+	t             object.ObjectType
+	uninteresting bool
+}
+
+// NewRevWalk はoptsの条件で履歴を辿るRevWalkを作成する
+func (r *Repository) NewRevWalk(opts RevWalkOptions) *RevWalk {
+	return &RevWalk{
+		r:       r,
+		opts:    opts,
+		commits: make(map[string]*walkCommit),
+		queue:   &commitQueue{},
+		limited: opts.Order != SortDefault,
+	}
+}
+
+// Push はshaから辿れるコミットを出力の対象に加える
+// タグはコミットまでたどり、ツリーやブロブは WalkObjects でのみ出力する nameは WalkObjects で表示する名前
+// ツリーやブロブの名前はgitと同じく "REV:PATH" の形式のPATHとし、それ以外の形式では空とする
+func (w *RevWalk) Push(sha, name string) error {
+	return w.add(sha, name, false)
+}
+
+// Hide はshaから辿れるコミットを出力の対象から除く
+func (w *RevWalk) Hide(sha string) error {
+	w.limited = true
+	return w.add(sha, "", true)
+}
+
+// revPath は "REV:PATH" や ":STAGE:PATH" の形式で指定されたオブジェクトのパスを返す
+func revPath(name string) string {
+	i := strings.Index(name, ":")
+	if i < 0 {
+		return ""
+	}
+	p := name[i+1:]
+	if i == 0 && len(p) >= 2 && p[1] == ':' && '0' <= p[0] && p[0] <= '3' {
+		p = p[2:]
+	}
+	return p
+}
+
+func (w *RevWalk) add(sha, name string, uninteresting bool) error {
+	if w.prepared {
+		return fmt.Errorf("revision walk has already started")
+	}
+	for {
+		h, rc, err := w.r.objects.OpenObject(sha)
+		if err != nil {
+			return err
+		}
+		rc.Close()
+		if h.Type == object.Commit {
+			break
+		}
+		if h.Type != object.Tag {
+			w.pending = append(w.pending, pendingObject{sha: sha, name: revPath(name), t: h.Type, uninteresting: uninteresting})
+			return nil
+		}
+		w.pending = append(w.pending, pendingObject{sha: sha, name: name, t: h.Type, uninteresting: uninteresting})
+		o, err := w.r.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		target := o.(*object.TagObject).Target()
+		if target == "" {
+			return fmt.Errorf("malformed tag %s", sha)
+		}
+		sha = target
+	}
+
+	c, err := w.parse(sha)
+	if err != nil {
+		return err
+	}
+	if uninteresting {
+		c.flags |= walkUninteresting
+		w.markParentsUninteresting(c)
+	}
+	if c.flags&walkSeen == 0 {
+		c.flags |= walkSeen
+		w.queue.push(c)
+	}
+	return nil
+}
+
+// lookup はshaのコミットを読み込まずに返す
+func (w *RevWalk) lookup(sha string) *walkCommit {
+	c, ok := w.commits[sha]
+	if !ok {
+		c = &walkCommit{sha: sha}
+		w.commits[sha] = c
+	}
+	return c
+}
+
+// parse はshaのコミットを読み込み、親とコミット日時を取り出す
+func (w *RevWalk) parse(sha string) (*walkCommit, error) {
+	c := w.lookup(sha)
+	if c.parsed {
+		return c, nil
+	}
+	o, err := w.r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	commit, ok := o.(*object.CommitObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a commit", sha)
+	}
+	if commit.Tree() == "" {
+		return nil, fmt.Errorf("malformed commit %s", sha)
+	}
+	parents := commit.Parents()
+	c.parents = make([]*walkCommit, len(parents))
+	for i, p := range parents {
+		c.parents[i] = w.lookup(p)
+	}
+	// 日時を解釈できないコミットは最も古いものとして扱う
+	if committer, err := commit.Committer(); err == nil {
+		c.date = committer.When.Unix()
+	}
+	c.tree, c.commit, c.parsed = commit.Tree(), commit, true
+	return c, nil
+}
+
+// markParentsUninteresting はcの祖先のうち読み込み済みのものを出力の対象から除く
+func (w *RevWalk) markParentsUninteresting(c *walkCommit) {
+	stack := append([]*walkCommit{}, c.parents...)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if p.flags&walkUninteresting != 0 {
+			continue
+		}
+		p.flags |= walkUninteresting
+		stack = append(stack, p.parents...)
+	}
+}
+
+// processParents はcの親を読み込んで辿る対象に加える
+func (w *RevWalk) processParents(c *walkCommit, push func(*walkCommit)) error {
+	if c.flags&walkUninteresting != 0 {
+		for _, p := range c.parents {
+			p.flags |= walkUninteresting
+			if _, err := w.parse(p.sha); err != nil {
+				return err
+			}
+			w.markParentsUninteresting(p)
+			if p.flags&walkSeen != 0 {
+				continue
+			}
+			p.flags |= walkSeen
+			push(p)
+		}
+		return nil
+	}
+	for _, p := range c.parents {
+		if _, err := w.parse(p.sha); err != nil {
+			return err
+		}
+		if p.flags&walkSeen == 0 {
+			p.flags |= walkSeen
+			push(p)
+		}
+		if w.opts.FirstParent {
+			break
+		}
+	}
+	return nil
+}
+
+// walkSlop は除外するコミットのみになった後も念のため辿るコミットの数
+const walkSlop = 5
+
+// limit はすべての出力対象のコミットを辿り、出力の対象から除くコミットを確定させる
+func (w *RevWalk) limit() ([]*walkCommit, error) {
+	var list []*walkCommit
+	slop := walkSlop
+	date := int64(1<<63 - 1)
+	push := func(c *walkCommit) { w.queue.push(c) }
+	for w.queue.Len() > 0 {
+		c := w.queue.pop()
+		if !w.opts.Since.IsZero() && c.date < w.opts.Since.Unix() {
+			c.flags |= walkUninteresting
+		}
+		if err := w.processParents(c, push); err != nil {
+			return nil, err
+		}
+		if c.flags&walkUninteresting != 0 {
+			w.markParentsUninteresting(c)
+			if slop = w.stillInteresting(date, slop); slop > 0 {
+				continue
+			}
+			break
+		}
+		if !w.opts.Until.IsZero() && c.date > w.opts.Until.Unix() {
+			continue
+		}
+		date = c.date
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// stillInteresting は残りのコミットを辿る必要があるか判定し、0なら辿るのをやめる
+func (w *RevWalk) stillInteresting(date int64, slop int) int {
+	if w.queue.Len() == 0 {
+		return 0
+	}
+	if date <= w.queue.peek().date {
+		return walkSlop
+	}
+	for _, c := range w.queue.items {
+		if c.flags&walkUninteresting == 0 {
+			return walkSlop
+		}
+	}
+	return slop - 1
+}
+
+// sortTopo は子が親より先になるようにlistを並べ替える
+func (w *RevWalk) sortTopo(list []*walkCommit) []*walkCommit {
+	// 0はlistに含まれないことを表すため、子の数に1を足して数える
+	indegree := make(map[*walkCommit]int, len(list))
+	for _, c := range list {
+		indegree[c] = 1
+	}
+	for _, c := range list {
+		for _, p := range c.parents {
+			if indegree[p] > 0 {
+				indegree[p]++
+			}
+		}
+	}
+
+	var tips []*walkCommit
+	for _, c := range list {
+		if indegree[c] == 1 {
+			tips = append(tips, c)
+		}
+	}
+
+	// topo-orderは最後に加えたコミットから取り出し、同じ系統のコミットを続けて出力する
+	var stack []*walkCommit
+	queue := &commitQueue{}
+	put := func(c *walkCommit) {
+		if w.opts.Order == SortTopo {
+			stack = append(stack, c)
+		} else {
+			queue.push(c)
+		}
+	}
+	get := func() *walkCommit {
+		if w.opts.Order == SortTopo {
+			if len(stack) == 0 {
+				return nil
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			return c
+		}
+		if queue.Len() == 0 {
+			return nil
+		}
+		return queue.pop()
+	}
+	// 起点は辿った順に出力する
+	for i := len(tips) - 1; i >= 0 && w.opts.Order == SortTopo; i-- {
+		put(tips[i])
+	}
+	for i := 0; i < len(tips) && w.opts.Order != SortTopo; i++ {
+		put(tips[i])
+	}
+
+	sorted := make([]*walkCommit, 0, len(list))
+	for c := get(); c != nil; c = get() {
+		for _, p := range c.parents {
+			if indegree[p] == 0 {
+				continue
+			}
+			// 子がすべて出力された親のみ出力できる
+			if indegree[p]--; indegree[p] == 1 {
+				put(p)
+			}
+		}
+		indegree[c] = 0
+		sorted = append(sorted, c)
+	}
+	return sorted
+}
+
+func (w *RevWalk) prepare() error {
+	w.prepared = true
+	if w.limited {
+		list, err := w.limit()
+		if err != nil {
+			return err
+		}
+		if w.opts.Order != SortDefault {
+			list = w.sortTopo(list)
+		}
+		w.output = list
+	}
+	if !w.opts.Reverse {
+		return nil
+	}
+	var all []*walkCommit
+	for {
+		c, err := w.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		all = append(all, c)
+	}
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	w.output, w.limited = all, true
+	return nil
+}
+
+// Next は次に出力するコミットのshaを返す すべて出力した後は io.EOF を返す
+func (w *RevWalk) Next() (string, error) {
+	if !w.prepared {
+		if err := w.prepare(); err != nil {
+			return "", err
+		}
+	}
+	var c *walkCommit
+	if w.opts.Reverse {
+		if len(w.output) == 0 {
+			return "", io.EOF
+		}
+		c, w.output = w.output[0], w.output[1:]
+	} else {
+		var err error
+		if c, err = w.next(); err != nil {
+			return "", err
+		}
+	}
+	w.shown = append(w.shown, c)
+	return c.sha, nil
+}
+
+// next は上限の数までコミットを取り出す
+func (w *RevWalk) next() (*walkCommit, error) {
+	if w.opts.MaxCount >= 0 && w.count >= w.opts.MaxCount {
+		return nil, io.EOF
+	}
+	c, err := w.nextCommit()
+	if err != nil {
+		return nil, err
+	}
+	w.count++
+	return c, nil
+}
+
+func (w *RevWalk) nextCommit() (*walkCommit, error) {
+	push := func(c *walkCommit) { w.queue.push(c) }
+	for {
+		var c *walkCommit
+		if w.limited {
+			if len(w.output) == 0 {
+				return nil, io.EOF
+			}
+			c, w.output = w.output[0], w.output[1:]
+		} else {
+			if w.queue.Len() == 0 {
+				return nil, io.EOF
+			}
+			c = w.queue.pop()
+			if !w.opts.Since.IsZero() && c.date < w.opts.Since.Unix() {
+				continue
+			}
+			if err := w.processParents(c, push); err != nil {
+				return nil, err
+			}
+		}
+		if w.match(c) {
+			return c, nil
+		}
+	}
+}
+
+// match はコミットが出力する条件を満たすか判定する
+func (w *RevWalk) match(c *walkCommit) bool {
+	if c.flags&walkUninteresting != 0 {
+		return false
+	}
+	if !w.opts.Until.IsZero() && c.date > w.opts.Until.Unix() {
+		return false
+	}
+	if len(w.opts.Authors) > 0 {
+		author, err := c.commit.Author()
+		if err != nil || !matchAny(w.opts.Authors, author.Ident()) {
+			return false
+		}
+	}
+	if len(w.opts.Greps) > 0 && !matchAny(w.opts.Greps, c.commit.Message()) {
+		return false
+	}
+	return true
+}
+
+func matchAny(regs []*regexp.Regexp, s string) bool {
+	for _, reg := range regs {
+		if reg.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkObjects は出力したコミットから辿れるツリーとブロブ、起点に指定したコミット以外のオブジェクトを順に渡す
+// Hide で除いたコミットから辿れるオブジェクトは除く pathはルートのツリーからのパス
+func (w *RevWalk) WalkObjects(fn func(sha string, t object.ObjectType, path string) error) error {
+	seen := make(map[string]bool)
+	uninteresting := make(map[string]bool)
+
+	// 出力したコミットに隣接する除外されたコミットのオブジェクトを除く
+	for _, c := range w.shown {
+		for _, p := range c.parents {
+			if p.flags&walkUninteresting == 0 || !p.parsed {
+				continue
+			}
+			if err := w.markTreeUninteresting(p.tree, uninteresting); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range w.pending {
+		if !p.uninteresting {
+			continue
+		}
+		switch p.t {
+		case object.Tree:
+			if err := w.markTreeUninteresting(p.sha, uninteresting); err != nil {
+				return err
+			}
+		default:
+			uninteresting[p.sha] = true
+		}
+	}
+
+	for _, p := range w.pending {
+		if p.uninteresting || seen[p.sha] || uninteresting[p.sha] {
+			continue
+		}
+		switch p.t {
+		case object.Tree:
+			if err := w.walkTree(p.sha, p.name, seen, uninteresting, fn); err != nil {
+				return err
+			}
+		default:
+			seen[p.sha] = true
+			if err := fn(p.sha, p.t, p.name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range w.shown {
+		if err := w.walkTree(c.tree, "", seen, uninteresting, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeWalkItem はツリーを辿る途中のオブジェクト
+type treeWalkItem struct {
+	sha, path string
+	t         object.ObjectType
+}
+
+func (w *RevWalk) walkTree(sha, root string, seen, uninteresting map[string]bool, fn func(string, object.ObjectType, string) error) error {
+	stack := []treeWalkItem{{sha: sha, path: root, t: object.Tree}}
+	for len(stack) > 0 {
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[item.sha] || uninteresting[item.sha] {
+			continue
+		}
+		seen[item.sha] = true
+		if err := fn(item.sha, item.t, item.path); err != nil {
+			return err
+		}
+		if item.t != object.Tree {
+			continue
+		}
+		items, err := w.r.readTree(item.sha)
+		if err != nil {
+			return err
+		}
+		// スタックから名前の順に取り出せるように逆順に積む
+		for i := len(items) - 1; i >= 0; i-- {
+			t := items[i].Type()
+			if t != object.Tree && t != object.Blob {
+				continue
+			}
+			stack = append(stack, treeWalkItem{sha: items[i].Sha(), path: path.Join(item.path, items[i].Path()), t: t})
+		}
+	}
+	return nil
+}
+
+// markTreeUninteresting はツリーとそこから辿れるオブジェクトを除外する
+func (w *RevWalk) markTreeUninteresting(sha string, uninteresting map[string]bool) error {
+	stack := []string{sha}
+	for len(stack) > 0 {
+		tree := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if uninteresting[tree] {
+			continue
+		}
+		uninteresting[tree] = true
+		items, err := w.r.readTree(tree)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			switch item.Type() {
+			case object.Tree:
+				stack = append(stack, item.Sha())
+			case object.Blob:
+				uninteresting[item.Sha()] = true
+			}
+		}
+	}
+	return nil
+}
+
+// commitQueue はコミット日時の新しい順に取り出すキュー 同じ日時のものは加えた順に取り出す
+type commitQueue struct {
+	items []*walkCommit
+	order []int
+	ctr   int
+}
+
+func (q *commitQueue) Len() int { return len(q.items) }
+
+func (q *commitQueue) Less(i, j int) bool {
+	if q.items[i].date != q.items[j].date {
+		return q.items[i].date > q.items[j].date
+	}
+	return q.order[i] < q.order[j]
+}
+
+func (q *commitQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.order[i], q.order[j] = q.order[j], q.order[i]
+}
+
+func (q *commitQueue) Push(x interface{}) {
+	q.items = append(q.items, x.(*walkCommit))
+	q.order = append(q.order, q.ctr)
+	q.ctr++
+}
+
+func (q *commitQueue) Pop() interface{} {
+	n := len(q.items) - 1
+	c := q.items[n]
+	q.items, q.order = q.items[:n], q.order[:n]
+	return c
+}
+
+func (q *commitQueue) push(c *walkCommit) { heap.Push(q, c) }
+
+func (q *commitQueue) pop() *walkCommit { return heap.Pop(q).(*walkCommit) }
+
+func (q *commitQueue) peek() *walkCommit { return q.items[0] }
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// buildWalkHistory は次の履歴を作成し、名前とコミットのshaの組を返す
+// 各コミットは親のファイルと "名前.txt" を含み、Xは親より古い日時を持つ
+//
+//	A(100) - B(200) - D(400) ----------- M(500) - F(600)
+//	 \        \                         /
+//	  \        G(700)                  /
+//	   C(150) - E(300) - X(50) -------
+func buildWalkHistory(t *testing.T, r *Repository) map[string]string {
+	t.Helper()
+	history := []struct {
+		name    string
+		parents []string
+		date    int64
+	}{
+		{"A", nil, 100},
+		{"B", []string{"A"}, 200},
+		{"C", []string{"A"}, 150},
+		{"D", []string{"B"}, 400},
+		{"E", []string{"C"}, 300},
+		{"X", []string{"E"}, 50},
+		{"M", []string{"D", "X"}, 500},
+		{"F", []string{"M"}, 600},
+		{"G", []string{"B"}, 700},
+	}
+
+	shas := make(map[string]string)
+	files := make(map[string][]string)
+	for _, h := range history {
+		names := map[string]bool{h.name: true}
+		b := &object.CommitBuilder{Message: h.name + "\n"}
+		for _, p := range h.parents {
+			for _, f := range files[p] {
+				names[f] = true
+			}
+			b.Parents = append(b.Parents, shas[p])
+		}
+		idx := index.New(r.format)
+		for name := range names {
+			blob, err := r.WriteObject(object.NewBlobObject([]byte(name+"\n")), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx.Add(&index.Entry{Mode: 0100644, Sha: blob, Path: name + ".txt"})
+			files[h.name] = append(files[h.name], name)
+		}
+		tree, err := r.WriteTree(idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Tree = tree
+		sig := object.NewSignature("T", "t@example.com", time.Unix(1700000000+h.date, 0).In(time.FixedZone("", 9*60*60)))
+		b.Author, b.Committer = sig, sig
+		if shas[h.name], err = r.CreateCommit(b, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return shas
+}
+
+func TestRevWalk(t *testing.T) {
+	r := newTestRepository(t)
+	shas := buildWalkHistory(t, r)
+	// 同じ履歴をgitで作成した場合のF
+	if want := "3dbcd991c7b7670655901dd80c18cb12fda9b680"; shas["F"] != want {
+		t.Fatalf("F = %s, want %s", shas["F"], want)
+	}
+	names := make(map[string]string)
+	for name, sha := range shas {
+		names[sha] = name
+	}
+
+	// 期待する結果は同じ履歴での git rev-list の出力
+	tests := []struct {
+		name string
+		push []string
+		hide []string
+		opts RevWalkOptions
+		want string
+	}{
+		{name: "default", push: []string{"F"}, want: "F M D B A X E C"},
+		{name: "topo order", push: []string{"F"}, opts: RevWalkOptions{Order: SortTopo}, want: "F M X E C D B A"},
+		{name: "date order", push: []string{"F"}, opts: RevWalkOptions{Order: SortDate}, want: "F M D B X E C A"},
+		{name: "topo order with two tips", push: []string{"G", "F"}, opts: RevWalkOptions{Order: SortTopo}, want: "G F M X E C D B A"},
+		{name: "date order with two tips", push: []string{"G", "F"}, opts: RevWalkOptions{Order: SortDate}, want: "G F M D B X E C A"},
+		{name: "reverse", push: []string{"F"}, opts: RevWalkOptions{Order: SortTopo, Reverse: true}, want: "A B D C E X M F"},
+		{name: "first parent", push: []string{"F"}, opts: RevWalkOptions{FirstParent: true}, want: "F M D B A"},
+		{name: "B..F", push: []string{"F"}, hide: []string{"B"}, want: "F M D X E C"},
+		{name: "F ^E", push: []string{"F"}, hide: []string{"E"}, want: "F M D B X"},
+		{name: "G F ^D", push: []string{"G", "F"}, hide: []string{"D"}, want: "G F M X E C"},
+		{name: "since", push: []string{"F"}, opts: RevWalkOptions{Since: time.Unix(1700000250, 0)}, want: "F M D"},
+		{name: "until", push: []string{"F"}, opts: RevWalkOptions{Until: time.Unix(1700000450, 0)}, want: "D B A X E C"},
+		{name: "max count", push: []string{"F"}, opts: RevWalkOptions{Order: SortTopo, MaxCount: 3}, want: "F M X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if opts.MaxCount == 0 {
+				opts.MaxCount = -1
+			}
+			w := r.NewRevWalk(opts)
+			for _, name := range tt.push {
+				if err := w.Push(shas[name], name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, name := range tt.hide {
+				if err := w.Hide(shas[name]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []string
+			for {
+				sha, err := w.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, names[sha])
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("RevWalk = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestRevWalkObjects(t *testing.T) {
+	r := newTestRepository(t)
+	shas := buildWalkHistory(t, r)
+
+	w := r.NewRevWalk(RevWalkOptions{MaxCount: -1})
+	if err := w.Push(shas["F"], "F"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Hide(shas["B"]); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := w.Next(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := w.WalkObjects(func(sha string, typ object.ObjectType, path string) error {
+		got = append(got, fmt.Sprintf("%s %s", typ, path))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 期待する結果は同じ履歴での git rev-list --objects B..F のツリーとブロブ
+	// Bから辿れるA.txtとB.txtは含まない
+	want := []string{
+		"tree ", "blob C.txt", "blob D.txt", "blob E.txt", "blob F.txt", "blob M.txt", "blob X.txt",
+		"tree ", "tree ", "tree ", "tree ", "tree ",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("WalkObjects() = %q, want %q", got, want)
+	}
+}
+
+func TestRevWalkObjectsTreeArgument(t *testing.T) {
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"b": "b\n", "d/a": "a\n"})
+
+	// 期待する結果は git rev-list --objects REV の出力のパス
+	// ツリーのパスは起点のツリーからの相対パスとし、"REV:PATH" の形式ではPATHを前につける
+	tests := []struct {
+		rev  string
+		want []string
+	}{
+		{rev: "HEAD^{tree}", want: []string{"tree ", "blob b", "tree d", "blob d/a"}},
+		{rev: "HEAD:d", want: []string{"tree d", "blob d/a"}},
+		{rev: "HEAD:b", want: []string{"blob b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rev, func(t *testing.T) {
+			sha, err := r.FindObject(tt.rev, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := r.NewRevWalk(RevWalkOptions{MaxCount: -1})
+			if err := w.Push(sha, tt.rev); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Next(); !errors.Is(err, io.EOF) {
+				t.Fatalf("Next() error = %v, want io.EOF", err)
+			}
+			var got []string
+			err = w.WalkObjects(func(sha string, typ object.ObjectType, path string) error {
+				got = append(got, fmt.Sprintf("%s %s", typ, path))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("WalkObjects() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,496 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/showa-93/wyag-go/config"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+)
+
+// SequenceAction はcherry-pickとrevertで各コミットに行う操作
+type SequenceAction string
+
+const (
+	ActionPick   SequenceAction = "pick"
+	ActionRevert SequenceAction = "revert"
+)
+
+var (
+	ErrNoSequence         = errors.New("no cherry-pick or revert in progress")
+	ErrSequenceInProgress = errors.New("cherry-pick or revert is already in progress")
+	ErrDirtyIndex         = errors.New("your local changes would be overwritten")
+	ErrEmptyPick          = errors.New("the previous cherry-pick is now empty, possibly due to conflict resolution")
+	ErrMovedHead          = errors.New("You seem to have moved HEAD. Not rewinding, check your HEAD!")
+)
+
+// sequencerDir は途中で止まったcherry-pickとrevertの状態を保存するgitdir配下のディレクトリ
+const sequencerDir = "sequencer"
+
+// SequenceOptions はcherry-pickとrevertの設定
+type SequenceOptions struct {
+	// RecordOrigin はcherry-pickしたコミットのメッセージに元のコミットを記録するか
+	RecordOrigin bool
+}
+
+// PickResult は1つのコミットを適用した結果
+type PickResult struct {
+	Action SequenceAction
+	// Commit は適用したコミット、Created は作成したコミット 衝突して止まった場合はCreatedが空になる
+	Commit  string
+	Created string
+	// Merge はコミットの変更を3方向マージした結果 止まった後に続けた場合はnil
+	Merge *MergeResult
+}
+
+// sequence は適用するコミットの一覧と、中止したときに戻すHEAD
+type sequence struct {
+	head string
+	todo []sequenceStep
+	opts SequenceOptions
+}
+
+type sequenceStep struct {
+	action SequenceAction
+	commit string
+}
+
+// StartSequence はcommitsを順にactionで適用したコミットをHEADに積み重ねる
+// 衝突したり変更が空になった場合は状態を保存して止まり、ContinueSequenceなどで再開できる
+func (r *Repository) StartSequence(action SequenceAction, commits []string, opts SequenceOptions) ([]*PickResult, error) {
+	if r.gitdir == "" {
+		return nil, ErrInMemory
+	}
+	if _, err := os.Stat(r.Path(sequencerDir)); err == nil {
+		return nil, ErrSequenceInProgress
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	if head == "" {
+		return nil, fmt.Errorf("cannot %s onto an unborn branch", action)
+	}
+	if err := r.checkCleanIndex(action, head); err != nil {
+		return nil, err
+	}
+
+	seq := &sequence{head: head, opts: opts}
+	for _, c := range commits {
+		seq.todo = append(seq.todo, sequenceStep{action: action, commit: c})
+	}
+	return r.runSequence(seq, nil)
+}
+
+// checkCleanIndex はインデックスがHEADと同じ内容か確認する
+func (r *Repository) checkCleanIndex(action SequenceAction, head string) error {
+	idx, err := r.Index()
+	if err != nil {
+		return err
+	}
+	if idx.Unmerged() {
+		return fmt.Errorf("%w: %s is not possible because you have unmerged files", ErrUnmergedIndex, actionName(action))
+	}
+	tree, err := r.WriteTree(idx)
+	if err != nil {
+		return err
+	}
+	headTree, err := r.FindObject(head, string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	if tree != headTree {
+		return fmt.Errorf("%w by %s", ErrDirtyIndex, actionName(action))
+	}
+	return nil
+}
+
+func actionName(action SequenceAction) string {
+	if action == ActionPick {
+		return "cherry-pick"
+	}
+	return string(action)
+}
+
+// pickHead はactionで止まったコミットを記録する参照
+func pickHead(action SequenceAction) string {
+	if action == ActionPick {
+		return "CHERRY_PICK_HEAD"
+	}
+	return "REVERT_HEAD"
+}
+
+// runSequence はseqの残りのコミットを順に適用する
+// 止まった場合は状態を保存し、すべて適用した場合は状態を削除する
+func (r *Repository) runSequence(seq *sequence, results []*PickResult) ([]*PickResult, error) {
+	for len(seq.todo) > 0 {
+		step := seq.todo[0]
+		res, err := r.pickCommit(step.action, step.commit, seq.opts)
+		if res != nil {
+			results = append(results, res)
+		}
+		if err != nil && !errors.Is(err, ErrEmptyPick) {
+			// 適用済みのコミットを中止で戻せるように、残りのコミットと現在のHEADを保存する
+			if _, serr := os.Stat(r.Path(sequencerDir)); len(results) > 0 || serr == nil {
+				if serr := r.saveSequence(seq); serr != nil {
+					return results, serr
+				}
+			}
+			return results, err
+		}
+		if err != nil || res.Created == "" {
+			if serr := r.saveSequence(seq); serr != nil {
+				return results, serr
+			}
+			return results, err
+		}
+		seq.todo = seq.todo[1:]
+	}
+	return results, os.RemoveAll(r.Path(sequencerDir))
+}
+
+// pickCommit はコミットの変更か、revertの場合はその逆の変更をHEADに3方向マージしてコミットする
+// 衝突した場合はワークツリーに衝突を残し、コミットのメッセージをMERGE_MSGに保存する
+func (r *Repository) pickCommit(action SequenceAction, sha string, opts SequenceOptions) (*PickResult, error) {
+	o, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	commit, ok := o.(*object.CommitObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a commit", sha)
+	}
+	parents := commit.Parents()
+	if len(parents) > 1 {
+		return nil, fmt.Errorf("commit %s is a merge which is not supported", sha)
+	}
+	var parentTree string
+	if len(parents) == 1 {
+		if parentTree, err = r.FindObject(parents[0], string(object.Tree), true); err != nil {
+			return nil, err
+		}
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := r.FindObject(head, string(object.Tree), true)
+	if err != nil {
+		return nil, err
+	}
+
+	abbrev, err := r.Abbrev(sha, DefaultAbbrev)
+	if err != nil {
+		return nil, err
+	}
+	label := fmt.Sprintf("%s (%s)", abbrev, firstLine(commit.Message()))
+	opt := CommitOptions{Reason: actionName(action)}
+	var base, theirs string
+	switch action {
+	case ActionPick:
+		base, theirs = parentTree, commit.Tree()
+		opt.Message = commit.Message()
+		if opts.RecordOrigin {
+			opt.Message = appendOrigin(opt.Message, sha)
+		}
+		if opt.Author, err = commit.Author(); err != nil {
+			return nil, err
+		}
+	case ActionRevert:
+		base, theirs = commit.Tree(), parentTree
+		label = "parent of " + label
+		opt.Message = fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.\n", commit.Subject(), sha)
+	default:
+		return nil, fmt.Errorf("unknown action %s", action)
+	}
+
+	m, err := r.MergeTrees(base, headTree, theirs, "HEAD", label)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.CheckoutMerge(m); err != nil {
+		return nil, err
+	}
+	res := &PickResult{Action: action, Commit: sha, Merge: m}
+	if !m.Clean() {
+		message := opt.Message + "\n# Conflicts:\n"
+		for _, p := range m.Conflicts {
+			message += "#\t" + p + "\n"
+		}
+		return res, r.stopPick(action, sha, message)
+	}
+
+	if res.Created, err = r.Commit(opt); err != nil {
+		if errors.Is(err, ErrNothingToCommit) {
+			if err := r.stopPick(action, sha, opt.Message); err != nil {
+				return nil, err
+			}
+			return res, ErrEmptyPick
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// stopPick は止まったコミットとコミットのメッセージを保存する
+func (r *Repository) stopPick(action SequenceAction, sha, message string) error {
+	if err := r.refs.WriteRef(pickHead(action), sha); err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path("MERGE_MSG"), []byte(message), os.FileMode(0644))
+}
+
+// clearPick は止まったコミットの記録を削除する
+func (r *Repository) clearPick() error {
+	for _, name := range []string{"CHERRY_PICK_HEAD", "REVERT_HEAD"} {
+		if err := r.refs.DeleteRef(name); err != nil && !errors.Is(err, refs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Remove(r.Path("MERGE_MSG")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(message, "\n"), "\n")
+	return line
+}
+
+var trailerReg = regexp.MustCompile(`^([A-Za-z0-9-]+: |\(cherry picked from commit )`)
+
+// appendOrigin はgitの-xと同じくメッセージの末尾に元のコミットを記録する
+// 最後の段落がトレーラーであれば空行をはさまずに続ける
+func appendOrigin(message, sha string) string {
+	message = strings.TrimRight(message, "\n")
+	paragraphs := strings.Split(message, "\n\n")
+	trailers := len(paragraphs) > 1
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		trailers = trailers && trailerReg.MatchString(line)
+	}
+	if trailers {
+		message += "\n"
+	} else {
+		message += "\n\n"
+	}
+	return message + "(cherry picked from commit " + sha + ")\n"
+}
+
+// saveSequence は適用する残りのコミットと中止したときに戻すHEADをgitと同じ形式で保存する
+func (r *Repository) saveSequence(seq *sequence) error {
+	dir := r.Path(sequencerDir)
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return err
+	}
+	var todo strings.Builder
+	for _, step := range seq.todo {
+		abbrev, err := r.Abbrev(step.commit, DefaultAbbrev)
+		if err != nil {
+			return err
+		}
+		o, err := r.ReadObject(step.commit)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&todo, "%s %s %s\n", step.action, abbrev, firstLine(o.(*object.CommitObject).Message()))
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"head":         seq.head + "\n",
+		"abort-safety": head + "\n",
+		"todo":         todo.String(),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(r.Path(sequencerDir+"/"+name), []byte(data), os.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+	opts, err := config.ReadFile(r.Path(sequencerDir+"/opts"), config.ScopeLocal)
+	if err != nil {
+		return err
+	}
+	if seq.opts.RecordOrigin {
+		if err := opts.Set("options.record-origin", "true"); err != nil {
+			return err
+		}
+	}
+	return opts.Save()
+}
+
+// loadSequence は保存した状態を読み込む
+func (r *Repository) loadSequence() (*sequence, error) {
+	if r.gitdir == "" {
+		return nil, ErrInMemory
+	}
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(r.Path(sequencerDir + "/" + name))
+		if os.IsNotExist(err) {
+			return "", ErrNoSequence
+		}
+		return strings.TrimRight(string(data), "\n"), err
+	}
+	head, err := read("head")
+	if err != nil {
+		return nil, err
+	}
+	todo, err := read("todo")
+	if err != nil {
+		return nil, err
+	}
+	opts, err := config.ReadFile(r.Path(sequencerDir+"/opts"), config.ScopeLocal)
+	if err != nil {
+		return nil, err
+	}
+	seq := &sequence{head: head}
+	if seq.opts.RecordOrigin, err = opts.Config().GetBool("options.record-origin", false); err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(todo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		action := SequenceAction(fields[0])
+		if len(fields) < 2 || action != ActionPick && action != ActionRevert {
+			return nil, fmt.Errorf("invalid line in %s/todo: %s", sequencerDir, line)
+		}
+		sha, err := r.FindObject(fields[1], string(object.Commit), true)
+		if err != nil {
+			return nil, err
+		}
+		seq.todo = append(seq.todo, sequenceStep{action: action, commit: sha})
+	}
+	return seq, nil
+}
+
+// ContinueSequence は衝突を解決したインデックスの内容で止まったコミットをコミットし、残りのコミットを適用する
+// 止まった後にコミット済みの場合はそのまま残りを適用する
+func (r *Repository) ContinueSequence() ([]*PickResult, error) {
+	seq, err := r.loadSequence()
+	if err != nil {
+		return nil, err
+	}
+	if len(seq.todo) == 0 {
+		return r.runSequence(seq, nil)
+	}
+	step := seq.todo[0]
+	if _, err := r.refs.ReadRef(pickHead(step.action)); errors.Is(err, refs.ErrNotExist) {
+		seq.todo = seq.todo[1:]
+		return r.runSequence(seq, nil)
+	} else if err != nil {
+		return nil, err
+	}
+
+	idx, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	if idx.Unmerged() {
+		return nil, fmt.Errorf("%w: committing is not possible because you have unmerged files", ErrUnmergedIndex)
+	}
+	data, err := os.ReadFile(r.Path("MERGE_MSG"))
+	if err != nil {
+		return nil, err
+	}
+	opt := CommitOptions{Message: stripComments(string(data)), Reason: "commit (" + actionName(step.action) + ")"}
+	if step.action == ActionPick {
+		o, err := r.ReadObject(step.commit)
+		if err != nil {
+			return nil, err
+		}
+		if opt.Author, err = o.(*object.CommitObject).Author(); err != nil {
+			return nil, err
+		}
+	}
+	created, err := r.Commit(opt)
+	if err != nil {
+		if errors.Is(err, ErrNothingToCommit) {
+			return nil, ErrEmptyPick
+		}
+		return nil, err
+	}
+	if err := r.clearPick(); err != nil {
+		return nil, err
+	}
+	seq.todo = seq.todo[1:]
+	return r.runSequence(seq, []*PickResult{{Action: step.action, Commit: step.commit, Created: created}})
+}
+
+// stripComments は "#" から始まる行を取り除いてメッセージの空白を整える
+func stripComments(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return object.CleanupMessage(strings.Join(lines, "\n"))
+}
+
+// SkipSequence は止まったコミットの変更を取り消し、残りのコミットを適用する
+func (r *Repository) SkipSequence() ([]*PickResult, error) {
+	seq, err := r.loadSequence()
+	if err != nil {
+		return nil, err
+	}
+	if len(seq.todo) > 0 {
+		if _, err := r.refs.ReadRef(pickHead(seq.todo[0].action)); err == nil {
+			if err := r.resetHead(); err != nil {
+				return nil, err
+			}
+			if err := r.clearPick(); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, refs.ErrNotExist) {
+			return nil, err
+		}
+		seq.todo = seq.todo[1:]
+	}
+	return r.runSequence(seq, nil)
+}
+
+// resetHead はインデックスとワークツリーをHEADの内容に戻す
+func (r *Repository) resetHead() error {
+	tree, err := r.FindObject("HEAD", string(object.Tree), true)
+	if err != nil {
+		return err
+	}
+	return r.resetIndex(tree, true)
+}
+
+// AbortSequence は適用を中止し、ブランチとインデックスとワークツリーを始める前のHEADに戻す
+// 止まった後にHEADが別のコミットに移動されていた場合は戻さずに状態だけを削除する
+func (r *Repository) AbortSequence() error {
+	seq, err := r.loadSequence()
+	if err != nil {
+		return err
+	}
+	safety, err := os.ReadFile(r.Path(sequencerDir + "/abort-safety"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if err := r.clearPick(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(r.Path(sequencerDir)); err != nil {
+		return err
+	}
+	if len(safety) > 0 && strings.TrimSpace(string(safety)) != head {
+		return ErrMovedHead
+	}
+	_, err = r.Reset(seq.head, ResetHard)
+	return err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// 期待する結果は git cherry-pick -x で作成したコミットのメッセージ
+func TestAppendOrigin(t *testing.T) {
+	const sha = "400b63892e8267c373522ce38dc20dcfad7e8a6c"
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "subject only",
+			message: "side one\n",
+			want:    "side one\n\n(cherry picked from commit " + sha + ")\n",
+		},
+		{
+			name:    "body",
+			message: "side one\n\nbody\n",
+			want:    "side one\n\nbody\n\n(cherry picked from commit " + sha + ")\n",
+		},
+		{
+			name:    "trailers",
+			message: "side two\n\nSigned-off-by: X <x@y>\n",
+			want:    "side two\n\nSigned-off-by: X <x@y>\n(cherry picked from commit " + sha + ")\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendOrigin(tt.message, sha); got != tt.want {
+				t.Errorf("appendOrigin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceAbortAfterError(t *testing.T) {
+	// gitと同じく途中のコミットを適用できずに止まった場合も、中止すると始める前のHEADに戻す
+	r := newTestRepository(t)
+	base := commitTestFiles(t, r, "base", map[string]string{"f.txt": "base\n"})
+	one := commitTestFiles(t, r, "one", map[string]string{"one.txt": "1\n"})
+	tree, err := r.FindObject(one, string(object.Tree), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := r.Identity(Committer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := r.CreateCommit(&object.CommitBuilder{
+		Tree:      tree,
+		Parents:   []string{one, base},
+		Author:    sig,
+		Committer: sig,
+		Message:   "merge\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reset(base, ResetHard); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := r.StartSequence(ActionPick, []string{one, merge}, SequenceOptions{})
+	if err == nil {
+		t.Fatal("StartSequence() succeeded with a merge commit")
+	}
+	if len(results) != 1 || results[0].Created == "" {
+		t.Fatalf("StartSequence() results = %v, want one created commit", results)
+	}
+	seq, err := r.loadSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq.todo) != 1 || seq.todo[0].commit != merge || seq.head != base {
+		t.Errorf("saved sequence = head %s todo %v, want head %s todo [%s]", seq.head, seq.todo, base, merge)
+	}
+
+	if err := r.AbortSequence(); err != nil {
+		t.Fatal(err)
+	}
+	if _, head, err := r.Head(); err != nil || head != base {
+		t.Errorf("HEAD = %s, %v, want %s", head, err, base)
+	}
+	if _, err := os.Stat(filepath.Join(r.worktree, "one.txt")); !os.IsNotExist(err) {
+		t.Errorf("one.txt remains after abort: %v", err)
+	}
+}
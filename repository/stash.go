@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/refs"
+)
+
+// StashRef は退避した変更を指す参照 更新履歴を退避した変更の一覧として使う
+const StashRef = "refs/stash"
+
+var (
+	ErrNoLocalChanges = errors.New("No local changes to save")
+	ErrNoStash        = errors.New("No stash entries found.")
+	ErrIndexConflicts = errors.New("Conflicts in index. Try without --index.")
+)
+
+// Stash はインデックスとワークツリーの変更をgitと同じ形式のコミットとして退避し、インデックスとワークツリーをHEADに戻す
+// インデックスの内容を親にHEADを持つコミットとし、ワークツリーの内容をHEADとそのコミットを親に持つマージコミットとする
+// messageが空の場合は "WIP on ブランチ: 短縮sha 件名" を退避した変更の説明とする
+func (r *Repository) Stash(message string) (string, error) {
+	ref, head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	if head == "" {
+		return "", errors.New("You do not have the initial commit yet")
+	}
+	o, err := r.ReadObject(head)
+	if err != nil {
+		return "", err
+	}
+	headCommit := o.(*object.CommitObject)
+
+	idx, err := r.Index()
+	if err != nil {
+		return "", err
+	}
+	indexTree, err := r.WriteTree(idx)
+	if err != nil {
+		return "", err
+	}
+	worktreeTree, err := r.worktreeTree(idx)
+	if err != nil {
+		return "", err
+	}
+	if indexTree == headCommit.Tree() && worktreeTree == headCommit.Tree() {
+		return "", ErrNoLocalChanges
+	}
+
+	branch := "(no branch)"
+	if ref != "HEAD" {
+		branch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	abbrev, err := r.Abbrev(head, DefaultAbbrev)
+	if err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("%s: %s %s", branch, abbrev, headCommit.Subject())
+	if message == "" {
+		message = "WIP on " + summary
+	} else {
+		message = fmt.Sprintf("On %s: %s", branch, message)
+	}
+
+	author, err := r.Identity(Author)
+	if err != nil {
+		return "", err
+	}
+	committer, err := r.Identity(Committer)
+	if err != nil {
+		return "", err
+	}
+	indexCommit, err := r.CreateCommit(&object.CommitBuilder{
+		Tree: indexTree, Parents: []string{head}, Author: author, Committer: committer,
+		Message: "index on " + summary + "\n",
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	// gitと同じくワークツリーのコミットのメッセージは末尾に改行をつけない
+	stash, err := r.CreateCommit(&object.CommitBuilder{
+		Tree: worktreeTree, Parents: []string{head, indexCommit}, Author: author, Committer: committer,
+		Message: message,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := r.updateRef(StashRef, stash, message, true); err != nil {
+		return "", err
+	}
+	if _, err := r.Reset("HEAD", ResetHard); err != nil {
+		return "", err
+	}
+	return stash, nil
+}
+
+// worktreeTree はインデックスの追跡中のファイルをワークツリーの内容に置き換えたツリーを書き込む
+// 追跡していないファイルは含めない
+func (r *Repository) worktreeTree(idx *index.Index) (string, error) {
+	if r.worktree == "" {
+		return "", ErrInMemory
+	}
+	conv, err := r.Converter()
+	if err != nil {
+		return "", err
+	}
+	m, err := r.Ignore()
+	if err != nil {
+		return "", err
+	}
+	wt := index.New(r.format)
+	for _, e := range idx.Entries() {
+		entry := *e
+		wt.Add(&entry)
+	}
+	a := &adder{repo: r, idx: wt, ignore: m, conv: conv}
+	for _, e := range idx.Entries() {
+		if e.Mode == 0160000 || e.IntentToAdd {
+			continue
+		}
+		modified, err := r.Modified(conv, e)
+		if err != nil {
+			return "", err
+		}
+		if !modified {
+			continue
+		}
+		if err := a.add(e.Path); err != nil {
+			return "", err
+		}
+	}
+	return r.WriteTree(wt)
+}
+
+// StashList は退避した変更を新しい順に返す
+func (r *Repository) StashList() ([]*refs.ReflogEntry, error) {
+	return r.Reflog(StashRef)
+}
+
+// stashTrees は退避した変更の親のHEADとインデックスのコミット、退避した変更のコミットのツリーを返す
+func (r *Repository) stashTrees(rev string) (base, indexTree, worktreeTree string, err error) {
+	sha, err := r.FindObject(rev, string(object.Commit), true)
+	if err != nil {
+		return "", "", "", err
+	}
+	if sha == "" {
+		return "", "", "", fmt.Errorf("'%s' is not a stash-like commit", rev)
+	}
+	o, err := r.ReadObject(sha)
+	if err != nil {
+		return "", "", "", err
+	}
+	commit := o.(*object.CommitObject)
+	parents := commit.Parents()
+	if len(parents) < 2 {
+		return "", "", "", fmt.Errorf("'%s' is not a stash-like commit", rev)
+	}
+	trees := make([]string, 2)
+	for i, p := range parents[:2] {
+		if trees[i], err = r.FindObject(p, string(object.Tree), true); err != nil {
+			return "", "", "", err
+		}
+	}
+	return trees[0], trees[1], commit.Tree(), nil
+}
+
+// ApplyStash はrevの退避した変更を現在のインデックスとワークツリーに3方向マージで適用する
+// 衝突しなかった場合、restoreIndexが真ならインデックスの変更も戻し、偽なら新しく追加したファイル以外の変更をインデックスから外す
+// 衝突した場合は衝突したパスを含むマージの結果を返す
+func (r *Repository) ApplyStash(rev string, restoreIndex bool) (*MergeResult, error) {
+	base, indexTree, worktreeTree, err := r.stashTrees(rev)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	if idx.Unmerged() {
+		return nil, errors.New("cannot apply a stash in the middle of a merge")
+	}
+	current, err := r.WriteTree(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	var staged *MergeResult
+	if restoreIndex && indexTree != base {
+		if staged, err = r.MergeTrees(base, current, indexTree, "Updated upstream", "Stashed changes"); err != nil {
+			return nil, err
+		}
+		if !staged.Clean() {
+			return nil, ErrIndexConflicts
+		}
+	}
+
+	m, err := r.MergeTrees(base, current, worktreeTree, "Updated upstream", "Stashed changes")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.CheckoutMerge(m); err != nil {
+		return nil, err
+	}
+	if !m.Clean() {
+		return m, nil
+	}
+
+	if idx, err = r.Index(); err != nil {
+		return nil, err
+	}
+	if staged != nil {
+		for p := range staged.changed {
+			idx.Remove(p)
+			if e, ok := staged.Index.Entry(p); ok {
+				idx.Add(&index.Entry{Mode: e.Mode, Sha: e.Sha, Path: p})
+			}
+		}
+	}
+	for p := range m.changed {
+		if staged != nil {
+			if _, ok := staged.changed[p]; ok {
+				continue
+			}
+		}
+		if o := m.ours[p]; o != nil {
+			idx.Add(&index.Entry{Mode: o.Mode, Sha: o.Sha, Path: p})
+		}
+	}
+	return m, r.WriteIndex(idx)
+}
+
+// DropStash はn番目に新しい退避した変更を一覧から取り除き、そのコミットを返す
+func (r *Repository) DropStash(n int) (string, error) {
+	entries, err := r.refs.ReadReflog(StashRef)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", ErrNoStash
+	}
+	if n < 0 || n >= len(entries) {
+		return "", fmt.Errorf("stash@{%d} is not a valid reference", n)
+	}
+
+	// 更新履歴は古い順に並んでいる 取り除いた後の次の履歴の変更前の値をつなぎ直す
+	i := len(entries) - 1 - n
+	dropped := entries[i].New
+	if i+1 < len(entries) {
+		next := *entries[i+1]
+		next.Old = entries[i].Old
+		entries[i+1] = &next
+	}
+	entries = append(entries[:i], entries[i+1:]...)
+	if len(entries) == 0 {
+		return dropped, r.refs.DeleteRef(StashRef)
+	}
+	if err := r.refs.WriteReflog(StashRef, entries); err != nil {
+		return "", err
+	}
+	return dropped, r.refs.WriteRef(StashRef, entries[len(entries)-1].New)
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// writeTestFile はワークツリーにファイルを書き込む コミットもインデックスへの追加もしない
+func writeTestFile(t *testing.T, r *Repository, p, content string) {
+	t.Helper()
+	full := filepath.Join(r.worktree, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stagedBlob はインデックスに登録されたpathの内容を返す
+func stagedBlob(t *testing.T, r *Repository, p string) string {
+	t.Helper()
+	idx, err := r.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := idx.Entry(p)
+	if !ok {
+		t.Fatalf("%s is not in the index", p)
+	}
+	return blobContent(t, r, e.Sha)
+}
+
+// blobContent はshaのblobの内容を返す
+func blobContent(t *testing.T, r *Repository, sha string) string {
+	t.Helper()
+	o, err := r.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := o.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStashPush(t *testing.T) {
+	r := newTestRepository(t)
+	head := commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	// a.txtはインデックスとワークツリーで異なる変更、b.txtはワークツリーのみの変更
+	writeTestFile(t, r, "a.txt", "a staged\n")
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, r, "a.txt", "a worktree\n")
+	writeTestFile(t, r, "b.txt", "b worktree\n")
+	writeTestFile(t, r, "untracked.txt", "u\n")
+
+	sha, err := r.Stash("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := r.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stash := o.(*object.CommitObject)
+	// gitと同じく-uを指定しない場合は追跡していないファイルの親を作らない
+	parents := stash.Parents()
+	if len(parents) != 2 || parents[0] != head {
+		t.Fatalf("stash parents = %v, want [%s <index>]", parents, head)
+	}
+	// 期待する結果は git stash の出力
+	if got, want := stash.Message(), "WIP on master: "+head[:7]+" first"; got != want {
+		t.Errorf("stash message = %q, want %q", got, want)
+	}
+
+	o, err = r.ReadObject(parents[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexCommit := o.(*object.CommitObject)
+	if got := indexCommit.Parents(); len(got) != 1 || got[0] != head {
+		t.Errorf("index commit parents = %v, want [%s]", got, head)
+	}
+	if got, want := indexCommit.Message(), "index on master: "+head[:7]+" first\n"; got != want {
+		t.Errorf("index commit message = %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		tree, path, want string
+	}{
+		{tree: indexCommit.Tree(), path: "a.txt", want: "a staged\n"},
+		{tree: indexCommit.Tree(), path: "b.txt", want: "b\n"},
+		{tree: stash.Tree(), path: "a.txt", want: "a worktree\n"},
+		{tree: stash.Tree(), path: "b.txt", want: "b worktree\n"},
+	} {
+		leaf, err := r.TreeEntry(tt.tree, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := blobContent(t, r, leaf.Sha()); got != tt.want {
+			t.Errorf("%s:%s = %q, want %q", tt.tree, tt.path, got, tt.want)
+		}
+	}
+	if _, err := r.TreeEntry(stash.Tree(), "untracked.txt"); err == nil {
+		t.Error("untracked.txt is stashed")
+	}
+
+	// インデックスとワークツリーはHEADに戻り、追跡していないファイルは残す
+	if got := readTestFile(t, r, "a.txt"); got != "a\n" {
+		t.Errorf("a.txt = %q, want %q", got, "a\n")
+	}
+	if got := stagedBlob(t, r, "a.txt"); got != "a\n" {
+		t.Errorf("staged a.txt = %q, want %q", got, "a\n")
+	}
+	if got := readTestFile(t, r, "untracked.txt"); got != "u\n" {
+		t.Errorf("untracked.txt = %q, want %q", got, "u\n")
+	}
+
+	entries, err := r.StashList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].New != sha {
+		t.Errorf("StashList = %v, want [%s]", entries, sha)
+	}
+
+	if _, err := r.Stash(""); !errors.Is(err, ErrNoLocalChanges) {
+		t.Errorf("Stash without changes = %v, want %v", err, ErrNoLocalChanges)
+	}
+}
+
+func TestStashApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		restoreIndex bool
+		// wantStaged はインデックスに登録されるa.txtの内容
+		wantStaged string
+	}{
+		// gitと同じく--indexを指定しない場合はインデックスの変更を戻さない
+		{name: "worktree only", wantStaged: "a\n"},
+		{name: "restore index", restoreIndex: true, wantStaged: "a staged\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t)
+			commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+			writeTestFile(t, r, "a.txt", "a staged\n")
+			if err := r.Add([]string{"a.txt"}); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFile(t, r, "a.txt", "a worktree\n")
+			writeTestFile(t, r, "b.txt", "b worktree\n")
+			sha, err := r.Stash("message")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := r.ApplyStash("stash@{0}", tt.restoreIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !m.Clean() {
+				t.Fatalf("ApplyStash conflicts = %v", m.Conflicts)
+			}
+			if got := readTestFile(t, r, "a.txt"); got != "a worktree\n" {
+				t.Errorf("a.txt = %q, want %q", got, "a worktree\n")
+			}
+			if got := readTestFile(t, r, "b.txt"); got != "b worktree\n" {
+				t.Errorf("b.txt = %q, want %q", got, "b worktree\n")
+			}
+			if got := stagedBlob(t, r, "a.txt"); got != tt.wantStaged {
+				t.Errorf("staged a.txt = %q, want %q", got, tt.wantStaged)
+			}
+			if got := stagedBlob(t, r, "b.txt"); got != "b\n" {
+				t.Errorf("staged b.txt = %q, want %q", got, "b\n")
+			}
+
+			// applyは退避した変更を一覧に残す
+			entries, err := r.StashList()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].New != sha || entries[0].Message != "On master: message" {
+				t.Errorf("StashList = %v, want [%s On master: message]", entries, sha)
+			}
+		})
+	}
+}
+
+func TestStashPop(t *testing.T) {
+	// popは適用してから一覧から取り除く
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n"})
+	writeTestFile(t, r, "a.txt", "first stash\n")
+	first, err := r.Stash("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, r, "a.txt", "second stash\n")
+	second, err := r.Stash("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := r.ApplyStash("stash@{0}", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Clean() {
+		t.Fatalf("ApplyStash conflicts = %v", m.Conflicts)
+	}
+	dropped, err := r.DropStash(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != second {
+		t.Errorf("DropStash = %s, want %s", dropped, second)
+	}
+	if got := readTestFile(t, r, "a.txt"); got != "second stash\n" {
+		t.Errorf("a.txt = %q, want %q", got, "second stash\n")
+	}
+
+	entries, err := r.StashList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].New != first {
+		t.Fatalf("StashList = %v, want [%s]", entries, first)
+	}
+	if b, err := r.ResolveRef(StashRef); err != nil || string(b) != first {
+		t.Errorf("%s = %s, %v, want %s", StashRef, b, err, first)
+	}
+}
+
+func TestStashDrop(t *testing.T) {
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n"})
+	var shas []string
+	for _, content := range []string{"1\n", "2\n", "3\n"} {
+		writeTestFile(t, r, "a.txt", content)
+		sha, err := r.Stash("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		shas = append(shas, sha)
+	}
+
+	// 途中の履歴を取り除くと前後の履歴をつなぎ直す
+	dropped, err := r.DropStash(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != shas[1] {
+		t.Errorf("DropStash(1) = %s, want %s", dropped, shas[1])
+	}
+	entries, err := r.StashList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].New != shas[2] || entries[1].New != shas[0] {
+		t.Fatalf("StashList = %v, want [%s %s]", entries, shas[2], shas[0])
+	}
+	if entries[0].Old != shas[0] {
+		t.Errorf("stash@{0} old = %s, want %s", entries[0].Old, shas[0])
+	}
+
+	if _, err := r.DropStash(2); err == nil {
+		t.Error("DropStash(2) succeeded, want error")
+	}
+
+	// 最後の履歴を取り除くと参照も削除する
+	for i := 0; i < 2; i++ {
+		if _, err := r.DropStash(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.ResolveRef(StashRef); err == nil {
+		t.Errorf("%s exists after dropping all entries", StashRef)
+	}
+	if _, err := r.DropStash(0); !errors.Is(err, ErrNoStash) {
+		t.Errorf("DropStash on empty = %v, want %v", err, ErrNoStash)
+	}
+}
+
+func TestStashApplyConflict(t *testing.T) {
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n"})
+	writeTestFile(t, r, "a.txt", "stashed\n")
+	sha, err := r.Stash("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, r, "second", map[string]string{"a.txt": "upstream\n"})
+
+	m, err := r.ApplyStash("stash@{0}", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Conflicts) != 1 || m.Conflicts[0] != "a.txt" {
+		t.Fatalf("ApplyStash conflicts = %v, want [a.txt]", m.Conflicts)
+	}
+	// 期待する結果は git stash apply の出力
+	want := "<<<<<<< Updated upstream\nupstream\n=======\nstashed\n>>>>>>> Stashed changes\n"
+	if got := readTestFile(t, r, "a.txt"); got != want {
+		t.Errorf("a.txt = %q, want %q", got, want)
+	}
+	idx, err := r.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.Unmerged() {
+		t.Error("index has no unmerged entries")
+	}
+
+	// 衝突した変更は一覧に残り、衝突したままでは再度適用できない
+	entries, err := r.StashList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].New != sha {
+		t.Errorf("StashList = %v, want [%s]", entries, sha)
+	}
+	if _, err := r.ApplyStash("stash@{0}", false); err == nil {
+		t.Error("ApplyStash with unmerged index succeeded, want error")
+	}
+}
+
+func TestStashApplyIndexConflict(t *testing.T) {
+	// インデックスの変更が衝突する場合は--indexでは適用しない
+	r := newTestRepository(t)
+	commitTestFiles(t, r, "first", map[string]string{"a.txt": "a\n"})
+	writeTestFile(t, r, "a.txt", "staged\n")
+	if err := r.Add([]string{"a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Stash(""); err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, r, "second", map[string]string{"a.txt": "upstream\n"})
+
+	if _, err := r.ApplyStash("stash@{0}", true); !errors.Is(err, ErrIndexConflicts) {
+		t.Fatalf("ApplyStash = %v, want %v", err, ErrIndexConflicts)
+	}
+	if got := readTestFile(t, r, "a.txt"); got != "upstream\n" {
+		t.Errorf("a.txt = %q, want %q", got, "upstream\n")
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/showa-93/wyag-go/gpg"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// Verifier はgpg.openpgp.keyringのOpenPGPの公開鍵とgpg.ssh.allowedSignersFileのSSHの署名者を読み込む
+// 設定されていない場合は鍵を持たないVerifierを返す
+func (r *Repository) Verifier() (*gpg.Verifier, error) {
+	v := &gpg.Verifier{}
+	keyring, ok, err := r.conf.GetPath("gpg.openpgp.keyring")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		data, err := os.ReadFile(keyring)
+		if err != nil {
+			return nil, err
+		}
+		if v.Keyring, err = gpg.ReadKeyring(data); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, keyring)
+		}
+	}
+
+	allowedSigners, ok, err := r.conf.GetPath("gpg.ssh.allowedsignersfile")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		data, err := os.ReadFile(allowedSigners)
+		if err != nil {
+			return nil, err
+		}
+		if v.AllowedSigners, err = gpg.ParseAllowedSigners(data); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, allowedSigners)
+		}
+	}
+	return v, nil
+}
+
+// VerifyCommit はコミットの署名を検証する 署名されていない場合はnilを返す
+// SSHの鍵の有効期限はコミットした日時で判定する
+func (r *Repository) VerifyCommit(v *gpg.Verifier, commit *object.CommitObject) (*gpg.Result, error) {
+	sig := commit.GPGSig()
+	if sig == "" {
+		return nil, nil
+	}
+	var when time.Time
+	if committer, err := commit.Committer(); err == nil {
+		when = committer.When
+	}
+	return v.Verify(commit.Payload(), sig, when)
+}
+
+// VerifyTag はタグの署名を検証する 署名されていない場合はnilを返す
+func (r *Repository) VerifyTag(v *gpg.Verifier, tag *object.TagObject) (*gpg.Result, error) {
+	sig := tag.GPGSig()
+	if sig == "" {
+		return nil, nil
+	}
+	var when time.Time
+	if tagger, err := tag.Tagger(); err == nil {
+		when = tagger.When
+	}
+	return v.Verify(tag.Payload(), sig, when)
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+)
+
+// Modified はインデックスのエントリとワークツリーのファイルの内容が異なるか判定する
+// ファイルが存在しない場合も真を返す
+// ファイルの情報が記録されたものと同じであれば内容は比較しない
+func (r *Repository) Modified(conv *Converter, e *index.Entry) (bool, error) {
+	full := filepath.Join(r.worktree, filepath.FromSlash(e.Path))
+	fi, err := os.Lstat(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if !e.StatChanged(fi) {
+		return false, nil
+	}
+
+	var data []byte
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		if e.Mode != 0120000 {
+			return true, nil
+		}
+		target, err := os.Readlink(full)
+		if err != nil {
+			return false, err
+		}
+		data = []byte(target)
+	case fi.Mode().IsRegular():
+		if e.Mode&0170000 != 0100000 {
+			return true, nil
+		}
+		filemode, err := r.conf.GetBool("core.filemode", true)
+		if err != nil {
+			return false, err
+		}
+		if filemode && (fi.Mode()&0100 != 0) != (e.Mode == 0100755) {
+			return true, nil
+		}
+		if data, err = os.ReadFile(full); err != nil {
+			return false, err
+		}
+		if data, err = conv.ToGit(e.Path, data); err != nil {
+			return false, err
+		}
+	default:
+		return true, nil
+	}
+	return object.HashRaw(r.Format(), object.Blob, data) != e.Sha, nil
+}
+
+// Untracked はワークツリーにあってインデックスにないファイルをワークツリーからの相対パスで返す
+// dirはワークツリーからの相対パスで、空文字の場合はワークツリー全体を探す
+// excludeが真の場合は.gitignoreなどで除外されたファイルを含めない
+// 別のリポジトリは中を探さず "dir/" の形式で返す
+func (r *Repository) Untracked(idx *index.Index, dir string, exclude bool) ([]string, error) {
+	if r.worktree == "" {
+		return nil, ErrInMemory
+	}
+	m, err := r.Ignore()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]struct{}, len(idx.Entries()))
+	for _, e := range idx.Entries() {
+		for p := e.Path; p != "."; p = filepath.ToSlash(filepath.Dir(p)) {
+			if _, ok := tracked[p]; ok {
+				break
+			}
+			tracked[p] = struct{}{}
+		}
+	}
+
+	var paths []string
+	root := filepath.Join(r.worktree, filepath.FromSlash(dir))
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := r.RelativePath(p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if rel == "" {
+				return nil
+			}
+			if exclude {
+				if ignored, err := m.Ignored(rel, true); err != nil {
+					return err
+				} else if ignored {
+					return filepath.SkipDir
+				}
+			}
+			if _, ok := tracked[rel]; ok {
+				return nil
+			}
+			if _, err := os.Stat(filepath.Join(p, ".git")); err == nil {
+				paths = append(paths, rel+"/")
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if _, ok := tracked[rel]; ok {
+			return nil
+		}
+		if exclude {
+			if ignored, err := m.Ignored(rel, false); err != nil {
+				return err
+			} else if ignored {
+				return nil
+			}
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ResetCommand struct {
+	*flag.FlagSet
+	soft  bool
+	mixed bool
+	hard  bool
+	rev   string
+	paths []string
+	// pathMode はパスを指定してインデックスのエントリのみを戻すか
+	pathMode bool
+}
+
+func NewResetCommand(args []string) *ResetCommand {
+	c := &ResetCommand{}
+	c.FlagSet = flag.NewFlagSet("reset", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.soft, "soft", false, "Only move HEAD to the commit")
+	c.FlagSet.BoolVar(&c.mixed, "mixed", false, "Move HEAD and reset the index (default)")
+	c.FlagSet.BoolVar(&c.hard, "hard", false, "Move HEAD and reset the index and the working tree")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go reset [--soft | --mixed | --hard] [REV]\n")
+		fmt.Fprint(o, "       wyag-go reset [REV] [--] PATH...\n")
+		fmt.Fprint(o, "\tReset current HEAD to the specified state\n")
+	}
+
+	// "--" より後はすべてパスとして扱う
+	for i, arg := range args {
+		if arg == "--" {
+			args, c.paths, c.pathMode = args[:i], args[i+1:], true
+			break
+		}
+	}
+	c.Parse(args)
+	n := 0
+	for _, b := range []bool{c.soft, c.mixed, c.hard} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		fmt.Println("--soft, --mixed and --hard are mutually exclusive")
+		os.Exit(1)
+	}
+
+	rest := c.Args()
+	c.rev = "HEAD"
+	if c.pathMode {
+		if len(rest) > 1 {
+			c.Usage()
+			os.Exit(1)
+		}
+		if len(rest) == 1 {
+			c.rev = rest[0]
+		}
+	} else if len(rest) > 0 {
+		// "--" がない場合は先頭の引数がコミットとして解決できなければパスとみなす
+		c.rev, c.paths = rest[0], rest[1:]
+		c.pathMode = len(c.paths) > 0
+	}
+	return c
+}
+
+func (c *ResetCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	if !c.pathMode && c.rev != "HEAD" {
+		if sha, err := repo.FindObject(c.rev, string(object.Commit), true); err != nil || sha == "" {
+			if _, serr := os.Lstat(filepath.Join(BasePath, c.rev)); serr != nil {
+				return fmt.Errorf("ambiguous argument '%s': unknown revision or path not in the working tree", c.rev)
+			}
+			c.rev, c.paths, c.pathMode = "HEAD", []string{c.rev}, true
+		}
+	}
+
+	if c.pathMode {
+		switch {
+		case c.soft:
+			return errors.New("Cannot do soft reset with paths.")
+		case c.hard:
+			return errors.New("Cannot do hard reset with paths.")
+		}
+		paths := make([]string, 0, len(c.paths))
+		for _, p := range c.paths {
+			rel, err := repo.RelativePath(filepath.Join(BasePath, p))
+			if err != nil {
+				return err
+			}
+			paths = append(paths, rel)
+		}
+		if err := repo.ResetPaths(c.rev, paths); err != nil {
+			return err
+		}
+		return printUnstaged(repo)
+	}
+
+	mode := repository.ResetMixed
+	switch {
+	case c.soft:
+		mode = repository.ResetSoft
+	case c.hard:
+		mode = repository.ResetHard
+	}
+	sha, err := repo.Reset(c.rev, mode)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case repository.ResetHard:
+		o, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		abbrev, err := repo.Abbrev(sha, repository.DefaultAbbrev)
+		if err != nil {
+			return err
+		}
+		subject, _, _ := strings.Cut(o.(*object.CommitObject).Message(), "\n")
+		fmt.Fprintf(os.Stdout, "HEAD is now at %s %s\n", abbrev, subject)
+	case repository.ResetMixed:
+		return printUnstaged(repo)
+	}
+	return nil
+}
+
+// printUnstaged はgitと同じくインデックスとワークツリーで内容が異なるファイルを表示する
+func printUnstaged(repo *repository.Repository) error {
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	conv, err := repo.Converter()
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, e := range idx.Entries() {
+		if e.Stage != 0 {
+			continue
+		}
+		modified, err := repo.Modified(conv, e)
+		if err != nil {
+			return err
+		}
+		if !modified {
+			continue
+		}
+		status := "M"
+		if _, err := os.Lstat(filepath.Join(repo.Worktree(), filepath.FromSlash(e.Path))); os.IsNotExist(err) {
+			status = "D"
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%s", status, quotePath(e.Path)))
+	}
+	if len(lines) > 0 {
+		fmt.Fprintln(os.Stdout, "Unstaged changes after reset:")
+		fmt.Fprintln(os.Stdout, strings.Join(lines, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// patternsFlag は複数回指定できる正規表現のフラグ
+type patternsFlag []*regexp.Regexp
+
+func (p *patternsFlag) String() string {
+	return ""
+}
+
+func (p *patternsFlag) Set(s string) error {
+	reg, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, reg)
+	return nil
+}
+
+// dateFlag は日時か "2 weeks ago" のような相対的な日時を受け付けるフラグ
+type dateFlag struct {
+	time.Time
+}
+
+func (d *dateFlag) String() string {
+	return ""
+}
+
+func (d *dateFlag) Set(s string) error {
+	t, err := parseDate(s, time.Now())
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
+
+type RevListCommand struct {
+	*flag.FlagSet
+	topoOrder   bool
+	dateOrder   bool
+	reverse     bool
+	firstParent bool
+	maxCount    int
+	since       dateFlag
+	until       dateFlag
+	authors     patternsFlag
+	greps       patternsFlag
+	count       bool
+	objects     bool
+	revs        []string
+}
+
+func NewRevListCommand(args []string) *RevListCommand {
+	c := &RevListCommand{}
+	c.FlagSet = flag.NewFlagSet("rev-list", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.topoOrder, "topo-order", false, "Show no parents before all of its children are shown, and avoid interleaving lines of history")
+	c.FlagSet.BoolVar(&c.dateOrder, "date-order", false, "Show no parents before all of its children are shown, otherwise in commit timestamp order")
+	c.FlagSet.BoolVar(&c.reverse, "reverse", false, "Output the commits chosen to be shown in reverse order")
+	c.FlagSet.BoolVar(&c.firstParent, "first-parent", false, "Follow only the first parent commit upon seeing a merge commit")
+	c.FlagSet.IntVar(&c.maxCount, "max-count", -1, "Limit the number of commits to output")
+	c.FlagSet.IntVar(&c.maxCount, "n", -1, "Limit the number of commits to output")
+	c.FlagSet.Var(&c.since, "since", "Show commits more recent than a specific date")
+	c.FlagSet.Var(&c.since, "after", "Show commits more recent than a specific date")
+	c.FlagSet.Var(&c.until, "until", "Show commits older than a specific date")
+	c.FlagSet.Var(&c.until, "before", "Show commits older than a specific date")
+	c.FlagSet.Var(&c.authors, "author", "Limit the commits output to ones with author header lines that match the pattern")
+	c.FlagSet.Var(&c.greps, "grep", "Limit the commits output to ones with a log message that matches the pattern")
+	c.FlagSet.BoolVar(&c.count, "count", false, "Print a number stating how many commits would have been listed")
+	c.FlagSet.BoolVar(&c.objects, "objects", false, "Print the object IDs of any object referenced by the listed commits")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go rev-list [OPTIONS] COMMIT... [^COMMIT | A..B | A...B]...\n")
+		fmt.Fprint(o, "\tLists commit objects in reverse chronological order\n")
+	}
+
+	c.Parse(args)
+	if c.topoOrder && c.dateOrder {
+		fmt.Println("--topo-order and --date-order cannot be used together")
+		os.Exit(1)
+	}
+	c.revs = c.Args()
+	if len(c.revs) == 0 {
+		fmt.Println("expected at least 1 revision")
+		os.Exit(1)
+	}
+
+	return c
+}
+
+func (c *RevListCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	opts := repository.RevWalkOptions{
+		Reverse:     c.reverse,
+		FirstParent: c.firstParent,
+		MaxCount:    c.maxCount,
+		Since:       c.since.Time,
+		Until:       c.until.Time,
+		Authors:     c.authors,
+		Greps:       c.greps,
+	}
+	switch {
+	case c.topoOrder:
+		opts.Order = repository.SortTopo
+	case c.dateOrder:
+		opts.Order = repository.SortDate
+	}
+	walk := repo.NewRevWalk(opts)
+	if err := addRevisions(repo, walk, c.revs); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	count := 0
+	for {
+		sha, err := walk.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		count++
+		if !c.count {
+			fmt.Fprintln(out, sha)
+		}
+	}
+	if c.count {
+		fmt.Fprintln(out, count)
+		return nil
+	}
+	if !c.objects {
+		return nil
+	}
+	return walk.WalkObjects(func(sha string, _ object.ObjectType, path string) error {
+		_, err := fmt.Fprintf(out, "%s %s\n", sha, path)
+		return err
+	})
+}
+
+// addRevisions は "A"、"^A"、"A..B"、"A...B" の形式の範囲を辿る起点として加える
+// 範囲の片側を省略した場合はHEADとみなす
+func addRevisions(repo *repository.Repository, walk *repository.RevWalk, revs []string) error {
+	resolve := func(rev string) (string, error) {
+		if rev == "" {
+			rev = "HEAD"
+		}
+		return repo.FindObject(rev, "", false)
+	}
+	for _, rev := range revs {
+		if i := strings.Index(rev, "..."); i >= 0 {
+			a, err := resolve(rev[:i])
+			if err != nil {
+				return err
+			}
+			b, err := resolve(rev[i+3:])
+			if err != nil {
+				return err
+			}
+			ca, err := repo.FindObject(a, string(object.Commit), true)
+			if err != nil {
+				return err
+			}
+			cb, err := repo.FindObject(b, string(object.Commit), true)
+			if err != nil {
+				return err
+			}
+			if ca == "" || cb == "" {
+				return fmt.Errorf("%s: not a commit range", rev)
+			}
+			bases, err := repo.MergeBases(ca, cb)
+			if err != nil {
+				return err
+			}
+			for _, base := range bases {
+				if err := walk.Hide(base); err != nil {
+					return err
+				}
+			}
+			if err := walk.Push(a, rev[:i]); err != nil {
+				return err
+			}
+			if err := walk.Push(b, rev[i+3:]); err != nil {
+				return err
+			}
+			continue
+		}
+		if i := strings.Index(rev, ".."); i >= 0 {
+			a, err := resolve(rev[:i])
+			if err != nil {
+				return err
+			}
+			b, err := resolve(rev[i+2:])
+			if err != nil {
+				return err
+			}
+			if err := walk.Hide(a); err != nil {
+				return err
+			}
+			if err := walk.Push(b, rev[i+2:]); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(rev, "^") {
+			sha, err := resolve(rev[1:])
+			if err != nil {
+				return err
+			}
+			if err := walk.Hide(sha); err != nil {
+				return err
+			}
+			continue
+		}
+		sha, err := resolve(rev)
+		if err != nil {
+			return err
+		}
+		if err := walk.Push(sha, rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var relativeDateReg = regexp.MustCompile(`^(\d+)[ .](second|minute|hour|day|week|month|year)s?[ .]ago$`)
+
+// dateLayouts はタイムゾーンを含む日時の形式
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	"Mon Jan 2 15:04:05 2006 -0700",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// localDateLayouts はタイムゾーンを含まずローカル時刻として扱う日時の形式
+var localDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseDate は日時、"@unix時刻"、"3 days ago" などのnowからの相対的な日時を解釈する
+func parseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+	if sec, err := strconv.ParseInt(strings.TrimPrefix(s, "@"), 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if m := relativeDateReg.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	// gitと同じく日付のみの場合は現在の時刻を使う
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		h, m, sec := now.Clock()
+		return time.Date(t.Year(), t.Month(), t.Day(), h, m, sec, 0, now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %s", s)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type RevParseCommand struct {
+	*flag.FlagSet
+	wyagType string
+	name     string
+}
+
+func NewRevParseCommand(args []string) *RevParseCommand {
+	c := &RevParseCommand{}
+	c.FlagSet = flag.NewFlagSet("rev-parse", flag.ExitOnError)
+	c.FlagSet.StringVar(&c.wyagType, "wyag-type", "", "Specify the expected type")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go rev-parse [--wyag-type] [TYPE] NAME\n")
+		fmt.Fprint(o, "\tParse revision (or other objects )identifiers\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) != 1 {
+		fmt.Printf("expected 1 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+	c.name = c.Args()[0]
+
+	return c
+}
+
+func (c *RevParseCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	sha, err := repo.FindObject(c.name, string(c.wyagType), true)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, sha)
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+func NewRevertCommand(args []string) *SequenceCommand {
+	c := &SequenceCommand{action: repository.ActionRevert}
+	c.FlagSet = flag.NewFlagSet("revert", flag.ExitOnError)
+	// エディタを開かないため、常に--no-editと同じ動作になる
+	c.FlagSet.Bool("no-edit", false, "Do not start the commit message editor")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go revert [--no-edit] REV...\n")
+		fmt.Fprint(o, "       wyag-go revert (--continue | --skip | --abort)\n")
+		fmt.Fprint(o, "\tRevert some existing commits\n")
+	}
+	return c.parse(args)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ShowCommand struct {
+	*flag.FlagSet
+	stat        bool
+	nameOnly    bool
+	merges      bool
+	firstParent bool
+	revs        []string
+}
+
+func NewShowCommand(args []string) *ShowCommand {
+	c := &ShowCommand{}
+	c.FlagSet = flag.NewFlagSet("show", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.stat, "stat", false, "Show a diffstat instead of the patch")
+	c.FlagSet.BoolVar(&c.nameOnly, "name-only", false, "Show only names of changed files")
+	c.FlagSet.BoolVar(&c.merges, "m", false, "Show the changes of merge commits against each parent")
+	c.FlagSet.BoolVar(&c.firstParent, "first-parent", false, "Show the changes of merge commits against the first parent")
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go show [--stat | --name-only] [-m | --first-parent] [OBJECT...]\n")
+		fmt.Fprint(o, "\tShow various types of objects\n")
+	}
+
+	c.Parse(args)
+	if c.stat && c.nameOnly {
+		fmt.Println("--stat and --name-only cannot be used together")
+		os.Exit(1)
+	}
+	c.revs = c.Args()
+	if len(c.revs) == 0 {
+		c.revs = []string{"HEAD"}
+	}
+
+	return c
+}
+
+func (c *ShowCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	printer, err := newDiffPrinter(repo)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	s := &shower{ShowCommand: c, repo: repo, printer: printer, out: out}
+	for _, rev := range c.revs {
+		sha, err := repo.FindObject(rev, "", false)
+		if err != nil {
+			return err
+		}
+		if err := s.show(rev, sha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type shower struct {
+	*ShowCommand
+	repo    *repository.Repository
+	printer *diffPrinter
+	out     *bufio.Writer
+	// commits は表示したコミットの数 2つ目以降のコミットの前に空行を入れる
+	commits int
+}
+
+func (s *shower) show(rev, sha string) error {
+	o, err := s.repo.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+	switch o := o.(type) {
+	case *object.TagObject:
+		return s.showTag(sha, o)
+	case *object.CommitObject:
+		return s.showCommit(sha, o)
+	case *object.TreeObject:
+		fmt.Fprintf(s.out, "tree %s\n\n", rev)
+		for _, item := range o.Items() {
+			name := item.Path()
+			if item.Type() == object.Tree {
+				name += "/"
+			}
+			// gitと同じくツリーの一覧ではパスを引用符で囲まない
+			fmt.Fprintln(s.out, name)
+		}
+		return nil
+	case *object.BlobObject:
+		_, err := s.out.Write(o.Data())
+		return err
+	}
+	return fmt.Errorf("unexpected type: %s", o.TypeHeader())
+}
+
+// showTag はタグのヘッダーとメッセージを表示した後、タグが指すオブジェクトを表示する
+func (s *shower) showTag(sha string, tag *object.TagObject) error {
+	target := tag.Target()
+	if tag.Name() == "" || target == "" {
+		return fmt.Errorf("malformed tag %s", sha)
+	}
+	fmt.Fprintf(s.out, "tag %s\n", tag.Name())
+	if tagger, err := tag.Tagger(); err == nil {
+		fmt.Fprintf(s.out, "Tagger: %s\n", tagger.Ident())
+		fmt.Fprintf(s.out, "Date:   %s\n", formatDate(tagger))
+	}
+	fmt.Fprintln(s.out)
+	// 署名はメッセージに含めて表示する
+	fmt.Fprint(s.out, tag.Message()+tag.GPGSig())
+	fmt.Fprintln(s.out)
+	return s.show(target, target)
+}
+
+func (s *shower) showCommit(sha string, commit *object.CommitObject) error {
+	parents := commit.Parents()
+	switch {
+	case len(parents) > 1 && s.merges:
+		// -m はgitと同じくそれぞれの親との差分を、どの親との差分かをshaの後に添えて順に表示する
+		for _, p := range parents {
+			if err := s.showCommitDiff(fmt.Sprintf("%s (from %s)", sha, p), commit, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(parents) > 1 && !s.firstParent && !s.stat:
+		// gitの既定の結合差分は衝突を解決していないマージでは差分もパスも表示しない
+		// 統計のみ最初の親との差分を表示する 差分を表示しない場合もメッセージの後に空行を入れる
+		if err := s.writeHeader(sha, commit); err != nil {
+			return err
+		}
+		fmt.Fprintln(s.out)
+		return nil
+	}
+	parent := ""
+	if len(parents) > 0 {
+		parent = parents[0]
+	}
+	return s.showCommitDiff(sha, commit, parent)
+}
+
+// writeHeader は2つ目以降のコミットの前に空行を入れてコミットのヘッダーとメッセージを書き込む
+func (s *shower) writeHeader(label string, commit *object.CommitObject) error {
+	if s.commits > 0 {
+		fmt.Fprintln(s.out)
+	}
+	s.commits++
+	return writeCommit(s.out, s.repo, label, commit, "")
+}
+
+// showCommitDiff はコミットのヘッダーとparentとの差分を表示する parentが空の場合はすべて追加されたものとする
+func (s *shower) showCommitDiff(label string, commit *object.CommitObject, parent string) error {
+	if err := s.writeHeader(label, commit); err != nil {
+		return err
+	}
+	tree := commit.Tree()
+	if tree == "" {
+		return fmt.Errorf("malformed commit %s", label)
+	}
+	base := ""
+	if parent != "" {
+		var err error
+		if base, err = s.repo.FindObject(parent, string(object.Tree), true); err != nil {
+			return err
+		}
+	}
+	changes, err := s.repo.DiffTrees(base, tree, true)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(s.out)
+	switch {
+	case s.stat:
+		return s.printer.writeStat(s.out, changes)
+	case s.nameOnly:
+		s.printer.writeNameOnly(s.out, changes)
+		return nil
+	}
+	return s.printer.writePatch(s.out, changes)
+}
+
+// writeCommit はgitの既定の形式でコミットのヘッダーとメッセージを書き込む
+// shaは "commit" の行にそのまま書き込む
+// signatureは "commit" の行の後に書き込む署名の検証結果で、空の場合は何も書き込まない
+func writeCommit(w io.Writer, repo *repository.Repository, sha string, commit *object.CommitObject, signature string) error {
+	fmt.Fprintf(w, "commit %s\n", sha)
+	fmt.Fprint(w, signature)
+	if parents := commit.Parents(); len(parents) > 1 {
+		abbrevs := make([]string, len(parents))
+		for i, p := range parents {
+			a, err := repo.Abbrev(p, repository.DefaultAbbrev)
+			if err != nil {
+				return err
+			}
+			abbrevs[i] = a
+		}
+		fmt.Fprintf(w, "Merge: %s\n", strings.Join(abbrevs, " "))
+	}
+	if author, err := commit.Author(); err == nil {
+		fmt.Fprintf(w, "Author: %s\n", author.Ident())
+		fmt.Fprintf(w, "Date:   %s\n", formatDate(author))
+	}
+	fmt.Fprintln(w)
+	writeIndentedMessage(w, commit.Message())
+	return nil
+}
+
+// writeIndentedMessage はメッセージの各行を4文字字下げして書き込む
+// 先頭の空行は除き、タブは8文字ごとの位置まで空白に展開する
+func writeIndentedMessage(w io.Writer, message string) {
+	message = strings.TrimLeft(message, "\n")
+	message = strings.TrimSuffix(message, "\n")
+	if message == "" {
+		return
+	}
+	for _, line := range strings.Split(message, "\n") {
+		fmt.Fprintf(w, "    %s\n", expandTabs(line))
+	}
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 8 - col%8
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(r)
+		col++
+	}
+	return sb.String()
+}
+
+// formatDate はgitの既定の形式で記録された時差の日時を返す
+func formatDate(sig *object.Signature) string {
+	return sig.When.Format("Mon Jan 2 15:04:05 2006 -0700")
+}
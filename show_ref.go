@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type ShowRefCommand struct {
+	*flag.FlagSet
+}
+
+func NewShowRefCommand(args []string) *ShowRefCommand {
+	c := &ShowRefCommand{}
+	c.FlagSet = flag.NewFlagSet("show-ref", flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: show-ref\n")
+		fmt.Fprint(o, "\tList references.\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) != 0 {
+		fmt.Printf("expected 0 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+
+	return c
+}
+
+func (i *ShowRefCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	refs, err := repo.ListRef("refs")
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		fmt.Fprintf(os.Stdout, "%s %s\n", ref.Sha(), ref.Path())
+	}
+
+	return err
+}
+
+func ResolveRef(repo *repository.Repository, ref string) ([]byte, error) {
+	f, err := repo.MakeFile(ref, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	data := b[:len(b)-1]
+
+	if strings.HasPrefix(string(data), "ref: ") {
+		return ResolveRef(repo, string(data[5:]))
+	} else {
+		return data, nil
+	}
+}
+
+type Ref struct {
+	sha  string
+	path string
+}
+
+func ListRef(repo *repository.Repository, path string, refs []Ref) ([]Ref, error) {
+	entries, err := os.ReadDir(repo.Path(path))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			refs, err = ListRef(repo, filepath.Join(path, e.Name()), refs)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			p := filepath.Join(path, e.Name())
+			b, err := ResolveRef(repo, p)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, Ref{
+				sha:  string(b),
+				path: p,
+			})
+		}
+	}
+
+	return refs, err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/showa-93/wyag-go/index"
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+// runShow はcのオプションでのshowの出力を返す
+func runShow(t *testing.T, repo *repository.Repository, c *ShowCommand, revs ...string) string {
+	t.Helper()
+	printer, err := newDiffPrinter(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	s := &shower{ShowCommand: c, repo: repo, printer: printer, out: out}
+	for _, rev := range revs {
+		sha, err := repo.FindObject(rev, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.show(rev, sha); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestShowTreeDoesNotQuote(t *testing.T) {
+	// 期待する結果は git show HEAD:d の出力
+	repo, _ := newTestRepository(t, map[string]string{
+		"d/ü.txt":    "a\n",
+		"d/sp ace":   "b\n",
+		"d/sub/x.go": "c\n",
+	})
+	want := "tree HEAD:d\n\nsp ace\nsub/\nü.txt\n"
+	if got := runShow(t, repo, &ShowCommand{}, "HEAD:d"); got != want {
+		t.Errorf("show HEAD:d = %q, want %q", got, want)
+	}
+}
+
+func TestShowPatchPathWithSpace(t *testing.T) {
+	// 期待する結果は git show HEAD の出力 空白を含むパスの---と+++の行は末尾にタブがつく
+	repo, _ := newTestRepository(t, map[string]string{"d/sp ace": "a\n"})
+	sha := commitTestFiles(t, repo, "second", map[string]string{"d/sp ace": "a\nc\n", "d/new file": "n\n"})
+
+	want := "commit " + sha + "\n" +
+		"Author: T <t@example.com>\n" +
+		"Date:   Wed Nov 15 07:13:20 2023 +0900\n" +
+		"\n" +
+		"    second\n" +
+		"\n" +
+		"diff --git a/d/new file b/d/new file\n" +
+		"new file mode 100644\n" +
+		"index 0000000..8ba3a16\n" +
+		"--- /dev/null\n" +
+		"+++ b/d/new file\t\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+n\n" +
+		"diff --git a/d/sp ace b/d/sp ace\n" +
+		"index 7898192..0f7bc76 100644\n" +
+		"--- a/d/sp ace\t\n" +
+		"+++ b/d/sp ace\t\n" +
+		"@@ -1 +1,2 @@\n" +
+		" a\n" +
+		"+c\n"
+	if got := runShow(t, repo, &ShowCommand{}, "HEAD"); got != want {
+		t.Errorf("show HEAD =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShowMergeWithoutDiff(t *testing.T) {
+	// 期待する結果は git show MERGE PARENT の出力 差分を表示しないマージコミットもメッセージの後に空行が入る
+	repo, first := newTestRepository(t, map[string]string{"a.txt": "a\n"})
+	second := commitTestFiles(t, repo, "second", map[string]string{"b.txt": "b\n"})
+	o, err := repo.ReadObject(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := o.(*object.CommitObject).Author()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := repo.CreateCommit(&object.CommitBuilder{
+		Tree:    o.(*object.CommitObject).Tree(),
+		Parents: []string{second, first},
+		Author:  sig, Committer: sig,
+		Message: "merge\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "commit " + merge + "\n" +
+		"Merge: " + second[:7] + " " + first[:7] + "\n" +
+		"Author: T <t@example.com>\n" +
+		"Date:   Wed Nov 15 07:13:20 2023 +0900\n" +
+		"\n" +
+		"    merge\n" +
+		"\n" +
+		"\n" +
+		"commit " + first + "\n" +
+		"Author: T <t@example.com>\n" +
+		"Date:   Wed Nov 15 07:13:20 2023 +0900\n" +
+		"\n" +
+		"    first\n" +
+		"\n" +
+		"diff --git a/a.txt b/a.txt\n" +
+		"new file mode 100644\n" +
+		"index 0000000..7898192\n" +
+		"--- /dev/null\n" +
+		"+++ b/a.txt\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+a\n"
+	if got := runShow(t, repo, &ShowCommand{}, merge, first); got != want {
+		t.Errorf("show =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShowMergeOptions(t *testing.T) {
+	// 期待する結果は git show OPTIONS MERGE の出力
+	// 既定では結合差分となり衝突のないマージはパスを表示せず、統計のみ最初の親との差分を表示する
+	repo, base := newTestRepository(t, map[string]string{"a": "a\n"})
+	side := commitTestFiles(t, repo, "s", map[string]string{"s": "s\n"})
+	if _, err := repo.Reset(base, repository.ResetHard); err != nil {
+		t.Fatal(err)
+	}
+	main := commitTestFiles(t, repo, "m", map[string]string{"m": "m\n"})
+	tree, err := repo.WriteTree(addShowTestFile(t, repo, "s", "s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := repo.ReadObject(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := o.(*object.CommitObject).Author()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := repo.CreateCommit(&object.CommitBuilder{
+		Tree:    tree,
+		Parents: []string{main, side},
+		Author:  sig, Committer: sig,
+		Message: "merge\n",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := func(from string) string {
+		line := "commit " + merge
+		if from != "" {
+			line += " (from " + from + ")"
+		}
+		return line + "\n" +
+			"Merge: " + main[:7] + " " + side[:7] + "\n" +
+			"Author: T <t@example.com>\n" +
+			"Date:   Wed Nov 15 07:13:20 2023 +0900\n" +
+			"\n" +
+			"    merge\n" +
+			"\n"
+	}
+	tests := []struct {
+		name string
+		cmd  *ShowCommand
+		want string
+	}{
+		{name: "name-only", cmd: &ShowCommand{nameOnly: true}, want: header("")},
+		{name: "stat", cmd: &ShowCommand{stat: true}, want: header("") + " s | 1 +\n 1 file changed, 1 insertion(+)\n"},
+		{name: "name-only first-parent", cmd: &ShowCommand{nameOnly: true, firstParent: true}, want: header("") + "s\n"},
+		{name: "m name-only", cmd: &ShowCommand{nameOnly: true, merges: true}, want: header(main) + "s\n\n" + header(side) + "m\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runShow(t, repo, tt.cmd, merge); got != tt.want {
+				t.Errorf("show =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// addShowTestFile はpathをワークツリーに書き込んでインデックスに加え、そのインデックスを返す
+func addShowTestFile(t *testing.T, repo *repository.Repository, path, content string) *index.Index {
+	t.Helper()
+	writeTestFile(t, repo, path, content)
+	if err := repo.Add([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
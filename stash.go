@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type StashCommand struct {
+	*flag.FlagSet
+	action  string
+	message string
+	index   bool
+	patch   bool
+	stash   string
+}
+
+func NewStashCommand(args []string) *StashCommand {
+	c := &StashCommand{action: "push"}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		c.action, args = args[0], args[1:]
+	}
+	c.FlagSet = flag.NewFlagSet("stash "+c.action, flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go stash [push [-m MESSAGE]]\n")
+		fmt.Fprint(o, "       wyag-go stash save [MESSAGE]\n")
+		fmt.Fprint(o, "       wyag-go stash list\n")
+		fmt.Fprint(o, "       wyag-go stash show [-p] [STASH]\n")
+		fmt.Fprint(o, "       wyag-go stash (apply | pop) [--index] [STASH]\n")
+		fmt.Fprint(o, "       wyag-go stash drop [STASH]\n")
+		fmt.Fprint(o, "\tStash the changes in a dirty working directory away\n")
+	}
+
+	maxArgs := 1
+	switch c.action {
+	case "push":
+		c.FlagSet.StringVar(&c.message, "m", "", "Use the given message to describe the stash")
+		maxArgs = 0
+	case "save":
+		maxArgs = -1
+	case "list":
+		maxArgs = 0
+	case "show":
+		c.FlagSet.BoolVar(&c.patch, "p", false, "Show the changes as a patch")
+	case "apply", "pop":
+		c.FlagSet.BoolVar(&c.index, "index", false, "Also reinstate the changes of the index")
+	case "drop":
+	default:
+		c.Usage()
+		os.Exit(1)
+	}
+
+	c.Parse(args)
+	if maxArgs >= 0 && len(c.Args()) > maxArgs {
+		fmt.Printf("too many arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+	switch {
+	case c.action == "save":
+		c.message = strings.Join(c.Args(), " ")
+	case len(c.Args()) == 1:
+		c.stash = c.Args()[0]
+	}
+
+	return c
+}
+
+func (c *StashCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+
+	switch c.action {
+	case "push", "save":
+		return c.push(repo)
+	case "list":
+		entries, err := repo.StashList()
+		if err != nil {
+			return err
+		}
+		for i, e := range entries {
+			fmt.Fprintf(os.Stdout, "stash@{%d}: %s\n", i, e.Message)
+		}
+		return nil
+	}
+
+	entries, err := repo.StashList()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return repository.ErrNoStash
+	}
+	name, n, err := stashName(c.stash)
+	if err != nil {
+		return err
+	}
+	if n >= len(entries) {
+		return fmt.Errorf("%s is not a valid reference", name)
+	}
+
+	switch c.action {
+	case "show":
+		return c.show(repo, name)
+	case "apply", "pop":
+		m, err := repo.ApplyStash(name, c.index)
+		if err != nil {
+			return err
+		}
+		for _, msg := range m.Messages {
+			fmt.Fprintln(os.Stdout, msg)
+		}
+		if !m.Clean() {
+			if c.action == "pop" {
+				fmt.Fprintln(os.Stdout, "The stash entry is kept in case you need it again.")
+			}
+			return ExitCode(1)
+		}
+		if c.action == "apply" {
+			return nil
+		}
+	}
+
+	sha, err := repo.DropStash(n)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Dropped %s (%s)\n", name, sha)
+	return nil
+}
+
+func (c *StashCommand) push(repo *repository.Repository) error {
+	if _, err := repo.Stash(c.message); err != nil {
+		if errors.Is(err, repository.ErrNoLocalChanges) {
+			fmt.Fprintln(os.Stdout, err)
+			return nil
+		}
+		return err
+	}
+	entries, err := repo.StashList()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Saved working directory and index state %s\n", entries[0].Message)
+	return nil
+}
+
+func (c *StashCommand) show(repo *repository.Repository, name string) error {
+	base, err := repo.FindObject(name+"^1", "tree", true)
+	if err != nil {
+		return err
+	}
+	tree, err := repo.FindObject(name, "tree", true)
+	if err != nil {
+		return err
+	}
+	changes, err := repo.DiffTrees(base, tree, true)
+	if err != nil {
+		return err
+	}
+	p, err := newDiffPrinter(repo)
+	if err != nil {
+		return err
+	}
+	if c.patch {
+		return p.writePatch(os.Stdout, changes)
+	}
+	return p.writeStat(os.Stdout, changes)
+}
+
+var stashNameReg = regexp.MustCompile(`^(?:refs/)?stash@\{(\d+)\}$`)
+
+// stashName はgitと同じく "stash@{n}" か番号で指定された退避した変更の名前と番号を返す
+// 省略した場合は最新の "refs/stash@{0}" とする
+func stashName(s string) (string, int, error) {
+	if s == "" {
+		return repository.StashRef + "@{0}", 0, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return fmt.Sprintf("%s@{%d}", repository.StashRef, n), n, nil
+	}
+	if m := stashNameReg.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err == nil {
+			return s, n, nil
+		}
+	}
+	return "", 0, fmt.Errorf("'%s' is not a stash reference", s)
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// LooseStore は objects/xx/yyyy... にzlib圧縮したオブジェクトを1ファイルずつ保存する
+type LooseStore struct {
+	dir    string
+	format oid.Format
+}
+
+func NewLooseStore(dir string, f oid.Format) *LooseStore {
+	return &LooseStore{dir: dir, format: f}
+}
+
+func (s *LooseStore) path(sha string) string {
+	return filepath.Join(s.dir, sha[0:2], sha[2:])
+}
+
+func (s *LooseStore) HasObject(sha string) (bool, error) {
+	if len(sha) < 3 {
+		return false, nil
+	}
+	_, err := os.Stat(s.path(sha))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *LooseStore) ReadObject(sha string) (object.ObjectType, []byte, error) {
+	if len(sha) < 3 {
+		return "", nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+	}
+	f, err := os.Open(s.path(sha))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+		}
+		return "", nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	t, data, err := object.Decode(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w sha=%s", err, sha)
+	}
+	return t, data, nil
+}
+
+func (s *LooseStore) WriteObject(t object.ObjectType, data []byte) (string, error) {
+	// 既に存在する場合は圧縮せずに済ませる
+	sha, err := object.HashStream(s.format, t, int64(len(data)), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if ok, err := s.HasObject(sha); err != nil || ok {
+		return sha, err
+	}
+	return s.WriteObjectStream(t, int64(len(data)), bytes.NewReader(data))
+}
+
+func (s *LooseStore) OpenObject(sha string) (Header, io.ReadCloser, error) {
+	if len(sha) < 3 {
+		return Header{}, nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+	}
+	f, err := os.Open(s.path(sha))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Header{}, nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+		}
+		return Header{}, nil, err
+	}
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		f.Close()
+		return Header{}, nil, err
+	}
+	br := bufio.NewReader(zr)
+	t, size, err := object.ReadHeader(br)
+	if err != nil {
+		zr.Close()
+		f.Close()
+		return Header{}, nil, fmt.Errorf("%w sha=%s", err, sha)
+	}
+	return Header{Type: t, Size: size}, newObjectReader(br, size, zr, f), nil
+}
+
+// WriteObjectStream は一時ファイルに圧縮しながら書き込み、shaが決まった後に所定の位置に移動する
+// 書き込み途中で中断しても壊れたオブジェクトが残らないよう、fsyncしてからrenameする
+// 既に同じオブジェクトが存在する場合は書き込まない
+func (s *LooseStore) WriteObjectStream(t object.ObjectType, size int64, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.dir, os.FileMode(0755)); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(s.dir, "tmp_obj_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := s.format.New()
+	zw := zlib.NewWriter(tmp)
+	w := io.MultiWriter(h, zw)
+	if _, err := w.Write(object.EncodeHeader(t, size)); err != nil {
+		return "", err
+	}
+	n, err := io.Copy(w, io.LimitReader(r, size))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("%w: short read %d/%d", object.ErrMalformed, n, size)
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	sha := hex.EncodeToString(h.Sum(nil))
+	if ok, err := s.HasObject(sha); err != nil || ok {
+		return sha, err
+	}
+	if err := tmp.Chmod(os.FileMode(0444)); err != nil {
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(s.path(sha))
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), s.path(sha)); err != nil {
+		return "", err
+	}
+	if err := syncDir(dir); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// syncDir はrenameをディスクに反映させるためにディレクトリをfsyncする
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *LooseStore) FindObjects(prefix string) ([]string, error) {
+	if len(prefix) < 2 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(s.dir, prefix[0:2]))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rem := prefix[2:]
+	objects := []string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), rem) {
+			objects = append(objects, prefix[0:2]+e.Name())
+		}
+	}
+	return objects, nil
+}
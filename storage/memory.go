@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+type memoryObject struct {
+	t    object.ObjectType
+	data []byte
+}
+
+// MemoryStore はオブジェクトをメモリ上に保持する
+// テストや一時的な処理のためにディスクに触れずに使える
+type MemoryStore struct {
+	format oid.Format
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+func NewMemoryStore(f oid.Format) *MemoryStore {
+	return &MemoryStore{
+		format:  f,
+		objects: make(map[string]memoryObject),
+	}
+}
+
+func (s *MemoryStore) HasObject(sha string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[sha]
+	return ok, nil
+}
+
+func (s *MemoryStore) ReadObject(sha string) (object.ObjectType, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.objects[sha]
+	if !ok {
+		return "", nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+	}
+	return o.t, o.data, nil
+}
+
+func (s *MemoryStore) WriteObject(t object.ObjectType, data []byte) (string, error) {
+	sha := object.HashRaw(s.format, t, data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[sha]; !ok {
+		// 呼び出し元がスライスを書き換えても影響しないようにコピーする
+		s.objects[sha] = memoryObject{t: t, data: append([]byte(nil), data...)}
+	}
+	return sha, nil
+}
+
+func (s *MemoryStore) OpenObject(sha string) (Header, io.ReadCloser, error) {
+	t, data, err := s.ReadObject(sha)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	return Header{Type: t, Size: int64(len(data))}, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// WriteObjectStream はメモリ上に保持するため中身をすべて読み込む
+func (s *MemoryStore) WriteObjectStream(t object.ObjectType, size int64, r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) != size {
+		return "", fmt.Errorf("%w: short read %d/%d", object.ErrMalformed, len(data), size)
+	}
+	return s.WriteObject(t, data)
+}
+
+func (s *MemoryStore) FindObjects(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	objects := []string{}
+	for sha := range s.objects {
+		if strings.HasPrefix(sha, prefix) {
+			objects = append(objects, sha)
+		}
+	}
+	sort.Strings(objects)
+	return objects, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+// OverlayStore は書き込みを1つのストアに行い、読み込みは複数のストアから順に探す
+type OverlayStore struct {
+	write ObjectStore
+	reads []ObjectStore
+}
+
+// NewOverlayStore は書き込み先のwriteと読み込み専用のreadsを重ねたストアを作る
+// 読み込みはwrite、readsの順に探す
+func NewOverlayStore(write ObjectStore, reads ...ObjectStore) *OverlayStore {
+	return &OverlayStore{
+		write: write,
+		reads: reads,
+	}
+}
+
+func (s *OverlayStore) stores() []ObjectStore {
+	return append([]ObjectStore{s.write}, s.reads...)
+}
+
+func (s *OverlayStore) HasObject(sha string) (bool, error) {
+	for _, store := range s.stores() {
+		ok, err := store.HasObject(sha)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *OverlayStore) ReadObject(sha string) (object.ObjectType, []byte, error) {
+	for _, store := range s.stores() {
+		t, data, err := store.ReadObject(sha)
+		if err == nil {
+			return t, data, nil
+		}
+		if !errors.Is(err, ErrObjectNotFound) {
+			return "", nil, err
+		}
+	}
+	return "", nil, ErrObjectNotFound
+}
+
+func (s *OverlayStore) WriteObject(t object.ObjectType, data []byte) (string, error) {
+	return s.write.WriteObject(t, data)
+}
+
+func (s *OverlayStore) OpenObject(sha string) (Header, io.ReadCloser, error) {
+	for _, store := range s.stores() {
+		h, rc, err := store.OpenObject(sha)
+		if err == nil {
+			return h, rc, nil
+		}
+		if !errors.Is(err, ErrObjectNotFound) {
+			return Header{}, nil, err
+		}
+	}
+	return Header{}, nil, ErrObjectNotFound
+}
+
+func (s *OverlayStore) WriteObjectStream(t object.ObjectType, size int64, r io.Reader) (string, error) {
+	return s.write.WriteObjectStream(t, size, r)
+}
+
+func (s *OverlayStore) FindObjects(prefix string) ([]string, error) {
+	exist := make(map[string]struct{})
+	objects := []string{}
+	for _, store := range s.stores() {
+		found, err := store.FindObjects(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, sha := range found {
+			if _, ok := exist[sha]; ok {
+				continue
+			}
+			exist[sha] = struct{}{}
+			objects = append(objects, sha)
+		}
+	}
+	sort.Strings(objects)
+	return objects, nil
+}
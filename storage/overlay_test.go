@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestOverlayStore(t *testing.T) {
+	write := NewMemoryStore(oid.SHA1)
+	read := NewMemoryStore(oid.SHA1)
+	s := NewOverlayStore(write, read)
+	testObjectStore(t, s, oid.SHA1)
+
+	// 書き込みはwriteのみに行う
+	if found, _ := read.FindObjects(""); len(found) != 0 {
+		t.Errorf("read store has %v, want no objects", found)
+	}
+	written, _ := write.FindObjects("")
+	if len(written) != 2 {
+		t.Errorf("write store has %v, want 2 objects", written)
+	}
+
+	// 読み込みはwrite、readの順に探す
+	packed, err := read.WriteObject(object.Blob, []byte("packed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.HasObject(packed); err != nil || !ok {
+		t.Errorf("HasObject(%s) = %v, %v, want true", packed, ok, err)
+	}
+	if _, data, err := s.ReadObject(packed); err != nil || string(data) != "packed\n" {
+		t.Errorf("ReadObject(%s) = %q, %v, want packed", packed, data, err)
+	}
+	h, rc, err := s.OpenObject(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	if h.Type != object.Blob || h.Size != 7 {
+		t.Errorf("OpenObject(%s) = %s %d, want blob 7", packed, h.Type, h.Size)
+	}
+
+	// 両方にあるオブジェクトは重複せずに列挙する
+	if _, err := write.WriteObject(object.Blob, []byte("packed\n")); err != nil {
+		t.Fatal(err)
+	}
+	found, err := s.FindObjects("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Errorf("FindObjects() = %v, want 3 objects", found)
+	}
+}
+
+func TestOverlayStoreReadOnly(t *testing.T) {
+	// 書き込み先が読み込み専用の場合はエラーをそのまま返す
+	s := NewOverlayStore(NewPackedStore(t.TempDir(), oid.SHA1), NewMemoryStore(oid.SHA1))
+	if _, err := s.WriteObjectStream(object.Blob, 1, bytes.NewReader([]byte("a"))); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteObjectStream() error = %v, want ErrReadOnly", err)
+	}
+}
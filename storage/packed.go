@@ -0,0 +1,439 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// packファイル内のオブジェクトの種類
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+var (
+	ErrInvalidPack = errors.New("invalid pack")
+
+	packTypes = map[byte]object.ObjectType{
+		packCommit: object.Commit,
+		packTree:   object.Tree,
+		packBlob:   object.Blob,
+		packTag:    object.Tag,
+	}
+)
+
+// PackedStore は objects/pack 配下のpackファイルからオブジェクトを読み込む
+// packファイルへの書き込みはサポートしない
+type PackedStore struct {
+	dir    string
+	format oid.Format
+
+	mu    sync.Mutex
+	packs map[string]*packIndex
+}
+
+func NewPackedStore(dir string, f oid.Format) *PackedStore {
+	return &PackedStore{
+		dir:    dir,
+		format: f,
+		packs:  make(map[string]*packIndex),
+	}
+}
+
+// packIndex は.idxファイルの内容
+type packIndex struct {
+	pack    string
+	shas    []string
+	offsets map[string]int64
+}
+
+// reload はまだ読み込んでいない.idxファイルを読み込む
+// gcなどで後からpackが追加される場合があるため、オブジェクトが見つからない場合に呼ぶ
+func (s *PackedStore) reload() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".idx") {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ".idx")
+		if _, ok := s.packs[name]; ok {
+			continue
+		}
+		idx, err := readPackIndex(filepath.Join(s.dir, e.Name()), s.format.Size())
+		if err != nil {
+			return err
+		}
+		idx.pack = filepath.Join(s.dir, name+".pack")
+		s.packs[name] = idx
+	}
+	return nil
+}
+
+// lookup はshaを含むpackとそのオフセットを探す
+func (s *PackedStore) lookup(sha string) (*packIndex, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		for _, idx := range s.packs {
+			if offset, ok := idx.offsets[sha]; ok {
+				return idx, offset, nil
+			}
+		}
+		if i == 0 {
+			if err := s.reload(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return nil, 0, nil
+}
+
+func (s *PackedStore) HasObject(sha string) (bool, error) {
+	idx, _, err := s.lookup(sha)
+	if err != nil {
+		return false, err
+	}
+	return idx != nil, nil
+}
+
+func (s *PackedStore) ReadObject(sha string) (object.ObjectType, []byte, error) {
+	idx, offset, err := s.lookup(sha)
+	if err != nil {
+		return "", nil, err
+	}
+	if idx == nil {
+		return "", nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+	}
+
+	f, err := os.Open(idx.pack)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	code, data, err := s.readPacked(f, offset)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w sha=%s", err, sha)
+	}
+	return packTypes[code], data, nil
+}
+
+func (s *PackedStore) WriteObject(t object.ObjectType, data []byte) (string, error) {
+	return "", ErrReadOnly
+}
+
+// OpenObject はdeltaでないオブジェクトはpackファイルから展開しながら読み込む
+// deltaは元のオブジェクトが必要なためメモリ上で展開する
+func (s *PackedStore) OpenObject(sha string) (Header, io.ReadCloser, error) {
+	idx, offset, err := s.lookup(sha)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	if idx == nil {
+		return Header{}, nil, fmt.Errorf("%w sha=%s", ErrObjectNotFound, sha)
+	}
+
+	f, err := os.Open(idx.pack)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	br := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	code, size, err := readPackedHeader(br)
+	if err != nil {
+		f.Close()
+		return Header{}, nil, fmt.Errorf("%w sha=%s", err, sha)
+	}
+	if t, ok := packTypes[code]; ok {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			f.Close()
+			return Header{}, nil, err
+		}
+		return Header{Type: t, Size: size}, newObjectReader(zr, size, zr, f), nil
+	}
+
+	code, data, err := s.readPacked(f, offset)
+	f.Close()
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("%w sha=%s", err, sha)
+	}
+	return Header{Type: packTypes[code], Size: int64(len(data))}, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *PackedStore) WriteObjectStream(t object.ObjectType, size int64, r io.Reader) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (s *PackedStore) FindObjects(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	objects := []string{}
+	for _, idx := range s.packs {
+		i := sort.SearchStrings(idx.shas, prefix)
+		for ; i < len(idx.shas) && strings.HasPrefix(idx.shas[i], prefix); i++ {
+			objects = append(objects, idx.shas[i])
+		}
+	}
+	sort.Strings(objects)
+	return objects, nil
+}
+
+// readPackedHeader はpackファイル内のオブジェクトの種類とサイズを読み込む
+// 先頭バイトの4〜6bitが種類、残りとそれ以降の7bitずつがサイズ
+func readPackedHeader(br *bufio.Reader) (byte, int64, error) {
+	c, err := br.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	code := (c >> 4) & 0x07
+	size := int64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = br.ReadByte(); err != nil {
+			return 0, 0, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+	return code, size, nil
+}
+
+// readPacked はoffsetにあるオブジェクトを読み込み、deltaであれば展開して返す
+func (s *PackedStore) readPacked(f *os.File, offset int64) (byte, []byte, error) {
+	br := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	code, size, err := readPackedHeader(br)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch code {
+	case packCommit, packTree, packBlob, packTag:
+		data, err := inflate(br, size)
+		return code, data, err
+	case packOfsDelta:
+		// 可変長で表現されたこのオブジェクトからの相対位置
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			if rel >= math.MaxInt64>>7 {
+				return 0, nil, fmt.Errorf("%w: delta base offset overflow at %d", ErrInvalidPack, offset)
+			}
+			rel = ((rel + 1) << 7) | int64(c&0x7f)
+		}
+		// 自身やpackファイルの先頭より前を指す場合は読み込みが終わらなくなるため不正なpackとする
+		if rel <= 0 || rel > offset {
+			return 0, nil, fmt.Errorf("%w: delta base offset out of bound at %d", ErrInvalidPack, offset)
+		}
+		delta, err := inflate(br, size)
+		if err != nil {
+			return 0, nil, err
+		}
+		baseCode, base, err := s.readPacked(f, offset-rel)
+		if err != nil {
+			return 0, nil, err
+		}
+		data, err := applyDelta(base, delta)
+		return baseCode, data, err
+	case packRefDelta:
+		raw := make([]byte, s.format.Size())
+		if _, err := io.ReadFull(br, raw); err != nil {
+			return 0, nil, err
+		}
+		delta, err := inflate(br, size)
+		if err != nil {
+			return 0, nil, err
+		}
+		t, base, err := s.ReadObject(hex.EncodeToString(raw))
+		if err != nil {
+			return 0, nil, err
+		}
+		data, err := applyDelta(base, delta)
+		for c, pt := range packTypes {
+			if pt == t {
+				return c, data, err
+			}
+		}
+		return 0, nil, ErrInvalidPack
+	}
+
+	return 0, nil, fmt.Errorf("%w: unknown object type %d", ErrInvalidPack, code)
+}
+
+func inflate(r io.Reader, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// applyDelta はbaseにdeltaの命令を適用する
+func applyDelta(base, delta []byte) ([]byte, error) {
+	br := bytes.NewReader(delta)
+	srcSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if srcSize != uint64(len(base)) {
+		return nil, fmt.Errorf("%w: delta base size mismatch", ErrInvalidPack)
+	}
+	dstSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := make([]byte, 0, dstSize)
+	for {
+		cmd, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case cmd&0x80 != 0:
+			// baseからのコピー
+			// 下位4bitがオフセット、次の3bitがサイズのどのバイトが存在するかを表す
+			var offset, size uint32
+			for i := 0; i < 4; i++ {
+				if cmd&(1<<i) != 0 {
+					b, err := br.ReadByte()
+					if err != nil {
+						return nil, err
+					}
+					offset |= uint32(b) << (8 * i)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if cmd&(1<<(4+i)) != 0 {
+					b, err := br.ReadByte()
+					if err != nil {
+						return nil, err
+					}
+					size |= uint32(b) << (8 * i)
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if uint64(offset)+uint64(size) > uint64(len(base)) {
+				return nil, fmt.Errorf("%w: delta copy out of range", ErrInvalidPack)
+			}
+			dst = append(dst, base[offset:offset+size]...)
+		case cmd != 0:
+			// deltaに含まれるデータの挿入
+			buf := make([]byte, cmd)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return nil, err
+			}
+			dst = append(dst, buf...)
+		default:
+			return nil, fmt.Errorf("%w: unexpected delta opcode 0", ErrInvalidPack)
+		}
+	}
+
+	if uint64(len(dst)) != dstSize {
+		return nil, fmt.Errorf("%w: delta result size mismatch", ErrInvalidPack)
+	}
+	return dst, nil
+}
+
+// readPackIndex は.idxファイル(version 1, 2)を読み込む
+// hashSizeはオブジェクトフォーマットのバイナリ表現のIDの長さ
+func readPackIndex(path string, hashSize int) (*packIndex, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &packIndex{offsets: make(map[string]int64)}
+
+	if bytes.HasPrefix(raw, []byte("\377tOc")) {
+		// version 2
+		// magic(4) version(4) fanout(256*4) sha(N*20) crc32(N*4) offset(N*4) large offset(M*8)
+		if len(raw) < 8+256*4 || binary.BigEndian.Uint32(raw[4:8]) != 2 {
+			return nil, fmt.Errorf("%w: unsupported index %s", ErrInvalidPack, path)
+		}
+		n := int(binary.BigEndian.Uint32(raw[8+255*4:]))
+		shaStart := 8 + 256*4
+		offsetStart := shaStart + n*hashSize + n*4
+		largeStart := offsetStart + n*4
+		if len(raw) < largeStart {
+			return nil, fmt.Errorf("%w: truncated index %s", ErrInvalidPack, path)
+		}
+		for i := 0; i < n; i++ {
+			sha := hex.EncodeToString(raw[shaStart+i*hashSize : shaStart+(i+1)*hashSize])
+			offset := int64(binary.BigEndian.Uint32(raw[offsetStart+i*4:]))
+			if offset&0x80000000 != 0 {
+				pos := largeStart + int(offset&0x7fffffff)*8
+				if len(raw) < pos+8 {
+					return nil, fmt.Errorf("%w: truncated index %s", ErrInvalidPack, path)
+				}
+				offset = int64(binary.BigEndian.Uint64(raw[pos:]))
+			}
+			idx.shas = append(idx.shas, sha)
+			idx.offsets[sha] = offset
+		}
+		return idx, nil
+	}
+
+	// version 1
+	// fanout(256*4) (offset(4) sha(20))*N
+	if len(raw) < 256*4 {
+		return nil, fmt.Errorf("%w: truncated index %s", ErrInvalidPack, path)
+	}
+	n := int(binary.BigEndian.Uint32(raw[255*4:]))
+	if len(raw) < 256*4+n*(4+hashSize) {
+		return nil, fmt.Errorf("%w: truncated index %s", ErrInvalidPack, path)
+	}
+	for i := 0; i < n; i++ {
+		pos := 256*4 + i*(4+hashSize)
+		sha := hex.EncodeToString(raw[pos+4 : pos+4+hashSize])
+		idx.shas = append(idx.shas, sha)
+		idx.offsets[sha] = int64(binary.BigEndian.Uint32(raw[pos:]))
+	}
+	return idx, nil
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// testdata のpackは同じリポジトリから git pack-objects で作成した
+//   v1: --index-version=1 で作成し、deltaは REF_DELTA
+//   v2: --index-version=2,0x100 --delta-base-offset で作成し、0x100より後のオブジェクトは64bitのオフセットの表を使い、deltaは OFS_DELTA
+//   sha256: git init --object-format=sha256 で作成したリポジトリから既定の設定で作成し、deltaは REF_DELTA
+// objects.txt は git cat-file --batch-all-objects --batch-check='%(objectname) %(objecttype) %(objectsize)' の出力
+
+type testObject struct {
+	sha  string
+	typ  object.ObjectType
+	size int64
+}
+
+func readTestObjects(t *testing.T, path string) []testObject {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var objects []testObject
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, testObject{sha: fields[0], typ: object.ObjectType(fields[1]), size: size})
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestPackedStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		format  oid.Format
+		objects string
+		delta   byte
+		large   bool
+	}{
+		{name: "idx v1 and REF_DELTA", dir: "v1", format: oid.SHA1, objects: "objects.txt", delta: packRefDelta},
+		{name: "idx v2 with 64-bit offsets and OFS_DELTA", dir: "v2", format: oid.SHA1, objects: "objects.txt", delta: packOfsDelta, large: true},
+		{name: "sha256", dir: "sha256", format: oid.SHA256, objects: "sha256/objects.txt", delta: packRefDelta},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := readTestObjects(t, filepath.Join("testdata", tt.objects))
+			dir := filepath.Join("testdata", tt.dir)
+			s := NewPackedStore(dir, tt.format)
+
+			found, err := s.FindObjects("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != len(objects) {
+				t.Fatalf("FindObjects() returned %d objects, want %d", len(found), len(objects))
+			}
+
+			for _, o := range objects {
+				typ, data, err := s.ReadObject(o.sha)
+				if err != nil {
+					t.Fatalf("ReadObject(%s) error = %v", o.sha, err)
+				}
+				if typ != o.typ || int64(len(data)) != o.size {
+					t.Errorf("ReadObject(%s) = %s %d, want %s %d", o.sha, typ, len(data), o.typ, o.size)
+				}
+				if got := object.HashRaw(tt.format, typ, data); got != o.sha {
+					t.Errorf("ReadObject(%s) content hashes to %s", o.sha, got)
+				}
+
+				h, r, err := s.OpenObject(o.sha)
+				if err != nil {
+					t.Fatalf("OpenObject(%s) error = %v", o.sha, err)
+				}
+				streamed, err := io.ReadAll(r)
+				r.Close()
+				if err != nil {
+					t.Fatalf("OpenObject(%s) read error = %v", o.sha, err)
+				}
+				if h.Type != o.typ || h.Size != o.size || string(streamed) != string(data) {
+					t.Errorf("OpenObject(%s) = %s %d, want %s %d", o.sha, h.Type, h.Size, o.typ, o.size)
+				}
+			}
+
+			missing := tt.format.Zero()
+			if ok, err := s.HasObject(missing); err != nil || ok {
+				t.Errorf("HasObject(%s) = %v, %v, want false", missing, ok, err)
+			}
+			if found, err := s.FindObjects(objects[0].sha[:4]); err != nil || len(found) == 0 {
+				t.Errorf("FindObjects(%s) = %v, %v", objects[0].sha[:4], found, err)
+			}
+
+			// fixtureが期待する形式のpackになっていることを確認する v2では0x100より後のオフセットは64bitの表から読み込んでいる
+			var idx *packIndex
+			for _, i := range s.packs {
+				idx = i
+			}
+			f, err := os.Open(idx.pack)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			deltas, large := 0, false
+			for _, offset := range idx.offsets {
+				code, _, err := readPackedHeader(bufio.NewReader(io.NewSectionReader(f, offset, 1<<62)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if code == tt.delta {
+					deltas++
+				}
+				large = large || offset > 0x100
+			}
+			if deltas == 0 {
+				t.Errorf("pack has no delta of type %d", tt.delta)
+			}
+			if tt.large && !large {
+				t.Errorf("pack has no object in the large offset table")
+			}
+		})
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("0123456789abcdef")
+	tests := []struct {
+		name    string
+		delta   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "copy and insert",
+			// src=16 dst=9 copy(offset=10,size=3) insert("xyz") copy(offset=0,size=3)
+			delta: []byte{16, 9, 0x91, 10, 3, 3, 'x', 'y', 'z', 0x90, 3},
+			want:  "abcxyz012",
+		},
+		{
+			name:  "omitted offset and size bytes",
+			delta: []byte{16, 16, 0x80 | 0x10, 16},
+			want:  "0123456789abcdef",
+		},
+		{
+			name:    "base size mismatch",
+			delta:   []byte{15, 1, 1, 'x'},
+			wantErr: true,
+		},
+		{
+			name:    "copy out of range",
+			delta:   []byte{16, 4, 0x91, 14, 4},
+			wantErr: true,
+		},
+		{
+			name:    "result size mismatch",
+			delta:   []byte{16, 5, 1, 'x'},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyDelta(base, tt.delta)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyDelta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applyDelta() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPackedInvalidOfsDelta(t *testing.T) {
+	// 自身やpackファイルの先頭より前を指すOFS_DELTAは辿らずに不正なpackとする
+	var delta bytes.Buffer
+	w := zlib.NewWriter(&delta)
+	if _, err := w.Write([]byte{1, 1, 1, 'x'}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		rel  []byte
+	}{
+		{name: "self", rel: []byte{0x00}},
+		{name: "before header", rel: []byte{0x0d}},
+		{name: "overflow", rel: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ヘッダーの後のオフセット12にサイズ1のOFS_DELTAを1つだけ置く
+			data := []byte("PACK\x00\x00\x00\x02\x00\x00\x00\x01")
+			data = append(data, packOfsDelta<<4|1)
+			data = append(data, tt.rel...)
+			data = append(data, delta.Bytes()...)
+			p := filepath.Join(t.TempDir(), "pack-crafted.pack")
+			if err := os.WriteFile(p, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			s := NewPackedStore(t.TempDir(), oid.SHA1)
+			if _, _, err := s.readPacked(f, 12); !errors.Is(err, ErrInvalidPack) {
+				t.Errorf("readPacked() error = %v, want %v", err, ErrInvalidPack)
+			}
+		})
+	}
+}
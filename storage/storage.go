@@ -0,0 +1,71 @@
+// Package storage はオブジェクトの保存先を抽象化する
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/showa-93/wyag-go/object"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrReadOnly       = errors.New("object store is read only")
+)
+
+// ObjectStore はオブジェクトデータベースのインターフェース
+// オブジェクトは型とシリアライズされた中身の組で扱う
+type ObjectStore interface {
+	// HasObject はshaのオブジェクトが存在するか判定する
+	HasObject(sha string) (bool, error)
+	// ReadObject はshaのオブジェクトの型と中身を返す
+	// 存在しない場合はErrObjectNotFoundを返す
+	ReadObject(sha string) (object.ObjectType, []byte, error)
+	// WriteObject はオブジェクトを保存してshaを返す
+	WriteObject(t object.ObjectType, data []byte) (string, error)
+	// OpenObject はshaのオブジェクトの型と大きさ、中身を読み込むReadCloserを返す
+	// 中身をすべてメモリに読み込まずに扱える 存在しない場合はErrObjectNotFoundを返す
+	OpenObject(sha string) (Header, io.ReadCloser, error)
+	// WriteObjectStream はrからsizeバイトを読み込みながらオブジェクトを保存してshaを返す
+	WriteObjectStream(t object.ObjectType, size int64, r io.Reader) (string, error)
+	// FindObjects はprefixから始まるshaの一覧を返す
+	FindObjects(prefix string) ([]string, error)
+}
+
+// Header はオブジェクトの型と中身の大きさ
+type Header struct {
+	Type object.ObjectType
+	Size int64
+}
+
+// objectReader はオブジェクトの中身を読み込む
+// ヘッダーの大きさより先に終端に達した場合はエラーを返す
+type objectReader struct {
+	r       io.Reader
+	remain  int64
+	closers []io.Closer
+}
+
+func newObjectReader(r io.Reader, size int64, closers ...io.Closer) *objectReader {
+	return &objectReader{r: io.LimitReader(r, size), remain: size, closers: closers}
+}
+
+func (o *objectReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	o.remain -= int64(n)
+	if err == io.EOF && o.remain > 0 {
+		return n, fmt.Errorf("%w: unexpected end of object", io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+func (o *objectReader) Close() error {
+	var err error
+	for _, c := range o.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+// testObjectStore は書き込んだオブジェクトを同じ内容で読み込めることを確認する
+func testObjectStore(t *testing.T, s ObjectStore, format oid.Format) {
+	t.Helper()
+	data := []byte("hello\n")
+	want := object.HashRaw(format, object.Blob, data)
+
+	sha, err := s.WriteObject(object.Blob, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != want {
+		t.Fatalf("WriteObject() = %s, want %s", sha, want)
+	}
+	// 同じオブジェクトを再度書き込んでも同じshaを返す
+	if sha, err := s.WriteObject(object.Blob, data); err != nil || sha != want {
+		t.Errorf("WriteObject() again = %s, %v, want %s", sha, err, want)
+	}
+	if ok, err := s.HasObject(sha); err != nil || !ok {
+		t.Errorf("HasObject(%s) = %v, %v, want true", sha, ok, err)
+	}
+	typ, got, err := s.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != object.Blob || !bytes.Equal(got, data) {
+		t.Errorf("ReadObject(%s) = %s %q, want blob %q", sha, typ, got, data)
+	}
+
+	h, rc, err := s.OpenObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != object.Blob || h.Size != int64(len(data)) || !bytes.Equal(streamed, data) {
+		t.Errorf("OpenObject(%s) = %s %d %q, want blob %d %q", sha, h.Type, h.Size, streamed, len(data), data)
+	}
+
+	tree := []byte("100644 a\x00" + string(bytes.Repeat([]byte{1}, format.Size())))
+	treeSha, err := s.WriteObjectStream(object.Tree, int64(len(tree)), bytes.NewReader(tree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := object.HashRaw(format, object.Tree, tree); treeSha != want {
+		t.Errorf("WriteObjectStream() = %s, want %s", treeSha, want)
+	}
+
+	for _, prefix := range []string{sha[:2], sha[:7], sha} {
+		found, err := s.FindObjects(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0] != sha {
+			t.Errorf("FindObjects(%s) = %v, want [%s]", prefix, found, sha)
+		}
+	}
+
+	missing := format.Zero()
+	if ok, err := s.HasObject(missing); err != nil || ok {
+		t.Errorf("HasObject(%s) = %v, %v, want false", missing, ok, err)
+	}
+	if _, _, err := s.ReadObject(missing); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("ReadObject(%s) error = %v, want ErrObjectNotFound", missing, err)
+	}
+	if _, _, err := s.OpenObject(missing); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("OpenObject(%s) error = %v, want ErrObjectNotFound", missing, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	for _, format := range []oid.Format{oid.SHA1, oid.SHA256} {
+		t.Run(string(format), func(t *testing.T) {
+			s := NewMemoryStore(format)
+			testObjectStore(t, s, format)
+
+			// 書き込んだ後に呼び出し元がスライスを書き換えても影響しない
+			data := []byte("mutable")
+			sha, err := s.WriteObject(object.Blob, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[0] = 'M'
+			if _, got, err := s.ReadObject(sha); err != nil || string(got) != "mutable" {
+				t.Errorf("ReadObject(%s) = %q, %v, want mutable", sha, got, err)
+			}
+		})
+	}
+}
+
+func TestLooseStore(t *testing.T) {
+	for _, format := range []oid.Format{oid.SHA1, oid.SHA256} {
+		t.Run(string(format), func(t *testing.T) {
+			testObjectStore(t, NewLooseStore(t.TempDir(), format), format)
+		})
+	}
+}
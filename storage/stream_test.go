@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/oid"
+)
+
+func TestWriteObjectStream(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789\n"), 1000)
+	want := object.HashRaw(oid.SHA1, object.Blob, data)
+	stores := map[string]func(t *testing.T) (ObjectStore, string){
+		"loose": func(t *testing.T) (ObjectStore, string) {
+			dir := t.TempDir()
+			return NewLooseStore(dir, oid.SHA1), dir
+		},
+		"memory": func(t *testing.T) (ObjectStore, string) {
+			return NewMemoryStore(oid.SHA1), ""
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s, dir := newStore(t)
+
+			// 1バイトずつしか読めない場合も同じ内容を書き込む
+			sha, err := s.WriteObjectStream(object.Blob, int64(len(data)), iotest.OneByteReader(bytes.NewReader(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sha != want {
+				t.Errorf("WriteObjectStream() = %s, want %s", sha, want)
+			}
+			if _, got, err := s.ReadObject(sha); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("ReadObject(%s) returned %d bytes, %v, want %d", sha, len(got), err, len(data))
+			}
+
+			// 宣言した大きさより後のデータは読み込まない
+			r := strings.NewReader("abcdef")
+			sha, err = s.WriteObjectStream(object.Blob, 3, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := object.HashRaw(oid.SHA1, object.Blob, []byte("abc")); sha != want {
+				t.Errorf("WriteObjectStream(size 3) = %s, want %s", sha, want)
+			}
+			if r.Len() != 3 {
+				t.Errorf("WriteObjectStream(size 3) left %d bytes, want 3", r.Len())
+			}
+
+			// 宣言した大きさより短い場合はエラーとし、何も書き込まない
+			before, err := s.FindObjects("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.WriteObjectStream(object.Blob, 10, strings.NewReader("short")); !errors.Is(err, object.ErrMalformed) {
+				t.Errorf("WriteObjectStream(short) error = %v, want ErrMalformed", err)
+			}
+			if _, err := s.WriteObjectStream(object.Blob, 10, io.MultiReader(strings.NewReader("01234"), iotest.ErrReader(errors.New("boom")))); err == nil {
+				t.Error("WriteObjectStream() ignored a read error")
+			}
+			after, err := s.FindObjects("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(after) != len(before) {
+				t.Errorf("objects = %v after failed writes, want %v", after, before)
+			}
+			if dir != "" {
+				tmps, err := filepath.Glob(filepath.Join(dir, "tmp_obj_*"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(tmps) != 0 {
+					t.Errorf("temporary files %v are left", tmps)
+				}
+			}
+		})
+	}
+}
+
+// writeLooseRaw はzlib圧縮したrawをルーズオブジェクトとして直接書き込む
+func writeLooseRaw(t *testing.T, dir, sha string, raw []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(raw)
+	zw.Close()
+	p := filepath.Join(dir, sha[:2], sha[2:])
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLooseOpenObject(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLooseStore(dir, oid.SHA1)
+
+	// 少しずつ読み込んでも中身をすべて読める
+	data := bytes.Repeat([]byte("x"), 100000)
+	sha, err := s.WriteObject(object.Blob, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, rc, err := s.OpenObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(bufio.NewReaderSize(iotest.OneByteReader(rc), 16))
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != object.Blob || h.Size != int64(len(data)) || !bytes.Equal(got, data) {
+		t.Errorf("OpenObject() = %s %d with %d bytes, want blob %d", h.Type, h.Size, len(got), len(data))
+	}
+
+	// ヘッダーの大きさより中身が短い場合は読み込みでエラーとする
+	short := strings.Repeat("1", 40)
+	writeLooseRaw(t, dir, short, []byte("blob 10\x00abc"))
+	h, rc, err = s.OpenObject(short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Size != 10 {
+		t.Errorf("OpenObject() size = %d, want 10", h.Size)
+	}
+	got, err = io.ReadAll(rc)
+	rc.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if string(got) != "abc" {
+		t.Errorf("read %q, want abc", got)
+	}
+
+	// ヘッダーの大きさより長い中身は読み込まない
+	long := strings.Repeat("2", 40)
+	writeLooseRaw(t, dir, long, []byte("blob 3\x00abcdef"))
+	_, rc, err = s.OpenObject(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "abc" {
+		t.Errorf("read %q, %v, want abc", got, err)
+	}
+
+	// ヘッダーが壊れている場合は開けない
+	broken := strings.Repeat("3", 40)
+	writeLooseRaw(t, dir, broken, []byte("blob"))
+	if _, _, err := s.OpenObject(broken); !errors.Is(err, object.ErrMalformed) {
+		t.Errorf("OpenObject(broken) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestPackedOpenObjectDelta(t *testing.T) {
+	// deltaは元のオブジェクトが必要なため、全体を読み込んでから返す
+	s := NewPackedStore(filepath.Join("testdata", "sha256"), oid.SHA256)
+	const delta = "437dd9d653be2b8864fc1fec221e1b6ecd6cc5f2941afd8a21641aa6366d01bc"
+	idx, offset, err := s.lookup(delta)
+	if err != nil || idx == nil {
+		t.Fatalf("lookup(%s) = %v, %v", delta, idx, err)
+	}
+	f, err := os.Open(idx.pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := readPackedHeader(bufio.NewReader(io.NewSectionReader(f, offset, 1<<62)))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != packRefDelta {
+		t.Fatalf("%s is stored as type %d, want a delta", delta, code)
+	}
+
+	typ, data, err := s.ReadObject(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, rc, err := s.OpenObject(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := io.ReadAll(iotest.OneByteReader(rc))
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != typ || h.Size != int64(len(data)) || !bytes.Equal(streamed, data) {
+		t.Errorf("OpenObject(%s) = %s %d %q, want %s %d %q", delta, h.Type, h.Size, streamed, typ, len(data), data)
+	}
+	if got := object.HashRaw(oid.SHA256, h.Type, streamed); got != delta {
+		t.Errorf("OpenObject(%s) content hashes to %s", delta, got)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type TagCommand struct {
+	*flag.FlagSet
+	isObject bool
+	sign     bool
+	keyID    string
+	force    bool
+	messages messagesFlag
+	file     string
+	name     string
+	object   string
+}
+
+func NewTagCommand(args []string) *TagCommand {
+	c := &TagCommand{
+		object: "HEAD",
+	}
+	c.FlagSet = flag.NewFlagSet("tag", flag.ExitOnError)
+	c.FlagSet.BoolVar(&c.isObject, "a", false, "Whether to create a tag object")
+	c.FlagSet.BoolVar(&c.sign, "s", false, "Make a signed tag object")
+	c.FlagSet.StringVar(&c.keyID, "u", "", "Make a signed tag object using the given key")
+	c.FlagSet.BoolVar(&c.force, "f", false, "Replace an existing tag with the given name")
+	c.FlagSet.Var(&c.messages, "m", "Use the given tag message")
+	c.FlagSet.StringVar(&c.file, "F", "", "Take the tag message from the given file")
+
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go tag [-a | -s | -u KEYID] [-f] [-m MESSAGE... | -F FILE] NAME [OBJECT]\n")
+		fmt.Fprint(o, "\tList and create tags\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) > 2 {
+		fmt.Printf("expected less than 2 arguments count=%d\n", len(c.Args()))
+		os.Exit(1)
+	}
+
+	switch len(c.Args()) {
+	case 0:
+	case 1:
+		c.name = c.Args()[0]
+	default:
+		c.name = c.Args()[0]
+		c.object = c.Args()[1]
+	}
+	if c.keyID != "" {
+		c.sign = true
+	}
+
+	return c
+}
+
+func (c *TagCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	if c.name != "" {
+		target, err := repo.FindObject(c.object, "", false)
+		if err != nil {
+			return err
+		}
+		// 署名やメッセージを指定した場合はタグオブジェクトを作成する
+		if !c.isObject && !c.sign && len(c.messages) == 0 && c.file == "" {
+			return repo.CreateTag(c.name, target, c.force)
+		}
+
+		if len(c.messages) == 0 && c.file == "" {
+			return errors.New("no tag message given (use -m or -F)")
+		}
+		message, err := readMessage(c.messages, c.file)
+		if err != nil {
+			return err
+		}
+		// gitと同じく-aを明示した場合はtag.gpgSignに従わない
+		sign := signFlag{set: c.sign || c.isObject, enabled: c.sign, key: c.keyID}
+		signer, err := sign.signer(repo, "tag.gpgsign")
+		if err != nil {
+			return err
+		}
+		_, err = repo.CreateAnnotatedTag(c.name, target, message, signer, c.force)
+		return err
+	} else {
+		refs, err := repo.ListRef("refs")
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			if strings.HasPrefix(ref.Path(), "refs/tags") {
+				fmt.Fprintf(os.Stdout, "%s %s\n", ref.Sha(), ref.Path())
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type VerifyCommitCommand struct {
+	*flag.FlagSet
+	revs []string
+}
+
+func NewVerifyCommitCommand(args []string) *VerifyCommitCommand {
+	c := &VerifyCommitCommand{}
+	c.FlagSet = flag.NewFlagSet("verify-commit", flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go verify-commit COMMIT...\n")
+		fmt.Fprint(o, "\tCheck the signature of commits\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) == 0 {
+		c.Usage()
+		os.Exit(1)
+	}
+	c.revs = c.Args()
+
+	return c
+}
+
+func (c *VerifyCommitCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	verifier, err := repo.Verifier()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, rev := range c.revs {
+		sha, err := repo.FindObject(rev, "", false)
+		if err != nil {
+			return err
+		}
+		o, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		commit, ok := o.(*object.CommitObject)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: %s: cannot verify a non-commit object of type %s.\n", rev, o.TypeHeader())
+			failed = true
+			continue
+		}
+		result, err := repo.VerifyCommit(verifier, commit)
+		if err != nil {
+			return err
+		}
+		// 署名されていないコミットは何も出力せずに失敗とする
+		if result == nil {
+			failed = true
+			continue
+		}
+		fmt.Fprint(os.Stderr, result)
+		if !result.Good() {
+			failed = true
+		}
+	}
+	if failed {
+		return ExitCode(1)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/showa-93/wyag-go/object"
+	"github.com/showa-93/wyag-go/repository"
+)
+
+type VerifyTagCommand struct {
+	*flag.FlagSet
+	tags []string
+}
+
+func NewVerifyTagCommand(args []string) *VerifyTagCommand {
+	c := &VerifyTagCommand{}
+	c.FlagSet = flag.NewFlagSet("verify-tag", flag.ExitOnError)
+	c.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprint(o, "Usage: wyag-go verify-tag TAG...\n")
+		fmt.Fprint(o, "\tCheck the signature of tags\n")
+	}
+
+	c.Parse(args)
+	if len(c.Args()) == 0 {
+		c.Usage()
+		os.Exit(1)
+	}
+	c.tags = c.Args()
+
+	return c
+}
+
+func (c *VerifyTagCommand) Run() error {
+	repo, err := repository.FindRepository(BasePath, true)
+	if err != nil {
+		return err
+	}
+	verifier, err := repo.Verifier()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, name := range c.tags {
+		sha, err := repo.FindObject(name, "", false)
+		if err != nil {
+			return err
+		}
+		o, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		tag, ok := o.(*object.TagObject)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: %s: cannot verify a non-tag object of type %s.\n", name, o.TypeHeader())
+			failed = true
+			continue
+		}
+		result, err := repo.VerifyTag(verifier, tag)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			fmt.Fprintln(os.Stderr, "error: no signature found")
+			failed = true
+			continue
+		}
+		fmt.Fprint(os.Stderr, result)
+		if !result.Good() {
+			failed = true
+		}
+	}
+	if failed {
+		return ExitCode(1)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+// Package wildmatch はgitのglobパターン(wildmatch)の照合を行う
+package wildmatch
+
+import (
+	"strings"
+)
+
+type Flag int
+
+const (
+	// CaseFold は大文字小文字を区別せずに照合する
+	CaseFold Flag = 1 << iota
+	// PathName はワイルドカードが"/"に一致しないようにし、"**"をディレクトリをまたぐ一致として扱う
+	PathName
+)
+
+type result int
+
+const (
+	matched result = iota
+	noMatch
+	// abortAll はこれ以上textの位置をずらしても一致しないことを表す
+	abortAll
+	// abortToStarStar は"/"をまたげない"*"の探索を打ち切り、外側の"**"に委ねることを表す
+	abortToStarStar
+)
+
+// Match はtextがpatternに一致するか判定する
+// "*", "**", "?", "[...]"("!"または"^"による否定、"[:alpha:]"などのクラス)、"\"によるエスケープを扱う
+// 照合はgitと同じくバイト単位で行う
+func Match(pattern, text string, flags Flag) bool {
+	return match(pattern, 0, text, flags) == matched
+}
+
+func fold(c byte, flags Flag) byte {
+	if flags&CaseFold != 0 && c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+func isGlobSpecial(c byte) bool {
+	return c == '*' || c == '?' || c == '[' || c == '\\'
+}
+
+// match はpattern[pi:]とtextを照合する
+// "**"の直前の文字を調べるためにパターン全体と位置を受け取る
+func match(pattern string, pi int, text string, flags Flag) result {
+	pathName := flags&PathName != 0
+
+	for ; pi < len(pattern); pi++ {
+		pc := pattern[pi]
+		if len(text) == 0 && pc != '*' {
+			return abortAll
+		}
+
+		switch pc {
+		case '\\':
+			// 次の文字をそのまま比較する
+			pi++
+			if pi >= len(pattern) {
+				return noMatch
+			}
+			if fold(pattern[pi], flags) != fold(text[0], flags) {
+				return noMatch
+			}
+			text = text[1:]
+		case '?':
+			if pathName && text[0] == '/' {
+				return noMatch
+			}
+			text = text[1:]
+		case '*':
+			var matchSlash bool
+			if pi+1 < len(pattern) && pattern[pi+1] == '*' {
+				prev := pi - 1
+				for pi+1 < len(pattern) && pattern[pi+1] == '*' {
+					pi++
+				}
+				rest := pattern[pi+1:]
+				if !pathName {
+					// PathNameでなければ"*"と"**"は同じ
+					matchSlash = true
+				} else if (prev < 0 || pattern[prev] == '/') &&
+					(rest == "" || rest[0] == '/' || strings.HasPrefix(rest, "\\/")) {
+					// "foo/**/bar"が"foo/bar"にも一致するように、"**/"が何にも一致しない場合を先に試す
+					if rest != "" && rest[0] == '/' && match(pattern, pi+2, text, flags) == matched {
+						return matched
+					}
+					matchSlash = true
+				} else {
+					matchSlash = false
+				}
+			} else {
+				matchSlash = !pathName
+			}
+
+			rest := pattern[pi+1:]
+			if rest == "" {
+				// 末尾の"**"はすべてに、"*"は"/"を含まない場合のみ一致する
+				if !matchSlash && strings.Contains(text, "/") {
+					return noMatch
+				}
+				return matched
+			} else if !matchSlash && rest[0] == '/' {
+				// "*/"は次のディレクトリまでに一致する "/"は次のループで比較する
+				i := strings.IndexByte(text, '/')
+				if i < 0 {
+					return noMatch
+				}
+				text = text[i:]
+				continue
+			}
+
+			for len(text) > 0 {
+				// リテラルが続く場合はそこまで読み飛ばす
+				if !isGlobSpecial(rest[0]) {
+					pc := fold(rest[0], flags)
+					for len(text) > 0 && (matchSlash || text[0] != '/') && fold(text[0], flags) != pc {
+						text = text[1:]
+					}
+					if len(text) == 0 || fold(text[0], flags) != pc {
+						return noMatch
+					}
+				}
+				r := match(pattern, pi+1, text, flags)
+				if r != noMatch {
+					if !matchSlash || r != abortToStarStar {
+						return r
+					}
+				} else if !matchSlash && text[0] == '/' {
+					return abortToStarStar
+				}
+				text = text[1:]
+			}
+			return abortAll
+		case '[':
+			next, ok, r := matchClass(pattern, pi+1, text[0], flags)
+			if r != matched {
+				return r
+			}
+			if !ok || (pathName && text[0] == '/') {
+				return noMatch
+			}
+			pi = next
+			text = text[1:]
+		default:
+			if fold(pc, flags) != fold(text[0], flags) {
+				return noMatch
+			}
+			text = text[1:]
+		}
+	}
+
+	if len(text) > 0 {
+		return noMatch
+	}
+	return matched
+}
+
+var classes = map[string]func(c byte) bool{
+	"alnum":  func(c byte) bool { return isAlpha(c) || isDigit(c) },
+	"alpha":  isAlpha,
+	"blank":  func(c byte) bool { return c == ' ' || c == '\t' },
+	"cntrl":  func(c byte) bool { return c < 0x20 || c == 0x7f },
+	"digit":  isDigit,
+	"graph":  func(c byte) bool { return c > 0x20 && c < 0x7f },
+	"lower":  func(c byte) bool { return c >= 'a' && c <= 'z' },
+	"print":  func(c byte) bool { return c >= 0x20 && c < 0x7f },
+	"punct":  func(c byte) bool { return c > 0x20 && c < 0x7f && !isAlpha(c) && !isDigit(c) },
+	"space":  func(c byte) bool { return strings.IndexByte(" \t\n\r\f\v", c) >= 0 },
+	"upper":  func(c byte) bool { return c >= 'A' && c <= 'Z' },
+	"xdigit": func(c byte) bool { return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') },
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// matchClass はpattern[pi:]から始まる"[...]"の文字クラスとcを照合する
+// クラスを閉じる"]"の位置と一致したかを返す 不正なクラスの場合はabortAllを返す
+func matchClass(pattern string, pi int, c byte, flags Flag) (int, bool, result) {
+	at := func(i int) byte {
+		if i < len(pattern) {
+			return pattern[i]
+		}
+		return 0
+	}
+	lc := fold(c, flags)
+
+	negated := false
+	pc := at(pi)
+	if pc == '!' || pc == '^' {
+		negated = true
+		pi++
+		pc = at(pi)
+	}
+
+	var prev byte
+	found := false
+	for {
+		if pc == 0 {
+			return 0, false, abortAll
+		}
+		switch {
+		case pc == '\\':
+			pi++
+			pc = at(pi)
+			if pc == 0 {
+				return 0, false, abortAll
+			}
+			if fold(pc, flags) == lc {
+				found = true
+			}
+		case pc == '-' && prev != 0 && at(pi+1) != 0 && at(pi+1) != ']':
+			pi++
+			pc = at(pi)
+			if pc == '\\' {
+				pi++
+				pc = at(pi)
+				if pc == 0 {
+					return 0, false, abortAll
+				}
+			}
+			if prev <= c && c <= pc {
+				found = true
+			} else if flags&CaseFold != 0 && prev <= lc && lc <= fold(pc, flags) {
+				found = true
+			}
+			pc = 0
+		case pc == '[' && at(pi+1) == ':':
+			end := strings.Index(pattern[pi+2:], ":]")
+			if end < 0 {
+				// ":]"が見つからなければ通常の文字として扱う
+				if c == '[' {
+					found = true
+				}
+				break
+			}
+			fn, ok := classes[pattern[pi+2:pi+2+end]]
+			if !ok {
+				return 0, false, abortAll
+			}
+			// gitと同じくCaseFoldでは[:upper:]が小文字にも一致する
+			if fn(c) || (flags&CaseFold != 0 && (fn(lc) || fn(toUpper(c)))) {
+				found = true
+			}
+			pi += 2 + end + 1
+			pc = 0
+		default:
+			if fold(pc, flags) == lc {
+				found = true
+			}
+		}
+
+		prev = pc
+		pi++
+		pc = at(pi)
+		if pc == ']' {
+			break
+		}
+	}
+
+	return pi, found != negated, matched
+}
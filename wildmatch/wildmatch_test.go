@@ -0,0 +1,202 @@
+package wildmatch
+
+import (
+	"testing"
+)
+
+// tests はgitのt/t3070-wildmatch.shのケース
+// glob、iglobはPathName付き、pathmatch、ipathmatchはPathNameなしで照合した結果で、iはCaseFoldを表す
+var tests = []struct {
+	glob, iglob, pathmatch, ipathmatch bool
+	text, pattern                      string
+}{
+	{true, true, true, true, `foo`, `foo`},
+	{true, true, true, true, ``, ``},
+	{false, false, false, false, `foo`, ``},
+	{false, false, false, false, ``, `\`},
+	{false, false, false, false, `foo`, `bar`},
+	{true, true, true, true, `foo`, `???`},
+	{false, false, false, false, `foo`, `??`},
+	{true, true, true, true, `foo`, `*`},
+	{true, true, true, true, `foo`, `f*`},
+	{false, false, false, false, `foo`, `*f`},
+	{true, true, true, true, `foo`, `*foo*`},
+	{true, true, true, true, `foobar`, `*ob*a*r*`},
+	{true, true, true, true, `aaaaaaabababab`, `*ab`},
+	{true, true, true, true, `foo*`, `foo\*`},
+	{false, false, false, false, `foobar`, `foo\*bar`},
+	{true, true, true, true, `f\oo`, `f\\oo`},
+	{true, true, true, true, `ball`, `*[al]?`},
+	{false, false, false, false, `ten`, `[ten]`},
+	{true, true, true, true, `ten`, `**[!te]`},
+	{false, false, false, false, `ten`, `**[!ten]`},
+	{true, true, true, true, `ten`, `t[a-g]n`},
+	{false, false, false, false, `ten`, `t[!a-g]n`},
+	{true, true, true, true, `ton`, `t[!a-g]n`},
+	{true, true, true, true, `ton`, `t[^a-g]n`},
+	{true, true, true, true, `a]b`, `a[]]b`},
+	{true, true, true, true, `a-b`, `a[]-]b`},
+	{true, true, true, true, `a]b`, `a[]-]b`},
+	{false, false, false, false, `aab`, `a[]-]b`},
+	{true, true, true, true, `aab`, `a[]a-]b`},
+	{true, true, true, true, `]`, `]`},
+	{false, false, true, true, `foo/baz/bar`, `foo*bar`},
+	{false, false, true, true, `foo/baz/bar`, `foo**bar`},
+	{true, true, true, true, `foobazbar`, `foo**bar`},
+	{true, true, true, true, `foo/baz/bar`, `foo/**/bar`},
+	{true, true, false, false, `foo/baz/bar`, `foo/**/**/bar`},
+	{true, true, true, true, `foo/b/a/z/bar`, `foo/**/bar`},
+	{true, true, true, true, `foo/b/a/z/bar`, `foo/**/**/bar`},
+	{true, true, false, false, `foo/bar`, `foo/**/bar`},
+	{true, true, false, false, `foo/bar`, `foo/**/**/bar`},
+	{false, false, true, true, `foo/bar`, `foo?bar`},
+	{false, false, true, true, `foo/bar`, `foo[/]bar`},
+	{false, false, true, true, `foo/bar`, `foo[^a-z]bar`},
+	{false, false, true, true, `foo/bar`, `f[^eiu][^eiu][^eiu][^eiu][^eiu]r`},
+	{true, true, true, true, `foo-bar`, `f[^eiu][^eiu][^eiu][^eiu][^eiu]r`},
+	{true, true, false, false, `foo`, `**/foo`},
+	{true, true, true, true, `XXX/foo`, `**/foo`},
+	{true, true, true, true, `bar/baz/foo`, `**/foo`},
+	{false, false, true, true, `bar/baz/foo`, `*/foo`},
+	{false, false, true, true, `foo/bar/baz`, `**/bar*`},
+	{true, true, true, true, `deep/foo/bar/baz`, `**/bar/*`},
+	{false, false, true, true, `deep/foo/bar/baz/`, `**/bar/*`},
+	{true, true, true, true, `deep/foo/bar/baz/`, `**/bar/**`},
+	{false, false, false, false, `deep/foo/bar`, `**/bar/*`},
+	{true, true, true, true, `deep/foo/bar/`, `**/bar/**`},
+	{false, false, true, true, `foo/bar/baz`, `**/bar**`},
+	{true, true, true, true, `foo/bar/baz/x`, `*/bar/**`},
+	{false, false, true, true, `deep/foo/bar/baz/x`, `*/bar/**`},
+	{true, true, true, true, `deep/foo/bar/baz/x`, `**/bar/*/*`},
+	{false, false, false, false, `acrt`, `a[c-c]st`},
+	{true, true, true, true, `acrt`, `a[c-c]rt`},
+	{false, false, false, false, `]`, `[!]-]`},
+	{true, true, true, true, `a`, `[!]-]`},
+	{false, false, false, false, `XXX/\`, `*/\`},
+	{true, true, true, true, `XXX/\`, `*/\\`},
+	{true, true, true, true, `@foo`, `@foo`},
+	{false, false, false, false, `foo`, `@foo`},
+	{true, true, true, true, `[ab]`, `\[ab]`},
+	{true, true, true, true, `[ab]`, `[[]ab]`},
+	{true, true, true, true, `[ab]`, `[[:]ab]`},
+	{false, false, false, false, `[ab]`, `[[::]ab]`},
+	{true, true, true, true, `[ab]`, `[[:digit]ab]`},
+	{true, true, true, true, `[ab]`, `[\[:]ab]`},
+	{true, true, true, true, `?a?b`, `\??\?b`},
+	{true, true, true, true, `abc`, `\a\b\c`},
+	{true, true, true, true, `foo/bar/baz/to`, `**/t[o]`},
+	{true, true, true, true, `a1B`, `[[:alpha:]][[:digit:]][[:upper:]]`},
+	{false, true, false, true, `a`, `[[:digit:][:upper:][:space:]]`},
+	{true, true, true, true, `A`, `[[:digit:][:upper:][:space:]]`},
+	{true, true, true, true, `1`, `[[:digit:][:upper:][:space:]]`},
+	{false, false, false, false, `1`, `[[:digit:][:upper:][:spaci:]]`},
+	{false, false, false, false, `.`, `[[:digit:][:upper:][:space:]]`},
+	{true, true, true, true, `.`, `[[:digit:][:punct:][:space:]]`},
+	{true, true, true, true, `5`, `[[:xdigit:]]`},
+	{true, true, true, true, `f`, `[[:xdigit:]]`},
+	{true, true, true, true, `D`, `[[:xdigit:]]`},
+	{true, true, true, true, `_`, `[[:alnum:][:alpha:][:blank:][:cntrl:][:digit:][:graph:][:lower:][:print:][:punct:][:space:][:upper:][:xdigit:]]`},
+	{true, true, true, true, `.`, `[^[:alnum:][:alpha:][:blank:][:cntrl:][:digit:][:lower:][:space:][:upper:][:xdigit:]]`},
+	{true, true, true, true, `5`, `[a-c[:digit:]x-z]`},
+	{true, true, true, true, `b`, `[a-c[:digit:]x-z]`},
+	{true, true, true, true, `y`, `[a-c[:digit:]x-z]`},
+	{false, false, false, false, `q`, `[a-c[:digit:]x-z]`},
+	{true, true, true, true, `]`, `[\\-^]`},
+	{false, false, false, false, `[`, `[\\-^]`},
+	{true, true, true, true, `-`, `[\-_]`},
+	{true, true, true, true, `]`, `[\]]`},
+	{false, false, false, false, `\]`, `[\]]`},
+	{false, false, false, false, `\`, `[\]]`},
+	{false, false, false, false, `ab`, `a[]b`},
+	{false, false, false, false, `a[]b`, `a[]b`},
+	{false, false, false, false, `ab[`, `ab[`},
+	{false, false, false, false, `ab`, `[!`},
+	{false, false, false, false, `ab`, `[-`},
+	{true, true, true, true, `-`, `[-]`},
+	{false, false, false, false, `-`, `[a-`},
+	{false, false, false, false, `-`, `[!a-`},
+	{true, true, true, true, `-`, `[--A]`},
+	{true, true, true, true, `5`, `[--A]`},
+	{true, true, true, true, `$`, `[ --]`},
+	{true, true, true, true, `-`, `[ --]`},
+	{false, false, false, false, `0`, `[ --]`},
+	{true, true, true, true, `-`, `[---]`},
+	{true, true, true, true, `-`, `[------]`},
+	{false, false, false, false, `j`, `[a-e-n]`},
+	{true, true, true, true, `-`, `[a-e-n]`},
+	{true, true, true, true, `a`, `[!------]`},
+	{false, false, false, false, `[`, `[]-a]`},
+	{true, true, true, true, `^`, `[]-a]`},
+	{false, false, false, false, `^`, `[!]-a]`},
+	{true, true, true, true, `[`, `[!]-a]`},
+	{true, true, true, true, `^`, `[a^bc]`},
+	{true, true, true, true, `-b]`, `[a-]b]`},
+	{false, false, false, false, `\`, `[\]`},
+	{true, true, true, true, `\`, `[\\]`},
+	{false, false, false, false, `\`, `[!\\]`},
+	{true, true, true, true, `G`, `[A-\\]`},
+	{false, false, false, false, `aaabbb`, `b*a`},
+	{false, false, false, false, `aabcaa`, `*ba*`},
+	{true, true, true, true, `,`, `[,]`},
+	{true, true, true, true, `,`, `[\\,]`},
+	{true, true, true, true, `\`, `[\\,]`},
+	{true, true, true, true, `-`, `[,-.]`},
+	{false, false, false, false, `+`, `[,-.]`},
+	{false, false, false, false, `-.]`, `[,-.]`},
+	{true, true, true, true, `2`, `[\1-\3]`},
+	{true, true, true, true, `3`, `[\1-\3]`},
+	{false, false, false, false, `4`, `[\1-\3]`},
+	{true, true, true, true, `\`, `[[-\]]`},
+	{true, true, true, true, `[`, `[[-\]]`},
+	{true, true, true, true, `]`, `[[-\]]`},
+	{false, false, false, false, `-`, `[[-\]]`},
+	{true, true, true, true, `-adobe-courier-bold-o-normal--12-120-75-75-m-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{false, false, false, false, `-adobe-courier-bold-o-normal--12-120-75-75-X-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{false, false, false, false, `-adobe-courier-bold-o-normal--12-120-75-75-/-70-iso8859-1`, `-*-*-*-*-*-*-12-*-*-*-m-*-*-*`},
+	{true, true, true, true, `XXX/adobe/courier/bold/o/normal//12/120/75/75/m/70/iso8859/1`, `XXX/*/*/*/*/*/*/12/*/*/*/m/*/*/*`},
+	{false, false, false, false, `XXX/adobe/courier/bold/o/normal//12/120/75/75/X/70/iso8859/1`, `XXX/*/*/*/*/*/*/12/*/*/*/m/*/*/*`},
+	{true, true, true, true, `abcd/abcdefg/abcdefghijk/abcdefghijklmnop.txt`, `**/*a*b*g*n*t`},
+	{false, false, false, false, `abcd/abcdefg/abcdefghijk/abcdefghijklmnop.txtz`, `**/*a*b*g*n*t`},
+	{false, false, false, false, `foo`, `*/*/*`},
+	{false, false, false, false, `foo/bar`, `*/*/*`},
+	{true, true, true, true, `foo/bba/arr`, `*/*/*`},
+	{false, false, true, true, `foo/bb/aa/rr`, `*/*/*`},
+	{true, true, true, true, `foo/bb/aa/rr`, `**/**/**`},
+	{true, true, true, true, `abcXdefXghi`, `*X*i`},
+	{false, false, true, true, `ab/cXd/efXg/hi`, `*X*i`},
+	{true, true, true, true, `ab/cXd/efXg/hi`, `*/*X*/*/*i`},
+	{true, true, true, true, `ab/cXd/efXg/hi`, `**/*X*/**/*i`},
+	{false, true, false, true, `a`, `[A-Z]`},
+	{true, true, true, true, `A`, `[A-Z]`},
+	{false, true, false, true, `A`, `[a-z]`},
+	{true, true, true, true, `a`, `[a-z]`},
+	{false, true, false, true, `a`, `[[:upper:]]`},
+	{true, true, true, true, `A`, `[[:upper:]]`},
+	{false, true, false, true, `A`, `[[:lower:]]`},
+	{true, true, true, true, `a`, `[[:lower:]]`},
+	{false, true, false, true, `A`, `[B-Za]`},
+	{true, true, true, true, `a`, `[B-Za]`},
+	{false, true, false, true, `A`, `[B-a]`},
+	{true, true, true, true, `a`, `[B-a]`},
+	{false, true, false, true, `z`, `[Z-y]`},
+	{true, true, true, true, `Z`, `[Z-y]`},
+}
+
+func TestMatch(t *testing.T) {
+	for _, tt := range tests {
+		for _, c := range []struct {
+			name  string
+			flags Flag
+			want  bool
+		}{
+			{"glob", PathName, tt.glob},
+			{"iglob", PathName | CaseFold, tt.iglob},
+			{"pathmatch", 0, tt.pathmatch},
+			{"ipathmatch", CaseFold, tt.ipathmatch},
+		} {
+			if got := Match(tt.pattern, tt.text, c.flags); got != c.want {
+				t.Errorf("%s: Match(%q, %q) = %v, want %v", c.name, tt.pattern, tt.text, got, c.want)
+			}
+		}
+	}
+}